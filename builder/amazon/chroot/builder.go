@@ -25,13 +25,21 @@ type Config struct {
 	common.PackerConfig    `mapstructure:",squash"`
 	awscommon.AccessConfig `mapstructure:",squash"`
 	awscommon.AMIConfig    `mapstructure:",squash"`
-
-	ChrootMounts   [][]string `mapstructure:"chroot_mounts"`
-	CommandWrapper string     `mapstructure:"command_wrapper"`
-	CopyFiles      []string   `mapstructure:"copy_files"`
-	DevicePath     string     `mapstructure:"device_path"`
-	MountPath      string     `mapstructure:"mount_path"`
-	SourceAmi      string     `mapstructure:"source_ami"`
+	awscommon.BlockDevices `mapstructure:",squash"`
+
+	AMIArchitecture  string                     `mapstructure:"ami_architecture"`
+	ChrootMounts     [][]string                 `mapstructure:"chroot_mounts"`
+	CommandWrapper   string                     `mapstructure:"command_wrapper"`
+	CopyFiles        []string                   `mapstructure:"copy_files"`
+	DevicePath       string                     `mapstructure:"device_path"`
+	FromScratch      bool                       `mapstructure:"from_scratch"`
+	MountPartition   int                        `mapstructure:"mount_partition"`
+	MountPath        string                     `mapstructure:"mount_path"`
+	PreMountCommands []string                   `mapstructure:"pre_mount_commands"`
+	RootDeviceName   string                     `mapstructure:"root_device_name"`
+	RootVolumeSize   int64                      `mapstructure:"root_volume_size"`
+	SourceAmi        string                     `mapstructure:"source_ami"`
+	SourceAmiFilter  awscommon.AMIFilterOptions `mapstructure:"source_ami_filter"`
 
 	tpl *packer.ConfigTemplate
 }
@@ -121,13 +129,49 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		}
 	}
 
-	if b.config.SourceAmi == "" {
-		errs = packer.MultiErrorAppend(errs, errors.New("source_ami is required."))
+	for i, command := range b.config.PreMountCommands {
+		if err := b.config.tpl.Validate(command); err != nil {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("Error parsing pre_mount_commands[%d]: %s", i, err))
+		}
+	}
+
+	if b.config.MountPartition < 0 {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("mount_partition can't be negative."))
 	}
 
+	if len(b.config.LaunchMappings) > 0 {
+		errs = packer.MultiErrorAppend(errs, errors.New(
+			"launch_block_device_mappings isn't used by the amazon-chroot builder."))
+	}
+
+	if b.config.FromScratch {
+		errs = packer.MultiErrorAppend(errs, b.config.prepareFromScratch()...)
+	} else {
+		if b.config.SourceAmi == "" && b.config.SourceAmiFilter.Empty() {
+			errs = packer.MultiErrorAppend(
+				errs, errors.New("source_ami or source_ami_filter is required."))
+		} else if b.config.SourceAmi != "" && !b.config.SourceAmiFilter.Empty() {
+			errs = packer.MultiErrorAppend(errs, errors.New(
+				"Only one of source_ami or source_ami_filter can be specified."))
+		}
+
+		if len(b.config.AMIMappings) > 0 || b.config.RootDeviceName != "" ||
+			b.config.AMIArchitecture != "" {
+			errs = packer.MultiErrorAppend(errs, errors.New(
+				"ami_architecture, ami_block_device_mappings and root_device_name "+
+					"can only be specified with from_scratch."))
+		}
+	}
+
+	errs = packer.MultiErrorAppend(
+		errs, b.config.SourceAmiFilter.Prepare(b.config.tpl)...)
+
 	templates := map[string]*string{
-		"device_path": &b.config.DevicePath,
-		"source_ami":  &b.config.SourceAmi,
+		"ami_architecture": &b.config.AMIArchitecture,
+		"device_path":      &b.config.DevicePath,
+		"source_ami":       &b.config.SourceAmi,
 	}
 
 	for n, ptr := range templates {
@@ -143,10 +187,66 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		return nil, errs
 	}
 
-	log.Println(common.ScrubConfig(b.config, b.config.AccessKey, b.config.SecretKey))
+	log.Println(common.ScrubConfig(b.config, b.config.AccessKey, b.config.SecretKey, b.config.Token))
 	return nil, nil
 }
 
+// prepareFromScratch validates the settings of a build that creates its
+// root volume from scratch, rather than from the snapshot of a source AMI.
+func (c *Config) prepareFromScratch() []error {
+	var errs []error
+	if c.SourceAmi != "" || !c.SourceAmiFilter.Empty() {
+		errs = append(errs, errors.New(
+			"source_ami and source_ami_filter can't be specified with from_scratch."))
+	}
+
+	if len(c.PreMountCommands) == 0 {
+		errs = append(errs, errors.New(
+			"pre_mount_commands is required with from_scratch."))
+	}
+
+	if c.RootVolumeSize <= 0 {
+		errs = append(errs, errors.New(
+			"root_volume_size is required with from_scratch."))
+	}
+
+	if c.AMIVirtType == "" {
+		errs = append(errs, errors.New(
+			"ami_virtualization_type is required with from_scratch."))
+	}
+
+	if c.AMIArchitecture == "" {
+		c.AMIArchitecture = "x86_64"
+	}
+	if c.AMIArchitecture != "i386" && c.AMIArchitecture != "x86_64" {
+		errs = append(errs, errors.New(
+			"ami_architecture must be one of 'i386' or 'x86_64'."))
+	}
+
+	if c.RootDeviceName == "" {
+		errs = append(errs, errors.New(
+			"root_device_name is required with from_scratch."))
+	} else if c.rootDevice() == nil {
+		errs = append(errs, fmt.Errorf(
+			"ami_block_device_mappings must map the root device %s.",
+			c.RootDeviceName))
+	}
+
+	return errs
+}
+
+// rootDevice returns the ami_block_device_mappings entry of the root
+// device, or nil if there isn't one.
+func (c *Config) rootDevice() *awscommon.BlockDevice {
+	for i, device := range c.AMIMappings {
+		if device.DeviceName == c.RootDeviceName {
+			return &c.AMIMappings[i]
+		}
+	}
+
+	return nil
+}
+
 func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
 	if runtime.GOOS != "linux" {
 		return nil, errors.New("The amazon-chroot builder only works on Linux environments.")
@@ -162,7 +262,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		return nil, err
 	}
 
-	ec2conn := ec2.New(auth, region)
+	ec2conn := awscommon.NewEC2(auth, region)
 
 	wrappedCommand := func(command string) (string, error) {
 		return b.config.tpl.Process(
@@ -174,6 +274,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	// Setup the state bag and initial state for the steps
 	state := new(multistep.BasicStateBag)
 	state.Put("config", &b.config)
+	state.Put("aws_polling", &b.config.AWSPollingConfig)
 	state.Put("ec2", ec2conn)
 	state.Put("hook", hook)
 	state.Put("ui", ui)
@@ -182,12 +283,23 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	// Build the steps
 	steps := []multistep.Step{
 		&StepInstanceInfo{},
-		&StepSourceAMIInfo{},
+	}
+
+	if !b.config.FromScratch {
+		steps = append(steps, &awscommon.StepSourceAMIInfo{
+			SourceAMI:          b.config.SourceAmi,
+			AMIFilter:          b.config.SourceAmiFilter,
+			ExpectedRootDevice: "ebs",
+		})
+	}
+
+	steps = append(steps,
 		&StepFlock{},
 		&StepPrepareDevice{},
 		&StepCreateVolume{},
 		&StepAttachVolume{},
 		&StepEarlyUnflock{},
+		&StepPreMountCommands{},
 		&StepMountDevice{},
 		&StepMountExtra{},
 		&StepCopyFiles{},
@@ -196,17 +308,26 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&StepSnapshot{},
 		&StepRegisterAMI{},
 		&awscommon.StepAMIRegionCopy{
-			Regions: b.config.AMIRegions,
+			Name:              b.config.AMIName,
+			Regions:           b.config.AMIRegions,
+			EncryptBootVolume: b.config.AMIEncryptBootVolume,
+			KmsKeyId:          b.config.AMIKmsKeyId,
+			RegionKmsKeyIds:   b.config.AMIRegionKmsKeyIds,
 		},
 		&awscommon.StepModifyAMIAttributes{
-			Description: b.config.AMIDescription,
-			Users:       b.config.AMIUsers,
-			Groups:      b.config.AMIGroups,
+			Description:    b.config.AMIDescription,
+			Tpl:            b.config.tpl,
+			Users:          b.config.AMIUsers,
+			Groups:         b.config.AMIGroups,
+			SnapshotUsers:  b.config.AMISnapshotUsers,
+			SnapshotGroups: b.config.AMISnapshotGroups,
 		},
 		&awscommon.StepCreateTags{
-			Tags: b.config.AMITags,
+			Tags:         b.config.AMITags,
+			SnapshotTags: b.config.SnapshotTags,
+			Tpl:          b.config.tpl,
 		},
-	}
+	)
 
 	// Run!
 	if b.config.PackerDebug {
@@ -237,6 +358,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		Conn:           ec2conn,
 	}
 
+	if sourceImage, ok := state.GetOk("source_image"); ok {
+		artifact.SourceAmi = sourceImage.(*ec2.Image).Id
+	}
+
 	return artifact, nil
 }
 
@@ -104,6 +104,37 @@ func TestBuilderPrepare_SourceAmi(t *testing.T) {
 	}
 }
 
+func TestBuilderPrepare_SourceAmiFilter(t *testing.T) {
+	b := &Builder{}
+	config := testConfig()
+
+	delete(config, "source_ami")
+	config["source_ami_filter"] = map[string]interface{}{
+		"filters": map[string]string{
+			"name": "amzn-ami-hvm-*",
+		},
+		"owners":      []string{"amazon"},
+		"most_recent": true,
+	}
+	warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	config["source_ami"] = "foo"
+	b = &Builder{}
+	warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
 func TestBuilderPrepare_CommandWrapper(t *testing.T) {
 	b := &Builder{}
 	config := testConfig()
@@ -117,3 +148,99 @@ func TestBuilderPrepare_CommandWrapper(t *testing.T) {
 		t.Errorf("err: %s", err)
 	}
 }
+
+func TestBuilderPrepare_FromScratch(t *testing.T) {
+	b := &Builder{}
+	config := testConfig()
+	delete(config, "source_ami")
+	config["from_scratch"] = true
+	config["pre_mount_commands"] = []string{"mkfs.ext4 {{.Device}}"}
+	config["root_volume_size"] = 8
+	config["root_device_name"] = "/dev/sda1"
+	config["ami_virtualization_type"] = "hvm"
+	config["ami_block_device_mappings"] = []map[string]interface{}{
+		{"device_name": "/dev/sda1", "volume_type": "gp2"},
+	}
+
+	warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if b.config.AMIArchitecture != "x86_64" {
+		t.Fatalf("bad: %s", b.config.AMIArchitecture)
+	}
+
+	// Only EC2 architectures are allowed
+	config["ami_architecture"] = "arm"
+	b = &Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+	config["ami_architecture"] = "i386"
+	b = &Builder{}
+	if _, err := b.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	delete(config, "ami_architecture")
+
+	// The root device must be mapped
+	config["root_device_name"] = "/dev/xvda"
+	b = &Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+	config["root_device_name"] = "/dev/sda1"
+
+	// A source AMI can't be used
+	config["source_ami"] = "foo"
+	b = &Builder{}
+	if _, err := b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+	delete(config, "source_ami")
+
+	for _, key := range []string{"pre_mount_commands", "root_volume_size", "ami_virtualization_type"} {
+		value := config[key]
+		delete(config, key)
+		b = &Builder{}
+		if _, err := b.Prepare(config); err == nil {
+			t.Fatalf("should have error without %s", key)
+		}
+		config[key] = value
+	}
+}
+
+func TestBuilderPrepare_AMIBlockDeviceMappings(t *testing.T) {
+	b := &Builder{}
+	config := testConfig()
+
+	// Only used when building from scratch
+	config["ami_block_device_mappings"] = []map[string]interface{}{
+		{"device_name": "/dev/sda1"},
+	}
+	warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_AMIArchitecture(t *testing.T) {
+	b := &Builder{}
+	config := testConfig()
+
+	// Only used when building from scratch
+	config["ami_architecture"] = "i386"
+	warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
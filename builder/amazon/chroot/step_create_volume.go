@@ -9,8 +9,11 @@ import (
 	"log"
 )
 
-// StepCreateVolume creates a new volume from the snapshot of the root
-// device of the AMI.
+// StepCreateVolume creates the root volume, either from the snapshot of
+// the root device of the source AMI, or empty when building from scratch.
+//
+// Uses:
+//   source_image *ec2.Image - unless building from scratch
 //
 // Produces:
 //   volume_id string - The ID of the created volume
@@ -19,36 +22,64 @@ type StepCreateVolume struct {
 }
 
 func (s *StepCreateVolume) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
 	ec2conn := state.Get("ec2").(*ec2.EC2)
-	image := state.Get("source_image").(*ec2.Image)
 	instance := state.Get("instance").(*ec2.Instance)
 	ui := state.Get("ui").(packer.Ui)
 
-	// Determine the root device snapshot
-	log.Printf("Searching for root device of the image (%s)", image.RootDeviceName)
-	var rootDevice *ec2.BlockDeviceMapping
-	for _, device := range image.BlockDevices {
-		if device.DeviceName == image.RootDeviceName {
-			rootDevice = &device
-			break
+	var createVolume *ec2.CreateVolume
+	if config.FromScratch {
+		rootDevice := config.rootDevice()
+		createVolume = &ec2.CreateVolume{
+			AvailZone:  instance.AvailZone,
+			Size:       config.RootVolumeSize,
+			VolumeType: rootDevice.VolumeType,
+			IOPS:       rootDevice.IOPS,
 		}
-	}
+	} else {
+		image := state.Get("source_image").(*ec2.Image)
 
-	if rootDevice == nil {
-		err := fmt.Errorf("Couldn't find root device!")
-		state.Put("error", err)
-		ui.Error(err.Error())
-		return multistep.ActionHalt
+		// Determine the root device snapshot
+		log.Printf("Searching for root device of the image (%s)", image.RootDeviceName)
+		var rootDevice *ec2.BlockDeviceMapping
+		for _, device := range image.BlockDevices {
+			if device.DeviceName == image.RootDeviceName {
+				rootDevice = &device
+				break
+			}
+		}
+
+		if rootDevice == nil {
+			err := fmt.Errorf("Couldn't find root device!")
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		size := rootDevice.VolumeSize
+		if config.RootVolumeSize != 0 {
+			if config.RootVolumeSize < size {
+				err := fmt.Errorf(
+					"root_volume_size (%d GB) is smaller than the root device "+
+						"of the source AMI (%d GB).", config.RootVolumeSize, size)
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+
+			size = config.RootVolumeSize
+		}
+
+		createVolume = &ec2.CreateVolume{
+			AvailZone:  instance.AvailZone,
+			Size:       size,
+			SnapshotId: rootDevice.SnapshotId,
+			VolumeType: rootDevice.VolumeType,
+			IOPS:       rootDevice.IOPS,
+		}
 	}
 
 	ui.Say("Creating the root volume...")
-	createVolume := &ec2.CreateVolume{
-		AvailZone:  instance.AvailZone,
-		Size:       rootDevice.VolumeSize,
-		SnapshotId: rootDevice.SnapshotId,
-		VolumeType: rootDevice.VolumeType,
-		IOPS:       rootDevice.IOPS,
-	}
 	log.Printf("Create args: %#v", createVolume)
 
 	createVolumeResp, err := ec2conn.CreateVolume(createVolume)
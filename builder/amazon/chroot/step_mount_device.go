@@ -57,6 +57,11 @@ func (s *StepMountDevice) Run(state multistep.StateBag) multistep.StepAction {
 		return multistep.ActionHalt
 	}
 
+	// The volume is mounted as a whole unless it has been partitioned
+	if config.MountPartition > 0 {
+		device = fmt.Sprintf("%s%d", device, config.MountPartition)
+	}
+
 	ui.Say("Mounting the root device...")
 	stderr := new(bytes.Buffer)
 	mountCommand, err := wrappedCommand(
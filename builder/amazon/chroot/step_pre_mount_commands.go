@@ -0,0 +1,69 @@
+package chroot
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	"log"
+)
+
+type preMountCommandsData struct {
+	Device string
+}
+
+// StepPreMountCommands runs the pre_mount_commands on the host, once the
+// volume is attached but before it is mounted. This is where a volume
+// created from scratch is partitioned and formatted.
+type StepPreMountCommands struct{}
+
+func (s *StepPreMountCommands) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	device := state.Get("device").(string)
+	ui := state.Get("ui").(packer.Ui)
+	wrappedCommand := state.Get("wrappedCommand").(CommandWrapper)
+
+	if len(config.PreMountCommands) == 0 {
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Running pre-mount commands...")
+	for _, rawCommand := range config.PreMountCommands {
+		command, err := config.tpl.Process(rawCommand, &preMountCommandsData{
+			Device: device,
+		})
+		if err != nil {
+			err := fmt.Errorf("Error processing pre-mount command: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		command, err = wrappedCommand(command)
+		if err != nil {
+			err := fmt.Errorf("Error creating pre-mount command: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		ui.Message(fmt.Sprintf("Executing: %s", command))
+		output := new(bytes.Buffer)
+		cmd := ShellCommand(command)
+		cmd.Stdout = output
+		cmd.Stderr = output
+		err = cmd.Run()
+		log.Printf("Pre-mount command output:\n%s", output.String())
+		if err != nil {
+			err := fmt.Errorf(
+				"Error running pre-mount command: %s\nOutput: %s", err, output.String())
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepPreMountCommands) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,71 @@
+package chroot
+
+import (
+	"bytes"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStepPreMountCommands_impl(t *testing.T) {
+	var _ multistep.Step = new(StepPreMountCommands)
+}
+
+func TestStepPreMountCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tpl, err := packer.NewConfigTemplate()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	output := filepath.Join(dir, "output")
+	config := &Config{
+		PreMountCommands: []string{
+			"echo {{.Device}} > " + output,
+			"echo wrapped >> " + output,
+		},
+		tpl: tpl,
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("config", config)
+	state.Put("device", "/dev/xvdf")
+	state.Put("ui", &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	})
+	state.Put("wrappedCommand", CommandWrapper(func(command string) (string, error) {
+		return "true && " + command, nil
+	}))
+
+	step := new(StepPreMountCommands)
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad: %#v", state.Get("error"))
+	}
+
+	contents, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if strings.TrimSpace(string(contents)) != "/dev/xvdf\nwrapped" {
+		t.Fatalf("bad: %q", contents)
+	}
+
+	// A failing command halts the build
+	config.PreMountCommands = []string{"false"}
+	if action := step.Run(state); action != multistep.ActionHalt {
+		t.Fatal("should halt")
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
@@ -5,37 +5,57 @@ import (
 	"github.com/mitchellh/goamz/ec2"
 	"github.com/mitchellh/multistep"
 	awscommon "github.com/mitchellh/packer/builder/amazon/common"
+	"github.com/mitchellh/packer/common/uuid"
 	"github.com/mitchellh/packer/packer"
 )
 
-// StepRegisterAMI creates the AMI.
+// StepRegisterAMI creates the AMI. Its block devices are those of the
+// source AMI, or the ami_block_device_mappings when building from scratch,
+// with the root device backed by the new snapshot.
 type StepRegisterAMI struct{}
 
 func (s *StepRegisterAMI) Run(state multistep.StateBag) multistep.StepAction {
 	config := state.Get("config").(*Config)
 	ec2conn := state.Get("ec2").(*ec2.EC2)
-	image := state.Get("source_image").(*ec2.Image)
 	snapshotId := state.Get("snapshot_id").(string)
 	ui := state.Get("ui").(packer.Ui)
 
+	// An encrypted copy of the AMI takes its name later, which EC2 only
+	// allows if this one has another name.
+	name := config.AMIName
+	if config.AMIEncryptBootVolume {
+		name = fmt.Sprintf("packer-tmp-%s", uuid.TimeOrderedUUID())
+	}
+
 	ui.Say("Registering the AMI...")
-	blockDevices := make([]ec2.BlockDeviceMapping, len(image.BlockDevices))
-	for i, device := range image.BlockDevices {
-		newDevice := device
-		if newDevice.DeviceName == image.RootDeviceName {
-			newDevice.SnapshotId = snapshotId
+	var registerOpts *ec2.RegisterImage
+	if config.FromScratch {
+		registerOpts = &ec2.RegisterImage{
+			Name:           name,
+			Architecture:   config.AMIArchitecture,
+			RootDeviceName: config.RootDeviceName,
+			VirtType:       config.AMIVirtType,
+			BlockDevices: rootSnapshotDevices(
+				config.BuildAMIDevices(), config.RootDeviceName, snapshotId),
 		}
+	} else {
+		image := state.Get("source_image").(*ec2.Image)
 
-		blockDevices[i] = newDevice
-	}
+		virtType := config.AMIVirtType
+		if virtType == "" {
+			virtType = image.VirtualizationType
+		}
 
-	registerOpts := &ec2.RegisterImage{
-		Name:           config.AMIName,
-		Architecture:   image.Architecture,
-		KernelId:       image.KernelId,
-		RamdiskId:      image.RamdiskId,
-		RootDeviceName: image.RootDeviceName,
-		BlockDevices:   blockDevices,
+		registerOpts = &ec2.RegisterImage{
+			Name:           name,
+			Architecture:   image.Architecture,
+			KernelId:       image.KernelId,
+			RamdiskId:      image.RamdiskId,
+			RootDeviceName: image.RootDeviceName,
+			VirtType:       virtType,
+			BlockDevices: rootSnapshotDevices(
+				image.BlockDevices, image.RootDeviceName, snapshotId),
+		}
 	}
 
 	registerResp, err := ec2conn.RegisterImage(registerOpts)
@@ -71,3 +91,19 @@ func (s *StepRegisterAMI) Run(state multistep.StateBag) multistep.StepAction {
 }
 
 func (s *StepRegisterAMI) Cleanup(state multistep.StateBag) {}
+
+// rootSnapshotDevices returns a copy of the block devices, with the root
+// device backed by the given snapshot.
+func rootSnapshotDevices(devices []ec2.BlockDeviceMapping, rootDeviceName, snapshotId string) []ec2.BlockDeviceMapping {
+	result := make([]ec2.BlockDeviceMapping, len(devices))
+	for i, device := range devices {
+		newDevice := device
+		if newDevice.DeviceName == rootDeviceName {
+			newDevice.SnapshotId = snapshotId
+		}
+
+		result[i] = newDevice
+	}
+
+	return result
+}
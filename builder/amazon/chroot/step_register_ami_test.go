@@ -0,0 +1,28 @@
+package chroot
+
+import (
+	"github.com/mitchellh/goamz/ec2"
+	"reflect"
+	"testing"
+)
+
+func TestRootSnapshotDevices(t *testing.T) {
+	devices := []ec2.BlockDeviceMapping{
+		{DeviceName: "/dev/sda1", VolumeType: "gp2"},
+		{DeviceName: "/dev/sdb", VirtualName: "ephemeral0"},
+	}
+
+	result := rootSnapshotDevices(devices, "/dev/sda1", "snap-1234")
+	expected := []ec2.BlockDeviceMapping{
+		{DeviceName: "/dev/sda1", VolumeType: "gp2", SnapshotId: "snap-1234"},
+		{DeviceName: "/dev/sdb", VirtualName: "ephemeral0"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	// The given devices are left alone
+	if devices[0].SnapshotId != "" {
+		t.Fatalf("bad: %#v", devices)
+	}
+}
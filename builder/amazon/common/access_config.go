@@ -1,33 +1,104 @@
 package common
 
 import (
+	"errors"
 	"fmt"
 	"github.com/mitchellh/goamz/aws"
 	"github.com/mitchellh/packer/packer"
 	"strings"
+	"time"
 	"unicode"
 )
 
 // AccessConfig is for common configuration related to AWS access
 type AccessConfig struct {
-	AccessKey string `mapstructure:"access_key"`
-	SecretKey string `mapstructure:"secret_key"`
-	RawRegion string `mapstructure:"region"`
+	AccessKey        string           `mapstructure:"access_key"`
+	AssumeRoleArn    string           `mapstructure:"assume_role_arn"`
+	ExternalId       string           `mapstructure:"external_id"`
+	MFACode          string           `mapstructure:"mfa_code"`
+	MFASerial        string           `mapstructure:"mfa_serial"`
+	Profile          string           `mapstructure:"profile"`
+	SecretKey        string           `mapstructure:"secret_key"`
+	Token            string           `mapstructure:"token"`
+	RawRegion        string           `mapstructure:"region"`
+	AWSPollingConfig AWSPollingConfig `mapstructure:"aws_polling"`
+
+	creds *Credentials
 }
 
 // Auth returns a valid aws.Auth object for access to AWS services, or
-// an error if the authentication couldn't be resolved.
+// an error if the authentication couldn't be resolved. The credentials
+// of an assumed role are refreshed by calling Auth again.
 func (c *AccessConfig) Auth() (aws.Auth, error) {
-	auth, err := aws.GetAuth(c.AccessKey, c.SecretKey)
+	if c.creds == nil {
+		creds, err := c.credentials()
+		if err != nil {
+			return aws.Auth{}, err
+		}
+
+		c.creds = creds
+	}
+
+	auth, err := c.creds.Auth()
 	if err == nil {
 		// Store the accesskey and secret that we got...
 		c.AccessKey = auth.AccessKey
 		c.SecretKey = auth.SecretKey
+		c.Token = auth.Token
 	}
 
 	return auth, err
 }
 
+// credentials resolves the credentials to build with. They start from
+// the access key, the profile, or the environment, then an MFA session is
+// started if there is an MFA code, and finally the role is assumed.
+func (c *AccessConfig) credentials() (*Credentials, error) {
+	var auth aws.Auth
+	var err error
+	if c.Profile != "" {
+		auth, err = sharedCredentialsAuth(c.Profile)
+	} else {
+		auth, err = aws.GetAuth(c.AccessKey, c.SecretKey)
+		if c.AccessKey != "" && c.SecretKey != "" {
+			auth.Token = c.Token
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var expiration time.Time
+	if c.MFACode != "" {
+		session, err := stsGetSessionToken(auth, c.MFASerial, c.MFACode)
+		if err != nil {
+			return nil, fmt.Errorf("Error starting MFA session: %s", err)
+		}
+
+		auth = session.Auth()
+		expiration = session.Expiration
+	}
+
+	if c.AssumeRoleArn == "" {
+		return &Credentials{auth: auth, expiration: expiration}, nil
+	}
+
+	// The role is assumed by the first call to Auth
+	roleArn := c.AssumeRoleArn
+	externalId := c.ExternalId
+	return &Credentials{
+		refresh: func() (aws.Auth, time.Time, error) {
+			role, err := stsAssumeRole(auth, roleArn, externalId)
+			if err != nil {
+				return aws.Auth{}, time.Time{}, fmt.Errorf(
+					"Error assuming role %s: %s", roleArn, err)
+			}
+
+			return role.Auth(), role.Expiration, nil
+		},
+	}, nil
+}
+
 // Region returns the aws.Region object for access to AWS services, requesting
 // the region from the instance metadata if possible.
 func (c *AccessConfig) Region() (aws.Region, error) {
@@ -54,9 +125,15 @@ func (c *AccessConfig) Prepare(t *packer.ConfigTemplate) []error {
 	}
 
 	templates := map[string]*string{
-		"access_key": &c.AccessKey,
-		"secret_key": &c.SecretKey,
-		"region":     &c.RawRegion,
+		"access_key":      &c.AccessKey,
+		"assume_role_arn": &c.AssumeRoleArn,
+		"external_id":     &c.ExternalId,
+		"mfa_code":        &c.MFACode,
+		"mfa_serial":      &c.MFASerial,
+		"profile":         &c.Profile,
+		"secret_key":      &c.SecretKey,
+		"token":           &c.Token,
+		"region":          &c.RawRegion,
 	}
 
 	errs := make([]error, 0)
@@ -75,6 +152,28 @@ func (c *AccessConfig) Prepare(t *packer.ConfigTemplate) []error {
 		}
 	}
 
+	if c.Profile != "" && (c.AccessKey != "" || c.SecretKey != "") {
+		errs = append(errs, errors.New(
+			"Only one of profile or access_key and secret_key can be specified."))
+	}
+
+	if c.Token != "" && (c.AccessKey == "" || c.SecretKey == "") {
+		errs = append(errs, errors.New(
+			"token can only be specified with access_key and secret_key."))
+	}
+
+	if c.ExternalId != "" && c.AssumeRoleArn == "" {
+		errs = append(errs, errors.New(
+			"external_id can only be specified with assume_role_arn."))
+	}
+
+	if (c.MFACode == "") != (c.MFASerial == "") {
+		errs = append(errs, errors.New(
+			"mfa_code and mfa_serial must be specified together."))
+	}
+
+	errs = append(errs, c.AWSPollingConfig.Prepare()...)
+
 	if len(errs) > 0 {
 		return errs
 	}
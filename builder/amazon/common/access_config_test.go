@@ -25,3 +25,41 @@ func TestAccessConfigPrepare_Region(t *testing.T) {
 		t.Fatalf("shouldn't have err: %s", err)
 	}
 }
+
+func TestAccessConfigPrepare_AWSPolling(t *testing.T) {
+	c := testAccessConfig()
+	c.AWSPollingConfig = AWSPollingConfig{DelaySeconds: 10, MaxAttempts: 60}
+	if err := c.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	c.AWSPollingConfig = AWSPollingConfig{DelaySeconds: -1}
+	if err := c.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestAccessConfigPrepare_Credentials(t *testing.T) {
+	cases := []struct {
+		Config AccessConfig
+		Valid  bool
+	}{
+		{AccessConfig{Profile: "build"}, true},
+		{AccessConfig{Profile: "build", AccessKey: "foo"}, false},
+		{AccessConfig{AccessKey: "foo", SecretKey: "bar", Token: "baz"}, true},
+		{AccessConfig{Token: "baz"}, false},
+		{AccessConfig{AssumeRoleArn: "arn", ExternalId: "foo"}, true},
+		{AccessConfig{ExternalId: "foo"}, false},
+		{AccessConfig{MFACode: "123456", MFASerial: "arn"}, true},
+		{AccessConfig{MFACode: "123456"}, false},
+		{AccessConfig{MFASerial: "arn"}, false},
+	}
+
+	for _, tc := range cases {
+		c := tc.Config
+		err := c.Prepare(nil)
+		if (err == nil) != tc.Valid {
+			t.Fatalf("bad: %#v: %s", tc.Config, err)
+		}
+	}
+}
@@ -8,14 +8,20 @@ import (
 
 // AMIConfig is for common configuration related to creating AMIs.
 type AMIConfig struct {
-	AMIName         string            `mapstructure:"ami_name"`
-	AMIDescription  string            `mapstructure:"ami_description"`
-	AMIVirtType     string            `mapstructure:"ami_virtualization_type"`
-	AMIUsers        []string          `mapstructure:"ami_users"`
-	AMIGroups       []string          `mapstructure:"ami_groups"`
-	AMIProductCodes []string          `mapstructure:"ami_product_codes"`
-	AMIRegions      []string          `mapstructure:"ami_regions"`
-	AMITags         map[string]string `mapstructure:"tags"`
+	AMIName              string            `mapstructure:"ami_name"`
+	AMIDescription       string            `mapstructure:"ami_description"`
+	AMIVirtType          string            `mapstructure:"ami_virtualization_type"`
+	AMIUsers             []string          `mapstructure:"ami_users"`
+	AMIGroups            []string          `mapstructure:"ami_groups"`
+	AMIProductCodes      []string          `mapstructure:"ami_product_codes"`
+	AMIRegions           []string          `mapstructure:"ami_regions"`
+	AMITags              map[string]string `mapstructure:"tags"`
+	AMIEncryptBootVolume bool              `mapstructure:"encrypt_boot"`
+	AMIKmsKeyId          string            `mapstructure:"kms_key_id"`
+	AMIRegionKmsKeyIds   map[string]string `mapstructure:"region_kms_key_ids"`
+	AMISnapshotUsers     []string          `mapstructure:"snapshot_users"`
+	AMISnapshotGroups    []string          `mapstructure:"snapshot_groups"`
+	SnapshotTags         map[string]string `mapstructure:"snapshot_tags"`
 }
 
 func (c *AMIConfig) Prepare(t *packer.ConfigTemplate) []error {
@@ -29,8 +35,8 @@ func (c *AMIConfig) Prepare(t *packer.ConfigTemplate) []error {
 
 	templates := map[string]*string{
 		"ami_name":                &c.AMIName,
-		"ami_description":         &c.AMIDescription,
 		"ami_virtualization_type": &c.AMIVirtType,
+		"kms_key_id":              &c.AMIKmsKeyId,
 	}
 
 	errs := make([]error, 0)
@@ -43,11 +49,20 @@ func (c *AMIConfig) Prepare(t *packer.ConfigTemplate) []error {
 		}
 	}
 
+	// The description is processed once the source AMI is known, so it
+	// can only be validated here.
+	if err := t.Validate(c.AMIDescription); err != nil {
+		errs = append(
+			errs, fmt.Errorf("Error parsing ami_description: %s", err))
+	}
+
 	sliceTemplates := map[string][]string{
 		"ami_users":         c.AMIUsers,
 		"ami_groups":        c.AMIGroups,
 		"ami_product_codes": c.AMIProductCodes,
 		"ami_regions":       c.AMIRegions,
+		"snapshot_users":    c.AMISnapshotUsers,
+		"snapshot_groups":   c.AMISnapshotGroups,
 	}
 
 	for n, slice := range sliceTemplates {
@@ -90,26 +105,50 @@ func (c *AMIConfig) Prepare(t *packer.ConfigTemplate) []error {
 		c.AMIRegions = regions
 	}
 
-	newTags := make(map[string]string)
-	for k, v := range c.AMITags {
-		k, err := t.Process(k, nil)
+	if c.AMIKmsKeyId != "" && !c.AMIEncryptBootVolume {
+		errs = append(errs, fmt.Errorf("kms_key_id requires encrypt_boot to be true"))
+	}
+
+	// Volumes encrypted with the default EBS key of the account can't be
+	// shared, so neither can the AMI.
+	if c.AMIEncryptBootVolume && c.AMIKmsKeyId == "" {
+		shared := len(c.AMIUsers) > 0 || len(c.AMIGroups) > 0 || len(c.AMISnapshotUsers) > 0
+		if shared {
+			errs = append(errs, fmt.Errorf(
+				"encrypt_boot needs a kms_key_id to share the AMI with ami_users, ami_groups or snapshot_users"))
+		}
+	}
+
+	newKmsKeyIds := make(map[string]string)
+	for region, keyId := range c.AMIRegionKmsKeyIds {
+		keyId, err := t.Process(keyId, nil)
 		if err != nil {
 			errs = append(errs,
-				fmt.Errorf("Error processing tag key %s: %s", k, err))
+				fmt.Errorf("Error processing region_kms_key_ids[%s]: %s", region, err))
 			continue
 		}
 
-		v, err := t.Process(v, nil)
-		if err != nil {
-			errs = append(errs,
-				fmt.Errorf("Error processing tag value '%s': %s", v, err))
+		found := false
+		for _, r := range c.AMIRegions {
+			if r == region {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			errs = append(errs, fmt.Errorf(
+				"region_kms_key_ids has a key for %s, which isn't in ami_regions", region))
 			continue
 		}
 
-		newTags[k] = v
+		newKmsKeyIds[region] = keyId
 	}
 
-	c.AMITags = newTags
+	c.AMIRegionKmsKeyIds = newKmsKeyIds
+
+	errs = append(errs, validateTags(t, "tags", c.AMITags)...)
+	errs = append(errs, validateTags(t, "snapshot_tags", c.SnapshotTags)...)
 
 	if len(errs) > 0 {
 		return errs
@@ -45,3 +45,81 @@ func TestAMIConfigPrepare_regions(t *testing.T) {
 		t.Fatalf("bad: %#v", c.AMIRegions)
 	}
 }
+
+func TestAMIConfigPrepare_description(t *testing.T) {
+	c := testAMIConfig()
+	c.AMIDescription = "built from {{.SourceAMI}}"
+	if err := c.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	// Processed at build time, once the source AMI is known
+	if c.AMIDescription != "built from {{.SourceAMI}}" {
+		t.Fatalf("bad: %s", c.AMIDescription)
+	}
+
+	c.AMIDescription = "{{"
+	if err := c.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestAMIConfigPrepare_encryptBoot(t *testing.T) {
+	c := testAMIConfig()
+	c.AMIKmsKeyId = "key"
+	if err := c.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	c.AMIEncryptBootVolume = true
+	if err := c.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+}
+
+func TestAMIConfigPrepare_encryptBootShared(t *testing.T) {
+	c := testAMIConfig()
+	c.AMIEncryptBootVolume = true
+	c.AMIUsers = []string{"123456789012"}
+	if err := c.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	c.AMIUsers = nil
+	c.AMISnapshotUsers = []string{"123456789012"}
+	if err := c.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	c.AMIKmsKeyId = "alias/packer"
+	if err := c.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+}
+
+func TestAMIConfigPrepare_regionKmsKeyIds(t *testing.T) {
+	c := testAMIConfig()
+	c.AMIRegions = []string{"us-west-1"}
+	c.AMIRegionKmsKeyIds = map[string]string{"us-west-1": "key"}
+	if err := c.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	c.AMIRegionKmsKeyIds = map[string]string{"us-west-2": "key"}
+	if err := c.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestAMIConfigPrepare_snapshotTags(t *testing.T) {
+	c := testAMIConfig()
+	c.SnapshotTags = map[string]string{"Base": "{{.SourceAMI}}"}
+	if err := c.Prepare(testConfigTemplate(t)); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	c.SnapshotTags = map[string]string{"Base": "{{"}
+	if err := c.Prepare(testConfigTemplate(t)); err == nil {
+		t.Fatal("should have error")
+	}
+}
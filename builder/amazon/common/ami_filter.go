@@ -0,0 +1,68 @@
+package common
+
+import (
+	"fmt"
+	"github.com/mitchellh/packer/packer"
+)
+
+// AMIFilterOptions is the configuration of a source_ami_filter, which
+// finds the source AMI by searching for it rather than by its ID.
+type AMIFilterOptions struct {
+	// Filters are the EC2 DescribeImages filters, such as "name",
+	// "virtualization-type", "root-device-type" or "tag:<key>".
+	Filters map[string]string `mapstructure:"filters"`
+
+	// Owners limits the search to AMIs owned by these account IDs or
+	// aliases, such as "self" or "amazon".
+	Owners []string `mapstructure:"owners"`
+
+	// MostRecent picks the latest AMI if more than one matches, instead
+	// of failing.
+	MostRecent bool `mapstructure:"most_recent"`
+}
+
+// Empty returns true if no filter was configured.
+func (f *AMIFilterOptions) Empty() bool {
+	return len(f.Filters) == 0 && len(f.Owners) == 0
+}
+
+func (f *AMIFilterOptions) Prepare(t *packer.ConfigTemplate) []error {
+	errs := make([]error, 0)
+
+	newFilters := make(map[string]string)
+	for k, v := range f.Filters {
+		key, err := t.Process(k, nil)
+		if err != nil {
+			errs = append(errs,
+				fmt.Errorf("Error processing source_ami_filter key %s: %s", k, err))
+			continue
+		}
+
+		value, err := t.Process(v, nil)
+		if err != nil {
+			errs = append(errs,
+				fmt.Errorf("Error processing source_ami_filter value '%s': %s", v, err))
+			continue
+		}
+
+		newFilters[key] = value
+	}
+
+	f.Filters = newFilters
+
+	for i, owner := range f.Owners {
+		var err error
+		f.Owners[i], err = t.Process(owner, nil)
+		if err != nil {
+			errs = append(errs,
+				fmt.Errorf("Error processing source_ami_filter owners[%d]: %s", i, err))
+		}
+	}
+
+	if len(f.Filters) == 0 && len(f.Owners) > 0 {
+		errs = append(errs, fmt.Errorf(
+			"source_ami_filter needs at least one filter besides owners"))
+	}
+
+	return errs
+}
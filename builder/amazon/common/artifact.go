@@ -17,6 +17,9 @@ type Artifact struct {
 	// BuilderId is the unique ID for the builder that created this AMI
 	BuilderIdValue string
 
+	// SourceAmi is the ID of the AMI that the build started from.
+	SourceAmi string
+
 	// EC2 connection for performing API stuff.
 	Conn *ec2.EC2
 }
@@ -46,7 +49,12 @@ func (a *Artifact) String() string {
 		amiStrings = append(amiStrings, single)
 	}
 
-	return fmt.Sprintf("AMIs were created:\n\n%s", strings.Join(amiStrings, "\n"))
+	result := fmt.Sprintf("AMIs were created:\n\n%s", strings.Join(amiStrings, "\n"))
+	if a.SourceAmi != "" {
+		result += fmt.Sprintf("\n\nSource AMI: %s", a.SourceAmi)
+	}
+
+	return result
 }
 
 func (a *Artifact) Destroy() error {
@@ -54,7 +62,7 @@ func (a *Artifact) Destroy() error {
 
 	for region, imageId := range a.Amis {
 		log.Printf("Deregistering image ID (%s) from region (%s)", imageId, region)
-		regionconn := ec2.New(a.Conn.Auth, aws.Regions[region])
+		regionconn := NewEC2(a.Conn.Auth, aws.Regions[region])
 		if _, err := regionconn.DeregisterImage(imageId); err != nil {
 			errors = append(errors, err)
 		}
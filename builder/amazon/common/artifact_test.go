@@ -42,3 +42,21 @@ west: bar`
 		t.Fatalf("bad: %s", result)
 	}
 }
+
+func TestArtifactString_sourceAmi(t *testing.T) {
+	expected := `AMIs were created:
+
+east: foo
+
+Source AMI: ami-1234`
+
+	a := &Artifact{
+		Amis:      map[string]string{"east": "foo"},
+		SourceAmi: "ami-1234",
+	}
+
+	result := a.String()
+	if result != expected {
+		t.Fatalf("bad: %s", result)
+	}
+}
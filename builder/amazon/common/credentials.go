@@ -0,0 +1,275 @@
+package common
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/mitchellh/goamz/aws"
+	"github.com/mitchellh/goamz/ec2"
+	"github.com/vaughan0/go-ini"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// stsEndpoint is where temporary credentials are requested from.
+var stsEndpoint = "https://sts.amazonaws.com/"
+
+const (
+	stsAPIVersion = "2011-06-15"
+
+	// assumeRoleDuration is how long the credentials of an assumed role
+	// are valid for. They are refreshed credentialsRefreshWindow before
+	// they expire.
+	assumeRoleDuration       = time.Hour
+	credentialsRefreshWindow = 5 * time.Minute
+
+	// mfaSessionDuration is how long the session started with an MFA code
+	// lasts. The role is assumed again with it, without a new MFA code.
+	mfaSessionDuration = 12 * time.Hour
+)
+
+// Credentials are AWS credentials that may expire, such as those of an
+// assumed role. Expiring credentials are refreshed shortly before they
+// expire, and requests that were signed with expired credentials are
+// signed again with the fresh ones.
+type Credentials struct {
+	l          sync.Mutex
+	auth       aws.Auth
+	expiration time.Time
+
+	// refresh returns new credentials and when they expire. It is nil if
+	// the credentials don't expire, or can't be refreshed.
+	refresh func() (aws.Auth, time.Time, error)
+}
+
+// Auth returns the current credentials, refreshing them if needed.
+func (c *Credentials) Auth() (aws.Auth, error) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if c.refresh == nil || time.Now().Add(credentialsRefreshWindow).Before(c.expiration) {
+		return c.auth, nil
+	}
+
+	log.Println("Refreshing AWS credentials...")
+	auth, expiration, err := c.refresh()
+	if err != nil {
+		return c.auth, fmt.Errorf("Error refreshing AWS credentials: %s", err)
+	}
+
+	c.auth = auth
+	c.expiration = expiration
+
+	issuedCredentials.Lock()
+	issuedCredentials.m[auth.AccessKey] = c
+	issuedCredentials.Unlock()
+
+	return c.auth, nil
+}
+
+// issuedCredentials maps the access keys of refreshable credentials to
+// the Credentials they were issued by, so that credentialsTransport can
+// find the fresh credentials of a request.
+var issuedCredentials = struct {
+	sync.Mutex
+	m map[string]*Credentials
+}{m: make(map[string]*Credentials)}
+
+// credentialsTransport is an http.RoundTripper that signs EC2 requests
+// again if the credentials they were signed with have been refreshed.
+// This way the connections made with NewEC2 keep working for builds that
+// outlast the credentials they were made with.
+type credentialsTransport struct {
+	Transport http.RoundTripper
+}
+
+func (t *credentialsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	query := req.URL.Query()
+	accessKey := query.Get("AWSAccessKeyId")
+
+	issuedCredentials.Lock()
+	creds := issuedCredentials.m[accessKey]
+	issuedCredentials.Unlock()
+	if creds == nil {
+		return t.Transport.RoundTrip(req)
+	}
+
+	auth, err := creds.Auth()
+	if err != nil {
+		return nil, err
+	}
+	if auth.AccessKey == accessKey {
+		return t.Transport.RoundTrip(req)
+	}
+
+	params := make(map[string]string, len(query))
+	for k := range query {
+		params[k] = query.Get(k)
+	}
+	delete(params, "Signature")
+	delete(params, "SecurityToken")
+	ec2Sign(auth, req.Method, req.URL.Path, params, req.URL.Host)
+
+	query = make(url.Values, len(params))
+	for k, v := range params {
+		query.Set(k, v)
+	}
+
+	newURL := *req.URL
+	newURL.RawQuery = query.Encode()
+	newReq := *req
+	newReq.URL = &newURL
+
+	return t.Transport.RoundTrip(&newReq)
+}
+
+// stsCredentials are the temporary credentials returned by STS.
+type stsCredentials struct {
+	AccessKeyId     string    `xml:"AccessKeyId"`
+	SecretAccessKey string    `xml:"SecretAccessKey"`
+	SessionToken    string    `xml:"SessionToken"`
+	Expiration      time.Time `xml:"Expiration"`
+}
+
+func (c *stsCredentials) Auth() aws.Auth {
+	return aws.Auth{
+		AccessKey: c.AccessKeyId,
+		SecretKey: c.SecretAccessKey,
+		Token:     c.SessionToken,
+	}
+}
+
+// stsAssumeRole returns the credentials of a role.
+func stsAssumeRole(auth aws.Auth, roleArn, externalId string) (*stsCredentials, error) {
+	params := map[string]string{
+		"Action":          "AssumeRole",
+		"RoleArn":         roleArn,
+		"RoleSessionName": fmt.Sprintf("packer-%d", time.Now().Unix()),
+		"DurationSeconds": fmt.Sprintf("%d", int(assumeRoleDuration.Seconds())),
+	}
+	if externalId != "" {
+		params["ExternalId"] = externalId
+	}
+
+	return stsQuery(auth, params)
+}
+
+// stsGetSessionToken returns the credentials of a session authenticated
+// with an MFA code.
+func stsGetSessionToken(auth aws.Auth, mfaSerial, mfaCode string) (*stsCredentials, error) {
+	params := map[string]string{
+		"Action":          "GetSessionToken",
+		"SerialNumber":    mfaSerial,
+		"TokenCode":       mfaCode,
+		"DurationSeconds": fmt.Sprintf("%d", int(mfaSessionDuration.Seconds())),
+	}
+
+	return stsQuery(auth, params)
+}
+
+// stsQuery makes an STS request that returns credentials. STS errors are
+// returned as *ec2.Error, so they are classified like EC2 errors.
+func stsQuery(auth aws.Auth, params map[string]string) (*stsCredentials, error) {
+	params["Version"] = stsAPIVersion
+	params["Timestamp"] = time.Now().In(time.UTC).Format(time.RFC3339)
+
+	endpoint, err := url.Parse(stsEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Path == "" {
+		endpoint.Path = "/"
+	}
+
+	ec2Sign(auth, "GET", endpoint.Path, params, endpoint.Host)
+	query := make(url.Values, len(params))
+	for k, v := range params {
+		query.Set(k, v)
+	}
+	endpoint.RawQuery = query.Encode()
+
+	r, err := ec2HTTPClient.Get(endpoint.String())
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != 200 {
+		var errResp struct {
+			Error     ec2.Error `xml:"Error"`
+			RequestId string    `xml:"RequestId"`
+		}
+		xml.NewDecoder(r.Body).Decode(&errResp)
+
+		err := errResp.Error
+		err.StatusCode = r.StatusCode
+		err.RequestId = errResp.RequestId
+		if err.Message == "" {
+			err.Message = err.Code
+		}
+
+		return nil, &err
+	}
+
+	// The credentials are in the result element of the action, such as
+	// AssumeRoleResult.
+	var resp struct {
+		Result struct {
+			Credentials stsCredentials `xml:"Credentials"`
+		} `xml:",any"`
+	}
+	if err := xml.NewDecoder(r.Body).Decode(&resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Result.Credentials.AccessKeyId == "" {
+		return nil, errors.New("STS didn't return any credentials")
+	}
+
+	return &resp.Result.Credentials, nil
+}
+
+// sharedCredentialsAuth returns the credentials of a profile in the shared
+// credentials file, ~/.aws/credentials by default.
+func sharedCredentialsAuth(profile string) (aws.Auth, error) {
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		path = os.Getenv("AWS_CREDENTIAL_FILE")
+	}
+	if path == "" {
+		home := os.Getenv("HOME")
+		if home == "" {
+			return aws.Auth{}, errors.New("Couldn't find the shared credentials file: HOME isn't set")
+		}
+
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+
+	file, err := ini.LoadFile(path)
+	if err != nil {
+		return aws.Auth{}, fmt.Errorf("Error reading shared credentials file %s: %s", path, err)
+	}
+
+	section, ok := file[profile]
+	if !ok {
+		return aws.Auth{}, fmt.Errorf("Profile %s not found in %s", profile, path)
+	}
+
+	auth := aws.Auth{
+		AccessKey: section["aws_access_key_id"],
+		SecretKey: section["aws_secret_access_key"],
+		Token:     section["aws_session_token"],
+	}
+	if auth.AccessKey == "" || auth.SecretKey == "" {
+		return aws.Auth{}, fmt.Errorf(
+			"Profile %s in %s doesn't have aws_access_key_id and aws_secret_access_key",
+			profile, path)
+	}
+
+	return auth, nil
+}
@@ -0,0 +1,209 @@
+package common
+
+import (
+	"github.com/mitchellh/goamz/aws"
+	"github.com/mitchellh/goamz/ec2"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func testSTSResponse(action, accessKey string, expiration time.Time) string {
+	return `<` + action + `Response><` + action + `Result><Credentials>` +
+		`<AccessKeyId>` + accessKey + `</AccessKeyId>` +
+		`<SecretAccessKey>secret</SecretAccessKey>` +
+		`<SessionToken>token</SessionToken>` +
+		`<Expiration>` + expiration.Format(time.RFC3339) + `</Expiration>` +
+		`</Credentials></` + action + `Result></` + action + `Response>`
+}
+
+// testSTSServer returns a fake endpoint that STS requests are sent to.
+// Call the returned function to restore the STS endpoint.
+func testSTSServer() (*testEC2Server, func()) {
+	s := newTestEC2Server()
+	stsEndpoint = s.URL
+
+	return s, func() {
+		stsEndpoint = "https://sts.amazonaws.com/"
+		s.Close()
+	}
+}
+
+func TestSharedCredentialsAuth(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(tf.Name())
+
+	tf.WriteString("[default]\naws_access_key_id = foo\naws_secret_access_key = bar\n\n")
+	tf.WriteString("[build]\naws_access_key_id = baz\naws_secret_access_key = qux\n")
+	tf.WriteString("aws_session_token = token\n")
+	tf.Close()
+
+	old := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", tf.Name())
+	defer os.Setenv("AWS_SHARED_CREDENTIALS_FILE", old)
+
+	auth, err := sharedCredentialsAuth("build")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := aws.Auth{AccessKey: "baz", SecretKey: "qux", Token: "token"}
+	if auth != expected {
+		t.Fatalf("bad: %#v", auth)
+	}
+
+	if _, err := sharedCredentialsAuth("nope"); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestAccessConfigAuth_assumeRole(t *testing.T) {
+	s, closeFn := testSTSServer()
+	defer closeFn()
+
+	expiration := time.Now().Add(time.Hour).UTC()
+	s.Respond("GetSessionToken", 200,
+		testSTSResponse("GetSessionToken", "session", expiration))
+	s.Respond("AssumeRole", 200,
+		testSTSResponse("AssumeRole", "role", expiration))
+
+	c := &AccessConfig{
+		AccessKey:     "access",
+		SecretKey:     "secret",
+		AssumeRoleArn: "arn:aws:iam::123456789012:role/packer",
+		ExternalId:    "external",
+		MFACode:       "123456",
+		MFASerial:     "arn:aws:iam::123456789012:mfa/user",
+	}
+
+	auth, err := c.Auth()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if auth.AccessKey != "role" || auth.Token != "token" {
+		t.Fatalf("bad: %#v", auth)
+	}
+	if c.AccessKey != "role" {
+		t.Fatalf("bad: %#v", c)
+	}
+
+	requests := s.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("bad: %#v", requests)
+	}
+
+	session := requests[0]
+	if session.Get("AWSAccessKeyId") != "access" ||
+		session.Get("SerialNumber") != c.MFASerial ||
+		session.Get("TokenCode") != "123456" {
+		t.Fatalf("bad: %#v", session)
+	}
+
+	// The role is assumed with the MFA session
+	role := requests[1]
+	if role.Get("AWSAccessKeyId") != "session" ||
+		role.Get("SecurityToken") != "token" ||
+		role.Get("RoleArn") != c.AssumeRoleArn ||
+		role.Get("ExternalId") != "external" {
+		t.Fatalf("bad: %#v", role)
+	}
+
+	// The credentials aren't refreshed until they are about to expire
+	if _, err := c.Auth(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(s.Requests()) != 2 {
+		t.Fatalf("bad: %#v", s.Requests())
+	}
+}
+
+func TestAccessConfigAuth_assumeRoleError(t *testing.T) {
+	s, closeFn := testSTSServer()
+	defer closeFn()
+
+	s.Respond("AssumeRole", 403, `<ErrorResponse><Error><Type>Sender</Type>`+
+		`<Code>AccessDenied</Code><Message>Not authorized</Message></Error>`+
+		`<RequestId>req-1</RequestId></ErrorResponse>`)
+
+	c := &AccessConfig{
+		AccessKey:     "access",
+		SecretKey:     "secret",
+		AssumeRoleArn: "arn:aws:iam::123456789012:role/packer",
+	}
+
+	if _, err := c.Auth(); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestCredentials_refresh(t *testing.T) {
+	refreshes := 0
+	creds := &Credentials{
+		refresh: func() (aws.Auth, time.Time, error) {
+			refreshes++
+
+			// Expires within the refresh window the first time
+			expiration := time.Now().Add(time.Minute)
+			if refreshes > 1 {
+				expiration = time.Now().Add(time.Hour)
+			}
+
+			return aws.Auth{AccessKey: "new"}, expiration, nil
+		},
+	}
+
+	for i := 0; i < 3; i++ {
+		auth, err := creds.Auth()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if auth.AccessKey != "new" {
+			t.Fatalf("bad: %#v", auth)
+		}
+	}
+
+	if refreshes != 2 {
+		t.Fatalf("bad: %d", refreshes)
+	}
+}
+
+func TestCredentialsTransport(t *testing.T) {
+	s := newTestEC2Server()
+	defer s.Close()
+
+	s.Respond("DescribeImages", 200, testImagesResponse)
+
+	// The connection was made with credentials that have been replaced
+	creds := &Credentials{
+		auth: aws.Auth{AccessKey: "access", SecretKey: "secret"},
+		refresh: func() (aws.Auth, time.Time, error) {
+			auth := aws.Auth{AccessKey: "fresh", SecretKey: "secret", Token: "token"}
+			return auth, time.Now().Add(time.Hour), nil
+		},
+	}
+	issuedCredentials.Lock()
+	issuedCredentials.m["access"] = creds
+	issuedCredentials.Unlock()
+	defer func() {
+		issuedCredentials.Lock()
+		delete(issuedCredentials.m, "access")
+		delete(issuedCredentials.m, "fresh")
+		issuedCredentials.Unlock()
+	}()
+
+	if _, err := s.Conn().Images([]string{"ami-1234"}, ec2.NewFilter()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	params := s.Requests()[0]
+	if params.Get("AWSAccessKeyId") != "fresh" || params.Get("SecurityToken") != "token" {
+		t.Fatalf("bad: %#v", params)
+	}
+	if params.Get("Action") != "DescribeImages" || params.Get("ImageId.1") != "ami-1234" {
+		t.Fatalf("bad: %#v", params)
+	}
+}
@@ -0,0 +1,241 @@
+package common
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"github.com/mitchellh/goamz/aws"
+	"github.com/mitchellh/goamz/ec2"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ec2APIVersion is the EC2 API version of the requests made by ec2Query.
+// It is newer than the one goamz uses, since encrypted AMI copies need it.
+const ec2APIVersion = "2016-11-15"
+
+// ec2Query makes an EC2 API request that goamz doesn't support. It signs
+// the request the same way goamz does, so it works with the same
+// credentials and endpoints, and EC2 errors are returned as *ec2.Error.
+func ec2Query(conn *ec2.EC2, params map[string]string, resp interface{}) error {
+	params["Version"] = ec2APIVersion
+	params["Timestamp"] = time.Now().In(time.UTC).Format(time.RFC3339)
+
+	endpoint, err := url.Parse(conn.Region.EC2Endpoint)
+	if err != nil {
+		return err
+	}
+	if endpoint.Path == "" {
+		endpoint.Path = "/"
+	}
+
+	ec2Sign(conn.Auth, "GET", endpoint.Path, params, endpoint.Host)
+	query := make(url.Values, len(params))
+	for k, v := range params {
+		query.Set(k, v)
+	}
+	endpoint.RawQuery = query.Encode()
+
+	r, err := ec2HTTPClient.Get(endpoint.String())
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != 200 {
+		var errors struct {
+			RequestId string      `xml:"RequestID"`
+			Errors    []ec2.Error `xml:"Errors>Error"`
+		}
+		xml.NewDecoder(r.Body).Decode(&errors)
+
+		var err ec2.Error
+		if len(errors.Errors) > 0 {
+			err = errors.Errors[0]
+		}
+		err.RequestId = errors.RequestId
+		err.StatusCode = r.StatusCode
+		if err.Message == "" {
+			err.Message = err.Code
+		}
+
+		return &err
+	}
+
+	if resp == nil {
+		return nil
+	}
+
+	return xml.NewDecoder(r.Body).Decode(resp)
+}
+
+// ec2Sign adds a version 2 signature to the request parameters.
+func ec2Sign(auth aws.Auth, method, path string, params map[string]string, host string) {
+	params["AWSAccessKeyId"] = auth.AccessKey
+	params["SignatureVersion"] = "2"
+	params["SignatureMethod"] = "HmacSHA256"
+	if auth.Token != "" {
+		params["SecurityToken"] = auth.Token
+	}
+
+	// The parameters are signed in the natural order of their keys
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = aws.Encode(k) + "=" + aws.Encode(params[k])
+	}
+
+	payload := method + "\n" + host + "\n" + path + "\n" + strings.Join(pairs, "&")
+	hash := hmac.New(sha256.New, []byte(auth.SecretKey))
+	hash.Write([]byte(payload))
+	params["Signature"] = base64.StdEncoding.EncodeToString(hash.Sum(nil))
+}
+
+// ec2ParamsList adds a list of values as label.1, label.2, etc.
+func ec2ParamsList(params map[string]string, label string, values []string) {
+	for i, v := range values {
+		params[label+"."+strconv.Itoa(i+1)] = v
+	}
+}
+
+// copyImageOptions are the options of an ec2CopyImage request.
+type copyImageOptions struct {
+	SourceRegion  string
+	SourceImageId string
+	Name          string
+	Description   string
+	Encrypted     bool
+	KmsKeyId      string
+}
+
+// ec2CopyImage is ec2.CopyImage with support for encrypting the copy.
+func ec2CopyImage(conn *ec2.EC2, options *copyImageOptions) (string, error) {
+	params := map[string]string{
+		"Action":        "CopyImage",
+		"SourceRegion":  options.SourceRegion,
+		"SourceImageId": options.SourceImageId,
+	}
+
+	if options.Name != "" {
+		params["Name"] = options.Name
+	}
+	if options.Description != "" {
+		params["Description"] = options.Description
+	}
+	if options.Encrypted {
+		params["Encrypted"] = "true"
+	}
+	if options.KmsKeyId != "" {
+		params["KmsKeyId"] = options.KmsKeyId
+	}
+
+	var resp struct {
+		ImageId string `xml:"imageId"`
+	}
+	if err := ec2Query(conn, params, &resp); err != nil {
+		return "", err
+	}
+
+	return resp.ImageId, nil
+}
+
+// ec2ImageCreationDates returns the creation dates of the given images by
+// their ID. The images of goamz don't have them.
+func ec2ImageCreationDates(conn *ec2.EC2, imageIds []string) (map[string]string, error) {
+	params := map[string]string{"Action": "DescribeImages"}
+	ec2ParamsList(params, "ImageId", imageIds)
+
+	var resp struct {
+		Images []struct {
+			Id           string `xml:"imageId"`
+			CreationDate string `xml:"creationDate"`
+		} `xml:"imagesSet>item"`
+	}
+	if err := ec2Query(conn, params, &resp); err != nil {
+		return nil, err
+	}
+
+	dates := make(map[string]string, len(resp.Images))
+	for _, image := range resp.Images {
+		dates[image.Id] = image.CreationDate
+	}
+
+	return dates, nil
+}
+
+// ec2SubnetAvailabilityZone returns the availability zone of a subnet.
+func ec2SubnetAvailabilityZone(conn *ec2.EC2, subnetId string) (string, error) {
+	params := map[string]string{
+		"Action":     "DescribeSubnets",
+		"SubnetId.1": subnetId,
+	}
+
+	var resp struct {
+		Subnets []struct {
+			AvailabilityZone string `xml:"availabilityZone"`
+		} `xml:"subnetSet>item"`
+	}
+	if err := ec2Query(conn, params, &resp); err != nil {
+		return "", err
+	}
+
+	if len(resp.Subnets) == 0 {
+		return "", fmt.Errorf("subnet %s was not found", subnetId)
+	}
+
+	return resp.Subnets[0].AvailabilityZone, nil
+}
+
+// ec2AddCreateVolumePermission allows the given users and groups to create
+// volumes from a snapshot.
+func ec2AddCreateVolumePermission(conn *ec2.EC2, snapshotId string, users, groups []string) error {
+	params := map[string]string{
+		"Action":        "ModifySnapshotAttribute",
+		"SnapshotId":    snapshotId,
+		"Attribute":     "createVolumePermission",
+		"OperationType": "add",
+	}
+	ec2ParamsList(params, "UserId", users)
+	ec2ParamsList(params, "UserGroup", groups)
+
+	return ec2Query(conn, params, nil)
+}
+
+// ec2DeleteSnapshot deletes a single snapshot. ec2.DeleteSnapshots sends
+// a list of IDs, which EC2 doesn't accept.
+func ec2DeleteSnapshot(conn *ec2.EC2, snapshotId string) error {
+	params := map[string]string{
+		"Action":     "DeleteSnapshot",
+		"SnapshotId": snapshotId,
+	}
+
+	return ec2Query(conn, params, nil)
+}
+
+// ec2GetPasswordData returns the encrypted administrator password of a
+// Windows instance, which is empty until Windows has generated it.
+func ec2GetPasswordData(conn *ec2.EC2, instanceId string) (string, error) {
+	params := map[string]string{
+		"Action":     "GetPasswordData",
+		"InstanceId": instanceId,
+	}
+
+	var resp struct {
+		PasswordData string `xml:"passwordData"`
+	}
+	if err := ec2Query(conn, params, &resp); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(resp.PasswordData), nil
+}
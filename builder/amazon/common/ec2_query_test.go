@@ -0,0 +1,224 @@
+package common
+
+import (
+	"fmt"
+	"github.com/mitchellh/goamz/aws"
+	"github.com/mitchellh/goamz/ec2"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+// testEC2Response is a canned response of the fake EC2 endpoint.
+type testEC2Response struct {
+	Status int
+	Body   string
+}
+
+// testEC2Server is a fake EC2 endpoint. Each action answers with the
+// queued responses in order, repeating the last one.
+type testEC2Server struct {
+	*httptest.Server
+
+	l         sync.Mutex
+	requests  []url.Values
+	responses map[string][]testEC2Response
+}
+
+func newTestEC2Server() *testEC2Server {
+	s := &testEC2Server{responses: make(map[string][]testEC2Response)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Respond queues a response to an action.
+func (s *testEC2Server) Respond(action string, status int, body string) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.responses[action] = append(s.responses[action], testEC2Response{status, body})
+}
+
+// RespondError queues an EC2 error response to an action.
+func (s *testEC2Server) RespondError(action string, status int, code string) {
+	s.Respond(action, status, fmt.Sprintf(
+		`<Response><Errors><Error><Code>%s</Code><Message>%s happened</Message>`+
+			`</Error></Errors><RequestID>req-1</RequestID></Response>`, code, code))
+}
+
+// Requests returns the parameters of the requests made so far.
+func (s *testEC2Server) Requests() []url.Values {
+	s.l.Lock()
+	defer s.l.Unlock()
+	return append([]url.Values(nil), s.requests...)
+}
+
+// Conn returns an EC2 connection to the fake endpoint.
+func (s *testEC2Server) Conn() *ec2.EC2 {
+	auth := aws.Auth{AccessKey: "access", SecretKey: "secret"}
+	return NewEC2(auth, aws.Region{Name: "us-east-1", EC2Endpoint: s.URL})
+}
+
+func (s *testEC2Server) handle(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	s.l.Lock()
+	s.requests = append(s.requests, r.Form)
+	action := r.Form.Get("Action")
+	queue := s.responses[action]
+	if len(queue) == 0 {
+		s.l.Unlock()
+		http.Error(w, "unexpected action "+action, http.StatusBadRequest)
+		return
+	}
+	resp := queue[0]
+	if len(queue) > 1 {
+		s.responses[action] = queue[1:]
+	}
+	s.l.Unlock()
+
+	w.WriteHeader(resp.Status)
+	fmt.Fprint(w, resp.Body)
+}
+
+func TestEC2CopyImage(t *testing.T) {
+	s := newTestEC2Server()
+	defer s.Close()
+
+	s.Respond("CopyImage", 200,
+		`<CopyImageResponse><imageId>ami-5678</imageId></CopyImageResponse>`)
+
+	id, err := ec2CopyImage(s.Conn(), &copyImageOptions{
+		SourceRegion:  "us-west-2",
+		SourceImageId: "ami-1234",
+		Name:          "foo",
+		Encrypted:     true,
+		KmsKeyId:      "key",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if id != "ami-5678" {
+		t.Fatalf("bad: %s", id)
+	}
+
+	params := s.Requests()[0]
+	expected := map[string]string{
+		"SourceRegion":   "us-west-2",
+		"SourceImageId":  "ami-1234",
+		"Name":           "foo",
+		"Encrypted":      "true",
+		"KmsKeyId":       "key",
+		"Version":        ec2APIVersion,
+		"AWSAccessKeyId": "access",
+	}
+	for k, v := range expected {
+		if params.Get(k) != v {
+			t.Fatalf("bad %s: %#v", k, params)
+		}
+	}
+	if params.Get("Signature") == "" {
+		t.Fatalf("should be signed: %#v", params)
+	}
+}
+
+func TestEC2Query_error(t *testing.T) {
+	s := newTestEC2Server()
+	defer s.Close()
+
+	s.RespondError("CopyImage", 400, "InvalidAMIID.NotFound")
+
+	_, err := ec2CopyImage(s.Conn(), &copyImageOptions{SourceImageId: "ami-1234"})
+	ec2err, ok := err.(*ec2.Error)
+	if !ok {
+		t.Fatalf("bad: %#v", err)
+	}
+	if ec2err.Code != "InvalidAMIID.NotFound" || ec2err.StatusCode != 400 {
+		t.Fatalf("bad: %#v", ec2err)
+	}
+}
+
+func TestEC2AddCreateVolumePermission(t *testing.T) {
+	s := newTestEC2Server()
+	defer s.Close()
+
+	s.Respond("ModifySnapshotAttribute", 200,
+		`<ModifySnapshotAttributeResponse><return>true</return></ModifySnapshotAttributeResponse>`)
+
+	err := ec2AddCreateVolumePermission(
+		s.Conn(), "snap-1234", []string{"1111", "2222"}, []string{"all"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	params := s.Requests()[0]
+	expected := map[string]string{
+		"SnapshotId":    "snap-1234",
+		"Attribute":     "createVolumePermission",
+		"OperationType": "add",
+		"UserId.1":      "1111",
+		"UserId.2":      "2222",
+		"UserGroup.1":   "all",
+	}
+	for k, v := range expected {
+		if params.Get(k) != v {
+			t.Fatalf("bad %s: %#v", k, params)
+		}
+	}
+}
+
+func TestEC2ImageCreationDates(t *testing.T) {
+	s := newTestEC2Server()
+	defer s.Close()
+
+	s.Respond("DescribeImages", 200, `<DescribeImagesResponse><imagesSet>
+<item><imageId>ami-1</imageId><creationDate>2015-04-09T12:00:00.000Z</creationDate></item>
+<item><imageId>ami-2</imageId><creationDate>2015-02-18T12:00:00.000Z</creationDate></item>
+</imagesSet></DescribeImagesResponse>`)
+
+	dates, err := ec2ImageCreationDates(s.Conn(), []string{"ami-1", "ami-2"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]string{
+		"ami-1": "2015-04-09T12:00:00.000Z",
+		"ami-2": "2015-02-18T12:00:00.000Z",
+	}
+	if len(dates) != len(expected) {
+		t.Fatalf("bad: %#v", dates)
+	}
+	for k, v := range expected {
+		if dates[k] != v {
+			t.Fatalf("bad %s: %#v", k, dates)
+		}
+	}
+
+	params := s.Requests()[0]
+	if params.Get("ImageId.1") != "ami-1" || params.Get("ImageId.2") != "ami-2" {
+		t.Fatalf("bad: %#v", params)
+	}
+}
+
+func TestEC2SubnetAvailabilityZone(t *testing.T) {
+	s := newTestEC2Server()
+	defer s.Close()
+
+	s.Respond("DescribeSubnets", 200, `<DescribeSubnetsResponse><subnetSet>
+<item><subnetId>subnet-1234</subnetId><availabilityZone>us-east-1b</availabilityZone></item>
+</subnetSet></DescribeSubnetsResponse>`)
+
+	zone, err := ec2SubnetAvailabilityZone(s.Conn(), "subnet-1234")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if zone != "us-east-1b" {
+		t.Fatalf("bad: %s", zone)
+	}
+
+	params := s.Requests()[0]
+	if params.Get("SubnetId.1") != "subnet-1234" {
+		t.Fatalf("bad: %#v", params)
+	}
+}
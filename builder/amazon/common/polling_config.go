@@ -0,0 +1,40 @@
+package common
+
+import (
+	"errors"
+	"time"
+)
+
+// AWSPollingConfig is how long to wait for AWS resources to reach a
+// state, such as an instance becoming ready or an AMI being copied.
+type AWSPollingConfig struct {
+	DelaySeconds int `mapstructure:"delay_seconds"`
+	MaxAttempts  int `mapstructure:"max_attempts"`
+}
+
+// Delay returns how long to wait between polls, which defaults to
+// two seconds.
+func (c *AWSPollingConfig) Delay() time.Duration {
+	if c.DelaySeconds == 0 {
+		return 2 * time.Second
+	}
+
+	return time.Duration(c.DelaySeconds) * time.Second
+}
+
+func (c *AWSPollingConfig) Prepare() []error {
+	errs := make([]error, 0)
+	if c.DelaySeconds < 0 {
+		errs = append(errs, errors.New("aws_polling delay_seconds can't be negative."))
+	}
+
+	if c.MaxAttempts < 0 {
+		errs = append(errs, errors.New("aws_polling max_attempts can't be negative."))
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
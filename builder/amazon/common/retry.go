@@ -0,0 +1,209 @@
+package common
+
+import (
+	"bytes"
+	"encoding/xml"
+	"github.com/mitchellh/goamz/aws"
+	"github.com/mitchellh/goamz/ec2"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// retryableErrorCodes are the codes of the EC2 errors that are worth
+// retrying, since the request was throttled or EC2 had an internal issue.
+var retryableErrorCodes = map[string]bool{
+	"InternalError":        true,
+	"InternalFailure":      true,
+	"RequestLimitExceeded": true,
+	"RequestThrottled":     true,
+	"ServiceUnavailable":   true,
+	"Throttling":           true,
+	"ThrottlingException":  true,
+	"Unavailable":          true,
+}
+
+// retrySleep is how Retry and WaitForState wait, so tests can skip it.
+var retrySleep = time.Sleep
+
+// RetryConfig is the configuration struct used for `Retry`.
+type RetryConfig struct {
+	// Delay is how long to wait before the first retry. It is doubled for
+	// every retry after that, up to MaxDelay. A random jitter is taken off
+	// every delay, so that parallel builds don't retry in lockstep.
+	Delay    time.Duration
+	MaxDelay time.Duration
+
+	// MaxAttempts is how many times the function is called at most.
+	MaxAttempts int
+
+	// Retryable decides whether an error is worth retrying. By default,
+	// throttling, internal EC2 errors and temporary network errors are.
+	Retryable func(error) bool
+}
+
+// DefaultRetryConfig is used for every request made by the EC2 connections
+// returned by NewEC2.
+var DefaultRetryConfig = &RetryConfig{
+	Delay:       1 * time.Second,
+	MaxDelay:    30 * time.Second,
+	MaxAttempts: 8,
+}
+
+// eventualRetryConfig is used for requests about resources that were just
+// created, which EC2 may not know about yet.
+var eventualRetryConfig = &RetryConfig{
+	Delay:       2 * time.Second,
+	MaxDelay:    30 * time.Second,
+	MaxAttempts: 8,
+	Retryable: func(err error) bool {
+		return isNotFoundError(err) || isRetryableError(err)
+	},
+}
+
+// Retry calls f until it succeeds, returns an error that isn't retryable,
+// or the attempts run out. The last error is returned.
+func Retry(conf *RetryConfig, f func() error) error {
+	retryable := conf.Retryable
+	if retryable == nil {
+		retryable = isRetryableError
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := f()
+		if err == nil {
+			return nil
+		}
+
+		if !retryable(err) || attempt+1 >= conf.MaxAttempts {
+			return err
+		}
+
+		delay := backoff(conf.Delay, conf.MaxDelay, attempt)
+		log.Printf("Retrying in %s after error: %s", delay, err)
+		retrySleep(delay)
+	}
+}
+
+// backoff returns how long to wait before the given retry: an exponential
+// delay, of which up to a half is taken off at random.
+func backoff(delay, maxDelay time.Duration, attempt int) time.Duration {
+	for i := 0; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if maxDelay > 0 && delay > maxDelay {
+		delay = maxDelay
+	}
+
+	if delay < 2 {
+		return delay
+	}
+
+	return delay - time.Duration(rand.Int63n(int64(delay/2)))
+}
+
+// isRetryableError tells whether a request that failed with err may
+// succeed if it is made again.
+func isRetryableError(err error) bool {
+	switch err := err.(type) {
+	case *ec2.Error:
+		return retryableErrorCodes[err.Code] || err.StatusCode >= 500
+	case net.Error:
+		return err.Temporary()
+	}
+
+	return false
+}
+
+// isNotFoundError tells whether err is EC2 saying that a resource doesn't
+// exist, such as InvalidAMIID.NotFound.
+func isNotFoundError(err error) bool {
+	ec2err, ok := err.(*ec2.Error)
+	return ok && strings.HasSuffix(ec2err.Code, ".NotFound")
+}
+
+// retryEventual retries f while the resources it is about are missing,
+// since EC2 is only eventually consistent after creating them.
+func retryEventual(f func() error) error {
+	return Retry(eventualRetryConfig, f)
+}
+
+// NewEC2 returns an EC2 connection whose requests are retried with
+// DefaultRetryConfig when they are throttled or fail temporarily, and
+// signed again if the credentials have been refreshed since.
+func NewEC2(auth aws.Auth, region aws.Region) *ec2.EC2 {
+	return ec2.NewWithClient(auth, region, ec2HTTPClient)
+}
+
+var ec2HTTPClient = &http.Client{
+	Transport: &retryTransport{
+		Transport: &credentialsTransport{Transport: http.DefaultTransport},
+		Retry:     DefaultRetryConfig,
+	},
+}
+
+// retryTransport is an http.RoundTripper that retries EC2 requests. The
+// EC2 requests are all GET requests, so they can be sent again as is.
+type retryTransport struct {
+	Transport http.RoundTripper
+	Retry     *RetryConfig
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+	err := Retry(t.Retry, func() error {
+		var err error
+		resp, err = t.Transport.RoundTrip(req)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == 200 {
+			return nil
+		}
+
+		// Read the error so it can be classified, and keep it around
+		// for the EC2 client in case this was the last attempt.
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			resp = nil
+			return err
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		return &ec2.Error{
+			StatusCode: resp.StatusCode,
+			Code:       ec2ErrorCode(body),
+		}
+	})
+
+	// An EC2 error is left to the client to return
+	if resp != nil {
+		return resp, nil
+	}
+
+	return nil, err
+}
+
+// ec2ErrorCode returns the code of the error in an EC2 or STS error
+// response.
+func ec2ErrorCode(body []byte) string {
+	var resp struct {
+		Errors []ec2.Error `xml:"Errors>Error"`
+		Error  ec2.Error   `xml:"Error"`
+	}
+	if err := xml.Unmarshal(body, &resp); err != nil {
+		return ""
+	}
+
+	if len(resp.Errors) > 0 {
+		return resp.Errors[0].Code
+	}
+
+	return resp.Error.Code
+}
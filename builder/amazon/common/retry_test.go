@@ -0,0 +1,188 @@
+package common
+
+import (
+	"errors"
+	"github.com/mitchellh/goamz/ec2"
+	"testing"
+	"time"
+)
+
+// testRetrySleep replaces the sleeping of Retry and WaitForState, and
+// records the delays instead. Call the returned function to restore it.
+func testRetrySleep(delays *[]time.Duration) func() {
+	retrySleep = func(d time.Duration) {
+		*delays = append(*delays, d)
+	}
+
+	return func() { retrySleep = time.Sleep }
+}
+
+func TestIsRetryableError(t *testing.T) {
+	cases := []struct {
+		Err       error
+		Retryable bool
+	}{
+		{&ec2.Error{StatusCode: 503, Code: "RequestLimitExceeded"}, true},
+		{&ec2.Error{StatusCode: 400, Code: "Throttling"}, true},
+		{&ec2.Error{StatusCode: 500, Code: "InternalError"}, true},
+		{&ec2.Error{StatusCode: 502}, true},
+		{&ec2.Error{StatusCode: 400, Code: "InvalidAMIID.NotFound"}, false},
+		{&ec2.Error{StatusCode: 400, Code: "UnauthorizedOperation"}, false},
+		{errors.New("foo"), false},
+	}
+
+	for _, tc := range cases {
+		if isRetryableError(tc.Err) != tc.Retryable {
+			t.Fatalf("bad: %#v", tc.Err)
+		}
+	}
+
+	if !isNotFoundError(&ec2.Error{Code: "InvalidInstanceID.NotFound"}) {
+		t.Fatal("should be not found")
+	}
+	if isNotFoundError(&ec2.Error{Code: "RequestLimitExceeded"}) {
+		t.Fatal("shouldn't be not found")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var delays []time.Duration
+	defer testRetrySleep(&delays)()
+
+	conf := &RetryConfig{
+		Delay:       time.Second,
+		MaxDelay:    4 * time.Second,
+		MaxAttempts: 5,
+	}
+
+	// Retries until the attempts run out
+	calls := 0
+	throttled := &ec2.Error{StatusCode: 503, Code: "RequestLimitExceeded"}
+	err := Retry(conf, func() error {
+		calls++
+		return throttled
+	})
+	if err != throttled {
+		t.Fatalf("bad: %#v", err)
+	}
+	if calls != 5 || len(delays) != 4 {
+		t.Fatalf("bad: %d calls, %#v", calls, delays)
+	}
+
+	// The delays grow exponentially up to the max, with jitter
+	maxDelays := []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second,
+	}
+	for i, d := range delays {
+		if d > maxDelays[i] || d < maxDelays[i]/2 {
+			t.Fatalf("bad delay %d: %s", i, d)
+		}
+	}
+
+	// Stops at success
+	calls = 0
+	err = Retry(conf, func() error {
+		calls++
+		if calls < 3 {
+			return throttled
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("bad: %d calls, %s", calls, err)
+	}
+
+	// Doesn't retry other errors
+	calls = 0
+	err = Retry(conf, func() error {
+		calls++
+		return &ec2.Error{StatusCode: 400, Code: "InvalidAMIID.NotFound"}
+	})
+	if err == nil || calls != 1 {
+		t.Fatalf("bad: %d calls, %s", calls, err)
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	var delays []time.Duration
+	defer testRetrySleep(&delays)()
+
+	s := newTestEC2Server()
+	defer s.Close()
+
+	s.RespondError("DescribeImages", 503, "RequestLimitExceeded")
+	s.RespondError("DescribeImages", 500, "InternalError")
+	s.Respond("DescribeImages", 200, testImagesResponse)
+
+	resp, err := s.Conn().Images([]string{"ami-1234"}, ec2.NewFilter())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(resp.Images) != 1 {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if len(s.Requests()) != 3 || len(delays) != 2 {
+		t.Fatalf("bad: %d requests, %#v", len(s.Requests()), delays)
+	}
+}
+
+func TestRetryTransport_error(t *testing.T) {
+	var delays []time.Duration
+	defer testRetrySleep(&delays)()
+
+	s := newTestEC2Server()
+	defer s.Close()
+
+	s.RespondError("DescribeImages", 400, "InvalidAMIID.NotFound")
+
+	_, err := s.Conn().Images([]string{"ami-1234"}, ec2.NewFilter())
+	ec2err, ok := err.(*ec2.Error)
+	if !ok || ec2err.Code != "InvalidAMIID.NotFound" {
+		t.Fatalf("bad: %#v", err)
+	}
+	if len(s.Requests()) != 1 || len(delays) != 0 {
+		t.Fatalf("bad: %d requests, %#v", len(s.Requests()), delays)
+	}
+}
+
+func TestRetryTransport_giveUp(t *testing.T) {
+	var delays []time.Duration
+	defer testRetrySleep(&delays)()
+
+	s := newTestEC2Server()
+	defer s.Close()
+
+	s.RespondError("DescribeImages", 503, "RequestLimitExceeded")
+
+	_, err := s.Conn().Images([]string{"ami-1234"}, ec2.NewFilter())
+	ec2err, ok := err.(*ec2.Error)
+	if !ok || ec2err.Code != "RequestLimitExceeded" {
+		t.Fatalf("bad: %#v", err)
+	}
+	if len(s.Requests()) != DefaultRetryConfig.MaxAttempts {
+		t.Fatalf("bad: %d requests", len(s.Requests()))
+	}
+}
+
+func TestRetryEventual(t *testing.T) {
+	var delays []time.Duration
+	defer testRetrySleep(&delays)()
+
+	s := newTestEC2Server()
+	defer s.Close()
+
+	s.RespondError("CreateTags", 400, "InvalidInstanceID.NotFound")
+	s.Respond("CreateTags", 200,
+		`<CreateTagsResponse><return>true</return></CreateTagsResponse>`)
+
+	err := retryEventual(func() error {
+		_, err := s.Conn().CreateTags([]string{"i-1234"}, []ec2.Tag{{"foo", "bar"}})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(s.Requests()) != 2 {
+		t.Fatalf("bad: %d requests", len(s.Requests()))
+	}
+}
@@ -5,32 +5,49 @@ import (
 	"fmt"
 	"github.com/mitchellh/packer/packer"
 	"os"
+	"strconv"
 	"time"
 )
 
+// spotPriceProducts are the valid values of spot_price_auto_product.
+var spotPriceProducts = map[string]bool{
+	"Linux/UNIX":              true,
+	"SUSE Linux":              true,
+	"Windows":                 true,
+	"Linux/UNIX (Amazon VPC)": true,
+	"SUSE Linux (Amazon VPC)": true,
+	"Windows (Amazon VPC)":    true,
+}
+
 // RunConfig contains configuration for running an instance from a source
 // AMI and details on how to access that launched image.
 type RunConfig struct {
-	AssociatePublicIpAddress bool              `mapstructure:"associate_public_ip_address"`
-	AvailabilityZone         string            `mapstructure:"availability_zone"`
-	IamInstanceProfile       string            `mapstructure:"iam_instance_profile"`
-	InstanceType             string            `mapstructure:"instance_type"`
-	RunTags                  map[string]string `mapstructure:"run_tags"`
-	SourceAmi                string            `mapstructure:"source_ami"`
-	RawSSHTimeout            string            `mapstructure:"ssh_timeout"`
-	SSHUsername              string            `mapstructure:"ssh_username"`
-	SSHPrivateKeyFile        string            `mapstructure:"ssh_private_key_file"`
-	SSHPort                  int               `mapstructure:"ssh_port"`
-	SecurityGroupId          string            `mapstructure:"security_group_id"`
-	SecurityGroupIds         []string          `mapstructure:"security_group_ids"`
-	SubnetId                 string            `mapstructure:"subnet_id"`
-	TemporaryKeyPairName     string            `mapstructure:"temporary_key_pair_name"`
-	UserData                 string            `mapstructure:"user_data"`
-	UserDataFile             string            `mapstructure:"user_data_file"`
-	VpcId                    string            `mapstructure:"vpc_id"`
+	AssociatePublicIpAddress  bool              `mapstructure:"associate_public_ip_address"`
+	AvailabilityZone          string            `mapstructure:"availability_zone"`
+	IamInstanceProfile        string            `mapstructure:"iam_instance_profile"`
+	InstanceType              string            `mapstructure:"instance_type"`
+	RunTags                   map[string]string `mapstructure:"run_tags"`
+	RunVolumeTags             map[string]string `mapstructure:"run_volume_tags"`
+	SourceAmi                 string            `mapstructure:"source_ami"`
+	SourceAmiFilter           AMIFilterOptions  `mapstructure:"source_ami_filter"`
+	SpotPrice                 string            `mapstructure:"spot_price"`
+	SpotPriceAutoProduct      string            `mapstructure:"spot_price_auto_product"`
+	RawSSHTimeout             string            `mapstructure:"ssh_timeout"`
+	SSHUsername               string            `mapstructure:"ssh_username"`
+	SSHPrivateKeyFile         string            `mapstructure:"ssh_private_key_file"`
+	SSHPort                   int               `mapstructure:"ssh_port"`
+	SecurityGroupId           string            `mapstructure:"security_group_id"`
+	SecurityGroupIds          []string          `mapstructure:"security_group_ids"`
+	SubnetId                  string            `mapstructure:"subnet_id"`
+	TemporaryKeyPairName      string            `mapstructure:"temporary_key_pair_name"`
+	UserData                  string            `mapstructure:"user_data"`
+	UserDataFile              string            `mapstructure:"user_data_file"`
+	VpcId                     string            `mapstructure:"vpc_id"`
+	RawWindowsPasswordTimeout string            `mapstructure:"windows_password_timeout"`
 
 	// Unexported fields that are calculated from others
-	sshTimeout time.Duration
+	sshTimeout             time.Duration
+	windowsPasswordTimeout time.Duration
 }
 
 func (c *RunConfig) Prepare(t *packer.ConfigTemplate) []error {
@@ -51,6 +68,10 @@ func (c *RunConfig) Prepare(t *packer.ConfigTemplate) []error {
 		c.RawSSHTimeout = "5m"
 	}
 
+	if c.RawWindowsPasswordTimeout == "" {
+		c.RawWindowsPasswordTimeout = "20m"
+	}
+
 	if c.TemporaryKeyPairName == "" {
 		c.TemporaryKeyPairName = "packer {{uuid}}"
 	}
@@ -58,10 +79,14 @@ func (c *RunConfig) Prepare(t *packer.ConfigTemplate) []error {
 	// Validation
 	var err error
 	errs := make([]error, 0)
-	if c.SourceAmi == "" {
-		errs = append(errs, errors.New("A source_ami must be specified"))
+	if c.SourceAmi == "" && c.SourceAmiFilter.Empty() {
+		errs = append(errs, errors.New("A source_ami or source_ami_filter must be specified"))
+	} else if c.SourceAmi != "" && !c.SourceAmiFilter.Empty() {
+		errs = append(errs, errors.New("Only one of source_ami or source_ami_filter can be specified."))
 	}
 
+	errs = append(errs, c.SourceAmiFilter.Prepare(t)...)
+
 	if c.InstanceType == "" {
 		errs = append(errs, errors.New("An instance_type must be specified"))
 	}
@@ -70,6 +95,21 @@ func (c *RunConfig) Prepare(t *packer.ConfigTemplate) []error {
 		errs = append(errs, errors.New("An ssh_username must be specified"))
 	}
 
+	if c.SpotPrice == "auto" {
+		if c.SpotPriceAutoProduct == "" {
+			errs = append(errs, errors.New(
+				"spot_price_auto_product must be specified when spot_price is auto"))
+		} else if !spotPriceProducts[c.SpotPriceAutoProduct] {
+			errs = append(errs, fmt.Errorf(
+				"Unknown spot_price_auto_product: %s", c.SpotPriceAutoProduct))
+		}
+	} else if c.SpotPrice != "" {
+		if price, err := strconv.ParseFloat(c.SpotPrice, 64); err != nil || price <= 0 {
+			errs = append(errs, fmt.Errorf(
+				"spot_price must be a positive price or \"auto\": %s", c.SpotPrice))
+		}
+	}
+
 	if c.UserData != "" && c.UserDataFile != "" {
 		errs = append(errs, fmt.Errorf("Only one of user_data or user_data_file can be specified."))
 	} else if c.UserDataFile != "" {
@@ -88,16 +128,19 @@ func (c *RunConfig) Prepare(t *packer.ConfigTemplate) []error {
 	}
 
 	templates := map[string]*string{
-		"iam_instance_profile":    &c.IamInstanceProfile,
-		"instance_type":           &c.InstanceType,
-		"ssh_timeout":             &c.RawSSHTimeout,
-		"ssh_username":            &c.SSHUsername,
-		"ssh_private_key_file":    &c.SSHPrivateKeyFile,
-		"source_ami":              &c.SourceAmi,
-		"subnet_id":               &c.SubnetId,
-		"temporary_key_pair_name": &c.TemporaryKeyPairName,
-		"vpc_id":                  &c.VpcId,
-		"availability_zone":       &c.AvailabilityZone,
+		"iam_instance_profile":     &c.IamInstanceProfile,
+		"instance_type":            &c.InstanceType,
+		"ssh_timeout":              &c.RawSSHTimeout,
+		"ssh_username":             &c.SSHUsername,
+		"ssh_private_key_file":     &c.SSHPrivateKeyFile,
+		"source_ami":               &c.SourceAmi,
+		"spot_price":               &c.SpotPrice,
+		"spot_price_auto_product":  &c.SpotPriceAutoProduct,
+		"subnet_id":                &c.SubnetId,
+		"temporary_key_pair_name":  &c.TemporaryKeyPairName,
+		"vpc_id":                   &c.VpcId,
+		"availability_zone":        &c.AvailabilityZone,
+		"windows_password_timeout": &c.RawWindowsPasswordTimeout,
 	}
 
 	for n, ptr := range templates {
@@ -124,35 +167,26 @@ func (c *RunConfig) Prepare(t *packer.ConfigTemplate) []error {
 		}
 	}
 
-	newTags := make(map[string]string)
-	for k, v := range c.RunTags {
-		k, err := t.Process(k, nil)
-		if err != nil {
-			errs = append(errs,
-				fmt.Errorf("Error processing tag key %s: %s", k, err))
-			continue
-		}
-
-		v, err := t.Process(v, nil)
-		if err != nil {
-			errs = append(errs,
-				fmt.Errorf("Error processing tag value '%s': %s", v, err))
-			continue
-		}
-
-		newTags[k] = v
-	}
-
-	c.RunTags = newTags
+	errs = append(errs, validateTags(t, "run_tags", c.RunTags)...)
+	errs = append(errs, validateTags(t, "run_volume_tags", c.RunVolumeTags)...)
 
 	c.sshTimeout, err = time.ParseDuration(c.RawSSHTimeout)
 	if err != nil {
 		errs = append(errs, fmt.Errorf("Failed parsing ssh_timeout: %s", err))
 	}
 
+	c.windowsPasswordTimeout, err = time.ParseDuration(c.RawWindowsPasswordTimeout)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("Failed parsing windows_password_timeout: %s", err))
+	}
+
 	return errs
 }
 
 func (c *RunConfig) SSHTimeout() time.Duration {
 	return c.sshTimeout
 }
+
+func (c *RunConfig) WindowsPasswordTimeout() time.Duration {
+	return c.windowsPasswordTimeout
+}
@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 )
 
 func init() {
@@ -47,6 +48,61 @@ func TestRunConfigPrepare_SourceAmi(t *testing.T) {
 	}
 }
 
+func TestRunConfigPrepare_SourceAmiFilter(t *testing.T) {
+	c := testConfig()
+	c.SourceAmi = ""
+	c.SourceAmiFilter = AMIFilterOptions{
+		Filters: map[string]string{
+			"name": "ubuntu/images/*",
+		},
+		Owners: []string{"099720109477"},
+	}
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Both a source AMI and a filter
+	c.SourceAmi = "abcd"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Owners alone aren't a filter
+	c.SourceAmi = ""
+	c.SourceAmiFilter.Filters = nil
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestRunConfigPrepare_SpotPrice(t *testing.T) {
+	c := testConfig()
+	c.SpotPrice = "0.05"
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+
+	c.SpotPrice = "cheap"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("err: %s", err)
+	}
+
+	c.SpotPrice = "auto"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("err: %s", err)
+	}
+
+	c.SpotPriceAutoProduct = "Linux/UNIX (Amazon VPC)"
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+
+	c.SpotPriceAutoProduct = "BeOS"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("err: %s", err)
+	}
+}
+
 func TestRunConfigPrepare_SSHPort(t *testing.T) {
 	c := testConfig()
 	c.SSHPort = 0
@@ -138,3 +194,32 @@ func TestRunConfigPrepare_TemporaryKeyPairName(t *testing.T) {
 		t.Fatal("keypair empty")
 	}
 }
+
+func TestRunConfigPrepare_RunVolumeTags(t *testing.T) {
+	c := testConfig()
+	c.RunVolumeTags = map[string]string{"Base": "{{.SourceAMI}}"}
+	if err := c.Prepare(testConfigTemplate(t)); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+
+	c.RunVolumeTags = map[string]string{"Base": "{{"}
+	if err := c.Prepare(testConfigTemplate(t)); len(err) != 1 {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestRunConfigPrepare_WindowsPasswordTimeout(t *testing.T) {
+	c := testConfig()
+	c.RawWindowsPasswordTimeout = ""
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if c.WindowsPasswordTimeout() != 20*time.Minute {
+		t.Fatalf("bad: %s", c.WindowsPasswordTimeout())
+	}
+
+	c.RawWindowsPasswordTimeout = "bad"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("err: %s", err)
+	}
+}
@@ -49,7 +49,7 @@ func SSHAddress(e *ec2.EC2, port int) func(multistep.StateBag) (string, error) {
 
 // SSHConfig returns a function that can be used for the SSH communicator
 // config for connecting to the instance created over SSH using the generated
-// private key.
+// private key, or the administrator password of a Windows instance.
 func SSHConfig(username string) func(multistep.StateBag) (*ssh.ClientConfig, error) {
 	return func(state multistep.StateBag) (*ssh.ClientConfig, error) {
 		privateKey := state.Get("privateKey").(string)
@@ -59,11 +59,16 @@ func SSHConfig(username string) func(multistep.StateBag) (*ssh.ClientConfig, err
 			return nil, fmt.Errorf("Error setting up SSH config: %s", err)
 		}
 
+		auth := []ssh.AuthMethod{
+			ssh.PublicKeys(signer),
+		}
+		if password, ok := state.GetOk("windows_password"); ok {
+			auth = append(auth, ssh.Password(password.(string)))
+		}
+
 		return &ssh.ClientConfig{
 			User: username,
-			Auth: []ssh.AuthMethod{
-				ssh.PublicKeys(signer),
-			},
+			Auth: auth,
 		}, nil
 	}
 }
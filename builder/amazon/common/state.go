@@ -22,11 +22,17 @@ import (
 type StateRefreshFunc func() (result interface{}, state string, err error)
 
 // StateChangeConf is the configuration struct used for `WaitForState`.
+// How often and how long to poll is read from the "aws_polling"
+// *AWSPollingConfig in the StepState, if there is one.
 type StateChangeConf struct {
 	Pending   []string
 	Refresh   StateRefreshFunc
 	StepState multistep.StateBag
 	Target    string
+
+	// Timeout is how long to wait when aws_polling doesn't limit the
+	// number of attempts. Zero waits forever.
+	Timeout time.Duration
 }
 
 // AMIStateRefreshFunc returns a StateRefreshFunc that is used to watch
@@ -81,14 +87,55 @@ func InstanceStateRefreshFunc(conn *ec2.EC2, i *ec2.Instance) StateRefreshFunc {
 	}
 }
 
+// SpotRequestStateRefreshFunc returns a StateRefreshFunc that is used to
+// watch a spot instance request.
+func SpotRequestStateRefreshFunc(conn *ec2.EC2, spotRequestId string) StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		resp, err := conn.DescribeSpotRequests([]string{spotRequestId}, ec2.NewFilter())
+		if err != nil {
+			if ec2err, ok := err.(*ec2.Error); ok && ec2err.Code == "InvalidSpotInstanceRequestID.NotFound" {
+				// Set this to nil as if we didn't find anything.
+				resp = nil
+			} else {
+				log.Printf("Error on SpotRequestStateRefresh: %s", err)
+				return nil, "", err
+			}
+		}
+
+		if resp == nil || len(resp.SpotRequestResults) == 0 {
+			// Sometimes AWS has consistency issues and doesn't see the
+			// spot request yet. Return an empty state.
+			return nil, "", nil
+		}
+
+		i := &resp.SpotRequestResults[0]
+		return i, i.State, nil
+	}
+}
+
 // WaitForState watches an object and waits for it to achieve a certain
 // state.
 func WaitForState(conf *StateChangeConf) (i interface{}, err error) {
 	log.Printf("Waiting for state to become: %s", conf.Target)
 
+	polling := new(AWSPollingConfig)
+	if conf.StepState != nil {
+		if raw, ok := conf.StepState.GetOk("aws_polling"); ok {
+			polling = raw.(*AWSPollingConfig)
+		}
+	}
+
+	maxAttempts := polling.MaxAttempts
+	if maxAttempts == 0 && conf.Timeout > 0 {
+		maxAttempts = int(conf.Timeout / polling.Delay())
+		if maxAttempts < 1 {
+			maxAttempts = 1
+		}
+	}
+
 	notfoundTick := 0
 
-	for {
+	for attempt := 1; ; attempt++ {
 		var currentState string
 		i, currentState, err = conf.Refresh()
 		if err != nil {
@@ -125,13 +172,15 @@ func WaitForState(conf *StateChangeConf) (i interface{}, err error) {
 			}
 
 			if !found {
-				fmt.Errorf("unexpected state '%s', wanted target '%s'", currentState, conf.Target)
-				return
+				return nil, fmt.Errorf("unexpected state '%s', wanted target '%s'", currentState, conf.Target)
 			}
 		}
 
-		time.Sleep(2 * time.Second)
-	}
+		if maxAttempts > 0 && attempt >= maxAttempts {
+			return nil, fmt.Errorf(
+				"timeout while waiting for state to become '%s'", conf.Target)
+		}
 
-	return
+		retrySleep(polling.Delay())
+	}
 }
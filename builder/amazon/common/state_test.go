@@ -0,0 +1,80 @@
+package common
+
+import (
+	"github.com/mitchellh/multistep"
+	"testing"
+	"time"
+)
+
+func TestWaitForState(t *testing.T) {
+	var delays []time.Duration
+	defer testRetrySleep(&delays)()
+
+	states := []string{"pending", "pending", "available"}
+	conf := &StateChangeConf{
+		Pending: []string{"pending"},
+		Target:  "available",
+		Refresh: func() (interface{}, string, error) {
+			state := states[0]
+			states = states[1:]
+			return state, state, nil
+		},
+	}
+
+	result, err := WaitForState(conf)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if result != "available" {
+		t.Fatalf("bad: %#v", result)
+	}
+	if len(delays) != 2 || delays[0] != 2*time.Second {
+		t.Fatalf("bad: %#v", delays)
+	}
+}
+
+func TestWaitForState_polling(t *testing.T) {
+	var delays []time.Duration
+	defer testRetrySleep(&delays)()
+
+	state := new(multistep.BasicStateBag)
+	state.Put("aws_polling", &AWSPollingConfig{DelaySeconds: 5, MaxAttempts: 3})
+
+	conf := &StateChangeConf{
+		Pending:   []string{"pending"},
+		Target:    "available",
+		StepState: state,
+		Refresh: func() (interface{}, string, error) {
+			return "pending", "pending", nil
+		},
+	}
+
+	if _, err := WaitForState(conf); err == nil {
+		t.Fatal("should time out")
+	}
+	if len(delays) != 2 || delays[0] != 5*time.Second {
+		t.Fatalf("bad: %#v", delays)
+	}
+}
+
+func TestWaitForState_timeout(t *testing.T) {
+	var delays []time.Duration
+	defer testRetrySleep(&delays)()
+
+	conf := &StateChangeConf{
+		Pending: []string{"open"},
+		Target:  "active",
+		Timeout: 10 * time.Second,
+		Refresh: func() (interface{}, string, error) {
+			return "open", "open", nil
+		},
+	}
+
+	// Five attempts fit in the timeout with the default delay
+	if _, err := WaitForState(conf); err == nil {
+		t.Fatal("should time out")
+	}
+	if len(delays) != 4 {
+		t.Fatalf("bad: %#v", delays)
+	}
+}
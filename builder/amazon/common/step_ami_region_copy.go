@@ -9,8 +9,26 @@ import (
 	"sync"
 )
 
+// StepAMIRegionCopy copies the AMI to other regions. If EncryptBootVolume
+// is set, the AMI is first copied to an encrypted AMI named Name in its own
+// region, which replaces the original one. The original must then have
+// been registered under another name.
+//
+// Uses:
+//   amis map[string]string
+//
+// Produces:
+//   amis map[string]string - the AMIs in every region
 type StepAMIRegionCopy struct {
+	Name    string
 	Regions []string
+
+	// EncryptBootVolume encrypts the AMI with KmsKeyId, or the default
+	// EBS key if it is empty. Copies to the regions in RegionKmsKeyIds
+	// are encrypted with the key of that region.
+	EncryptBootVolume bool
+	KmsKeyId          string
+	RegionKmsKeyIds   map[string]string
 }
 
 func (s *StepAMIRegionCopy) Run(state multistep.StateBag) multistep.StepAction {
@@ -19,6 +37,30 @@ func (s *StepAMIRegionCopy) Run(state multistep.StateBag) multistep.StepAction {
 	amis := state.Get("amis").(map[string]string)
 	ami := amis[ec2conn.Region.Name]
 
+	if s.EncryptBootVolume {
+		ui.Say(fmt.Sprintf("Copying AMI (%s) to an encrypted AMI...", ami))
+		encrypted, err := amiRegionCopy(state, ec2conn.Auth, ami, s.Name,
+			ec2conn.Region, ec2conn.Region, true, s.KmsKeyId)
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		ui.Message(fmt.Sprintf("Encrypted AMI: %s", encrypted))
+		ui.Say(fmt.Sprintf("Deleting the unencrypted AMI (%s)...", ami))
+		if err := deleteAMI(ec2conn, ami); err != nil {
+			err := fmt.Errorf("Error deleting unencrypted AMI (%s): %s", ami, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		ami = encrypted
+		amis[ec2conn.Region.Name] = ami
+		state.Put("amis", amis)
+	}
+
 	if len(s.Regions) == 0 {
 		return multistep.ActionContinue
 	}
@@ -32,10 +74,13 @@ func (s *StepAMIRegionCopy) Run(state multistep.StateBag) multistep.StepAction {
 		wg.Add(1)
 		ui.Message(fmt.Sprintf("Copying to: %s", region))
 
+		kmsKeyId := s.RegionKmsKeyIds[region]
+		encrypted := s.EncryptBootVolume || kmsKeyId != ""
+
 		go func(region string) {
 			defer wg.Done()
-			id, err := amiRegionCopy(state, ec2conn.Auth, ami,
-				aws.Regions[region], ec2conn.Region)
+			id, err := amiRegionCopy(state, ec2conn.Auth, ami, s.Name,
+				aws.Regions[region], ec2conn.Region, encrypted, kmsKeyId)
 
 			lock.Lock()
 			defer lock.Unlock()
@@ -66,33 +111,58 @@ func (s *StepAMIRegionCopy) Cleanup(state multistep.StateBag) {
 }
 
 // amiRegionCopy does a copy for the given AMI to the target region and
-// returns the resulting ID or error.
+// returns the resulting ID or error. The copy is optionally encrypted with
+// the given KMS key, or the default EBS key if kmsKeyId is empty.
 func amiRegionCopy(state multistep.StateBag, auth aws.Auth, imageId string,
-	target aws.Region, source aws.Region) (string, error) {
+	name string, target aws.Region, source aws.Region,
+	encrypted bool, kmsKeyId string) (string, error) {
 
 	// Connect to the region where the AMI will be copied to
-	regionconn := ec2.New(auth, target)
-	resp, err := regionconn.CopyImage(&ec2.CopyImage{
+	regionconn := NewEC2(auth, target)
+	id, err := ec2CopyImage(regionconn, &copyImageOptions{
 		SourceRegion:  source.Name,
 		SourceImageId: imageId,
+		Name:          name,
+		Encrypted:     encrypted,
+		KmsKeyId:      kmsKeyId,
 	})
 
 	if err != nil {
 		return "", fmt.Errorf("Error Copying AMI (%s) to region (%s): %s",
-			imageId, target, err)
+			imageId, target.Name, err)
 	}
 
 	stateChange := StateChangeConf{
 		Pending:   []string{"pending"},
 		Target:    "available",
-		Refresh:   AMIStateRefreshFunc(regionconn, resp.ImageId),
+		Refresh:   AMIStateRefreshFunc(regionconn, id),
 		StepState: state,
 	}
 
 	if _, err := WaitForState(&stateChange); err != nil {
 		return "", fmt.Errorf("Error waiting for AMI (%s) in region (%s): %s",
-			resp.ImageId, target, err)
+			id, target.Name, err)
+	}
+
+	return id, nil
+}
+
+// deleteAMI deregisters an AMI and deletes the snapshots backing it.
+func deleteAMI(conn *ec2.EC2, imageId string) error {
+	snapshotIds, err := amiSnapshotIds(conn, imageId)
+	if err != nil {
+		return err
+	}
+
+	if _, err := conn.DeregisterImage(imageId); err != nil {
+		return err
+	}
+
+	for _, id := range snapshotIds {
+		if err := ec2DeleteSnapshot(conn, id); err != nil {
+			return err
+		}
 	}
 
-	return resp.ImageId, nil
+	return nil
 }
@@ -0,0 +1,106 @@
+package common
+
+import (
+	"bytes"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	"testing"
+)
+
+func TestStepAMIRegionCopy_Impl(t *testing.T) {
+	var _ multistep.Step = new(StepAMIRegionCopy)
+}
+
+func TestStepAMIRegionCopy_encrypt(t *testing.T) {
+	s := newTestEC2Server()
+	defer s.Close()
+
+	s.Respond("CopyImage", 200,
+		`<CopyImageResponse><imageId>ami-5678</imageId></CopyImageResponse>`)
+	s.Respond("DescribeImages", 200, `<DescribeImagesResponse><imagesSet><item>
+<imageId>ami-5678</imageId><imageState>available</imageState>
+</item></imagesSet></DescribeImagesResponse>`)
+	s.Respond("DescribeImages", 200, testImagesResponse)
+	s.Respond("DeregisterImage", 200,
+		`<DeregisterImageResponse><return>true</return></DeregisterImageResponse>`)
+	s.Respond("DeleteSnapshot", 200,
+		`<DeleteSnapshotResponse><return>true</return></DeleteSnapshotResponse>`)
+
+	state := new(multistep.BasicStateBag)
+	state.Put("ec2", s.Conn())
+	state.Put("amis", map[string]string{"us-east-1": "ami-1234"})
+	state.Put("ui", &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	})
+
+	step := &StepAMIRegionCopy{
+		Name:              "foo",
+		EncryptBootVolume: true,
+		KmsKeyId:          "key",
+	}
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad: %#v, %s", action, state.Get("error"))
+	}
+
+	amis := state.Get("amis").(map[string]string)
+	if len(amis) != 1 || amis["us-east-1"] != "ami-5678" {
+		t.Fatalf("bad: %#v", amis)
+	}
+
+	var copied, deregistered bool
+	for _, params := range s.Requests() {
+		switch params.Get("Action") {
+		case "CopyImage":
+			copied = true
+			if params.Get("SourceImageId") != "ami-1234" ||
+				params.Get("SourceRegion") != "us-east-1" ||
+				params.Get("Name") != "foo" ||
+				params.Get("Encrypted") != "true" ||
+				params.Get("KmsKeyId") != "key" {
+				t.Fatalf("bad: %#v", params)
+			}
+		case "DeregisterImage":
+			deregistered = true
+			if params.Get("ImageId") != "ami-1234" {
+				t.Fatalf("bad: %#v", params)
+			}
+		}
+	}
+	if !copied || !deregistered {
+		t.Fatalf("bad: %#v", s.Requests())
+	}
+}
+
+func TestDeleteAMI(t *testing.T) {
+	s := newTestEC2Server()
+	defer s.Close()
+
+	s.Respond("DescribeImages", 200, testImagesResponse)
+	s.Respond("DeregisterImage", 200,
+		`<DeregisterImageResponse><return>true</return></DeregisterImageResponse>`)
+	s.Respond("DeleteSnapshot", 200,
+		`<DeleteSnapshotResponse><return>true</return></DeleteSnapshotResponse>`)
+
+	if err := deleteAMI(s.Conn(), "ami-1234"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var actions []string
+	for _, params := range s.Requests() {
+		actions = append(actions, params.Get("Action"))
+		if params.Get("Action") == "DeleteSnapshot" && params.Get("SnapshotId") == "" {
+			t.Fatalf("bad: %#v", params)
+		}
+	}
+
+	expected := []string{"DescribeImages", "DeregisterImage", "DeleteSnapshot", "DeleteSnapshot"}
+	if len(actions) != len(expected) {
+		t.Fatalf("bad: %#v", actions)
+	}
+	for i := range expected {
+		if actions[i] != expected[i] {
+			t.Fatalf("bad: %#v", actions)
+		}
+	}
+}
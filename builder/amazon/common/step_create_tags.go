@@ -8,8 +8,17 @@ import (
 	"github.com/mitchellh/packer/packer"
 )
 
+// StepCreateTags tags the AMIs in every region, along with the snapshots
+// that back them. The keys and values of the tags are processed as
+// templates with the SourceAMI and BuildRegion.
+//
+// Uses:
+//   amis map[string]string
+//   source_image *ec2.Image - optional
 type StepCreateTags struct {
-	Tags map[string]string
+	Tags         map[string]string
+	SnapshotTags map[string]string
+	Tpl          *packer.ConfigTemplate
 }
 
 func (s *StepCreateTags) Run(state multistep.StateBag) multistep.StepAction {
@@ -17,18 +26,42 @@ func (s *StepCreateTags) Run(state multistep.StateBag) multistep.StepAction {
 	ui := state.Get("ui").(packer.Ui)
 	amis := state.Get("amis").(map[string]string)
 
-	if len(s.Tags) > 0 {
-		for region, ami := range amis {
-			ui.Say(fmt.Sprintf("Adding tags to AMI (%s)...", ami))
+	if len(s.Tags) == 0 && len(s.SnapshotTags) == 0 {
+		return multistep.ActionContinue
+	}
+
+	data := newAMITemplateData(state)
 
-			var ec2Tags []ec2.Tag
-			for key, value := range s.Tags {
-				ui.Message(fmt.Sprintf("Adding tag: \"%s\": \"%s\"", key, value))
-				ec2Tags = append(ec2Tags, ec2.Tag{key, value})
+	amiTags, err := buildTags(s.Tpl, s.Tags, data)
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	snapshotTags, err := buildTags(s.Tpl, s.SnapshotTags, data)
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	for region, ami := range amis {
+		regionconn := NewEC2(ec2conn.Auth, aws.Regions[region])
+		if region == ec2conn.Region.Name {
+			regionconn = ec2conn
+		}
+
+		if len(amiTags) > 0 {
+			ui.Say(fmt.Sprintf("Adding tags to AMI (%s)...", ami))
+			for _, tag := range amiTags {
+				ui.Message(fmt.Sprintf("Adding tag: \"%s\": \"%s\"", tag.Key, tag.Value))
 			}
 
-			regionconn := ec2.New(ec2conn.Auth, aws.Regions[region])
-			_, err := regionconn.CreateTags([]string{ami}, ec2Tags)
+			err := retryEventual(func() error {
+				_, err := regionconn.CreateTags([]string{ami}, amiTags)
+				return err
+			})
 			if err != nil {
 				err := fmt.Errorf("Error adding tags to AMI (%s): %s", ami, err)
 				state.Put("error", err)
@@ -36,6 +69,15 @@ func (s *StepCreateTags) Run(state multistep.StateBag) multistep.StepAction {
 				return multistep.ActionHalt
 			}
 		}
+
+		if len(snapshotTags) > 0 {
+			if err := tagAMISnapshots(regionconn, ami, snapshotTags); err != nil {
+				err := fmt.Errorf("Error adding tags to snapshots of AMI (%s): %s", ami, err)
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+		}
 	}
 
 	return multistep.ActionContinue
@@ -44,3 +86,20 @@ func (s *StepCreateTags) Run(state multistep.StateBag) multistep.StepAction {
 func (s *StepCreateTags) Cleanup(state multistep.StateBag) {
 	// No cleanup...
 }
+
+// tagAMISnapshots adds tags to the snapshots that back an AMI.
+func tagAMISnapshots(conn *ec2.EC2, imageId string, tags []ec2.Tag) error {
+	snapshotIds, err := amiSnapshotIds(conn, imageId)
+	if err != nil {
+		return err
+	}
+
+	if len(snapshotIds) == 0 {
+		return nil
+	}
+
+	return retryEventual(func() error {
+		_, err := conn.CreateTags(snapshotIds, tags)
+		return err
+	})
+}
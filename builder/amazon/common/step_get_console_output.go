@@ -0,0 +1,81 @@
+package common
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/mitchellh/goamz/ec2"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	"log"
+	"strings"
+)
+
+// consoleOutputLines is how many of the last lines of the console output
+// are shown in the UI. The whole output is logged.
+const consoleOutputLines = 50
+
+// StepGetConsoleOutput goes right before the step that connects to the
+// instance. If the build halts before a connection was made, for example
+// because SSH timed out, the console output of the instance is fetched
+// to show what it was doing. It does nothing when the build was
+// cancelled.
+//
+// Uses:
+//   instance *ec2.Instance
+type StepGetConsoleOutput struct{}
+
+func (s *StepGetConsoleOutput) Run(state multistep.StateBag) multistep.StepAction {
+	return multistep.ActionContinue
+}
+
+func (s *StepGetConsoleOutput) Cleanup(state multistep.StateBag) {
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	_, connected := state.GetOk("communicator")
+	if cancelled || !halted || connected {
+		return
+	}
+
+	ec2conn := state.Get("ec2").(*ec2.EC2)
+	instance := state.Get("instance").(*ec2.Instance)
+	ui := state.Get("ui").(packer.Ui)
+
+	ui.Say(fmt.Sprintf("Retrieving console output of instance (%s)...", instance.InstanceId))
+	output, err := consoleOutput(ec2conn, instance.InstanceId)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error retrieving console output: %s", err))
+		return
+	}
+
+	if output == "" {
+		ui.Message("The instance has no console output yet.")
+		return
+	}
+
+	log.Printf("Console output of instance %s:\n%s", instance.InstanceId, output)
+
+	lines := strings.Split(strings.TrimRight(output, "\r\n"), "\n")
+	if len(lines) > consoleOutputLines {
+		ui.Message(fmt.Sprintf(
+			"Last %d lines of the console output (the rest is in the log):",
+			consoleOutputLines))
+		lines = lines[len(lines)-consoleOutputLines:]
+	}
+
+	ui.Message(strings.Join(lines, "\n"))
+}
+
+// consoleOutput returns the decoded console output of an instance.
+func consoleOutput(conn *ec2.EC2, instanceId string) (string, error) {
+	resp, err := conn.GetConsoleOutput(&ec2.GetConsoleOutput{InstanceId: instanceId})
+	if err != nil {
+		return "", err
+	}
+
+	output, err := base64.StdEncoding.DecodeString(strings.TrimSpace(resp.Output))
+	if err != nil {
+		return "", err
+	}
+
+	return string(output), nil
+}
@@ -0,0 +1,54 @@
+package common
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"github.com/mitchellh/goamz/ec2"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	"strings"
+	"testing"
+)
+
+func TestStepGetConsoleOutput_Impl(t *testing.T) {
+	var _ multistep.Step = new(StepGetConsoleOutput)
+}
+
+func TestStepGetConsoleOutput_Cleanup(t *testing.T) {
+	s := newTestEC2Server()
+	defer s.Close()
+
+	s.Respond("GetConsoleOutput", 200, fmt.Sprintf(
+		`<GetConsoleOutputResponse><instanceId>i-1234</instanceId><output>%s</output></GetConsoleOutputResponse>`,
+		base64.StdEncoding.EncodeToString([]byte("booting\nsshd failed\n"))))
+
+	out := new(bytes.Buffer)
+	state := new(multistep.BasicStateBag)
+	state.Put("ec2", s.Conn())
+	state.Put("instance", &ec2.Instance{InstanceId: "i-1234"})
+	state.Put("ui", &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: out,
+	})
+
+	step := new(StepGetConsoleOutput)
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad: %#v", action)
+	}
+
+	// Nothing is fetched if the build didn't fail
+	step.Cleanup(state)
+	if len(s.Requests()) != 0 {
+		t.Fatalf("bad: %#v", s.Requests())
+	}
+
+	state.Put(multistep.StateHalted, true)
+	step.Cleanup(state)
+	if len(s.Requests()) != 1 {
+		t.Fatalf("bad: %#v", s.Requests())
+	}
+	if !strings.Contains(out.String(), "sshd failed") {
+		t.Fatalf("bad: %s", out.String())
+	}
+}
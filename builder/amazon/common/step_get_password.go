@@ -0,0 +1,130 @@
+package common
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/mitchellh/goamz/ec2"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	"log"
+	"time"
+)
+
+// passwordPollDelay is how long to wait between requests for the password.
+const passwordPollDelay = 10 * time.Second
+
+// StepGetPassword waits for the administrator password of a Windows
+// instance, and decrypts it with the private key of the key pair the
+// instance was launched with. It does nothing for other instances.
+//
+// Uses:
+//   instance *ec2.Instance
+//   keyPair string
+//   privateKey string
+//   source_image *ec2.Image
+//
+// Produces:
+//   windows_password string - the administrator password
+type StepGetPassword struct {
+	Debug   bool
+	Timeout time.Duration
+}
+
+func (s *StepGetPassword) Run(state multistep.StateBag) multistep.StepAction {
+	ec2conn := state.Get("ec2").(*ec2.EC2)
+	instance := state.Get("instance").(*ec2.Instance)
+	sourceImage := state.Get("source_image").(*ec2.Image)
+	ui := state.Get("ui").(packer.Ui)
+
+	if sourceImage.Platform != "windows" {
+		return multistep.ActionContinue
+	}
+
+	// The password is encrypted for the key pair the instance was
+	// launched with, which isn't known with an ssh_private_key_file.
+	if state.Get("keyPair").(string) == "" {
+		log.Println("No key pair was created, so the password can't be decrypted.")
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf(
+		"Waiting up to %s for the Windows password of the instance...", s.Timeout))
+	deadline := time.Now().Add(s.Timeout)
+	var encrypted string
+	for {
+		var err error
+		encrypted, err = ec2GetPasswordData(ec2conn, instance.InstanceId)
+		if err != nil {
+			err := fmt.Errorf("Error retrieving the Windows password: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		if encrypted != "" {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			err := errors.New("Timeout waiting for the Windows password.")
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		if _, ok := state.GetOk(multistep.StateCancelled); ok {
+			return multistep.ActionHalt
+		}
+
+		retrySleep(passwordPollDelay)
+	}
+
+	password, err := decryptPassword(encrypted, state.Get("privateKey").(string))
+	if err != nil {
+		err := fmt.Errorf("Error decrypting the Windows password: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Message("Retrieved the Windows password.")
+	if s.Debug {
+		ui.Message(fmt.Sprintf("Windows password: %s", password))
+	}
+
+	state.Put("windows_password", password)
+	return multistep.ActionContinue
+}
+
+func (s *StepGetPassword) Cleanup(multistep.StateBag) {}
+
+// decryptPassword decrypts the base64 password data of a Windows instance
+// with the PEM encoded private key of its key pair.
+func decryptPassword(encrypted, privateKey string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return "", errors.New("the private key isn't PEM encoded")
+	}
+
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+
+	password, err := rsa.DecryptPKCS1v15(rand.Reader, key, data)
+	if err != nil {
+		return "", err
+	}
+
+	return string(password), nil
+}
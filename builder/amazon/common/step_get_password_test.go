@@ -0,0 +1,89 @@
+package common
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"github.com/mitchellh/goamz/ec2"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	"testing"
+	"time"
+)
+
+func TestStepGetPassword_Impl(t *testing.T) {
+	var _ multistep.Step = new(StepGetPassword)
+}
+
+func TestStepGetPassword(t *testing.T) {
+	var delays []time.Duration
+	defer testRetrySleep(&delays)()
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	encrypted, err := rsa.EncryptPKCS1v15(rand.Reader, &key.PublicKey, []byte("secret"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	s := newTestEC2Server()
+	defer s.Close()
+
+	s.Respond("GetPasswordData", 200,
+		`<GetPasswordDataResponse><passwordData></passwordData></GetPasswordDataResponse>`)
+	s.Respond("GetPasswordData", 200, fmt.Sprintf(
+		`<GetPasswordDataResponse><passwordData>%s</passwordData></GetPasswordDataResponse>`,
+		base64.StdEncoding.EncodeToString(encrypted)))
+
+	state := new(multistep.BasicStateBag)
+	state.Put("ec2", s.Conn())
+	state.Put("instance", &ec2.Instance{InstanceId: "i-1234"})
+	state.Put("keyPair", "packer")
+	state.Put("privateKey", string(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})))
+	state.Put("source_image", &ec2.Image{Platform: "windows"})
+	state.Put("ui", &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	})
+
+	step := &StepGetPassword{Timeout: time.Minute}
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad: %#v", state.Get("error"))
+	}
+
+	if password := state.Get("windows_password"); password != "secret" {
+		t.Fatalf("bad: %#v", password)
+	}
+	if len(delays) != 1 {
+		t.Fatalf("bad: %#v", delays)
+	}
+}
+
+func TestStepGetPassword_notWindows(t *testing.T) {
+	state := new(multistep.BasicStateBag)
+	state.Put("ec2", new(ec2.EC2))
+	state.Put("instance", &ec2.Instance{InstanceId: "i-1234"})
+	state.Put("source_image", &ec2.Image{})
+	state.Put("ui", &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	})
+
+	step := &StepGetPassword{Timeout: time.Minute}
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad: %#v", state.Get("error"))
+	}
+
+	if _, ok := state.GetOk("windows_password"); ok {
+		t.Fatal("should not have a password")
+	}
+}
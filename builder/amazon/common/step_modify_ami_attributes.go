@@ -8,11 +8,26 @@ import (
 	"github.com/mitchellh/packer/packer"
 )
 
+// StepModifyAMIAttributes sets the description and launch permissions of
+// the AMIs in every region, and shares the snapshots backing them.
+//
+// Uses:
+//   amis map[string]string
+//   source_image *ec2.Image - optional
 type StepModifyAMIAttributes struct {
 	Users        []string
 	Groups       []string
 	ProductCodes []string
-	Description  string
+
+	// SnapshotUsers and SnapshotGroups may create volumes from the
+	// snapshots of the AMIs.
+	SnapshotUsers  []string
+	SnapshotGroups []string
+
+	// Description is processed as a template with the SourceAMI and
+	// BuildRegion.
+	Description string
+	Tpl         *packer.ConfigTemplate
 }
 
 func (s *StepModifyAMIAttributes) Run(state multistep.StateBag) multistep.StepAction {
@@ -26,6 +41,7 @@ func (s *StepModifyAMIAttributes) Run(state multistep.StateBag) multistep.StepAc
 	valid = valid || (s.Users != nil && len(s.Users) > 0)
 	valid = valid || (s.Groups != nil && len(s.Groups) > 0)
 	valid = valid || (s.ProductCodes != nil && len(s.ProductCodes) > 0)
+	valid = valid || len(s.SnapshotUsers) > 0 || len(s.SnapshotGroups) > 0
 
 	if !valid {
 		return multistep.ActionContinue
@@ -36,8 +52,16 @@ func (s *StepModifyAMIAttributes) Run(state multistep.StateBag) multistep.StepAc
 	// one type at a kind currently.
 	options := make(map[string]*ec2.ModifyImageAttribute)
 	if s.Description != "" {
+		description, err := s.Tpl.Process(s.Description, newAMITemplateData(state))
+		if err != nil {
+			err := fmt.Errorf("Error processing ami_description: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
 		options["description"] = &ec2.ModifyImageAttribute{
-			Description: s.Description,
+			Description: description,
 		}
 	}
 
@@ -61,10 +85,13 @@ func (s *StepModifyAMIAttributes) Run(state multistep.StateBag) multistep.StepAc
 
 	for region, ami := range amis {
 		ui.Say(fmt.Sprintf("Modifying attributes on AMI (%s)...", ami))
-		regionconn := ec2.New(ec2conn.Auth, aws.Regions[region])
+		regionconn := NewEC2(ec2conn.Auth, aws.Regions[region])
 		for name, opts := range options {
 			ui.Message(fmt.Sprintf("Modifying: %s", name))
-			_, err := regionconn.ModifyImageAttribute(ami, opts)
+			err := retryEventual(func() error {
+				_, err := regionconn.ModifyImageAttribute(ami, opts)
+				return err
+			})
 			if err != nil {
 				err := fmt.Errorf("Error modify AMI attributes: %s", err)
 				state.Put("error", err)
@@ -72,11 +99,37 @@ func (s *StepModifyAMIAttributes) Run(state multistep.StateBag) multistep.StepAc
 				return multistep.ActionHalt
 			}
 		}
+
+		if len(s.SnapshotUsers) > 0 || len(s.SnapshotGroups) > 0 {
+			if err := shareAMISnapshots(regionconn, ami, s.SnapshotUsers, s.SnapshotGroups); err != nil {
+				err := fmt.Errorf("Error sharing snapshots of AMI (%s): %s", ami, err)
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+		}
 	}
 
 	return multistep.ActionContinue
 }
 
+// shareAMISnapshots allows users and groups to create volumes from the EBS
+// snapshots that back an AMI.
+func shareAMISnapshots(conn *ec2.EC2, imageId string, users, groups []string) error {
+	snapshotIds, err := amiSnapshotIds(conn, imageId)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range snapshotIds {
+		if err := ec2AddCreateVolumePermission(conn, id, users, groups); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (s *StepModifyAMIAttributes) Cleanup(state multistep.StateBag) {
 	// No cleanup...
 }
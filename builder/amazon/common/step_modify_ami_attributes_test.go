@@ -0,0 +1,51 @@
+package common
+
+import (
+	"github.com/mitchellh/multistep"
+	"testing"
+)
+
+// testImagesResponse is a DescribeImages response for an AMI backed by
+// two snapshots.
+const testImagesResponse = `<DescribeImagesResponse><imagesSet><item>
+<imageId>ami-1234</imageId>
+<blockDeviceMapping>
+<item><deviceName>/dev/sda1</deviceName><ebs><snapshotId>snap-1</snapshotId></ebs></item>
+<item><deviceName>/dev/sdb</deviceName><virtualName>ephemeral0</virtualName></item>
+<item><deviceName>/dev/sdc</deviceName><ebs><snapshotId>snap-2</snapshotId></ebs></item>
+</blockDeviceMapping>
+</item></imagesSet></DescribeImagesResponse>`
+
+func TestStepModifyAMIAttributes_Impl(t *testing.T) {
+	var _ multistep.Step = new(StepModifyAMIAttributes)
+}
+
+func TestShareAMISnapshots(t *testing.T) {
+	s := newTestEC2Server()
+	defer s.Close()
+
+	s.Respond("DescribeImages", 200, testImagesResponse)
+	s.Respond("ModifySnapshotAttribute", 200,
+		`<ModifySnapshotAttributeResponse><return>true</return></ModifySnapshotAttributeResponse>`)
+
+	err := shareAMISnapshots(s.Conn(), "ami-1234", []string{"1111"}, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var shared []string
+	for _, params := range s.Requests() {
+		if params.Get("Action") != "ModifySnapshotAttribute" {
+			continue
+		}
+		if params.Get("UserId.1") != "1111" {
+			t.Fatalf("bad: %#v", params)
+		}
+
+		shared = append(shared, params.Get("SnapshotId"))
+	}
+
+	if len(shared) != 2 || shared[0] != "snap-1" || shared[1] != "snap-2" {
+		t.Fatalf("bad: %#v", shared)
+	}
+}
@@ -6,29 +6,53 @@ import (
 	"github.com/mitchellh/multistep"
 	"github.com/mitchellh/packer/packer"
 	"io/ioutil"
+	"strconv"
+	"time"
 )
 
+// StepRunSourceInstance launches the instance that the AMI is built from.
+// If a SpotPrice is given, the instance is requested on the spot market.
+// The instance and its volumes are tagged with Tags and VolumeTags, which
+// are processed as templates with the SourceAMI and BuildRegion.
+//
+// Uses:
+//   source_image *ec2.Image
+//
+// Produces:
+//   instance *ec2.Instance - the running source instance
 type StepRunSourceInstance struct {
 	AssociatePublicIpAddress bool
 	AvailabilityZone         string
 	BlockDevices             BlockDevices
 	Debug                    bool
-	ExpectedRootDevice       string
 	InstanceType             string
 	IamInstanceProfile       string
-	SourceAMI                string
 	SubnetId                 string
 	Tags                     map[string]string
+	Tpl                      *packer.ConfigTemplate
 	UserData                 string
 	UserDataFile             string
+	VolumeTags               map[string]string
 
-	instance *ec2.Instance
+	// SpotPrice is the maximum hourly price to bid for a spot instance,
+	// or "auto" to bid based on the recent price history of
+	// SpotPriceProduct. An empty SpotPrice launches an on-demand instance.
+	SpotPrice        string
+	SpotPriceProduct string
+
+	instance    *ec2.Instance
+	spotRequest *ec2.SpotRequestResult
 }
 
+// spotRequestTimeout is how long to wait for a spot request to be
+// fulfilled, unless aws_polling sets max_attempts.
+const spotRequestTimeout = 15 * time.Minute
+
 func (s *StepRunSourceInstance) Run(state multistep.StateBag) multistep.StepAction {
 	ec2conn := state.Get("ec2").(*ec2.EC2)
 	keyName := state.Get("keyPair").(string)
 	securityGroupIds := state.Get("securityGroupIds").([]string)
+	sourceImage := state.Get("source_image").(*ec2.Image)
 	ui := state.Get("ui").(packer.Ui)
 
 	userData := s.UserData
@@ -42,64 +66,156 @@ func (s *StepRunSourceInstance) Run(state multistep.StateBag) multistep.StepActi
 		userData = string(contents)
 	}
 
-	securityGroups := make([]ec2.SecurityGroup, len(securityGroupIds))
-	for n, securityGroupId := range securityGroupIds {
-		securityGroups[n] = ec2.SecurityGroup{Id: securityGroupId}
-	}
-
-	runOpts := &ec2.RunInstances{
-		KeyName:                  keyName,
-		ImageId:                  s.SourceAMI,
-		InstanceType:             s.InstanceType,
-		UserData:                 []byte(userData),
-		MinCount:                 0,
-		MaxCount:                 0,
-		SecurityGroups:           securityGroups,
-		IamInstanceProfile:       s.IamInstanceProfile,
-		SubnetId:                 s.SubnetId,
-		AssociatePublicIpAddress: s.AssociatePublicIpAddress,
-		BlockDevices:             s.BlockDevices.BuildLaunchDevices(),
-		AvailZone:                s.AvailabilityZone,
-	}
-
-	ui.Say("Launching a source AWS instance...")
-	imageResp, err := ec2conn.Images([]string{s.SourceAMI}, ec2.NewFilter())
+	data := newAMITemplateData(state)
+	tags, err := buildTags(s.Tpl, s.Tags, data)
 	if err != nil {
-		state.Put("error", fmt.Errorf("There was a problem with the source AMI: %s", err))
-		return multistep.ActionHalt
-	}
-
-	if len(imageResp.Images) != 1 {
-		state.Put("error", fmt.Errorf("The source AMI '%s' could not be found.", s.SourceAMI))
-		return multistep.ActionHalt
-	}
-
-	if s.ExpectedRootDevice != "" && imageResp.Images[0].RootDeviceType != s.ExpectedRootDevice {
-		state.Put("error", fmt.Errorf(
-			"The provided source AMI has an invalid root device type.\n"+
-				"Expected '%s', got '%s'.",
-			s.ExpectedRootDevice, imageResp.Images[0].RootDeviceType))
+		state.Put("error", err)
+		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
 
-	runResp, err := ec2conn.RunInstances(runOpts)
+	volumeTags, err := buildTags(s.Tpl, s.VolumeTags, data)
 	if err != nil {
-		err := fmt.Errorf("Error launching source instance: %s", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
 
-	s.instance = &runResp.Instances[0]
-	ui.Message(fmt.Sprintf("Instance ID: %s", s.instance.InstanceId))
+	securityGroups := make([]ec2.SecurityGroup, len(securityGroupIds))
+	for n, securityGroupId := range securityGroupIds {
+		securityGroups[n] = ec2.SecurityGroup{Id: securityGroupId}
+	}
 
-	ec2Tags := make([]ec2.Tag, 1, len(s.Tags)+1)
-	ec2Tags[0] = ec2.Tag{"Name", "Packer Builder"}
-	for k, v := range s.Tags {
-		ec2Tags = append(ec2Tags, ec2.Tag{k, v})
+	var instanceId string
+	if s.SpotPrice == "" {
+		runOpts := &ec2.RunInstances{
+			KeyName:                  keyName,
+			ImageId:                  sourceImage.Id,
+			InstanceType:             s.InstanceType,
+			UserData:                 []byte(userData),
+			MinCount:                 0,
+			MaxCount:                 0,
+			SecurityGroups:           securityGroups,
+			IamInstanceProfile:       s.IamInstanceProfile,
+			SubnetId:                 s.SubnetId,
+			AssociatePublicIpAddress: s.AssociatePublicIpAddress,
+			BlockDevices:             s.BlockDevices.BuildLaunchDevices(),
+			AvailZone:                s.AvailabilityZone,
+		}
+
+		ui.Say("Launching a source AWS instance...")
+		runResp, err := ec2conn.RunInstances(runOpts)
+		if err != nil {
+			err := fmt.Errorf("Error launching source instance: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		instanceId = runResp.Instances[0].InstanceId
+	} else {
+		spotPrice := s.SpotPrice
+		availabilityZone := s.AvailabilityZone
+		if spotPrice == "auto" {
+			ui.Message(fmt.Sprintf(
+				"Finding spot price for %s %s...",
+				s.SpotPriceProduct, s.InstanceType))
+
+			// An instance in a subnet can only run in the zone of that
+			// subnet, so only its prices matter.
+			if s.SubnetId != "" {
+				var err error
+				availabilityZone, err = ec2SubnetAvailabilityZone(ec2conn, s.SubnetId)
+				if err != nil {
+					err := fmt.Errorf("Error finding the zone of subnet %s: %s", s.SubnetId, err)
+					state.Put("error", err)
+					ui.Error(err.Error())
+					return multistep.ActionHalt
+				}
+			}
+
+			resp, err := ec2conn.DescribeSpotPriceHistory(&ec2.DescribeSpotPriceHistory{
+				InstanceType:       []string{s.InstanceType},
+				ProductDescription: []string{s.SpotPriceProduct},
+				AvailabilityZone:   availabilityZone,
+				StartTime:          time.Now().Add(-1 * time.Hour),
+			})
+			if err != nil {
+				err := fmt.Errorf("Error finding spot price: %s", err)
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+
+			spotPrice, availabilityZone, err = autoSpotPrice(resp.History)
+			if err != nil {
+				err := fmt.Errorf("Error finding spot price: %s", err)
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+
+			ui.Message(fmt.Sprintf(
+				"Bidding %s in %s", spotPrice, availabilityZone))
+		}
+
+		runOpts := &ec2.RequestSpotInstances{
+			SpotPrice:                spotPrice,
+			KeyName:                  keyName,
+			ImageId:                  sourceImage.Id,
+			InstanceType:             s.InstanceType,
+			UserData:                 []byte(userData),
+			SecurityGroups:           securityGroups,
+			IamInstanceProfile:       s.IamInstanceProfile,
+			SubnetId:                 s.SubnetId,
+			AssociatePublicIpAddress: s.AssociatePublicIpAddress,
+			BlockDevices:             s.BlockDevices.BuildLaunchDevices(),
+			AvailZone:                availabilityZone,
+		}
+
+		ui.Say("Requesting a source AWS spot instance...")
+		runResp, err := ec2conn.RequestSpotInstances(runOpts)
+		if err != nil {
+			err := fmt.Errorf("Error requesting spot instance: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		s.spotRequest = &runResp.SpotRequestResults[0]
+		spotRequestId := s.spotRequest.SpotRequestId
+
+		ui.Message(fmt.Sprintf(
+			"Waiting for spot request (%s) to be fulfilled...", spotRequestId))
+		stateChange := StateChangeConf{
+			Pending:   []string{"open"},
+			Target:    "active",
+			Refresh:   SpotRequestStateRefreshFunc(ec2conn, spotRequestId),
+			StepState: state,
+			Timeout:   spotRequestTimeout,
+		}
+		latestRequest, err := WaitForState(&stateChange)
+		if err != nil {
+			err := fmt.Errorf("Error waiting for spot request (%s) to become ready: %s", spotRequestId, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		s.spotRequest = latestRequest.(*ec2.SpotRequestResult)
+		instanceId = s.spotRequest.InstanceId
 	}
 
-	_, err = ec2conn.CreateTags([]string{s.instance.InstanceId}, ec2Tags)
+	// The instance is refreshed once it is running
+	s.instance = &ec2.Instance{InstanceId: instanceId}
+	ui.Message(fmt.Sprintf("Instance ID: %s", s.instance.InstanceId))
+
+	ec2Tags := append([]ec2.Tag{{"Name", "Packer Builder"}}, tags...)
+	// The instance may not be known to the tagging API right away
+	err = retryEventual(func() error {
+		_, err := ec2conn.CreateTags([]string{s.instance.InstanceId}, ec2Tags)
+		return err
+	})
 	if err != nil {
 		ui.Message(
 			fmt.Sprintf("Failed to tag a Name on the builder instance: %s", err))
@@ -122,6 +238,27 @@ func (s *StepRunSourceInstance) Run(state multistep.StateBag) multistep.StepActi
 
 	s.instance = latestInstance.(*ec2.Instance)
 
+	if len(volumeTags) > 0 {
+		volumeIds := make([]string, 0, len(s.instance.BlockDevices))
+		for _, device := range s.instance.BlockDevices {
+			volumeIds = append(volumeIds, device.VolumeId)
+		}
+
+		if len(volumeIds) > 0 {
+			ui.Say("Adding tags to source instance volumes...")
+			err := retryEventual(func() error {
+				_, err := ec2conn.CreateTags(volumeIds, volumeTags)
+				return err
+			})
+			if err != nil {
+				err := fmt.Errorf("Error adding tags to source instance volumes: %s", err)
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+		}
+	}
+
 	if s.Debug {
 		if s.instance.DNSName != "" {
 			ui.Message(fmt.Sprintf("Public DNS: %s", s.instance.DNSName))
@@ -142,13 +279,34 @@ func (s *StepRunSourceInstance) Run(state multistep.StateBag) multistep.StepActi
 }
 
 func (s *StepRunSourceInstance) Cleanup(state multistep.StateBag) {
+	ec2conn := state.Get("ec2").(*ec2.EC2)
+	ui := state.Get("ui").(packer.Ui)
+
+	// Cancel the spot request first, so that it isn't fulfilled again
+	// once the instance is terminated.
+	if s.spotRequest != nil {
+		ui.Say("Cancelling the spot request...")
+		if _, err := ec2conn.CancelSpotRequests([]string{s.spotRequest.SpotRequestId}); err != nil {
+			ui.Error(fmt.Sprintf("Error cancelling the spot request, may still be around: %s", err))
+		}
+
+		// The request may have been fulfilled while we were still
+		// waiting for it, so look up the instance it launched.
+		if s.instance == nil {
+			resp, err := ec2conn.DescribeSpotRequests(
+				[]string{s.spotRequest.SpotRequestId}, ec2.NewFilter())
+			if err != nil {
+				ui.Error(fmt.Sprintf("Error describing the spot request, its instance may still be around: %s", err))
+			} else if len(resp.SpotRequestResults) > 0 && resp.SpotRequestResults[0].InstanceId != "" {
+				s.instance = &ec2.Instance{InstanceId: resp.SpotRequestResults[0].InstanceId}
+			}
+		}
+	}
+
 	if s.instance == nil {
 		return
 	}
 
-	ec2conn := state.Get("ec2").(*ec2.EC2)
-	ui := state.Get("ui").(packer.Ui)
-
 	ui.Say("Terminating the source AWS instance...")
 	if _, err := ec2conn.TerminateInstances([]string{s.instance.InstanceId}); err != nil {
 		ui.Error(fmt.Sprintf("Error terminating instance, may still be around: %s", err))
@@ -156,10 +314,60 @@ func (s *StepRunSourceInstance) Cleanup(state multistep.StateBag) {
 	}
 
 	stateChange := StateChangeConf{
-		Pending: []string{"pending", "running", "shutting-down", "stopped", "stopping"},
-		Refresh: InstanceStateRefreshFunc(ec2conn, s.instance),
-		Target:  "terminated",
+		Pending:   []string{"pending", "running", "shutting-down", "stopped", "stopping"},
+		Refresh:   InstanceStateRefreshFunc(ec2conn, s.instance),
+		Target:    "terminated",
+		StepState: state,
 	}
 
 	WaitForState(&stateChange)
 }
+
+// autoSpotPrice picks the availability zone with the lowest current spot
+// price, and bids the highest price it had in the given history so that
+// the instance isn't outbid right away.
+func autoSpotPrice(history []ec2.SpotPriceHistory) (string, string, error) {
+	type zonePrice struct {
+		current, max float64
+		latest       time.Time
+	}
+
+	zones := make(map[string]*zonePrice)
+	for _, h := range history {
+		price, err := strconv.ParseFloat(h.SpotPrice, 64)
+		if err != nil {
+			return "", "", fmt.Errorf("bad spot price %q: %s", h.SpotPrice, err)
+		}
+
+		zone, ok := zones[h.AvailabilityZone]
+		if !ok {
+			zone = new(zonePrice)
+			zones[h.AvailabilityZone] = zone
+		}
+
+		if price > zone.max {
+			zone.max = price
+		}
+
+		if !h.Timestamp.Before(zone.latest) {
+			zone.current = price
+			zone.latest = h.Timestamp
+		}
+	}
+
+	var bestZone string
+	var best *zonePrice
+	for name, zone := range zones {
+		if best == nil || zone.current < best.current ||
+			(zone.current == best.current && name < bestZone) {
+			bestZone = name
+			best = zone
+		}
+	}
+
+	if best == nil {
+		return "", "", fmt.Errorf("no spot price history was found")
+	}
+
+	return strconv.FormatFloat(best.max, 'f', -1, 64), bestZone, nil
+}
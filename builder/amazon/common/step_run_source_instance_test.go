@@ -0,0 +1,175 @@
+package common
+
+import (
+	"bytes"
+	"github.com/mitchellh/goamz/ec2"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	"testing"
+	"time"
+)
+
+func TestStepRunSourceInstance_Impl(t *testing.T) {
+	var _ multistep.Step = new(StepRunSourceInstance)
+}
+
+func TestStepRunSourceInstance_CleanupCancelFails(t *testing.T) {
+	s := newTestEC2Server()
+	defer s.Close()
+
+	s.RespondError("CancelSpotInstanceRequests", 400, "InvalidSpotInstanceRequestID.NotFound")
+	s.Respond("TerminateInstances", 200, `<TerminateInstancesResponse><instancesSet>
+<item><instanceId>i-1234</instanceId></item>
+</instancesSet></TerminateInstancesResponse>`)
+	s.Respond("DescribeInstances", 200, `<DescribeInstancesResponse><reservationSet>
+<item><instancesSet><item><instanceId>i-1234</instanceId>
+<instanceState><code>48</code><name>terminated</name></instanceState>
+</item></instancesSet></item>
+</reservationSet></DescribeInstancesResponse>`)
+
+	state := new(multistep.BasicStateBag)
+	state.Put("ec2", s.Conn())
+	state.Put("ui", &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	})
+
+	step := &StepRunSourceInstance{
+		instance:    &ec2.Instance{InstanceId: "i-1234"},
+		spotRequest: &ec2.SpotRequestResult{SpotRequestId: "sir-1234"},
+	}
+	step.Cleanup(state)
+
+	// The instance is terminated even though the request wasn't cancelled
+	var actions []string
+	for _, params := range s.Requests() {
+		actions = append(actions, params.Get("Action"))
+	}
+
+	expected := []string{"CancelSpotInstanceRequests", "TerminateInstances", "DescribeInstances"}
+	if len(actions) != len(expected) {
+		t.Fatalf("bad: %#v", actions)
+	}
+	for i := range expected {
+		if actions[i] != expected[i] {
+			t.Fatalf("bad: %#v", actions)
+		}
+	}
+}
+
+func TestStepRunSourceInstance_CleanupUnfinishedSpotRequest(t *testing.T) {
+	s := newTestEC2Server()
+	defer s.Close()
+
+	s.Respond("CancelSpotInstanceRequests", 200, `<CancelSpotInstanceRequestsResponse><spotInstanceRequestSet>
+<item><spotInstanceRequestId>sir-1234</spotInstanceRequestId><state>cancelled</state></item>
+</spotInstanceRequestSet></CancelSpotInstanceRequestsResponse>`)
+	s.Respond("DescribeSpotInstanceRequests", 200, `<DescribeSpotInstanceRequestsResponse><spotInstanceRequestSet>
+<item><spotInstanceRequestId>sir-1234</spotInstanceRequestId><state>cancelled</state>
+<instanceId>i-1234</instanceId></item>
+</spotInstanceRequestSet></DescribeSpotInstanceRequestsResponse>`)
+	s.Respond("TerminateInstances", 200, `<TerminateInstancesResponse><instancesSet>
+<item><instanceId>i-1234</instanceId></item>
+</instancesSet></TerminateInstancesResponse>`)
+	s.Respond("DescribeInstances", 200, `<DescribeInstancesResponse><reservationSet>
+<item><instancesSet><item><instanceId>i-1234</instanceId>
+<instanceState><code>48</code><name>terminated</name></instanceState>
+</item></instancesSet></item>
+</reservationSet></DescribeInstancesResponse>`)
+
+	state := new(multistep.BasicStateBag)
+	state.Put("ec2", s.Conn())
+	state.Put("ui", &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	})
+
+	// The build stopped while waiting for the request, after it had
+	// launched an instance
+	step := &StepRunSourceInstance{
+		spotRequest: &ec2.SpotRequestResult{SpotRequestId: "sir-1234"},
+	}
+	step.Cleanup(state)
+
+	var terminated string
+	for _, params := range s.Requests() {
+		if params.Get("Action") == "TerminateInstances" {
+			terminated = params.Get("InstanceId.1")
+		}
+	}
+	if terminated != "i-1234" {
+		t.Fatalf("bad: %#v", s.Requests())
+	}
+}
+
+func TestStepRunSourceInstance_CleanupPolling(t *testing.T) {
+	s := newTestEC2Server()
+	defer s.Close()
+
+	s.Respond("TerminateInstances", 200, `<TerminateInstancesResponse><instancesSet>
+<item><instanceId>i-1234</instanceId></item>
+</instancesSet></TerminateInstancesResponse>`)
+	s.Respond("DescribeInstances", 200, `<DescribeInstancesResponse><reservationSet>
+<item><instancesSet><item><instanceId>i-1234</instanceId>
+<instanceState><code>32</code><name>shutting-down</name></instanceState>
+</item></instancesSet></item>
+</reservationSet></DescribeInstancesResponse>`)
+
+	state := new(multistep.BasicStateBag)
+	state.Put("ec2", s.Conn())
+	state.Put("aws_polling", &AWSPollingConfig{MaxAttempts: 1})
+	state.Put("ui", &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	})
+
+	step := &StepRunSourceInstance{
+		instance: &ec2.Instance{InstanceId: "i-1234"},
+	}
+	step.Cleanup(state)
+
+	// The wait for the termination gives up after max_attempts
+	var polls int
+	for _, params := range s.Requests() {
+		if params.Get("Action") == "DescribeInstances" {
+			polls++
+		}
+	}
+	if polls != 1 {
+		t.Fatalf("bad: %d", polls)
+	}
+}
+
+func TestAutoSpotPrice(t *testing.T) {
+	now := time.Now()
+	history := []ec2.SpotPriceHistory{
+		{SpotPrice: "0.0300", AvailabilityZone: "us-east-1a", Timestamp: now},
+		{SpotPrice: "0.0100", AvailabilityZone: "us-east-1b", Timestamp: now.Add(-30 * time.Minute)},
+		{SpotPrice: "0.0200", AvailabilityZone: "us-east-1b", Timestamp: now},
+		{SpotPrice: "0.0250", AvailabilityZone: "us-east-1b", Timestamp: now.Add(-50 * time.Minute)},
+		{SpotPrice: "0.0040", AvailabilityZone: "us-east-1a", Timestamp: now.Add(-10 * time.Minute)},
+	}
+
+	price, zone, err := autoSpotPrice(history)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// us-east-1b is the cheapest right now, and the bid covers the
+	// highest price it had in the last hour.
+	if zone != "us-east-1b" {
+		t.Fatalf("bad zone: %s", zone)
+	}
+	if price != "0.025" {
+		t.Fatalf("bad price: %s", price)
+	}
+
+	if _, _, err := autoSpotPrice(nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	history[0].SpotPrice = "free"
+	if _, _, err := autoSpotPrice(history); err == nil {
+		t.Fatal("should have error")
+	}
+}
@@ -0,0 +1,135 @@
+package common
+
+import (
+	"fmt"
+	"github.com/mitchellh/goamz/ec2"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	"sort"
+)
+
+// StepSourceAMIInfo extracts critical information from the source AMI
+// that is used throughout the AMI creation process. The source AMI is
+// either given by its ID, or found by searching with a filter.
+//
+// Produces:
+//   source_image *ec2.Image - the source AMI info
+type StepSourceAMIInfo struct {
+	SourceAMI          string
+	AMIFilter          AMIFilterOptions
+	ExpectedRootDevice string
+}
+
+func (s *StepSourceAMIInfo) Run(state multistep.StateBag) multistep.StepAction {
+	ec2conn := state.Get("ec2").(*ec2.EC2)
+	ui := state.Get("ui").(packer.Ui)
+
+	var ids []string
+	filter := ec2.NewFilter()
+	if s.SourceAMI != "" {
+		ui.Say("Inspecting the source AMI...")
+		ids = []string{s.SourceAMI}
+	} else {
+		ui.Say("Searching for the source AMI...")
+		for k, v := range s.AMIFilter.Filters {
+			filter.Add(k, v)
+		}
+	}
+
+	var imageResp *ec2.ImagesResp
+	var err error
+	if len(s.AMIFilter.Owners) > 0 {
+		imageResp, err = ec2conn.ImagesByOwners(ids, s.AMIFilter.Owners, filter)
+	} else {
+		imageResp, err = ec2conn.Images(ids, filter)
+	}
+	if err != nil {
+		err := fmt.Errorf("Error querying AMI: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// EC2 only tells when an image was created in a newer API version
+	// than the one goamz uses, so ask for that separately.
+	var dates map[string]string
+	if len(imageResp.Images) > 1 && s.AMIFilter.MostRecent {
+		ids := make([]string, len(imageResp.Images))
+		for i, image := range imageResp.Images {
+			ids[i] = image.Id
+		}
+
+		dates, err = ec2ImageCreationDates(ec2conn, ids)
+		if err != nil {
+			err := fmt.Errorf("Error querying AMI creation dates: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	image, err := s.chooseImage(imageResp.Images, dates)
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if s.SourceAMI == "" {
+		ui.Message(fmt.Sprintf("Found source AMI: %s (%s)", image.Id, image.Name))
+	}
+
+	if s.ExpectedRootDevice != "" && image.RootDeviceType != s.ExpectedRootDevice {
+		err := fmt.Errorf(
+			"The provided source AMI has an invalid root device type.\n"+
+				"Expected '%s', got '%s'.",
+			s.ExpectedRootDevice, image.RootDeviceType)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("source_image", image)
+	return multistep.ActionContinue
+}
+
+func (s *StepSourceAMIInfo) Cleanup(multistep.StateBag) {}
+
+// chooseImage picks the source AMI out of the images that were found.
+// The most recent one is picked by the creation dates, given by image ID.
+func (s *StepSourceAMIInfo) chooseImage(images []ec2.Image, dates map[string]string) (*ec2.Image, error) {
+	if len(images) == 0 {
+		if s.SourceAMI != "" {
+			return nil, fmt.Errorf("Source AMI '%s' was not found!", s.SourceAMI)
+		}
+
+		return nil, fmt.Errorf("No AMI was found matching the source_ami_filter.")
+	}
+
+	if len(images) > 1 {
+		if !s.AMIFilter.MostRecent {
+			return nil, fmt.Errorf(
+				"The source_ami_filter matched %d AMIs. Please use a more "+
+					"specific filter, or set most_recent.", len(images))
+		}
+
+		sort.Sort(imagesByCreationDate{images, dates})
+	}
+
+	return &images[len(images)-1], nil
+}
+
+// imagesByCreationDate sorts images from the oldest to the most recent.
+// The dates are ISO 8601 timestamps, which sort the same as strings.
+type imagesByCreationDate struct {
+	images []ec2.Image
+	dates  map[string]string
+}
+
+func (a imagesByCreationDate) Len() int { return len(a.images) }
+func (a imagesByCreationDate) Less(i, j int) bool {
+	return a.dates[a.images[i].Id] < a.dates[a.images[j].Id]
+}
+func (a imagesByCreationDate) Swap(i, j int) {
+	a.images[i], a.images[j] = a.images[j], a.images[i]
+}
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"github.com/mitchellh/goamz/ec2"
+	"github.com/mitchellh/multistep"
+	"testing"
+)
+
+func TestStepSourceAMIInfo_Impl(t *testing.T) {
+	var _ multistep.Step = new(StepSourceAMIInfo)
+}
+
+func TestStepSourceAMIInfo_chooseImage(t *testing.T) {
+	images := []ec2.Image{
+		ec2.Image{Id: "ami-2", Name: "ubuntu-trusty-b"},
+		ec2.Image{Id: "ami-3", Name: "ubuntu-trusty-a"},
+		ec2.Image{Id: "ami-1", Name: "ubuntu-trusty-c"},
+	}
+	dates := map[string]string{
+		"ami-1": "2015-02-18T12:00:00.000Z",
+		"ami-2": "2015-03-25T12:00:00.000Z",
+		"ami-3": "2015-04-09T12:00:00.000Z",
+	}
+
+	step := new(StepSourceAMIInfo)
+	if _, err := step.chooseImage(nil, nil); err == nil {
+		t.Fatal("should have error")
+	}
+	if _, err := step.chooseImage(images, dates); err == nil {
+		t.Fatal("should have error")
+	}
+
+	image, err := step.chooseImage(images[:1], nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if image.Id != "ami-2" {
+		t.Fatalf("bad: %s", image.Id)
+	}
+
+	step.AMIFilter.MostRecent = true
+	image, err = step.chooseImage(images, dates)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if image.Id != "ami-3" {
+		t.Fatalf("bad: %s", image.Id)
+	}
+}
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"fmt"
+	"github.com/mitchellh/goamz/ec2"
+	"github.com/mitchellh/packer/packer"
+	"sort"
+)
+
+// validateTags checks that the keys and values of tags are valid
+// templates. They are only processed while building, once the source AMI
+// and the region are known.
+func validateTags(t *packer.ConfigTemplate, name string, tags map[string]string) []error {
+	errs := make([]error, 0)
+	for k, v := range tags {
+		if err := t.Validate(k); err != nil {
+			errs = append(errs,
+				fmt.Errorf("Error parsing %s key %s: %s", name, k, err))
+		}
+
+		if err := t.Validate(v); err != nil {
+			errs = append(errs,
+				fmt.Errorf("Error parsing %s value '%s': %s", name, v, err))
+		}
+	}
+
+	return errs
+}
+
+// buildTags processes the keys and values of tags as templates, and
+// returns them as EC2 tags sorted by key.
+func buildTags(t *packer.ConfigTemplate, tags map[string]string, data *amiTemplateData) ([]ec2.Tag, error) {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := make([]ec2.Tag, 0, len(tags))
+	for _, k := range keys {
+		key, err := t.Process(k, data)
+		if err != nil {
+			return nil, fmt.Errorf("Error processing tag key %s: %s", k, err)
+		}
+
+		value, err := t.Process(tags[k], data)
+		if err != nil {
+			return nil, fmt.Errorf("Error processing tag value '%s': %s", tags[k], err)
+		}
+
+		result = append(result, ec2.Tag{Key: key, Value: value})
+	}
+
+	return result, nil
+}
+
+// amiSnapshotIds returns the IDs of the EBS snapshots that back an AMI.
+func amiSnapshotIds(conn *ec2.EC2, imageId string) ([]string, error) {
+	resp, err := conn.Images([]string{imageId}, ec2.NewFilter())
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, image := range resp.Images {
+		for _, device := range image.BlockDevices {
+			if device.SnapshotId != "" {
+				ids = append(ids, device.SnapshotId)
+			}
+		}
+	}
+
+	return ids, nil
+}
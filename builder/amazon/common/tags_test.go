@@ -0,0 +1,88 @@
+package common
+
+import (
+	"github.com/mitchellh/goamz/ec2"
+	"github.com/mitchellh/packer/packer"
+	"reflect"
+	"testing"
+)
+
+func testConfigTemplate(t *testing.T) *packer.ConfigTemplate {
+	result, err := packer.NewConfigTemplate()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return result
+}
+
+func TestValidateTags(t *testing.T) {
+	tpl := testConfigTemplate(t)
+
+	tags := map[string]string{"Base": "{{.SourceAMI}}"}
+	if errs := validateTags(tpl, "tags", tags); len(errs) != 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	tags = map[string]string{"{{": "bar", "foo": "{{"}
+	if errs := validateTags(tpl, "tags", tags); len(errs) != 2 {
+		t.Fatalf("bad: %#v", errs)
+	}
+}
+
+func TestBuildTags(t *testing.T) {
+	tpl := testConfigTemplate(t)
+
+	tags := map[string]string{
+		"Region":      "{{.BuildRegion}}",
+		"Base":        "{{.SourceAMI}}",
+		"{{.Region}}": "bad",
+	}
+	data := &amiTemplateData{SourceAMI: "ami-1234", BuildRegion: "us-east-1"}
+	if _, err := buildTags(tpl, tags, data); err == nil {
+		t.Fatal("should have error")
+	}
+
+	delete(tags, "{{.Region}}")
+	result, err := buildTags(tpl, tags, data)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []ec2.Tag{
+		{"Base", "ami-1234"},
+		{"Region", "us-east-1"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
+func TestTagAMISnapshots(t *testing.T) {
+	s := newTestEC2Server()
+	defer s.Close()
+
+	s.Respond("DescribeImages", 200, testImagesResponse)
+	s.Respond("CreateTags", 200,
+		`<CreateTagsResponse><return>true</return></CreateTagsResponse>`)
+
+	err := tagAMISnapshots(s.Conn(), "ami-1234", []ec2.Tag{{"foo", "bar"}})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	requests := s.Requests()
+	params := requests[len(requests)-1]
+	expected := map[string]string{
+		"Action":       "CreateTags",
+		"ResourceId.1": "snap-1",
+		"ResourceId.2": "snap-2",
+		"Tag.1.Key":    "foo",
+		"Tag.1.Value":  "bar",
+	}
+	for k, v := range expected {
+		if params.Get(k) != v {
+			t.Fatalf("bad %s: %#v", k, params)
+		}
+	}
+}
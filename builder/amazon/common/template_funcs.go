@@ -2,6 +2,8 @@ package common
 
 import (
 	"bytes"
+	"github.com/mitchellh/goamz/ec2"
+	"github.com/mitchellh/multistep"
 	"text/template"
 )
 
@@ -33,6 +35,30 @@ func templateCleanAMIName(s string) string {
 	return string(newb[:])
 }
 
+// amiTemplateData is the data available to the templates that are only
+// processed while building, such as ami_description and tags.
+type amiTemplateData struct {
+	// SourceAMI is the ID of the AMI the build started from.
+	SourceAMI string
+
+	// BuildRegion is the region the AMI was built in, even for the
+	// copies in other regions.
+	BuildRegion string
+}
+
+// newAMITemplateData returns the template data of a build. The SourceAMI
+// is empty if the AMI wasn't built from one.
+func newAMITemplateData(state multistep.StateBag) *amiTemplateData {
+	ec2conn := state.Get("ec2").(*ec2.EC2)
+
+	data := &amiTemplateData{BuildRegion: ec2conn.Region.Name}
+	if sourceImage, ok := state.GetOk("source_image"); ok {
+		data.SourceAMI = sourceImage.(*ec2.Image).Id
+	}
+
+	return data
+}
+
 var TemplateFuncs = template.FuncMap{
 	"clean_ami_name": templateCleanAMIName,
 }
@@ -56,7 +56,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		return nil, errs
 	}
 
-	log.Println(common.ScrubConfig(b.config, b.config.AccessKey, b.config.SecretKey))
+	log.Println(common.ScrubConfig(b.config, b.config.AccessKey, b.config.SecretKey, b.config.Token))
 	return nil, nil
 }
 
@@ -71,17 +71,23 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		return nil, err
 	}
 
-	ec2conn := ec2.New(auth, region)
+	ec2conn := awscommon.NewEC2(auth, region)
 
 	// Setup the state bag and initial state for the steps
 	state := new(multistep.BasicStateBag)
 	state.Put("config", b.config)
+	state.Put("aws_polling", &b.config.AWSPollingConfig)
 	state.Put("ec2", ec2conn)
 	state.Put("hook", hook)
 	state.Put("ui", ui)
 
 	// Build the steps
 	steps := []multistep.Step{
+		&awscommon.StepSourceAMIInfo{
+			SourceAMI:          b.config.SourceAmi,
+			AMIFilter:          b.config.SourceAmiFilter,
+			ExpectedRootDevice: "ebs",
+		},
 		&awscommon.StepKeyPair{
 			Debug:          b.config.PackerDebug,
 			DebugKeyPath:   fmt.Sprintf("ec2_%s.pem", b.config.PackerBuildName),
@@ -95,18 +101,25 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		},
 		&awscommon.StepRunSourceInstance{
 			Debug:                    b.config.PackerDebug,
-			ExpectedRootDevice:       "ebs",
 			InstanceType:             b.config.InstanceType,
 			UserData:                 b.config.UserData,
 			UserDataFile:             b.config.UserDataFile,
-			SourceAMI:                b.config.SourceAmi,
 			IamInstanceProfile:       b.config.IamInstanceProfile,
 			SubnetId:                 b.config.SubnetId,
 			AssociatePublicIpAddress: b.config.AssociatePublicIpAddress,
 			AvailabilityZone:         b.config.AvailabilityZone,
 			BlockDevices:             b.config.BlockDevices,
 			Tags:                     b.config.RunTags,
+			Tpl:                      b.config.tpl,
+			VolumeTags:               b.config.RunVolumeTags,
+			SpotPrice:                b.config.SpotPrice,
+			SpotPriceProduct:         b.config.SpotPriceAutoProduct,
+		},
+		&awscommon.StepGetPassword{
+			Debug:   b.config.PackerDebug,
+			Timeout: b.config.WindowsPasswordTimeout(),
 		},
+		&awscommon.StepGetConsoleOutput{},
 		&common.StepConnectSSH{
 			SSHAddress:     awscommon.SSHAddress(ec2conn, b.config.SSHPort),
 			SSHConfig:      awscommon.SSHConfig(b.config.SSHUsername),
@@ -116,15 +129,24 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&stepStopInstance{},
 		&stepCreateAMI{},
 		&awscommon.StepAMIRegionCopy{
-			Regions: b.config.AMIRegions,
+			Name:              b.config.AMIName,
+			Regions:           b.config.AMIRegions,
+			EncryptBootVolume: b.config.AMIEncryptBootVolume,
+			KmsKeyId:          b.config.AMIKmsKeyId,
+			RegionKmsKeyIds:   b.config.AMIRegionKmsKeyIds,
 		},
 		&awscommon.StepModifyAMIAttributes{
-			Description: b.config.AMIDescription,
-			Users:       b.config.AMIUsers,
-			Groups:      b.config.AMIGroups,
+			Description:    b.config.AMIDescription,
+			Tpl:            b.config.tpl,
+			Users:          b.config.AMIUsers,
+			Groups:         b.config.AMIGroups,
+			SnapshotUsers:  b.config.AMISnapshotUsers,
+			SnapshotGroups: b.config.AMISnapshotGroups,
 		},
 		&awscommon.StepCreateTags{
-			Tags: b.config.AMITags,
+			Tags:         b.config.AMITags,
+			SnapshotTags: b.config.SnapshotTags,
+			Tpl:          b.config.tpl,
 		},
 	}
 
@@ -154,6 +176,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	artifact := &awscommon.Artifact{
 		Amis:           state.Get("amis").(map[string]string),
 		BuilderIdValue: BuilderId,
+		SourceAmi:      state.Get("source_image").(*ec2.Image).Id,
 		Conn:           ec2conn,
 	}
 
@@ -5,6 +5,7 @@ import (
 	"github.com/mitchellh/goamz/ec2"
 	"github.com/mitchellh/multistep"
 	awscommon "github.com/mitchellh/packer/builder/amazon/common"
+	"github.com/mitchellh/packer/common/uuid"
 	"github.com/mitchellh/packer/packer"
 )
 
@@ -16,11 +17,18 @@ func (s *stepCreateAMI) Run(state multistep.StateBag) multistep.StepAction {
 	instance := state.Get("instance").(*ec2.Instance)
 	ui := state.Get("ui").(packer.Ui)
 
+	// An encrypted copy of the AMI takes its name later, which EC2 only
+	// allows if this one has another name.
+	name := config.AMIName
+	if config.AMIEncryptBootVolume {
+		name = fmt.Sprintf("packer-tmp-%s", uuid.TimeOrderedUUID())
+	}
+
 	// Create the image
-	ui.Say(fmt.Sprintf("Creating the AMI: %s", config.AMIName))
+	ui.Say(fmt.Sprintf("Creating the AMI: %s", name))
 	createOpts := &ec2.CreateImage{
 		InstanceId:   instance.InstanceId,
-		Name:         config.AMIName,
+		Name:         name,
 		BlockDevices: config.BlockDevices.BuildAMIDevices(),
 	}
 
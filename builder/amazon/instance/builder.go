@@ -72,6 +72,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 			"-m {{.ManifestPath}} " +
 			"-a {{.AccessKey}} " +
 			"-s {{.SecretKey}} " +
+			"{{if .Token}}-t {{.Token}} {{end}}" +
 			"-d {{.BundleDirectory}} " +
 			"--batch " +
 			"--url {{.S3Endpoint}} " +
@@ -156,11 +157,17 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 			errs, fmt.Errorf("x509_key_path points to bad file: %s", err))
 	}
 
+	// Instance-store AMIs have no EBS volumes to encrypt
+	if b.config.AMIEncryptBootVolume || len(b.config.AMIRegionKmsKeyIds) > 0 {
+		errs = packer.MultiErrorAppend(errs, errors.New(
+			"encrypt_boot and region_kms_key_ids aren't supported for instance-store AMIs"))
+	}
+
 	if errs != nil && len(errs.Errors) > 0 {
 		return nil, errs
 	}
 
-	log.Println(common.ScrubConfig(b.config, b.config.AccessKey, b.config.SecretKey))
+	log.Println(common.ScrubConfig(b.config, b.config.AccessKey, b.config.SecretKey, b.config.Token))
 	return nil, nil
 }
 
@@ -175,17 +182,23 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		return nil, err
 	}
 
-	ec2conn := ec2.New(auth, region)
+	ec2conn := awscommon.NewEC2(auth, region)
 
 	// Setup the state bag and initial state for the steps
 	state := new(multistep.BasicStateBag)
 	state.Put("config", &b.config)
+	state.Put("aws_polling", &b.config.AWSPollingConfig)
 	state.Put("ec2", ec2conn)
 	state.Put("hook", hook)
 	state.Put("ui", ui)
 
 	// Build the steps
 	steps := []multistep.Step{
+		&awscommon.StepSourceAMIInfo{
+			SourceAMI:          b.config.SourceAmi,
+			AMIFilter:          b.config.SourceAmiFilter,
+			ExpectedRootDevice: "instance-store",
+		},
 		&awscommon.StepKeyPair{
 			Debug:          b.config.PackerDebug,
 			DebugKeyPath:   fmt.Sprintf("ec2_%s.pem", b.config.PackerBuildName),
@@ -199,18 +212,25 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		},
 		&awscommon.StepRunSourceInstance{
 			Debug:                    b.config.PackerDebug,
-			ExpectedRootDevice:       "instance-store",
 			InstanceType:             b.config.InstanceType,
 			IamInstanceProfile:       b.config.IamInstanceProfile,
 			UserData:                 b.config.UserData,
 			UserDataFile:             b.config.UserDataFile,
-			SourceAMI:                b.config.SourceAmi,
 			SubnetId:                 b.config.SubnetId,
 			AssociatePublicIpAddress: b.config.AssociatePublicIpAddress,
 			AvailabilityZone:         b.config.AvailabilityZone,
 			BlockDevices:             b.config.BlockDevices,
 			Tags:                     b.config.RunTags,
+			Tpl:                      b.config.tpl,
+			VolumeTags:               b.config.RunVolumeTags,
+			SpotPrice:                b.config.SpotPrice,
+			SpotPriceProduct:         b.config.SpotPriceAutoProduct,
+		},
+		&awscommon.StepGetPassword{
+			Debug:   b.config.PackerDebug,
+			Timeout: b.config.WindowsPasswordTimeout(),
 		},
+		&awscommon.StepGetConsoleOutput{},
 		&common.StepConnectSSH{
 			SSHAddress:     awscommon.SSHAddress(ec2conn, b.config.SSHPort),
 			SSHConfig:      awscommon.SSHConfig(b.config.SSHUsername),
@@ -222,16 +242,22 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&StepUploadBundle{},
 		&StepRegisterAMI{},
 		&awscommon.StepAMIRegionCopy{
+			Name:    b.config.AMIName,
 			Regions: b.config.AMIRegions,
 		},
 		&awscommon.StepModifyAMIAttributes{
-			Description:  b.config.AMIDescription,
-			Users:        b.config.AMIUsers,
-			Groups:       b.config.AMIGroups,
-			ProductCodes: b.config.AMIProductCodes,
+			Description:    b.config.AMIDescription,
+			Tpl:            b.config.tpl,
+			Users:          b.config.AMIUsers,
+			Groups:         b.config.AMIGroups,
+			ProductCodes:   b.config.AMIProductCodes,
+			SnapshotUsers:  b.config.AMISnapshotUsers,
+			SnapshotGroups: b.config.AMISnapshotGroups,
 		},
 		&awscommon.StepCreateTags{
-			Tags: b.config.AMITags,
+			Tags:         b.config.AMITags,
+			SnapshotTags: b.config.SnapshotTags,
+			Tpl:          b.config.tpl,
 		},
 	}
 
@@ -261,6 +287,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	artifact := &awscommon.Artifact{
 		Amis:           state.Get("amis").(map[string]string),
 		BuilderIdValue: BuilderId,
+		SourceAmi:      state.Get("source_image").(*ec2.Image).Id,
 		Conn:           ec2conn,
 	}
 
@@ -144,6 +144,20 @@ func TestBuilderPrepare_BundlePrefix(t *testing.T) {
 	}
 }
 
+func TestBuilderPrepare_EncryptBoot(t *testing.T) {
+	b := &Builder{}
+	config := testConfig()
+
+	config["encrypt_boot"] = true
+	warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
 func TestBuilderPrepare_InvalidKey(t *testing.T) {
 	var b Builder
 	config := testConfig()
@@ -13,6 +13,7 @@ type uploadCmdData struct {
 	ManifestPath    string
 	S3Endpoint      string
 	SecretKey       string
+	Token           string
 }
 
 type StepUploadBundle struct{}
@@ -32,13 +33,24 @@ func (s *StepUploadBundle) Run(state multistep.StateBag) multistep.StepAction {
 		return multistep.ActionHalt
 	}
 
+	// The credentials of an assumed role may have been refreshed since
+	// the build started.
+	auth, err := config.Auth()
+	if err != nil {
+		err := fmt.Errorf("Error retrieving credentials: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
 	config.BundleUploadCommand, err = config.tpl.Process(config.BundleUploadCommand, uploadCmdData{
-		AccessKey:       config.AccessKey,
+		AccessKey:       auth.AccessKey,
 		BucketName:      config.S3Bucket,
 		BundleDirectory: config.BundleDestination,
 		ManifestPath:    manifestPath,
 		S3Endpoint:      region.S3Endpoint,
-		SecretKey:       config.SecretKey,
+		SecretKey:       auth.SecretKey,
+		Token:           auth.Token,
 	})
 	if err != nil {
 		err := fmt.Errorf("Error processing bundle upload command: %s", err)
@@ -3,6 +3,7 @@ package digitalocean
 import (
 	"fmt"
 	"log"
+	"strings"
 )
 
 type Artifact struct {
@@ -12,11 +13,11 @@ type Artifact struct {
 	// The ID of the image
 	snapshotId uint
 
-	// The name of the region
-	regionName string
+	// The slugs of the regions the snapshot is in
+	regionNames []string
 
-	// The client for making API calls
-	client *DigitalOceanClient
+	// The driver for making API calls
+	driver Driver
 }
 
 func (*Artifact) BuilderId() string {
@@ -30,14 +31,15 @@ func (*Artifact) Files() []string {
 
 func (a *Artifact) Id() string {
 	// mimicing the aws builder
-	return fmt.Sprintf("%s:%s", a.regionName, a.snapshotName)
+	return fmt.Sprintf("%s:%s", strings.Join(a.regionNames, ","), a.snapshotName)
 }
 
 func (a *Artifact) String() string {
-	return fmt.Sprintf("A snapshot was created: '%v' in region '%v'", a.snapshotName, a.regionName)
+	return fmt.Sprintf("A snapshot was created: '%v' in region '%v'",
+		a.snapshotName, strings.Join(a.regionNames, ", "))
 }
 
 func (a *Artifact) Destroy() error {
 	log.Printf("Destroying image: %d (%s)", a.snapshotId, a.snapshotName)
-	return a.client.DestroyImage(a.snapshotId)
+	return a.driver.DestroyImage(a.snapshotId)
 }
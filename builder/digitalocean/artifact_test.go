@@ -14,8 +14,8 @@ func TestArtifact_Impl(t *testing.T) {
 }
 
 func TestArtifactString(t *testing.T) {
-	a := &Artifact{"packer-foobar", 42, "San Francisco", nil}
-	expected := "A snapshot was created: 'packer-foobar' in region 'San Francisco'"
+	a := &Artifact{"packer-foobar", 42, []string{"sfo1", "nyc2"}, nil}
+	expected := "A snapshot was created: 'packer-foobar' in region 'sfo1, nyc2'"
 
 	if a.String() != expected {
 		t.Fatalf("artifact string should match: %v", expected)
@@ -15,16 +15,16 @@ import (
 	"time"
 )
 
-// see https://api.digitalocean.com/images/?client_id=[client_id]&api_key=[api_key]
-// name="Ubuntu 12.04.4 x64", id=3101045,
+// see https://api.digitalocean.com/v2/images
+// name="Ubuntu 12.04.4 x64"
 const DefaultImage = "ubuntu-12-04-x64"
 
-// see https://api.digitalocean.com/regions/?client_id=[client_id]&api_key=[api_key]
-// name="New York", id=1
+// see https://api.digitalocean.com/v2/regions
+// name="New York 1"
 const DefaultRegion = "nyc1"
 
-// see https://api.digitalocean.com/sizes/?client_id=[client_id]&api_key=[api_key]
-// name="512MB", id=66 (the smallest droplet size)
+// see https://api.digitalocean.com/v2/sizes
+// the smallest droplet size
 const DefaultSize = "512mb"
 
 // The unique id for the builder
@@ -36,21 +36,20 @@ const BuilderId = "pearkes.digitalocean"
 type config struct {
 	common.PackerConfig `mapstructure:",squash"`
 
-	ClientID string `mapstructure:"client_id"`
-	APIKey   string `mapstructure:"api_key"`
-	RegionID uint   `mapstructure:"region_id"`
-	SizeID   uint   `mapstructure:"size_id"`
-	ImageID  uint   `mapstructure:"image_id"`
+	APIToken string `mapstructure:"api_token"`
 
 	Region string `mapstructure:"region"`
 	Size   string `mapstructure:"size"`
 	Image  string `mapstructure:"image"`
 
-	PrivateNetworking bool   `mapstructure:"private_networking"`
-	SnapshotName      string `mapstructure:"snapshot_name"`
-	DropletName       string `mapstructure:"droplet_name"`
-	SSHUsername       string `mapstructure:"ssh_username"`
-	SSHPort           uint   `mapstructure:"ssh_port"`
+	IPv6              bool     `mapstructure:"ipv6"`
+	PrivateNetworking bool     `mapstructure:"private_networking"`
+	SnapshotName      string   `mapstructure:"snapshot_name"`
+	SnapshotRegions   []string `mapstructure:"snapshot_regions"`
+	DropletName       string   `mapstructure:"droplet_name"`
+	UserData          string   `mapstructure:"user_data"`
+	SSHUsername       string   `mapstructure:"ssh_username"`
+	SSHPort           uint     `mapstructure:"ssh_port"`
 
 	RawSSHTimeout   string `mapstructure:"ssh_timeout"`
 	RawStateTimeout string `mapstructure:"state_timeout"`
@@ -84,38 +83,21 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	errs := common.CheckUnusedConfig(md)
 
 	// Optional configuration with defaults
-	if b.config.APIKey == "" {
-		// Default to environment variable for api_key, if it exists
-		b.config.APIKey = os.Getenv("DIGITALOCEAN_API_KEY")
-	}
-
-	if b.config.ClientID == "" {
-		// Default to environment variable for client_id, if it exists
-		b.config.ClientID = os.Getenv("DIGITALOCEAN_CLIENT_ID")
+	if b.config.APIToken == "" {
+		// Default to environment variable for api_token, if it exists
+		b.config.APIToken = os.Getenv("DIGITALOCEAN_API_TOKEN")
 	}
 
 	if b.config.Region == "" {
-		if b.config.RegionID != 0 {
-			b.config.Region = fmt.Sprintf("%v", b.config.RegionID)
-		} else {
-			b.config.Region = DefaultRegion
-		}
+		b.config.Region = DefaultRegion
 	}
 
 	if b.config.Size == "" {
-		if b.config.SizeID != 0 {
-			b.config.Size = fmt.Sprintf("%v", b.config.SizeID)
-		} else {
-			b.config.Size = DefaultSize
-		}
+		b.config.Size = DefaultSize
 	}
 
 	if b.config.Image == "" {
-		if b.config.ImageID != 0 {
-			b.config.Image = fmt.Sprintf("%v", b.config.ImageID)
-		} else {
-			b.config.Image = DefaultImage
-		}
+		b.config.Image = DefaultImage
 	}
 
 	if b.config.SnapshotName == "" {
@@ -151,10 +133,10 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	}
 
 	templates := map[string]*string{
-		"client_id":     &b.config.ClientID,
-		"api_key":       &b.config.APIKey,
+		"api_token":     &b.config.APIToken,
 		"snapshot_name": &b.config.SnapshotName,
 		"droplet_name":  &b.config.DropletName,
+		"user_data":     &b.config.UserData,
 		"ssh_username":  &b.config.SSHUsername,
 		"ssh_timeout":   &b.config.RawSSHTimeout,
 		"state_timeout": &b.config.RawStateTimeout,
@@ -169,15 +151,22 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		}
 	}
 
-	// Required configurations that will display errors if not set
-	if b.config.ClientID == "" {
-		errs = packer.MultiErrorAppend(
-			errs, errors.New("a client_id must be specified"))
+	for i, region := range b.config.SnapshotRegions {
+		var err error
+		b.config.SnapshotRegions[i], err = b.config.tpl.Process(region, nil)
+		if err != nil {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("Error processing snapshot_regions[%d]: %s", i, err))
+		} else if b.config.SnapshotRegions[i] == "" {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("snapshot_regions[%d] must not be empty", i))
+		}
 	}
 
-	if b.config.APIKey == "" {
+	// Required configurations that will display errors if not set
+	if b.config.APIToken == "" {
 		errs = packer.MultiErrorAppend(
-			errs, errors.New("an api_key must be specified"))
+			errs, errors.New("an api_token must be specified"))
 	}
 
 	sshTimeout, err := time.ParseDuration(b.config.RawSSHTimeout)
@@ -198,18 +187,18 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		return nil, errs
 	}
 
-	common.ScrubConfig(b.config, b.config.ClientID, b.config.APIKey)
+	common.ScrubConfig(b.config, b.config.APIToken)
 	return nil, nil
 }
 
 func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
-	// Initialize the DO API client
-	client := DigitalOceanClient{}.New(b.config.ClientID, b.config.APIKey)
+	// Initialize the driver that talks to the DO API
+	driver := &DigitalOceanDriver{APIToken: b.config.APIToken}
 
 	// Set up the state
 	state := new(multistep.BasicStateBag)
 	state.Put("config", b.config)
-	state.Put("client", client)
+	state.Put("driver", driver)
 	state.Put("hook", hook)
 	state.Put("ui", ui)
 
@@ -251,27 +240,11 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		return nil, nil
 	}
 
-	sregion := state.Get("region")
-
-	var region string
-
-	if sregion != nil {
-		region = sregion.(string)
-	} else {
-		region = fmt.Sprintf("%v", state.Get("region_id").(uint))
-	}
-
-	found_region, err := client.Region(region)
-
-	if err != nil {
-		return nil, err
-	}
-
 	artifact := &Artifact{
 		snapshotName: state.Get("snapshot_name").(string),
 		snapshotId:   state.Get("snapshot_image_id").(uint),
-		regionName:   found_region.Name,
-		client:       client,
+		regionNames:  state.Get("regions").([]string),
+		driver:       driver,
 	}
 
 	return artifact, nil
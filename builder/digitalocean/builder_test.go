@@ -9,14 +9,12 @@ import (
 
 func init() {
 	// Clear out the credential env vars
-	os.Setenv("DIGITALOCEAN_API_KEY", "")
-	os.Setenv("DIGITALOCEAN_CLIENT_ID", "")
+	os.Setenv("DIGITALOCEAN_API_TOKEN", "")
 }
 
 func testConfig() map[string]interface{} {
 	return map[string]interface{}{
-		"client_id": "foo",
-		"api_key":   "bar",
+		"api_token": "bar",
 	}
 }
 
@@ -31,7 +29,7 @@ func TestBuilder_ImplementsBuilder(t *testing.T) {
 func TestBuilder_Prepare_BadType(t *testing.T) {
 	b := &Builder{}
 	c := map[string]interface{}{
-		"api_key": []string{},
+		"api_token": []string{},
 	}
 
 	warnings, err := b.Prepare(c)
@@ -43,12 +41,12 @@ func TestBuilder_Prepare_BadType(t *testing.T) {
 	}
 }
 
-func TestBuilderPrepare_APIKey(t *testing.T) {
+func TestBuilderPrepare_APIToken(t *testing.T) {
 	var b Builder
 	config := testConfig()
 
 	// Test good
-	config["api_key"] = "foo"
+	config["api_token"] = "foo"
 	warnings, err := b.Prepare(config)
 	if len(warnings) > 0 {
 		t.Fatalf("bad: %#v", warnings)
@@ -57,12 +55,12 @@ func TestBuilderPrepare_APIKey(t *testing.T) {
 		t.Fatalf("should not have error: %s", err)
 	}
 
-	if b.config.APIKey != "foo" {
-		t.Errorf("access key invalid: %s", b.config.APIKey)
+	if b.config.APIToken != "foo" {
+		t.Errorf("access key invalid: %s", b.config.APIToken)
 	}
 
 	// Test bad
-	delete(config, "api_key")
+	delete(config, "api_token")
 	b = Builder{}
 	warnings, err = b.Prepare(config)
 	if len(warnings) > 0 {
@@ -73,51 +71,9 @@ func TestBuilderPrepare_APIKey(t *testing.T) {
 	}
 
 	// Test env variable
-	delete(config, "api_key")
-	os.Setenv("DIGITALOCEAN_API_KEY", "foo")
-	defer os.Setenv("DIGITALOCEAN_API_KEY", "")
-	warnings, err = b.Prepare(config)
-	if len(warnings) > 0 {
-		t.Fatalf("bad: %#v", warnings)
-	}
-	if err != nil {
-		t.Fatalf("should not have error: %s", err)
-	}
-}
-
-func TestBuilderPrepare_ClientID(t *testing.T) {
-	var b Builder
-	config := testConfig()
-
-	// Test good
-	config["client_id"] = "foo"
-	warnings, err := b.Prepare(config)
-	if len(warnings) > 0 {
-		t.Fatalf("bad: %#v", warnings)
-	}
-	if err != nil {
-		t.Fatalf("should not have error: %s", err)
-	}
-
-	if b.config.ClientID != "foo" {
-		t.Errorf("invalid: %s", b.config.ClientID)
-	}
-
-	// Test bad
-	delete(config, "client_id")
-	b = Builder{}
-	warnings, err = b.Prepare(config)
-	if len(warnings) > 0 {
-		t.Fatalf("bad: %#v", warnings)
-	}
-	if err == nil {
-		t.Fatal("should have error")
-	}
-
-	// Test env variable
-	delete(config, "client_id")
-	os.Setenv("DIGITALOCEAN_CLIENT_ID", "foo")
-	defer os.Setenv("DIGITALOCEAN_CLIENT_ID", "")
+	delete(config, "api_token")
+	os.Setenv("DIGITALOCEAN_API_TOKEN", "foo")
+	defer os.Setenv("DIGITALOCEAN_API_TOKEN", "")
 	warnings, err = b.Prepare(config)
 	if len(warnings) > 0 {
 		t.Fatalf("bad: %#v", warnings)
@@ -162,7 +118,6 @@ func TestBuilderPrepare_Region(t *testing.T) {
 	expected := "sfo1"
 
 	// Test set
-	config["region_id"] = 0
 	config["region"] = expected
 	b = Builder{}
 	warnings, err = b.Prepare(config)
@@ -198,7 +153,6 @@ func TestBuilderPrepare_Size(t *testing.T) {
 	expected := "1024mb"
 
 	// Test set
-	config["size_id"] = 0
 	config["size"] = expected
 	b = Builder{}
 	warnings, err = b.Prepare(config)
@@ -234,7 +188,6 @@ func TestBuilderPrepare_Image(t *testing.T) {
 	expected := "ubuntu-14-04-x64"
 
 	// Test set
-	config["image_id"] = 0
 	config["image"] = expected
 	b = Builder{}
 	warnings, err = b.Prepare(config)
@@ -495,3 +448,53 @@ func TestBuilderPrepare_DropletName(t *testing.T) {
 	}
 
 }
+
+func TestBuilderPrepare_SnapshotRegions(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test set
+	config["snapshot_regions"] = []string{"nyc2", "sfo1"}
+	warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if len(b.config.SnapshotRegions) != 2 || b.config.SnapshotRegions[1] != "sfo1" {
+		t.Errorf("invalid: %#v", b.config.SnapshotRegions)
+	}
+
+	// Test empty region
+	config["snapshot_regions"] = []string{""}
+	b = Builder{}
+	warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_UserData(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test set
+	config["user_data"] = "#cloud-config"
+	config["ipv6"] = true
+	warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.UserData != "#cloud-config" || !b.config.IPv6 {
+		t.Errorf("invalid: %#v", b.config)
+	}
+}
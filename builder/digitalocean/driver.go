@@ -0,0 +1,71 @@
+package digitalocean
+
+import (
+	"time"
+)
+
+// Driver is the interface that has to be implemented to communicate with
+// DigitalOcean. The Driver interface also allows the steps to be tested
+// since a mock driver can be shimmed in.
+type Driver interface {
+	// CreateKey creates an SSH key and returns its ID.
+	CreateKey(name string, pub string) (uint, error)
+
+	// DestroyKey destroys an SSH key.
+	DestroyKey(id uint) error
+
+	// CreateDroplet creates a droplet and returns its ID.
+	CreateDroplet(*DropletConfig) (uint, error)
+
+	// DestroyDroplet destroys a droplet.
+	DestroyDroplet(id uint) error
+
+	// DropletStatus returns the public IPv4 address and the status of a
+	// droplet, such as "new", "active" or "off".
+	DropletStatus(id uint) (string, string, error)
+
+	// PowerOffDroplet forcefully powers off a droplet.
+	PowerOffDroplet(id uint) error
+
+	// ShutdownDroplet gracefully shuts down a droplet.
+	ShutdownDroplet(id uint) error
+
+	// CreateSnapshot starts a snapshot of a droplet and returns the ID of
+	// the action taking it.
+	CreateSnapshot(id uint, name string) (uint, error)
+
+	// DropletSnapshots returns the snapshots that were taken of a droplet.
+	DropletSnapshots(id uint) ([]Image, error)
+
+	// DestroyImage destroys an image.
+	DestroyImage(id uint) error
+
+	// TransferImage starts copying an image to another region and returns
+	// the ID of the action copying it.
+	TransferImage(id uint, region string) (uint, error)
+
+	// ActionStatus returns the status of an action, which is
+	// "in-progress", "completed" or "errored".
+	ActionStatus(id uint) (string, error)
+}
+
+// DropletConfig is the configuration used to create a droplet.
+type DropletConfig struct {
+	Name              string
+	Region            string
+	Size              string
+	Image             string
+	SSHKeyId          uint
+	PrivateNetworking bool
+	IPv6              bool
+	UserData          string
+}
+
+type Image struct {
+	Id           uint
+	Name         string
+	Slug         string
+	Distribution string
+	Regions      []string
+	CreatedAt    time.Time
+}
@@ -0,0 +1,342 @@
+package digitalocean
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DIGITALOCEAN_API_URL = "https://api.digitalocean.com/v2"
+
+// requestRetryDelay is how long to wait before retrying a request that
+// DigitalOcean refused for now.
+var requestRetryDelay = 5 * time.Second
+
+// DigitalOceanDriver is a Driver that talks to the version 2 DigitalOcean
+// API, authenticating with a personal access token.
+type DigitalOceanDriver struct {
+	// The personal access token used as the bearer token of requests
+	APIToken string
+
+	// The base URL of the API. Defaults to DIGITALOCEAN_API_URL.
+	BaseURL string
+
+	// The http client for communicating. Defaults to one that uses the
+	// proxy of the environment.
+	Client *http.Client
+}
+
+// apiError is the body of an unsuccessful response.
+type apiError struct {
+	Id      string `json:"id"`
+	Message string `json:"message"`
+}
+
+type apiAction struct {
+	Id     uint   `json:"id"`
+	Status string `json:"status"`
+}
+
+type apiImage struct {
+	Id           uint      `json:"id"`
+	Name         string    `json:"name"`
+	Slug         string    `json:"slug"`
+	Distribution string    `json:"distribution"`
+	Regions      []string  `json:"regions"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type apiNetwork struct {
+	IPAddress string `json:"ip_address"`
+	Type      string `json:"type"`
+}
+
+type apiDroplet struct {
+	Id       uint   `json:"id"`
+	Status   string `json:"status"`
+	Networks struct {
+		V4 []apiNetwork `json:"v4"`
+		V6 []apiNetwork `json:"v6"`
+	} `json:"networks"`
+}
+
+func (d *DigitalOceanDriver) CreateKey(name string, pub string) (uint, error) {
+	params := map[string]string{
+		"name":       name,
+		"public_key": pub,
+	}
+
+	var resp struct {
+		SSHKey struct {
+			Id uint `json:"id"`
+		} `json:"ssh_key"`
+	}
+	if err := d.request("POST", "account/keys", params, &resp); err != nil {
+		return 0, err
+	}
+
+	return resp.SSHKey.Id, nil
+}
+
+func (d *DigitalOceanDriver) DestroyKey(id uint) error {
+	return d.request("DELETE", fmt.Sprintf("account/keys/%d", id), nil, nil)
+}
+
+func (d *DigitalOceanDriver) CreateDroplet(config *DropletConfig) (uint, error) {
+	params := map[string]interface{}{
+		"name":               config.Name,
+		"region":             config.Region,
+		"size":               config.Size,
+		"ssh_keys":           []uint{config.SSHKeyId},
+		"private_networking": config.PrivateNetworking,
+		"ipv6":               config.IPv6,
+	}
+
+	// Images are given by slug, but private images only have an ID
+	params["image"] = config.Image
+	if id, err := strconv.ParseUint(config.Image, 10, 0); err == nil {
+		params["image"] = id
+	}
+
+	if config.UserData != "" {
+		params["user_data"] = config.UserData
+	}
+
+	var resp struct {
+		Droplet apiDroplet `json:"droplet"`
+	}
+	if err := d.request("POST", "droplets", params, &resp); err != nil {
+		return 0, err
+	}
+
+	return resp.Droplet.Id, nil
+}
+
+func (d *DigitalOceanDriver) DestroyDroplet(id uint) error {
+	return d.request("DELETE", fmt.Sprintf("droplets/%d", id), nil, nil)
+}
+
+func (d *DigitalOceanDriver) DropletStatus(id uint) (string, string, error) {
+	var resp struct {
+		Droplet apiDroplet `json:"droplet"`
+	}
+	if err := d.request("GET", fmt.Sprintf("droplets/%d", id), nil, &resp); err != nil {
+		return "", "", err
+	}
+
+	var ip string
+	for _, network := range resp.Droplet.Networks.V4 {
+		if network.Type == "public" {
+			ip = network.IPAddress
+			break
+		}
+	}
+
+	return ip, resp.Droplet.Status, nil
+}
+
+func (d *DigitalOceanDriver) PowerOffDroplet(id uint) error {
+	_, err := d.dropletAction(id, map[string]string{"type": "power_off"})
+	return err
+}
+
+func (d *DigitalOceanDriver) ShutdownDroplet(id uint) error {
+	_, err := d.dropletAction(id, map[string]string{"type": "shutdown"})
+	return err
+}
+
+func (d *DigitalOceanDriver) CreateSnapshot(id uint, name string) (uint, error) {
+	return d.dropletAction(id, map[string]string{
+		"type": "snapshot",
+		"name": name,
+	})
+}
+
+func (d *DigitalOceanDriver) DropletSnapshots(id uint) ([]Image, error) {
+	var images []Image
+
+	path := fmt.Sprintf("droplets/%d/snapshots", id)
+	query := "per_page=200"
+	for query != "" {
+		var resp struct {
+			Snapshots []apiImage `json:"snapshots"`
+			Links     struct {
+				Pages struct {
+					Next string `json:"next"`
+				} `json:"pages"`
+			} `json:"links"`
+		}
+		if err := d.request("GET", path+"?"+query, nil, &resp); err != nil {
+			return nil, err
+		}
+
+		for _, image := range resp.Snapshots {
+			images = append(images, Image{
+				Id:           image.Id,
+				Name:         image.Name,
+				Slug:         image.Slug,
+				Distribution: image.Distribution,
+				Regions:      image.Regions,
+				CreatedAt:    image.CreatedAt,
+			})
+		}
+
+		// The next page is given as a full URL
+		query = ""
+		if next := resp.Links.Pages.Next; next != "" {
+			u, err := url.Parse(next)
+			if err != nil {
+				return nil, err
+			}
+
+			query = u.RawQuery
+		}
+	}
+
+	return images, nil
+}
+
+func (d *DigitalOceanDriver) DestroyImage(id uint) error {
+	return d.request("DELETE", fmt.Sprintf("images/%d", id), nil, nil)
+}
+
+func (d *DigitalOceanDriver) TransferImage(id uint, region string) (uint, error) {
+	params := map[string]string{
+		"type":   "transfer",
+		"region": region,
+	}
+
+	var resp struct {
+		Action apiAction `json:"action"`
+	}
+	if err := d.request("POST", fmt.Sprintf("images/%d/actions", id), params, &resp); err != nil {
+		return 0, err
+	}
+
+	return resp.Action.Id, nil
+}
+
+func (d *DigitalOceanDriver) ActionStatus(id uint) (string, error) {
+	var resp struct {
+		Action apiAction `json:"action"`
+	}
+	if err := d.request("GET", fmt.Sprintf("actions/%d", id), nil, &resp); err != nil {
+		return "", err
+	}
+
+	return resp.Action.Status, nil
+}
+
+// dropletAction starts an action on a droplet and returns its ID.
+func (d *DigitalOceanDriver) dropletAction(id uint, params map[string]string) (uint, error) {
+	var resp struct {
+		Action apiAction `json:"action"`
+	}
+	if err := d.request("POST", fmt.Sprintf("droplets/%d/actions", id), params, &resp); err != nil {
+		return 0, err
+	}
+
+	return resp.Action.Id, nil
+}
+
+// request sends an API request with the params as the JSON body, and
+// decodes the JSON response into resp if it isn't nil.
+func (d *DigitalOceanDriver) request(method, path string, params interface{}, resp interface{}) error {
+	client := d.Client
+	if client == nil {
+		client = &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+			},
+		}
+	}
+
+	baseURL := d.BaseURL
+	if baseURL == "" {
+		baseURL = DIGITALOCEAN_API_URL
+	}
+	endpoint := fmt.Sprintf("%s/%s", strings.TrimRight(baseURL, "/"), path)
+
+	var body []byte
+	if params != nil {
+		var err error
+		body, err = json.Marshal(params)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("sending new request to digitalocean: %s %s", method, endpoint)
+
+	var lastErr error
+	for attempts := 1; attempts < 10; attempts++ {
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(body)
+		}
+
+		req, err := http.NewRequest(method, endpoint, reqBody)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+d.APIToken)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		r, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+
+		respBody, err := ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		log.Printf("response from digitalocean (%d): %s", r.StatusCode, respBody)
+
+		if r.StatusCode >= 200 && r.StatusCode < 300 {
+			if resp == nil || len(respBody) == 0 {
+				return nil
+			}
+
+			if err := json.Unmarshal(respBody, resp); err != nil {
+				return fmt.Errorf(
+					"Failed to decode JSON response (HTTP %d) from DigitalOcean: %s",
+					r.StatusCode, respBody)
+			}
+
+			return nil
+		}
+
+		var apiErr apiError
+		if err := json.Unmarshal(respBody, &apiErr); err != nil || apiErr.Message == "" {
+			apiErr.Message = fmt.Sprintf("Unknown error. Full response body: %s", respBody)
+		}
+
+		lastErr = fmt.Errorf("Received error from DigitalOcean (%d): %s",
+			r.StatusCode, apiErr.Message)
+		log.Println(lastErr)
+		if r.StatusCode == 429 || strings.Contains(apiErr.Message, "pending event") {
+			// Retry, DigitalOcean refuses requests while the droplet
+			// has a pending event, and when they come too fast.
+			time.Sleep(requestRetryDelay)
+			continue
+		}
+
+		// Some other kind of error. Just return.
+		return lastErr
+	}
+
+	return lastErr
+}
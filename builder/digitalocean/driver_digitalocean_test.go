@@ -0,0 +1,195 @@
+package digitalocean
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testRequest is a request received by the fake DigitalOcean API.
+type testRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Auth   string
+	Body   map[string]interface{}
+}
+
+// testResponse is a canned response of the fake DigitalOcean API.
+type testResponse struct {
+	Status int
+	Body   string
+}
+
+// testServer returns a fake DigitalOcean API and a driver for it. It
+// answers requests with the response for their "METHOD /path?query", or
+// else "METHOD /path", and records them.
+func testServer(responses map[string]testResponse) (*httptest.Server, *DigitalOceanDriver, *[]testRequest) {
+	requests := new([]testRequest)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := testRequest{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.RawQuery,
+			Auth:   r.Header.Get("Authorization"),
+		}
+		json.NewDecoder(r.Body).Decode(&req.Body)
+		*requests = append(*requests, req)
+
+		resp, ok := responses[r.Method+" "+r.URL.RequestURI()]
+		if !ok {
+			resp, ok = responses[r.Method+" "+r.URL.Path]
+		}
+		if !ok {
+			resp = testResponse{404,
+				`{"id":"not_found","message":"The resource you were accessing could not be found."}`}
+		}
+
+		w.WriteHeader(resp.Status)
+		fmt.Fprint(w, resp.Body)
+	}))
+
+	driver := &DigitalOceanDriver{APIToken: "token", BaseURL: server.URL}
+	return server, driver, requests
+}
+
+func TestDigitalOceanDriver_impl(t *testing.T) {
+	var _ Driver = new(DigitalOceanDriver)
+}
+
+func TestDigitalOceanDriver_CreateDroplet(t *testing.T) {
+	server, driver, requests := testServer(map[string]testResponse{
+		"POST /droplets": {202, `{"droplet":{"id":42,"status":"new"}}`},
+	})
+	defer server.Close()
+
+	id, err := driver.CreateDroplet(&DropletConfig{
+		Name:     "packer",
+		Region:   "nyc3",
+		Size:     "512mb",
+		Image:    "1234",
+		SSHKeyId: 7,
+		IPv6:     true,
+		UserData: "#cloud-config",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if id != 42 {
+		t.Fatalf("bad: %d", id)
+	}
+
+	req := (*requests)[0]
+	if req.Auth != "Bearer token" {
+		t.Fatalf("bad: %s", req.Auth)
+	}
+
+	// Numeric images are sent as IDs
+	if req.Body["image"] != float64(1234) {
+		t.Fatalf("bad: %#v", req.Body["image"])
+	}
+	if req.Body["region"] != "nyc3" || req.Body["ipv6"] != true || req.Body["user_data"] != "#cloud-config" {
+		t.Fatalf("bad: %#v", req.Body)
+	}
+	if keys := req.Body["ssh_keys"].([]interface{}); len(keys) != 1 || keys[0] != float64(7) {
+		t.Fatalf("bad: %#v", keys)
+	}
+}
+
+func TestDigitalOceanDriver_DropletStatus(t *testing.T) {
+	server, driver, _ := testServer(map[string]testResponse{
+		"GET /droplets/42": {200, `{"droplet":{"id":42,"status":"active","networks":{` +
+			`"v4":[{"ip_address":"10.0.0.2","type":"private"},{"ip_address":"192.0.2.1","type":"public"}],` +
+			`"v6":[{"ip_address":"2001:db8::1","type":"public"}]}}}`},
+	})
+	defer server.Close()
+
+	ip, status, err := driver.DropletStatus(42)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if ip != "192.0.2.1" || status != "active" {
+		t.Fatalf("bad: %s %s", ip, status)
+	}
+}
+
+func TestDigitalOceanDriver_DropletSnapshots(t *testing.T) {
+	server, driver, requests := testServer(map[string]testResponse{
+		"GET /droplets/42/snapshots?per_page=200": {200,
+			`{"snapshots":[{"id":1,"name":"a","regions":["nyc1"],"created_at":"2014-11-14T16:07:38Z"}],` +
+				`"links":{"pages":{"next":"https://api.digitalocean.com/v2/droplets/42/snapshots?page=2&per_page=200"}}}`},
+		"GET /droplets/42/snapshots?page=2&per_page=200": {200,
+			`{"snapshots":[{"id":2,"name":"b","regions":["sfo1"]}],"links":{}}`},
+	})
+	defer server.Close()
+
+	images, err := driver.DropletSnapshots(42)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(images) != 2 || images[0].Name != "a" || images[1].Regions[0] != "sfo1" {
+		t.Fatalf("bad: %#v", images)
+	}
+	if images[0].CreatedAt.Year() != 2014 {
+		t.Fatalf("bad: %#v", images[0].CreatedAt)
+	}
+	if len(*requests) != 2 {
+		t.Fatalf("bad: %#v", *requests)
+	}
+}
+
+func TestDigitalOceanDriver_TransferImage(t *testing.T) {
+	server, driver, requests := testServer(map[string]testResponse{
+		"POST /images/5/actions": {201, `{"action":{"id":9,"status":"in-progress"}}`},
+		"GET /actions/9":         {200, `{"action":{"id":9,"status":"completed"}}`},
+	})
+	defer server.Close()
+
+	actionId, err := driver.TransferImage(5, "sfo1")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actionId != 9 {
+		t.Fatalf("bad: %d", actionId)
+	}
+	if body := (*requests)[0].Body; body["type"] != "transfer" || body["region"] != "sfo1" {
+		t.Fatalf("bad: %#v", body)
+	}
+
+	status, err := driver.ActionStatus(actionId)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if status != "completed" {
+		t.Fatalf("bad: %s", status)
+	}
+}
+
+func TestDigitalOceanDriver_error(t *testing.T) {
+	defer func(d time.Duration) { requestRetryDelay = d }(requestRetryDelay)
+	requestRetryDelay = 0
+
+	server, driver, requests := testServer(map[string]testResponse{
+		"POST /droplets/42/actions": {422, `{"id":"unprocessable_entity","message":"Droplet already has a pending event."}`},
+	})
+	defer server.Close()
+
+	if err := driver.DestroyDroplet(42); err == nil {
+		t.Fatal("should have error")
+	}
+	if len(*requests) != 1 {
+		t.Fatalf("bad: %#v", *requests)
+	}
+
+	// Pending events are retried
+	*requests = nil
+	if err := driver.PowerOffDroplet(42); err == nil {
+		t.Fatal("should have error")
+	}
+	if len(*requests) != 9 {
+		t.Fatalf("bad: %d", len(*requests))
+	}
+}
@@ -0,0 +1,138 @@
+package digitalocean
+
+// MockDriver is a driver implementation that can be used for tests.
+type MockDriver struct {
+	CreateKeyCalled bool
+	CreateKeyName   string
+	CreateKeyPub    string
+	CreateKeyId     uint
+	CreateKeyErr    error
+
+	DestroyKeyCalled bool
+	DestroyKeyId     uint
+	DestroyKeyErr    error
+
+	CreateDropletCalled bool
+	CreateDropletConfig *DropletConfig
+	CreateDropletId     uint
+	CreateDropletErr    error
+
+	DestroyDropletCalled bool
+	DestroyDropletId     uint
+	DestroyDropletErr    error
+
+	DropletStatusCalled bool
+	DropletStatusId     uint
+	DropletStatusIP     string
+	DropletStatusStatus string
+	DropletStatusErr    error
+
+	PowerOffDropletCalled bool
+	PowerOffDropletId     uint
+	PowerOffDropletErr    error
+
+	ShutdownDropletCalled bool
+	ShutdownDropletId     uint
+	ShutdownDropletErr    error
+
+	CreateSnapshotCalled   bool
+	CreateSnapshotId       uint
+	CreateSnapshotName     string
+	CreateSnapshotActionId uint
+	CreateSnapshotErr      error
+
+	DropletSnapshotsCalled bool
+	DropletSnapshotsId     uint
+	DropletSnapshotsResult []Image
+	DropletSnapshotsErr    error
+
+	DestroyImageCalled bool
+	DestroyImageId     uint
+	DestroyImageErr    error
+
+	TransferImageCalled   bool
+	TransferImageId       uint
+	TransferImageRegions  []string
+	TransferImageActionId uint
+	TransferImageErr      error
+
+	ActionStatusCalled bool
+	ActionStatusIds    []uint
+	ActionStatusStatus string
+	ActionStatusErr    error
+}
+
+func (d *MockDriver) CreateKey(name string, pub string) (uint, error) {
+	d.CreateKeyCalled = true
+	d.CreateKeyName = name
+	d.CreateKeyPub = pub
+	return d.CreateKeyId, d.CreateKeyErr
+}
+
+func (d *MockDriver) DestroyKey(id uint) error {
+	d.DestroyKeyCalled = true
+	d.DestroyKeyId = id
+	return d.DestroyKeyErr
+}
+
+func (d *MockDriver) CreateDroplet(config *DropletConfig) (uint, error) {
+	d.CreateDropletCalled = true
+	d.CreateDropletConfig = config
+	return d.CreateDropletId, d.CreateDropletErr
+}
+
+func (d *MockDriver) DestroyDroplet(id uint) error {
+	d.DestroyDropletCalled = true
+	d.DestroyDropletId = id
+	return d.DestroyDropletErr
+}
+
+func (d *MockDriver) DropletStatus(id uint) (string, string, error) {
+	d.DropletStatusCalled = true
+	d.DropletStatusId = id
+	return d.DropletStatusIP, d.DropletStatusStatus, d.DropletStatusErr
+}
+
+func (d *MockDriver) PowerOffDroplet(id uint) error {
+	d.PowerOffDropletCalled = true
+	d.PowerOffDropletId = id
+	return d.PowerOffDropletErr
+}
+
+func (d *MockDriver) ShutdownDroplet(id uint) error {
+	d.ShutdownDropletCalled = true
+	d.ShutdownDropletId = id
+	return d.ShutdownDropletErr
+}
+
+func (d *MockDriver) CreateSnapshot(id uint, name string) (uint, error) {
+	d.CreateSnapshotCalled = true
+	d.CreateSnapshotId = id
+	d.CreateSnapshotName = name
+	return d.CreateSnapshotActionId, d.CreateSnapshotErr
+}
+
+func (d *MockDriver) DropletSnapshots(id uint) ([]Image, error) {
+	d.DropletSnapshotsCalled = true
+	d.DropletSnapshotsId = id
+	return d.DropletSnapshotsResult, d.DropletSnapshotsErr
+}
+
+func (d *MockDriver) DestroyImage(id uint) error {
+	d.DestroyImageCalled = true
+	d.DestroyImageId = id
+	return d.DestroyImageErr
+}
+
+func (d *MockDriver) TransferImage(id uint, region string) (uint, error) {
+	d.TransferImageCalled = true
+	d.TransferImageId = id
+	d.TransferImageRegions = append(d.TransferImageRegions, region)
+	return d.TransferImageActionId, d.TransferImageErr
+}
+
+func (d *MockDriver) ActionStatus(id uint) (string, error) {
+	d.ActionStatusCalled = true
+	d.ActionStatusIds = append(d.ActionStatusIds, id)
+	return d.ActionStatusStatus, d.ActionStatusErr
+}
@@ -0,0 +1,7 @@
+package digitalocean
+
+import "testing"
+
+func TestMockDriver_impl(t *testing.T) {
+	var _ Driver = new(MockDriver)
+}
@@ -11,7 +11,7 @@ type stepCreateDroplet struct {
 }
 
 func (s *stepCreateDroplet) Run(state multistep.StateBag) multistep.StepAction {
-	client := state.Get("client").(*DigitalOceanClient)
+	driver := state.Get("driver").(Driver)
 	ui := state.Get("ui").(packer.Ui)
 	c := state.Get("config").(config)
 	sshKeyId := state.Get("ssh_key_id").(uint)
@@ -19,7 +19,16 @@ func (s *stepCreateDroplet) Run(state multistep.StateBag) multistep.StepAction {
 	ui.Say("Creating droplet...")
 
 	// Create the droplet based on configuration
-	dropletId, err := client.CreateDroplet(c.DropletName, c.Size, c.Image, c.Region, sshKeyId, c.PrivateNetworking)
+	dropletId, err := driver.CreateDroplet(&DropletConfig{
+		Name:              c.DropletName,
+		Region:            c.Region,
+		Size:              c.Size,
+		Image:             c.Image,
+		SSHKeyId:          sshKeyId,
+		PrivateNetworking: c.PrivateNetworking,
+		IPv6:              c.IPv6,
+		UserData:          c.UserData,
+	})
 
 	if err != nil {
 		err := fmt.Errorf("Error creating droplet: %s", err)
@@ -43,19 +52,16 @@ func (s *stepCreateDroplet) Cleanup(state multistep.StateBag) {
 		return
 	}
 
-	client := state.Get("client").(*DigitalOceanClient)
+	driver := state.Get("driver").(Driver)
 	ui := state.Get("ui").(packer.Ui)
-	c := state.Get("config").(config)
 
 	// Destroy the droplet we just created
 	ui.Say("Destroying droplet...")
 
-	err := client.DestroyDroplet(s.dropletId)
+	err := driver.DestroyDroplet(s.dropletId)
 	if err != nil {
-		curlstr := fmt.Sprintf("curl '%v/droplets/%v/destroy?client_id=%v&api_key=%v'",
-			DIGITALOCEAN_API_URL, s.dropletId, c.ClientID, c.APIKey)
-
 		ui.Error(fmt.Sprintf(
-			"Error destroying droplet. Please destroy it manually: %v", curlstr))
+			"Error destroying droplet. Please destroy droplet %d manually: %s",
+			s.dropletId, err))
 	}
 }
@@ -11,13 +11,13 @@ import (
 type stepSnapshot struct{}
 
 func (s *stepSnapshot) Run(state multistep.StateBag) multistep.StepAction {
-	client := state.Get("client").(*DigitalOceanClient)
+	driver := state.Get("driver").(Driver)
 	ui := state.Get("ui").(packer.Ui)
 	c := state.Get("config").(config)
 	dropletId := state.Get("droplet_id").(uint)
 
 	ui.Say(fmt.Sprintf("Creating snapshot: %v", c.SnapshotName))
-	err := client.CreateSnapshot(dropletId, c.SnapshotName)
+	actionId, err := driver.CreateSnapshot(dropletId, c.SnapshotName)
 	if err != nil {
 		err := fmt.Errorf("Error creating snapshot: %s", err)
 		state.Put("error", err)
@@ -26,7 +26,7 @@ func (s *stepSnapshot) Run(state multistep.StateBag) multistep.StepAction {
 	}
 
 	ui.Say("Waiting for snapshot to complete...")
-	err = waitForDropletState("active", dropletId, client, c.stateTimeout)
+	err = waitForAction(actionId, driver, c.stateTimeout)
 	if err != nil {
 		err := fmt.Errorf("Error waiting for snapshot to complete: %s", err)
 		state.Put("error", err)
@@ -34,8 +34,10 @@ func (s *stepSnapshot) Run(state multistep.StateBag) multistep.StepAction {
 		return multistep.ActionHalt
 	}
 
+	// Other snapshots of the account may have the same name, so the
+	// snapshot is looked up on the droplet, which took it last.
 	log.Printf("Looking up snapshot ID for snapshot: %s", c.SnapshotName)
-	images, err := client.Images()
+	images, err := driver.DropletSnapshots(dropletId)
 	if err != nil {
 		err := fmt.Errorf("Error looking up snapshot ID: %s", err)
 		state.Put("error", err)
@@ -43,26 +45,47 @@ func (s *stepSnapshot) Run(state multistep.StateBag) multistep.StepAction {
 		return multistep.ActionHalt
 	}
 
-	var imageId uint
-	for _, image := range images {
-		if image.Name == c.SnapshotName {
-			imageId = image.Id
-			break
+	var latest *Image
+	for i := range images {
+		if latest == nil || images[i].CreatedAt.After(latest.CreatedAt) {
+			latest = &images[i]
 		}
 	}
 
-	if imageId == 0 {
+	if latest == nil {
 		err := errors.New("Couldn't find snapshot to get the image ID. Bug?")
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
 
+	imageId := latest.Id
 	log.Printf("Snapshot image ID: %d", imageId)
 
+	regions := []string{c.Region}
+	for _, region := range c.SnapshotRegions {
+		if region == c.Region {
+			continue
+		}
+
+		ui.Say(fmt.Sprintf("Transferring snapshot to region: %s", region))
+		actionId, err := driver.TransferImage(imageId, region)
+		if err == nil {
+			err = waitForAction(actionId, driver, c.stateTimeout)
+		}
+		if err != nil {
+			err := fmt.Errorf("Error transferring snapshot to %s: %s", region, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		regions = append(regions, region)
+	}
+
 	state.Put("snapshot_image_id", imageId)
 	state.Put("snapshot_name", c.SnapshotName)
-	state.Put("region", c.Region)
+	state.Put("regions", regions)
 
 	return multistep.ActionContinue
 }
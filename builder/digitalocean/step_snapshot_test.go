@@ -0,0 +1,90 @@
+package digitalocean
+
+import (
+	"bytes"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testState(t *testing.T, c config) (multistep.StateBag, *MockDriver) {
+	driver := new(MockDriver)
+
+	state := new(multistep.BasicStateBag)
+	state.Put("config", c)
+	state.Put("driver", driver)
+	state.Put("ui", &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	})
+	return state, driver
+}
+
+func TestStepSnapshot_impl(t *testing.T) {
+	var _ multistep.Step = new(stepSnapshot)
+}
+
+func TestStepSnapshot(t *testing.T) {
+	state, driver := testState(t, config{
+		Region:          "nyc3",
+		SnapshotName:    "packer-foo",
+		SnapshotRegions: []string{"nyc3", "sfo1"},
+		stateTimeout:    time.Minute,
+	})
+	state.Put("droplet_id", uint(42))
+
+	driver.CreateSnapshotActionId = 7
+	now := time.Now()
+	driver.DropletSnapshotsResult = []Image{
+		{Id: 1, Name: "packer-foo", CreatedAt: now.Add(-time.Minute)},
+		{Id: 2, Name: "packer-foo", CreatedAt: now},
+	}
+	driver.TransferImageActionId = 8
+	driver.ActionStatusStatus = "completed"
+
+	step := new(stepSnapshot)
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad: %#v", state.Get("error"))
+	}
+
+	if driver.CreateSnapshotId != 42 || driver.CreateSnapshotName != "packer-foo" {
+		t.Fatalf("bad: %#v", driver)
+	}
+	if driver.DropletSnapshotsId != 42 {
+		t.Fatalf("bad: %#v", driver)
+	}
+	if driver.TransferImageId != 2 || !reflect.DeepEqual(driver.TransferImageRegions, []string{"sfo1"}) {
+		t.Fatalf("bad: %#v", driver.TransferImageRegions)
+	}
+	if !reflect.DeepEqual(driver.ActionStatusIds, []uint{7, 8}) {
+		t.Fatalf("bad: %#v", driver.ActionStatusIds)
+	}
+
+	if id := state.Get("snapshot_image_id"); id != uint(2) {
+		t.Fatalf("bad: %#v", id)
+	}
+	regions := state.Get("regions").([]string)
+	if !reflect.DeepEqual(regions, []string{"nyc3", "sfo1"}) {
+		t.Fatalf("bad: %#v", regions)
+	}
+}
+
+func TestStepSnapshot_actionErrored(t *testing.T) {
+	state, driver := testState(t, config{
+		SnapshotName: "packer-foo",
+		stateTimeout: time.Minute,
+	})
+	state.Put("droplet_id", uint(42))
+
+	driver.ActionStatusStatus = "errored"
+
+	step := new(stepSnapshot)
+	if action := step.Run(state); action != multistep.ActionHalt {
+		t.Fatalf("bad: %#v", action)
+	}
+	if driver.DropletSnapshotsCalled {
+		t.Fatal("should not look up the snapshot")
+	}
+}
@@ -1,37 +1,82 @@
 package digitalocean
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"time"
 )
 
-// waitForState simply blocks until the droplet is in
+// stateRefreshDelay is how long to wait between checks of a state.
+var stateRefreshDelay = 3 * time.Second
+
+// waitForDropletState simply blocks until the droplet is in
 // a state we expect, while eventually timing out.
-func waitForDropletState(desiredState string, dropletId uint, client *DigitalOceanClient, timeout time.Duration) error {
+func waitForDropletState(desiredState string, dropletId uint, driver Driver, timeout time.Duration) error {
+	attempts := 0
+	refresh := func() (string, error) {
+		attempts += 1
+		log.Printf("Checking droplet status... (attempt: %d)", attempts)
+		_, status, err := driver.DropletStatus(dropletId)
+		return status, err
+	}
+
+	log.Printf("Waiting for up to %d seconds for droplet to become %s", timeout/time.Second, desiredState)
+	err := waitForState(desiredState, refresh, timeout)
+	if err == errStateTimeout {
+		err = fmt.Errorf("Timeout while waiting to for droplet to become '%s'", desiredState)
+	}
+
+	return err
+}
+
+// waitForAction blocks until an action, such as taking a snapshot, has
+// completed, and fails if it errored.
+func waitForAction(actionId uint, driver Driver, timeout time.Duration) error {
+	refresh := func() (string, error) {
+		log.Printf("Checking status of action %d...", actionId)
+		status, err := driver.ActionStatus(actionId)
+		if err == nil && status == "errored" {
+			err = fmt.Errorf("Action %d errored", actionId)
+		}
+
+		return status, err
+	}
+
+	log.Printf("Waiting for up to %d seconds for action %d to complete", timeout/time.Second, actionId)
+	err := waitForState("completed", refresh, timeout)
+	if err == errStateTimeout {
+		err = fmt.Errorf("Timeout while waiting for action %d to complete", actionId)
+	}
+
+	return err
+}
+
+// errStateTimeout is returned by waitForState when it times out.
+var errStateTimeout = errors.New("timeout while waiting for state")
+
+// waitForState calls refresh until it returns the desired state or an
+// error, and returns errStateTimeout if that takes longer than timeout.
+func waitForState(desiredState string, refresh func() (string, error), timeout time.Duration) error {
 	done := make(chan struct{})
 	defer close(done)
 
 	result := make(chan error, 1)
 	go func() {
-		attempts := 0
 		for {
-			attempts += 1
-
-			log.Printf("Checking droplet status... (attempt: %d)", attempts)
-			_, status, err := client.DropletStatus(dropletId)
+			state, err := refresh()
 			if err != nil {
 				result <- err
 				return
 			}
 
-			if status == desiredState {
+			if state == desiredState {
 				result <- nil
 				return
 			}
 
-			// Wait 3 seconds in between
-			time.Sleep(3 * time.Second)
+			// Wait in between
+			time.Sleep(stateRefreshDelay)
 
 			// Verify we shouldn't exit
 			select {
@@ -44,12 +89,10 @@ func waitForDropletState(desiredState string, dropletId uint, client *DigitalOce
 		}
 	}()
 
-	log.Printf("Waiting for up to %d seconds for droplet to become %s", timeout/time.Second, desiredState)
 	select {
 	case err := <-result:
 		return err
 	case <-time.After(timeout):
-		err := fmt.Errorf("Timeout while waiting to for droplet to become '%s'", desiredState)
-		return err
+		return errStateTimeout
 	}
 }
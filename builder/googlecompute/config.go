@@ -18,13 +18,17 @@ type Config struct {
 
 	BucketName        string            `mapstructure:"bucket_name"`
 	ClientSecretsFile string            `mapstructure:"client_secrets_file"`
+	DiskSizeGb        int64             `mapstructure:"disk_size"`
+	DiskType          string            `mapstructure:"disk_type"`
 	ImageName         string            `mapstructure:"image_name"`
 	ImageDescription  string            `mapstructure:"image_description"`
+	ImageFamily       string            `mapstructure:"image_family"`
 	InstanceName      string            `mapstructure:"instance_name"`
 	MachineType       string            `mapstructure:"machine_type"`
 	Metadata          map[string]string `mapstructure:"metadata"`
 	Network           string            `mapstructure:"network"`
 	Passphrase        string            `mapstructure:"passphrase"`
+	Preemptible       bool              `mapstructure:"preemptible"`
 	PrivateKeyFile    string            `mapstructure:"private_key_file"`
 	ProjectId         string            `mapstructure:"project_id"`
 	SourceImage       string            `mapstructure:"source_image"`
@@ -32,7 +36,9 @@ type Config struct {
 	SSHPort           uint              `mapstructure:"ssh_port"`
 	RawSSHTimeout     string            `mapstructure:"ssh_timeout"`
 	RawStateTimeout   string            `mapstructure:"state_timeout"`
+	Subnetwork        string            `mapstructure:"subnetwork"`
 	Tags              []string          `mapstructure:"tags"`
+	UseInternalIP     bool              `mapstructure:"use_internal_ip"`
 	Zone              string            `mapstructure:"zone"`
 
 	clientSecrets   *clientSecrets
@@ -60,6 +66,10 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	errs := common.CheckUnusedConfig(md)
 
 	// Set defaults.
+	if c.DiskType == "" {
+		c.DiskType = "pd-standard"
+	}
+
 	if c.Network == "" {
 		c.Network = "default"
 	}
@@ -100,8 +110,10 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	templates := map[string]*string{
 		"bucket_name":         &c.BucketName,
 		"client_secrets_file": &c.ClientSecretsFile,
+		"disk_type":           &c.DiskType,
 		"image_name":          &c.ImageName,
 		"image_description":   &c.ImageDescription,
+		"image_family":        &c.ImageFamily,
 		"instance_name":       &c.InstanceName,
 		"machine_type":        &c.MachineType,
 		"network":             &c.Network,
@@ -112,6 +124,7 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		"ssh_username":        &c.SSHUsername,
 		"ssh_timeout":         &c.RawSSHTimeout,
 		"state_timeout":       &c.RawStateTimeout,
+		"subnetwork":          &c.Subnetwork,
 		"zone":                &c.Zone,
 	}
 
@@ -124,12 +137,16 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		}
 	}
 
-	// Process required parameters.
-	if c.BucketName == "" {
-		errs = packer.MultiErrorAppend(
-			errs, errors.New("a bucket_name must be specified"))
+	// Images are created from the disk of the instance, so there is
+	// nothing to upload to the bucket anymore.
+	var warnings []string
+	if c.BucketName != "" {
+		warnings = append(warnings,
+			"bucket_name is deprecated and no longer used, since images are\n"+
+				"created directly from the disk of the instance.")
 	}
 
+	// Process required parameters.
 	if c.ClientSecretsFile == "" {
 		errs = packer.MultiErrorAppend(
 			errs, errors.New("a client_secrets_file must be specified"))
@@ -155,6 +172,11 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 			errs, errors.New("a zone must be specified"))
 	}
 
+	if c.DiskSizeGb < 0 {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("disk_size must be a positive number of GB"))
+	}
+
 	// Process timeout settings.
 	sshTimeout, err := time.ParseDuration(c.RawSSHTimeout)
 	if err != nil {
@@ -191,8 +213,8 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 
 	// Check for any errors.
 	if errs != nil && len(errs.Errors) > 0 {
-		return nil, nil, errs
+		return nil, warnings, errs
 	}
 
-	return c, nil, nil
+	return c, warnings, nil
 }
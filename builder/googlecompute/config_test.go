@@ -6,7 +6,6 @@ import (
 
 func testConfig(t *testing.T) map[string]interface{} {
 	return map[string]interface{}{
-		"bucket_name":         "foo",
 		"client_secrets_file": testClientSecretsFile(t),
 		"private_key_file":    testPrivateKeyFile(t),
 		"project_id":          "hashicorp",
@@ -58,13 +57,48 @@ func TestConfigPrepare(t *testing.T) {
 		},
 
 		{
-			"bucket_name",
-			nil,
+			"disk_size",
+			-1,
 			true,
 		},
 		{
-			"bucket_name",
-			"good",
+			"disk_size",
+			50,
+			false,
+		},
+
+		{
+			"disk_type",
+			"pd-ssd",
+			false,
+		},
+
+		{
+			"image_family",
+			"{{",
+			true,
+		},
+		{
+			"image_family",
+			"foo-family",
+			false,
+		},
+
+		{
+			"preemptible",
+			true,
+			false,
+		},
+
+		{
+			"subnetwork",
+			"foo-subnet",
+			false,
+		},
+
+		{
+			"use_internal_ip",
+			true,
 			false,
 		},
 
@@ -174,3 +208,26 @@ func TestConfigPrepare(t *testing.T) {
 		}
 	}
 }
+
+func TestConfigDefaults(t *testing.T) {
+	c := testConfigStruct(t)
+	if c.DiskSizeGb != 0 {
+		t.Fatalf("bad: %d", c.DiskSizeGb)
+	}
+	if c.DiskType != "pd-standard" {
+		t.Fatalf("bad: %s", c.DiskType)
+	}
+}
+
+func TestConfigPrepare_bucketName(t *testing.T) {
+	raw := testConfig(t)
+	raw["bucket_name"] = "foo"
+
+	_, warns, errs := NewConfig(raw)
+	if len(warns) != 1 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if errs != nil {
+		t.Fatalf("bad: %s", errs)
+	}
+}
@@ -4,8 +4,12 @@ package googlecompute
 // with GCE. The Driver interface exists mostly to allow a mock implementation
 // to be used to test the steps.
 type Driver interface {
-	// CreateImage creates an image with the given URL in Google Storage.
-	CreateImage(name, description, url string) <-chan error
+	// CreateImage creates an image from the given disk. The image is added
+	// to the image family if it isn't empty.
+	CreateImage(name, description, family, zone, disk string) <-chan error
+
+	// DeleteDisk deletes the disk with the given name.
+	DeleteDisk(zone, name string) (<-chan error, error)
 
 	// DeleteImage deletes the image with the given name.
 	DeleteImage(name string) <-chan error
@@ -13,6 +17,9 @@ type Driver interface {
 	// DeleteInstance deletes the given instance.
 	DeleteInstance(zone, name string) (<-chan error, error)
 
+	// GetInternalIP gets the internal IP address for the instance.
+	GetInternalIP(zone, name string) (string, error)
+
 	// GetNatIP gets the NAT IP address for the instance.
 	GetNatIP(zone, name string) (string, error)
 
@@ -25,11 +32,15 @@ type Driver interface {
 
 type InstanceConfig struct {
 	Description string
+	DiskSizeGb  int64
+	DiskType    string
 	Image       string
 	MachineType string
 	Metadata    map[string]string
 	Name        string
 	Network     string
+	Preemptible bool
+	Subnetwork  string
 	Tags        []string
 	Zone        string
 }
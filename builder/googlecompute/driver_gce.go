@@ -3,13 +3,13 @@ package googlecompute
 import (
 	"fmt"
 	"log"
-	"net/http"
+	"path"
 	"time"
 
-	"code.google.com/p/goauth2/oauth"
-	"code.google.com/p/goauth2/oauth/jwt"
-	"code.google.com/p/google-api-go-client/compute/v1"
 	"github.com/mitchellh/packer/packer"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
+	"google.golang.org/api/compute/v1"
 )
 
 // driverGCE is a Driver implementation that actually talks to GCE.
@@ -20,8 +20,9 @@ type driverGCE struct {
 	ui        packer.Ui
 }
 
-const DriverScopes string = "https://www.googleapis.com/auth/compute " +
-	"https://www.googleapis.com/auth/devstorage.full_control"
+var DriverScopes = []string{
+	"https://www.googleapis.com/auth/compute",
+}
 
 func NewDriverGCE(ui packer.Ui, projectId string, c *clientSecrets, key []byte) (Driver, error) {
 	log.Printf("[INFO] Requesting token...")
@@ -29,25 +30,15 @@ func NewDriverGCE(ui packer.Ui, projectId string, c *clientSecrets, key []byte)
 	log.Printf("[INFO]   -- Scopes: %s", DriverScopes)
 	log.Printf("[INFO]   -- Private Key Length: %d", len(key))
 	log.Printf("[INFO]   -- Token URL: %s", c.Web.TokenURI)
-	jwtTok := jwt.NewToken(c.Web.ClientEmail, DriverScopes, key)
-	jwtTok.ClaimSet.Aud = c.Web.TokenURI
-	token, err := jwtTok.Assert(new(http.Client))
-	if err != nil {
-		return nil, err
-	}
-
-	transport := &oauth.Transport{
-		Config: &oauth.Config{
-			ClientId: c.Web.ClientId,
-			Scope:    DriverScopes,
-			TokenURL: c.Web.TokenURI,
-			AuthURL:  c.Web.AuthURI,
-		},
-		Token: token,
+	conf := &jwt.Config{
+		Email:      c.Web.ClientEmail,
+		PrivateKey: key,
+		Scopes:     DriverScopes,
+		TokenURL:   c.Web.TokenURI,
 	}
 
 	log.Printf("[INFO] Instantiating client...")
-	service, err := compute.New(transport.Client())
+	service, err := compute.New(conf.Client(oauth2.NoContext))
 	if err != nil {
 		return nil, err
 	}
@@ -59,15 +50,13 @@ func NewDriverGCE(ui packer.Ui, projectId string, c *clientSecrets, key []byte)
 	}, nil
 }
 
-func (d *driverGCE) CreateImage(name, description, url string) <-chan error {
+func (d *driverGCE) CreateImage(name, description, family, zone, disk string) <-chan error {
 	image := &compute.Image{
 		Description: description,
+		Family:      family,
 		Name:        name,
-		RawDisk: &compute.ImageRawDisk{
-			ContainerType: "TAR",
-			Source:        url,
-		},
-		SourceType: "RAW",
+		SourceDisk:  fmt.Sprintf("zones/%s/disks/%s", zone, disk),
+		SourceType:  "RAW",
 	}
 
 	errCh := make(chan error, 1)
@@ -81,6 +70,17 @@ func (d *driverGCE) CreateImage(name, description, url string) <-chan error {
 	return errCh
 }
 
+func (d *driverGCE) DeleteDisk(zone, name string) (<-chan error, error) {
+	op, err := d.service.Disks.Delete(d.projectId, zone, name).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	errCh := make(chan error, 1)
+	go waitForState(errCh, "DONE", d.refreshZoneOp(zone, op))
+	return errCh, nil
+}
+
 func (d *driverGCE) DeleteImage(name string) <-chan error {
 	errCh := make(chan error, 1)
 	op, err := d.service.Images.Delete(d.projectId, name).Do()
@@ -104,6 +104,21 @@ func (d *driverGCE) DeleteInstance(zone, name string) (<-chan error, error) {
 	return errCh, nil
 }
 
+func (d *driverGCE) GetInternalIP(zone, name string) (string, error) {
+	instance, err := d.service.Instances.Get(d.projectId, zone, name).Do()
+	if err != nil {
+		return "", err
+	}
+
+	for _, ni := range instance.NetworkInterfaces {
+		if ni.NetworkIP != "" {
+			return ni.NetworkIP, nil
+		}
+	}
+
+	return "", nil
+}
+
 func (d *driverGCE) GetNatIP(zone, name string) (string, error) {
 	instance, err := d.service.Instances.Get(d.projectId, zone, name).Do()
 	if err != nil {
@@ -156,15 +171,40 @@ func (d *driverGCE) RunInstance(c *InstanceConfig) (<-chan error, error) {
 		return nil, err
 	}
 
+	// Get the subnetwork, which is in the region of the zone
+	subnetworkSelfLink := ""
+	if c.Subnetwork != "" {
+		region := path.Base(zone.Region)
+		d.ui.Message(fmt.Sprintf("Loading subnetwork: %s in %s", c.Subnetwork, region))
+		subnetwork, err := d.service.Subnetworks.Get(
+			d.projectId, region, c.Subnetwork).Do()
+		if err != nil {
+			return nil, err
+		}
+		subnetworkSelfLink = subnetwork.SelfLink
+	}
+
 	// Build up the metadata
 	metadata := make([]*compute.MetadataItems, len(c.Metadata))
 	for k, v := range c.Metadata {
+		value := v
 		metadata = append(metadata, &compute.MetadataItems{
 			Key:   k,
-			Value: v,
+			Value: &value,
 		})
 	}
 
+	// Preemptible instances can't be live migrated
+	scheduling := &compute.Scheduling{
+		OnHostMaintenance: "MIGRATE",
+	}
+	if c.Preemptible {
+		scheduling = &compute.Scheduling{
+			OnHostMaintenance: "TERMINATE",
+			Preemptible:       true,
+		}
+	}
+
 	// Create the instance information
 	instance := compute.Instance{
 		Description: c.Description,
@@ -174,8 +214,11 @@ func (d *driverGCE) RunInstance(c *InstanceConfig) (<-chan error, error) {
 				Mode:       "READ_WRITE",
 				Kind:       "compute#attachedDisk",
 				Boot:       true,
-				AutoDelete: true,
+				AutoDelete: false,
 				InitializeParams: &compute.AttachedDiskInitializeParams{
+					DiskName:    c.Name,
+					DiskSizeGb:  c.DiskSizeGb,
+					DiskType:    fmt.Sprintf("zones/%s/diskTypes/%s", zone.Name, c.DiskType),
 					SourceImage: image.SelfLink,
 				},
 			},
@@ -193,9 +236,11 @@ func (d *driverGCE) RunInstance(c *InstanceConfig) (<-chan error, error) {
 						Type: "ONE_TO_ONE_NAT",
 					},
 				},
-				Network: network.SelfLink,
+				Network:    network.SelfLink,
+				Subnetwork: subnetworkSelfLink,
 			},
 		},
+		Scheduling: scheduling,
 		ServiceAccounts: []*compute.ServiceAccount{
 			&compute.ServiceAccount{
 				Email: "default",
@@ -1,11 +1,11 @@
 package googlecompute
 
 import (
-	"strings"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/mitchellh/multistep"
-	"github.com/mitchellh/packer/packer"
 )
 
 func TestStepCreateImage_impl(t *testing.T) {
@@ -17,80 +17,95 @@ func TestStepCreateImage(t *testing.T) {
 	step := new(StepCreateImage)
 	defer step.Cleanup(state)
 
-	comm := new(packer.MockCommunicator)
-	state.Put("communicator", comm)
+	config := state.Get("config").(*Config)
+	config.ImageFamily = "foo-family"
+	driver := state.Get("driver").(*DriverMock)
 
 	// run the step
 	if action := step.Run(state); action != multistep.ActionContinue {
 		t.Fatalf("bad action: %#v", action)
 	}
 
-	// Verify
-	if !comm.StartCalled {
-		t.Fatal("start should be called")
+	// Verify state
+	if driver.CreateImageName != config.ImageName {
+		t.Fatalf("bad: %#v", driver.CreateImageName)
+	}
+	if driver.CreateImageDesc != config.ImageDescription {
+		t.Fatalf("bad: %#v", driver.CreateImageDesc)
 	}
-	if strings.HasPrefix(comm.StartCmd.Command, "sudo") {
-		t.Fatal("should not sudo")
+	if driver.CreateImageFamily != "foo-family" {
+		t.Fatalf("bad: %#v", driver.CreateImageFamily)
 	}
-	if !strings.Contains(comm.StartCmd.Command, "gcimagebundle") {
-		t.Fatalf("bad command: %#v", comm.StartCmd.Command)
+	if driver.CreateImageZone != config.Zone {
+		t.Fatalf("bad: %#v", driver.CreateImageZone)
+	}
+	if driver.CreateImageDisk != config.InstanceName {
+		t.Fatalf("bad: %#v", driver.CreateImageDisk)
 	}
 
-	if _, ok := state.GetOk("image_file_name"); !ok {
-		t.Fatal("should have image")
+	nameRaw, ok := state.GetOk("image_name")
+	if !ok {
+		t.Fatal("should have name")
+	}
+	if name, ok := nameRaw.(string); !ok {
+		t.Fatal("name is not a string")
+	} else if name != config.ImageName {
+		t.Fatalf("bad name: %s", name)
 	}
 }
 
-func TestStepCreateImage_badExitStatus(t *testing.T) {
+func TestStepCreateImage_waitError(t *testing.T) {
 	state := testState(t)
 	step := new(StepCreateImage)
 	defer step.Cleanup(state)
 
-	comm := new(packer.MockCommunicator)
-	comm.StartExitStatus = 12
-	state.Put("communicator", comm)
+	errCh := make(chan error, 1)
+	errCh <- errors.New("error")
+
+	driver := state.Get("driver").(*DriverMock)
+	driver.CreateImageErrCh = errCh
 
 	// run the step
 	if action := step.Run(state); action != multistep.ActionHalt {
 		t.Fatalf("bad action: %#v", action)
 	}
 
+	// Verify state
 	if _, ok := state.GetOk("error"); !ok {
 		t.Fatal("should have error")
 	}
-	if _, ok := state.GetOk("image_file_name"); ok {
-		t.Fatal("should NOT have image")
+	if _, ok := state.GetOk("image_name"); ok {
+		t.Fatal("should NOT have image_name")
 	}
 }
 
-func TestStepCreateImage_nonRoot(t *testing.T) {
+func TestStepCreateImage_errorTimeout(t *testing.T) {
 	state := testState(t)
 	step := new(StepCreateImage)
 	defer step.Cleanup(state)
 
-	comm := new(packer.MockCommunicator)
-	state.Put("communicator", comm)
+	errCh := make(chan error, 1)
+	go func() {
+		<-time.After(10 * time.Millisecond)
+		errCh <- nil
+	}()
 
 	config := state.Get("config").(*Config)
-	config.SSHUsername = "bob"
+	config.stateTimeout = 1 * time.Microsecond
+
+	driver := state.Get("driver").(*DriverMock)
+	driver.CreateImageErrCh = errCh
 
 	// run the step
-	if action := step.Run(state); action != multistep.ActionContinue {
+	if action := step.Run(state); action != multistep.ActionHalt {
 		t.Fatalf("bad action: %#v", action)
 	}
 
-	// Verify
-	if !comm.StartCalled {
-		t.Fatal("start should be called")
-	}
-	if !strings.HasPrefix(comm.StartCmd.Command, "sudo") {
-		t.Fatal("should sudo")
-	}
-	if !strings.Contains(comm.StartCmd.Command, "gcimagebundle") {
-		t.Fatalf("bad command: %#v", comm.StartCmd.Command)
+	// Verify state
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
 	}
-
-	if _, ok := state.GetOk("image_file_name"); !ok {
-		t.Fatal("should have image")
+	if _, ok := state.GetOk("image_name"); ok {
+		t.Fatal("should NOT have image name")
 	}
 }
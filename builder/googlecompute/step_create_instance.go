@@ -28,18 +28,27 @@ func (s *StepCreateInstance) Run(state multistep.StateBag) multistep.StepAction
 
 	errCh, err := driver.RunInstance(&InstanceConfig{
 		Description: "New instance created by Packer",
+		DiskSizeGb:  config.DiskSizeGb,
+		DiskType:    config.DiskType,
 		Image:       config.SourceImage,
 		MachineType: config.MachineType,
 		Metadata: map[string]string{
 			"sshKeys": fmt.Sprintf("%s:%s", config.SSHUsername, sshPublicKey),
 		},
-		Name:    name,
-		Network: config.Network,
-		Tags:    config.Tags,
-		Zone:    config.Zone,
+		Name:        name,
+		Network:     config.Network,
+		Preemptible: config.Preemptible,
+		Subnetwork:  config.Subnetwork,
+		Tags:        config.Tags,
+		Zone:        config.Zone,
 	})
 
 	if err == nil {
+		// The instance and its disk may exist even if the operation
+		// fails or times out, so store the name to remove them later
+		state.Put("instance_name", name)
+		s.instanceName = name
+
 		ui.Message("Waiting for creation operation to complete...")
 		select {
 		case err = <-errCh:
@@ -63,14 +72,11 @@ func (s *StepCreateInstance) Run(state multistep.StateBag) multistep.StepAction
 		}
 	}
 
-	// Things succeeded, store the name so we can remove it later
-	state.Put("instance_name", name)
-	s.instanceName = name
-
 	return multistep.ActionContinue
 }
 
-// Cleanup destroys the GCE instance created during the image creation process.
+// Cleanup destroys the GCE instance created during the image creation
+// process, and its boot disk, which outlives the instance.
 func (s *StepCreateInstance) Cleanup(state multistep.StateBag) {
 	if s.instanceName == "" {
 		return
@@ -80,19 +86,41 @@ func (s *StepCreateInstance) Cleanup(state multistep.StateBag) {
 	driver := state.Get("driver").(Driver)
 	ui := state.Get("ui").(packer.Ui)
 
-	ui.Say("Deleting instance...")
-	errCh, err := driver.DeleteInstance(config.Zone, s.instanceName)
+	// StepTeardownInstance clears the instance name once it deleted
+	// the instance.
+	if name := state.Get("instance_name").(string); name != "" {
+		ui.Say("Deleting instance...")
+		errCh, err := driver.DeleteInstance(config.Zone, name)
+		if err == nil {
+			select {
+			case err = <-errCh:
+			case <-time.After(config.stateTimeout):
+				err = errors.New("time out while waiting for instance to delete")
+			}
+		}
+
+		if err != nil {
+			ui.Error(fmt.Sprintf(
+				"Error deleting instance. Please delete it manually.\n\n"+
+					"Name: %s\n"+
+					"Error: %s", name, err))
+		}
+	}
+
+	// The boot disk is named after the instance
+	ui.Say("Deleting disk...")
+	errCh, err := driver.DeleteDisk(config.Zone, s.instanceName)
 	if err == nil {
 		select {
 		case err = <-errCh:
 		case <-time.After(config.stateTimeout):
-			err = errors.New("time out while waiting for instance to delete")
+			err = errors.New("time out while waiting for disk to delete")
 		}
 	}
 
 	if err != nil {
 		ui.Error(fmt.Sprintf(
-			"Error deleting instance. Please delete it manually.\n\n"+
+			"Error deleting disk. Please delete it manually.\n\n"+
 				"Name: %s\n"+
 				"Error: %s", s.instanceName, err))
 	}
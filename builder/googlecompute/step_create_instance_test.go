@@ -41,6 +41,64 @@ func TestStepCreateInstance(t *testing.T) {
 	if driver.DeleteInstanceZone != config.Zone {
 		t.Fatal("bad zone: %#v", driver.DeleteInstanceZone)
 	}
+	if driver.DeleteDiskName != nameRaw.(string) {
+		t.Fatal("should've deleted disk")
+	}
+}
+
+func TestStepCreateInstance_config(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateInstance)
+	defer step.Cleanup(state)
+
+	state.Put("ssh_public_key", "key")
+
+	config := state.Get("config").(*Config)
+	config.DiskSizeGb = 50
+	config.DiskType = "pd-ssd"
+	config.Preemptible = true
+	config.Subnetwork = "foo-subnet"
+	driver := state.Get("driver").(*DriverMock)
+
+	// run the step
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	c := driver.RunInstanceConfig
+	if c.DiskSizeGb != 50 || c.DiskType != "pd-ssd" {
+		t.Fatalf("bad: %#v", c)
+	}
+	if !c.Preemptible || c.Subnetwork != "foo-subnet" {
+		t.Fatalf("bad: %#v", c)
+	}
+}
+
+func TestStepCreateInstance_cleanupTornDown(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateInstance)
+	defer step.Cleanup(state)
+
+	state.Put("ssh_public_key", "key")
+
+	driver := state.Get("driver").(*DriverMock)
+
+	// run the step
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	name := state.Get("instance_name").(string)
+
+	// StepTeardownInstance already deleted the instance
+	state.Put("instance_name", "")
+	step.Cleanup(state)
+
+	if driver.DeleteInstanceName != "" {
+		t.Fatalf("should not delete instance: %#v", driver.DeleteInstanceName)
+	}
+	if driver.DeleteDiskName != name {
+		t.Fatalf("should've deleted disk: %#v", driver.DeleteDiskName)
+	}
 }
 
 func TestStepCreateInstance_error(t *testing.T) {
@@ -89,8 +147,20 @@ func TestStepCreateInstance_errorOnChannel(t *testing.T) {
 	if _, ok := state.GetOk("error"); !ok {
 		t.Fatal("should have error")
 	}
-	if _, ok := state.GetOk("instance_name"); ok {
-		t.Fatal("should NOT have instance name")
+	name, ok := state.GetOk("instance_name")
+	if !ok {
+		t.Fatal("should have instance name")
+	}
+
+	// The instance and its disk are removed even though the creation
+	// operation failed
+	step.Cleanup(state)
+
+	if driver.DeleteInstanceName != name.(string) {
+		t.Fatalf("should've deleted instance: %#v", driver.DeleteInstanceName)
+	}
+	if driver.DeleteDiskName != name.(string) {
+		t.Fatalf("should've deleted disk: %#v", driver.DeleteDiskName)
 	}
 }
 
@@ -122,7 +192,19 @@ func TestStepCreateInstance_errorTimeout(t *testing.T) {
 	if _, ok := state.GetOk("error"); !ok {
 		t.Fatal("should have error")
 	}
-	if _, ok := state.GetOk("instance_name"); ok {
-		t.Fatal("should NOT have instance name")
+	name, ok := state.GetOk("instance_name")
+	if !ok {
+		t.Fatal("should have instance name")
+	}
+
+	// The instance and its disk are removed even though the creation
+	// operation failed
+	step.Cleanup(state)
+
+	if driver.DeleteInstanceName != name.(string) {
+		t.Fatalf("should've deleted instance: %#v", driver.DeleteInstanceName)
+	}
+	if driver.DeleteDiskName != name.(string) {
+		t.Fatalf("should've deleted disk: %#v", driver.DeleteDiskName)
 	}
 }
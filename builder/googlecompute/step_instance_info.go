@@ -40,6 +40,20 @@ func (s *StepInstanceInfo) Run(state multistep.StateBag) multistep.StepAction {
 		return multistep.ActionHalt
 	}
 
+	if config.UseInternalIP {
+		ip, err := driver.GetInternalIP(config.Zone, instanceName)
+		if err != nil {
+			err := fmt.Errorf("Error retrieving instance internal ip address: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		ui.Message(fmt.Sprintf("Internal IP: %s", ip))
+		state.Put("instance_ip", ip)
+		return multistep.ActionContinue
+	}
+
 	ip, err := driver.GetNatIP(config.Zone, instanceName)
 	if err != nil {
 		err := fmt.Errorf("Error retrieving instance nat ip address: %s", err)
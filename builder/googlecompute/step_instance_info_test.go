@@ -49,6 +49,33 @@ func TestStepInstanceInfo(t *testing.T) {
 	}
 }
 
+func TestStepInstanceInfo_useInternalIP(t *testing.T) {
+	state := testState(t)
+	step := new(StepInstanceInfo)
+	defer step.Cleanup(state)
+
+	state.Put("instance_name", "foo")
+
+	config := state.Get("config").(*Config)
+	config.UseInternalIP = true
+	driver := state.Get("driver").(*DriverMock)
+	driver.GetNatIPResult = "1.2.3.4"
+	driver.GetInternalIPResult = "10.0.0.2"
+
+	// run the step
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// Verify state
+	if driver.GetInternalIPName != "foo" {
+		t.Fatalf("bad: %#v", driver.GetInternalIPName)
+	}
+	if ip := state.Get("instance_ip"); ip != "10.0.0.2" {
+		t.Fatalf("bad ip: %#v", ip)
+	}
+}
+
 func TestStepInstanceInfo_getNatIPError(t *testing.T) {
 	state := testState(t)
 	step := new(StepInstanceInfo)
@@ -0,0 +1,48 @@
+package googlecompute
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// StepTeardownInstance represents a Packer build step that deletes the GCE
+// instance, so that an image can be created from its boot disk. The disk
+// is kept, and deleted by the cleanup of StepCreateInstance.
+type StepTeardownInstance int
+
+// Run executes the Packer build step that deletes the GCE instance.
+func (s *StepTeardownInstance) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	name := state.Get("instance_name").(string)
+	ui := state.Get("ui").(packer.Ui)
+
+	ui.Say("Deleting instance...")
+	errCh, err := driver.DeleteInstance(config.Zone, name)
+	if err == nil {
+		select {
+		case err = <-errCh:
+		case <-time.After(config.stateTimeout):
+			err = errors.New("time out while waiting for instance to delete")
+		}
+	}
+
+	if err != nil {
+		err := fmt.Errorf("Error deleting instance: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Message("Instance has been deleted!")
+	state.Put("instance_name", "")
+
+	return multistep.ActionContinue
+}
+
+// Cleanup.
+func (s *StepTeardownInstance) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,66 @@
+package googlecompute
+
+import (
+	"errors"
+	"github.com/mitchellh/multistep"
+	"testing"
+)
+
+func TestStepTeardownInstance_impl(t *testing.T) {
+	var _ multistep.Step = new(StepTeardownInstance)
+}
+
+func TestStepTeardownInstance(t *testing.T) {
+	state := testState(t)
+	step := new(StepTeardownInstance)
+	defer step.Cleanup(state)
+
+	state.Put("instance_name", "foo")
+
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(*DriverMock)
+
+	// run the step
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if driver.DeleteInstanceName != "foo" {
+		t.Fatalf("bad: %#v", driver.DeleteInstanceName)
+	}
+	if driver.DeleteInstanceZone != config.Zone {
+		t.Fatalf("bad: %#v", driver.DeleteInstanceZone)
+	}
+
+	// The disk is kept for the image
+	if driver.DeleteDiskName != "" {
+		t.Fatalf("bad: %#v", driver.DeleteDiskName)
+	}
+	if name := state.Get("instance_name"); name != "" {
+		t.Fatalf("bad: %#v", name)
+	}
+}
+
+func TestStepTeardownInstance_error(t *testing.T) {
+	state := testState(t)
+	step := new(StepTeardownInstance)
+	defer step.Cleanup(state)
+
+	state.Put("instance_name", "foo")
+
+	driver := state.Get("driver").(*DriverMock)
+	driver.DeleteInstanceErr = errors.New("error")
+
+	// run the step
+	if action := step.Run(state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// Verify state
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+	if name := state.Get("instance_name"); name != "foo" {
+		t.Fatalf("bad: %#v", name)
+	}
+}
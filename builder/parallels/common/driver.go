@@ -1,8 +1,12 @@
 package common
 
 import (
+	"fmt"
 	"log"
 	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
 )
 
 // A driver is able to talk to Parallels and perform certain
@@ -12,6 +16,14 @@ import (
 // versions out of the builder steps, so sometimes the methods are
 // extremely specific.
 type Driver interface {
+	// CompactDisk compacts the virtual disk at the given path with
+	// prl_disk_tool.
+	CompactDisk(string) error
+
+	// DiskPath returns the path of the image backing the first hard
+	// drive of the VM with the given name.
+	DiskPath(string) (string, error)
+
 	// Import a VM
 	Import(string, string, string) error
 
@@ -21,9 +33,19 @@ type Driver interface {
 	// Stop stops a running machine, forcefully.
 	Stop(string) error
 
+	// Pack packs the VM with the given name into a single .pvmp archive
+	// in the given directory and returns the path to that archive. The
+	// VM itself is left unpacked.
+	Pack(string, string) (string, error)
+
 	// Prlctl executes the given Prlctl command
 	Prlctl(...string) error
 
+	// SetDefaultConfiguration applies the settings every VM built by
+	// Packer should have, such as disabling features that get in the
+	// way of an unattended build.
+	SetDefaultConfiguration(string) error
+
 	// Verify checks to make sure that this driver should function
 	// properly. If there is any indication the driver can't function,
 	// this will return an error.
@@ -45,6 +67,12 @@ type Driver interface {
 func NewDriver() (Driver, error) {
 	var prlctlPath string
 
+	switch runtime.GOOS {
+	case "darwin", "linux":
+	default:
+		return nil, fmt.Errorf("can't find driver for OS: %s", runtime.GOOS)
+	}
+
 	if prlctlPath == "" {
 		var err error
 		prlctlPath, err = exec.LookPath("prlctl")
@@ -54,10 +82,40 @@ func NewDriver() (Driver, error) {
 	}
 
 	log.Printf("prlctl path: %s", prlctlPath)
-	driver := &Parallels9Driver{prlctlPath}
-	if err := driver.Verify(); err != nil {
-		return nil, err
+
+	drivers := []Driver{
+		&Parallels10Driver{
+			Parallels9Driver: Parallels9Driver{
+				PrlctlPath: prlctlPath,
+			},
+		},
+		&Parallels9Driver{
+			PrlctlPath: prlctlPath,
+		},
+	}
+
+	errs := ""
+	for _, driver := range drivers {
+		err := driver.Verify()
+		if err == nil {
+			return driver, nil
+		}
+		errs += "* " + err.Error() + "\n"
+	}
+
+	return nil, fmt.Errorf(
+		"Unable to initialize any driver for this version of Parallels. The\n"+
+			"errors from each driver are shown below:\n%s", errs)
+}
+
+// majorVersion returns the major component of a Parallels version
+// string such as "10.1.2".
+func majorVersion(version string) (int, error) {
+	parts := strings.SplitN(version, ".", 2)
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("Invalid Parallels version: %s", version)
 	}
 
-	return driver, nil
+	return major, nil
 }
@@ -0,0 +1,46 @@
+package common
+
+import (
+	"fmt"
+)
+
+// Parallels10Driver is a driver that can run Parallels Desktop 10 and
+// later.
+type Parallels10Driver struct {
+	Parallels9Driver
+}
+
+func (d *Parallels10Driver) SetDefaultConfiguration(name string) error {
+	commands := d.defaultConfiguration(name)
+
+	// Parallels 10 throttles the VM when running on battery by default,
+	// which makes boot command timing unreliable.
+	commands = append(commands,
+		[]string{"set", name, "--longer-battery-life", "off"})
+
+	for _, command := range commands {
+		if err := d.Prlctl(command...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *Parallels10Driver) Verify() error {
+	version, err := d.Version()
+	if err != nil {
+		return err
+	}
+
+	major, err := majorVersion(version)
+	if err != nil {
+		return err
+	}
+
+	if major < 10 {
+		return fmt.Errorf("Parallels 10 driver does not support Parallels %s", version)
+	}
+
+	return nil
+}
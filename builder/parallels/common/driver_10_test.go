@@ -0,0 +1,9 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestParallels10Driver_impl(t *testing.T) {
+	var _ Driver = new(Parallels10Driver)
+}
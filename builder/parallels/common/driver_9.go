@@ -4,10 +4,14 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/going/toolkit/xmlpath"
+	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
 	"time"
 )
@@ -17,6 +21,45 @@ type Parallels9Driver struct {
 	PrlctlPath string
 }
 
+func (d *Parallels9Driver) CompactDisk(diskPath string) error {
+	prlDiskToolPath, err := exec.LookPath("prl_disk_tool")
+	if err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+
+	log.Printf("Compacting disk: %s", diskPath)
+	cmd := exec.Command(prlDiskToolPath, "compact", "--hdd", diskPath)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			err = fmt.Errorf("prl_disk_tool error: %s",
+				strings.TrimSpace(stderr.String()))
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (d *Parallels9Driver) DiskPath(name string) (string, error) {
+	info, err := d.vmInfo(name)
+	if err != nil {
+		return "", err
+	}
+
+	re := regexp.MustCompile("hdd0 .* image='(.+?)'")
+	diskMatch := re.FindStringSubmatch(info)
+	if diskMatch == nil {
+		return "", fmt.Errorf("Could not find the hard drive of VM: %s", name)
+	}
+
+	diskPath := diskMatch[1]
+	log.Printf("Found hard drive image: %s", diskPath)
+	return diskPath, nil
+}
+
 func (d *Parallels9Driver) Import(name, srcPath, dstDir string) error {
 
 	err := d.Prlctl("register", srcPath, "--preserve-uuid")
@@ -106,6 +149,67 @@ func (d *Parallels9Driver) Stop(name string) error {
 	return nil
 }
 
+func (d *Parallels9Driver) Pack(name string, dir string) (string, error) {
+	info, err := d.vmInfo(name)
+	if err != nil {
+		return "", err
+	}
+
+	re := regexp.MustCompile("(?m)^Home: (.+?)/?$")
+	homeMatch := re.FindStringSubmatch(info)
+	if homeMatch == nil {
+		return "", fmt.Errorf("Could not find the home directory of VM: %s", name)
+	}
+	home := homeMatch[1]
+
+	if err := d.Prlctl("pack", name); err != nil {
+		return "", err
+	}
+
+	// prlctl packs the bundle in place, so look for the archive both in
+	// the home of the VM and next to it.
+	var matches []string
+	for _, pattern := range []string{
+		filepath.Join(home, "*.pvmp"),
+		filepath.Join(filepath.Dir(home), "*.pvmp"),
+	} {
+		found, err := filepath.Glob(pattern)
+		if err != nil {
+			return "", err
+		}
+		matches = append(matches, found...)
+	}
+	if len(matches) != 1 {
+		return "", fmt.Errorf("Could not find the packed VM near: %s", home)
+	}
+	packed := matches[0]
+
+	// Keep a copy of the archive, then unpack the VM again so the .pvm
+	// is usable by anything that can't read the archive.
+	dst := filepath.Join(dir, name+".pvmp")
+	tmp := dst + ".tmp"
+	if err := copyFile(tmp, packed); err != nil {
+		return "", err
+	}
+
+	if err := d.Prlctl("unpack", name); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	if packed != dst {
+		if err := os.Remove(packed); err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+
+	if err := os.Rename(tmp, dst); err != nil {
+		return "", err
+	}
+
+	return dst, nil
+}
+
 func (d *Parallels9Driver) Prlctl(args ...string) error {
 	var stdout, stderr bytes.Buffer
 
@@ -128,11 +232,43 @@ func (d *Parallels9Driver) Prlctl(args ...string) error {
 	return err
 }
 
+func (d *Parallels9Driver) SetDefaultConfiguration(name string) error {
+	for _, command := range d.defaultConfiguration(name) {
+		if err := d.Prlctl(command...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *Parallels9Driver) defaultConfiguration(name string) [][]string {
+	return [][]string{
+		{"set", name, "--cpus", "1"},
+		{"set", name, "--memsize", "512"},
+		{"set", name, "--startup-view", "same"},
+		{"set", name, "--on-shutdown", "close"},
+		{"set", name, "--on-window-close", "keep-running"},
+		{"set", name, "--auto-share-camera", "off"},
+		{"set", name, "--smart-guard", "off"},
+	}
+}
+
 func (d *Parallels9Driver) Verify() error {
-	version, _ := d.Version()
-	if !strings.HasPrefix(version, "9.") {
-		return fmt.Errorf("The packer-parallels builder plugin only supports Parallels Desktop v. 9. You have: %s!\n", version)
+	version, err := d.Version()
+	if err != nil {
+		return err
+	}
+
+	major, err := majorVersion(version)
+	if err != nil {
+		return err
+	}
+
+	if major != 9 {
+		return fmt.Errorf("Parallels 9 driver does not support Parallels %s", version)
 	}
+
 	return nil
 }
 
@@ -190,16 +326,12 @@ func prepend(head string, tail []string) []string {
 }
 
 func (d *Parallels9Driver) Mac(vmName string) (string, error) {
-	var stdout bytes.Buffer
-
-	cmd := exec.Command(d.PrlctlPath, "list", "-i", vmName)
-	cmd.Stdout = &stdout
-	if err := cmd.Run(); err != nil {
+	stdoutString, err := d.vmInfo(vmName)
+	if err != nil {
 		log.Printf("MAC address for NIC: nic0 on Virtual Machine: %s not found!\n", vmName)
 		return "", err
 	}
 
-	stdoutString := strings.TrimSpace(stdout.String())
 	re := regexp.MustCompile("net0.* mac=([0-9A-F]{12}) card=.*")
 	macMatch := re.FindAllStringSubmatch(stdoutString, 1)
 
@@ -214,28 +346,69 @@ func (d *Parallels9Driver) Mac(vmName string) (string, error) {
 
 // Finds the IP address of a VM connected that uses DHCP by its MAC address
 func (d *Parallels9Driver) IpAddress(mac string) (string, error) {
-	var stdout bytes.Buffer
-	dhcp_lease_file := "/Library/Preferences/Parallels/parallels_dhcp_leases"
+	dhcp_lease_file := dhcpLeaseFile()
 
 	if len(mac) != 12 {
 		return "", fmt.Errorf("Not a valid MAC address: %s. It should be exactly 12 digits.", mac)
 	}
 
-	cmd := exec.Command("grep", "-i", mac, dhcp_lease_file)
-	cmd.Stdout = &stdout
-	if err := cmd.Run(); err != nil {
+	leases, err := ioutil.ReadFile(dhcp_lease_file)
+	if err != nil {
 		return "", err
 	}
 
-	stdoutString := strings.TrimSpace(stdout.String())
-	re := regexp.MustCompile("(.*)=.*")
-	ipMatch := re.FindAllStringSubmatch(stdoutString, 1)
+	re := regexp.MustCompile("(?im)^([^=]*)=.*" + mac)
+	ipMatch := re.FindStringSubmatch(string(leases))
 
-	if len(ipMatch) != 1 {
+	if ipMatch == nil {
 		return "", fmt.Errorf("IP lease not found for MAC address %s in: %s\n", mac, dhcp_lease_file)
 	}
 
-	ip := ipMatch[0][1]
+	ip := ipMatch[1]
 	log.Printf("Found IP lease: %s for MAC address %s\n", ip, mac)
 	return ip, nil
 }
+
+// copyFile copies the file at src to dst.
+func copyFile(dst, src string) error {
+	srcF, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcF.Close()
+
+	dstF, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dstF, srcF); err != nil {
+		dstF.Close()
+		return err
+	}
+
+	return dstF.Close()
+}
+
+// vmInfo returns the output of "prlctl list -i" for the given VM.
+func (d *Parallels9Driver) vmInfo(name string) (string, error) {
+	var stdout bytes.Buffer
+
+	cmd := exec.Command(d.PrlctlPath, "list", "-i", name)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// dhcpLeaseFile returns the path of the file in which the Parallels
+// DHCP server records its leases on this platform.
+func dhcpLeaseFile() string {
+	if runtime.GOOS == "linux" {
+		return "/etc/parallels/parallels_dhcp_leases"
+	}
+
+	return "/Library/Preferences/Parallels/parallels_dhcp_leases"
+}
@@ -5,6 +5,14 @@ import "sync"
 type DriverMock struct {
 	sync.Mutex
 
+	CompactDiskCalled bool
+	CompactDiskPath   string
+	CompactDiskErr    error
+
+	DiskPathName   string
+	DiskPathResult string
+	DiskPathErr    error
+
 	ImportCalled  bool
 	ImportName    string
 	ImportSrcPath string
@@ -18,9 +26,19 @@ type DriverMock struct {
 	StopName string
 	StopErr  error
 
+	PackCalled bool
+	PackName   string
+	PackDir    string
+	PackResult string
+	PackErr    error
+
 	PrlctlCalls [][]string
 	PrlctlErrs  []error
 
+	SetDefaultConfigurationCalled bool
+	SetDefaultConfigurationName   string
+	SetDefaultConfigurationErr    error
+
 	VerifyCalled bool
 	VerifyErr    error
 
@@ -40,6 +58,17 @@ type DriverMock struct {
 	IpAddressError  error
 }
 
+func (d *DriverMock) CompactDisk(path string) error {
+	d.CompactDiskCalled = true
+	d.CompactDiskPath = path
+	return d.CompactDiskErr
+}
+
+func (d *DriverMock) DiskPath(name string) (string, error) {
+	d.DiskPathName = name
+	return d.DiskPathResult, d.DiskPathErr
+}
+
 func (d *DriverMock) Import(name, srcPath, dstPath string) error {
 	d.ImportCalled = true
 	d.ImportName = name
@@ -61,6 +90,13 @@ func (d *DriverMock) Stop(name string) error {
 	return d.StopErr
 }
 
+func (d *DriverMock) Pack(name string, dir string) (string, error) {
+	d.PackCalled = true
+	d.PackName = name
+	d.PackDir = dir
+	return d.PackResult, d.PackErr
+}
+
 func (d *DriverMock) Prlctl(args ...string) error {
 	d.PrlctlCalls = append(d.PrlctlCalls, args)
 
@@ -70,6 +106,12 @@ func (d *DriverMock) Prlctl(args ...string) error {
 	return nil
 }
 
+func (d *DriverMock) SetDefaultConfiguration(name string) error {
+	d.SetDefaultConfigurationCalled = true
+	d.SetDefaultConfigurationName = name
+	return d.SetDefaultConfigurationErr
+}
+
 func (d *DriverMock) Verify() error {
 	d.VerifyCalled = true
 	return d.VerifyErr
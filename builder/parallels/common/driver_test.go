@@ -0,0 +1,27 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestMajorVersion(t *testing.T) {
+	cases := map[string]int{
+		"9.0.24251": 9,
+		"10.1.2":    10,
+		"11":        11,
+	}
+
+	for version, expected := range cases {
+		major, err := majorVersion(version)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if major != expected {
+			t.Fatalf("bad major version for %s: %d", version, major)
+		}
+	}
+
+	if _, err := majorVersion("foo"); err == nil {
+		t.Fatal("should have error")
+	}
+}
@@ -0,0 +1,38 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mitchellh/packer/packer"
+)
+
+// ExportConfig configures the form the resulting VM is left in: the
+// .pvm directory itself or a packed .pvmp archive.
+type ExportConfig struct {
+	Format string `mapstructure:"format"`
+}
+
+func (c *ExportConfig) Prepare(t *packer.ConfigTemplate) []error {
+	if c.Format == "" {
+		c.Format = "pvm"
+	}
+
+	templates := map[string]*string{
+		"format": &c.Format,
+	}
+
+	errs := make([]error, 0)
+	for n, ptr := range templates {
+		var err error
+		*ptr, err = t.Process(*ptr, nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Error processing %s: %s", n, err))
+		}
+	}
+
+	if c.Format != "pvm" && c.Format != "pvmp" {
+		errs = append(errs, errors.New("format must be one of 'pvm' or 'pvmp'"))
+	}
+
+	return errs
+}
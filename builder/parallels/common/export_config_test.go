@@ -0,0 +1,37 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestExportConfigPrepare_Format(t *testing.T) {
+	var c *ExportConfig
+	var errs []error
+
+	// Test empty
+	c = new(ExportConfig)
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) > 0 {
+		t.Fatalf("should not have error: %s", errs)
+	}
+
+	if c.Format != "pvm" {
+		t.Fatalf("bad value: %s", c.Format)
+	}
+
+	// Test with a good one
+	c = new(ExportConfig)
+	c.Format = "pvmp"
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) > 0 {
+		t.Fatalf("should not have error: %s", errs)
+	}
+
+	// Test with a bad one
+	c = new(ExportConfig)
+	c.Format = "ova"
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) == 0 {
+		t.Fatal("should have error")
+	}
+}
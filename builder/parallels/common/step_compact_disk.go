@@ -0,0 +1,61 @@
+package common
+
+import (
+	"fmt"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	"log"
+	"os/exec"
+)
+
+// This step compacts the virtual disk for the VM unless the "skip_compaction"
+// boolean is true. Compaction is skipped with a warning if prl_disk_tool
+// isn't installed.
+//
+// Uses:
+//   driver Driver
+//   ui packer.Ui
+//   vmName string
+//
+// Produces:
+//   <nothing>
+type StepCompactDisk struct {
+	Skip bool
+}
+
+func (s *StepCompactDisk) Run(state multistep.StateBag) multistep.StepAction {
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+	vmName := state.Get("vmName").(string)
+
+	if s.Skip {
+		log.Println("Skipping disk compaction step...")
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Compacting the disk image")
+	diskPath, err := driver.DiskPath(vmName)
+	if err != nil {
+		err := fmt.Errorf("Error finding the disk image: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if err := driver.CompactDisk(diskPath); err != nil {
+		if e, ok := err.(*exec.Error); ok && e.Err == exec.ErrNotFound {
+			ui.Error(fmt.Sprintf(
+				"Skipping disk compaction, prl_disk_tool was not found: %s", err))
+			return multistep.ActionContinue
+		}
+
+		err := fmt.Errorf("Error compacting disk: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepCompactDisk) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"fmt"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	"log"
+)
+
+// This step packs the VM into a single .pvmp archive in the output
+// directory if the "pvmp" format was requested. The .pvm directory is
+// left next to it either way.
+//
+// Uses:
+//   driver Driver
+//   ui packer.Ui
+//   vmName string
+//
+// Produces:
+//   <nothing>
+type StepExport struct {
+	Format    string
+	OutputDir string
+}
+
+func (s *StepExport) Run(state multistep.StateBag) multistep.StepAction {
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+	vmName := state.Get("vmName").(string)
+
+	if s.Format != "pvmp" {
+		log.Println("Skipping packing the virtual machine...")
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Packing the virtual machine...")
+	path, err := driver.Pack(vmName, s.OutputDir)
+	if err != nil {
+		err := fmt.Errorf("Error packing virtual machine: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Message(fmt.Sprintf("Packed virtual machine: %s", path))
+	return multistep.ActionContinue
+}
+
+func (s *StepExport) Cleanup(state multistep.StateBag) {}
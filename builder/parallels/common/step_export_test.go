@@ -0,0 +1,72 @@
+package common
+
+import (
+	"errors"
+	"github.com/mitchellh/multistep"
+	"testing"
+)
+
+func TestStepExport_impl(t *testing.T) {
+	var _ multistep.Step = new(StepExport)
+}
+
+func TestStepExport(t *testing.T) {
+	state := testState(t)
+	step := &StepExport{Format: "pvmp", OutputDir: "output"}
+
+	state.Put("vmName", "foo")
+
+	driver := state.Get("driver").(*DriverMock)
+	driver.PackResult = "output/foo.pvmp"
+
+	// Test the run
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+
+	if driver.PackName != "foo" {
+		t.Fatalf("bad: %#v", driver.PackName)
+	}
+	if driver.PackDir != "output" {
+		t.Fatalf("bad: %#v", driver.PackDir)
+	}
+}
+
+func TestStepExport_pvm(t *testing.T) {
+	state := testState(t)
+	step := &StepExport{Format: "pvm"}
+
+	state.Put("vmName", "foo")
+
+	driver := state.Get("driver").(*DriverMock)
+
+	// Test the run
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if driver.PackCalled {
+		t.Fatal("should not have called")
+	}
+}
+
+func TestStepExport_error(t *testing.T) {
+	state := testState(t)
+	step := &StepExport{Format: "pvmp"}
+
+	state.Put("vmName", "foo")
+
+	driver := state.Get("driver").(*DriverMock)
+	driver.PackErr = errors.New("foo")
+
+	// Test the run
+	if action := step.Run(state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
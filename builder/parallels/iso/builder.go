@@ -20,6 +20,7 @@ type Builder struct {
 
 type config struct {
 	common.PackerConfig                 `mapstructure:",squash"`
+	parallelscommon.ExportConfig        `mapstructure:",squash"`
 	parallelscommon.FloppyConfig        `mapstructure:",squash"`
 	parallelscommon.OutputConfig        `mapstructure:",squash"`
 	parallelscommon.RunConfig           `mapstructure:",squash"`
@@ -43,6 +44,7 @@ type config struct {
 	ISOChecksum             string   `mapstructure:"iso_checksum"`
 	ISOChecksumType         string   `mapstructure:"iso_checksum_type"`
 	ISOUrls                 []string `mapstructure:"iso_urls"`
+	SkipCompaction          bool     `mapstructure:"skip_compaction"`
 	VMName                  string   `mapstructure:"vm_name"`
 
 	RawSingleISOUrl string `mapstructure:"iso_url"`
@@ -65,6 +67,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 
 	// Accumulate any errors and warnings
 	errs := common.CheckUnusedConfig(md)
+	errs = packer.MultiErrorAppend(errs, b.config.ExportConfig.Prepare(b.config.tpl)...)
 	errs = packer.MultiErrorAppend(errs, b.config.FloppyConfig.Prepare(b.config.tpl)...)
 	errs = packer.MultiErrorAppend(
 		errs, b.config.OutputConfig.Prepare(b.config.tpl, &b.config.PackerConfig)...)
@@ -319,6 +322,13 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Timeout: b.config.ShutdownTimeout,
 		},
 		new(parallelscommon.StepRemoveDevices),
+		&parallelscommon.StepCompactDisk{
+			Skip: b.config.SkipCompaction,
+		},
+		&parallelscommon.StepExport{
+			Format:    b.config.Format,
+			OutputDir: b.config.OutputDir,
+		},
 	}
 
 	// Setup the state bag
@@ -90,6 +90,13 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Timeout: b.config.ShutdownTimeout,
 		},
 		new(parallelscommon.StepRemoveDevices),
+		&parallelscommon.StepCompactDisk{
+			Skip: b.config.SkipCompaction,
+		},
+		&parallelscommon.StepExport{
+			Format:    b.config.Format,
+			OutputDir: b.config.OutputDir,
+		},
 	}
 
 	// Run the steps.
@@ -83,3 +83,20 @@ func TestNewConfig_shutdown_timeout(t *testing.T) {
 	_, warns, errs = NewConfig(c)
 	testConfigOk(t, warns, errs)
 }
+
+func TestNewConfig_format(t *testing.T) {
+	c := testConfig(t)
+	tf := getTempFile(t)
+	defer os.Remove(tf.Name())
+
+	// Bad
+	c["source_path"] = tf.Name()
+	c["format"] = "ova"
+	_, warns, errs := NewConfig(c)
+	testConfigErr(t, warns, errs)
+
+	// Good
+	c["format"] = "pvmp"
+	_, warns, errs = NewConfig(c)
+	testConfigOk(t, warns, errs)
+}
@@ -46,6 +46,19 @@ var diskInterface = map[string]bool{
 	"virtio": true,
 }
 
+var diskCache = map[string]bool{
+	"writethrough": true,
+	"writeback":    true,
+	"none":         true,
+	"unsafe":       true,
+	"directsync":   true,
+}
+
+var diskDiscard = map[string]bool{
+	"unmap":  true,
+	"ignore": true,
+}
+
 type Builder struct {
 	config config
 	runner multistep.Runner
@@ -54,33 +67,49 @@ type Builder struct {
 type config struct {
 	common.PackerConfig `mapstructure:",squash"`
 
-	Accelerator     string     `mapstructure:"accelerator"`
-	BootCommand     []string   `mapstructure:"boot_command"`
-	DiskInterface   string     `mapstructure:"disk_interface"`
-	DiskSize        uint       `mapstructure:"disk_size"`
-	FloppyFiles     []string   `mapstructure:"floppy_files"`
-	Format          string     `mapstructure:"format"`
-	Headless        bool       `mapstructure:"headless"`
-	HTTPDir         string     `mapstructure:"http_directory"`
-	HTTPPortMin     uint       `mapstructure:"http_port_min"`
-	HTTPPortMax     uint       `mapstructure:"http_port_max"`
-	ISOChecksum     string     `mapstructure:"iso_checksum"`
-	ISOChecksumType string     `mapstructure:"iso_checksum_type"`
-	ISOUrls         []string   `mapstructure:"iso_urls"`
-	NetDevice       string     `mapstructure:"net_device"`
-	OutputDir       string     `mapstructure:"output_directory"`
-	QemuArgs        [][]string `mapstructure:"qemuargs"`
-	QemuBinary      string     `mapstructure:"qemu_binary"`
-	ShutdownCommand string     `mapstructure:"shutdown_command"`
-	SSHHostPortMin  uint       `mapstructure:"ssh_host_port_min"`
-	SSHHostPortMax  uint       `mapstructure:"ssh_host_port_max"`
-	SSHPassword     string     `mapstructure:"ssh_password"`
-	SSHPort         uint       `mapstructure:"ssh_port"`
-	SSHUser         string     `mapstructure:"ssh_username"`
-	SSHKeyPath      string     `mapstructure:"ssh_key_path"`
-	VNCPortMin      uint       `mapstructure:"vnc_port_min"`
-	VNCPortMax      uint       `mapstructure:"vnc_port_max"`
-	VMName          string     `mapstructure:"vm_name"`
+	Accelerator        string     `mapstructure:"accelerator"`
+	BootCommand        []string   `mapstructure:"boot_command"`
+	CPUs               uint       `mapstructure:"cpus"`
+	DHCPLeasesPath     string     `mapstructure:"dhcp_leases_path"`
+	DiskAdditionalSize []uint     `mapstructure:"disk_additional_size"`
+	DiskCache          string     `mapstructure:"disk_cache"`
+	DiskCompression    bool       `mapstructure:"disk_compression"`
+	DiskDiscard        string     `mapstructure:"disk_discard"`
+	DiskInterface      string     `mapstructure:"disk_interface"`
+	DiskSize           uint       `mapstructure:"disk_size"`
+	FloppyFiles        []string   `mapstructure:"floppy_files"`
+	Firmware           string     `mapstructure:"firmware"`
+	FirmwareVars       string     `mapstructure:"firmware_vars"`
+	Format             string     `mapstructure:"format"`
+	Headless           bool       `mapstructure:"headless"`
+	HTTPDir            string     `mapstructure:"http_directory"`
+	HTTPPortMin        uint       `mapstructure:"http_port_min"`
+	HTTPPortMax        uint       `mapstructure:"http_port_max"`
+	ISOChecksum        string     `mapstructure:"iso_checksum"`
+	ISOChecksumType    string     `mapstructure:"iso_checksum_type"`
+	ISOUrls            []string   `mapstructure:"iso_urls"`
+	MachineType        string     `mapstructure:"machine_type"`
+	Memory             uint       `mapstructure:"memory"`
+	NetBridge          string     `mapstructure:"net_bridge"`
+	NetDevice          string     `mapstructure:"net_device"`
+	NetTap             string     `mapstructure:"net_tap"`
+	OutputDir          string     `mapstructure:"output_directory"`
+	QemuArgs           [][]string `mapstructure:"qemuargs"`
+	QemuBinary         string     `mapstructure:"qemu_binary"`
+	ShutdownCommand    string     `mapstructure:"shutdown_command"`
+	SkipCompaction     bool       `mapstructure:"skip_compaction"`
+	SSHHostPortMin     uint       `mapstructure:"ssh_host_port_min"`
+	SSHHostPortMax     uint       `mapstructure:"ssh_host_port_max"`
+	SSHPassword        string     `mapstructure:"ssh_password"`
+	SSHPort            uint       `mapstructure:"ssh_port"`
+	SSHUser            string     `mapstructure:"ssh_username"`
+	SSHKeyPath         string     `mapstructure:"ssh_key_path"`
+	SWTPM              bool       `mapstructure:"swtpm"`
+	SWTPMBinary        string     `mapstructure:"swtpm_binary"`
+	VNCPortMin         uint       `mapstructure:"vnc_port_min"`
+	VNCPortMax         uint       `mapstructure:"vnc_port_max"`
+	VNCRecord          string     `mapstructure:"vnc_record"`
+	VMName             string     `mapstructure:"vm_name"`
 
 	// TODO(mitchellh): deprecate
 	RunOnce bool `mapstructure:"run_once"`
@@ -90,10 +119,13 @@ type config struct {
 	RawShutdownTimeout string `mapstructure:"shutdown_timeout"`
 	RawSSHWaitTimeout  string `mapstructure:"ssh_wait_timeout"`
 
-	bootWait        time.Duration ``
-	shutdownTimeout time.Duration ``
-	sshWaitTimeout  time.Duration ``
-	tpl             *packer.ConfigTemplate
+	RawVNCRecordInterval string `mapstructure:"vnc_record_interval"`
+
+	bootWait          time.Duration ``
+	shutdownTimeout   time.Duration ``
+	sshWaitTimeout    time.Duration ``
+	vncRecordInterval time.Duration ``
+	tpl               *packer.ConfigTemplate
 }
 
 func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
@@ -119,6 +151,22 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		b.config.Accelerator = "kvm"
 	}
 
+	if b.config.CPUs == 0 {
+		b.config.CPUs = 1
+	}
+
+	if b.config.Memory == 0 {
+		b.config.Memory = 512
+	}
+
+	if b.config.MachineType == "" {
+		b.config.MachineType = "pc-1.0"
+	}
+
+	if b.config.SWTPMBinary == "" {
+		b.config.SWTPMBinary = "swtpm"
+	}
+
 	if b.config.HTTPPortMin == 0 {
 		b.config.HTTPPortMin = 8000
 	}
@@ -188,25 +236,44 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		b.config.DiskInterface = "virtio"
 	}
 
+	if b.config.DiskCache == "" {
+		b.config.DiskCache = "writeback"
+	}
+
+	if b.config.DiskDiscard == "" {
+		b.config.DiskDiscard = "ignore"
+	}
+
 	// Errors
 	templates := map[string]*string{
-		"http_directory":    &b.config.HTTPDir,
-		"iso_checksum":      &b.config.ISOChecksum,
-		"iso_checksum_type": &b.config.ISOChecksumType,
-		"iso_url":           &b.config.RawSingleISOUrl,
-		"output_directory":  &b.config.OutputDir,
-		"shutdown_command":  &b.config.ShutdownCommand,
-		"ssh_key_path":      &b.config.SSHKeyPath,
-		"ssh_password":      &b.config.SSHPassword,
-		"ssh_username":      &b.config.SSHUser,
-		"vm_name":           &b.config.VMName,
-		"format":            &b.config.Format,
-		"boot_wait":         &b.config.RawBootWait,
-		"shutdown_timeout":  &b.config.RawShutdownTimeout,
-		"ssh_wait_timeout":  &b.config.RawSSHWaitTimeout,
-		"accelerator":       &b.config.Accelerator,
-		"net_device":        &b.config.NetDevice,
-		"disk_interface":    &b.config.DiskInterface,
+		"http_directory":      &b.config.HTTPDir,
+		"iso_checksum":        &b.config.ISOChecksum,
+		"iso_checksum_type":   &b.config.ISOChecksumType,
+		"iso_url":             &b.config.RawSingleISOUrl,
+		"output_directory":    &b.config.OutputDir,
+		"shutdown_command":    &b.config.ShutdownCommand,
+		"ssh_key_path":        &b.config.SSHKeyPath,
+		"ssh_password":        &b.config.SSHPassword,
+		"ssh_username":        &b.config.SSHUser,
+		"vm_name":             &b.config.VMName,
+		"vnc_record":          &b.config.VNCRecord,
+		"vnc_record_interval": &b.config.RawVNCRecordInterval,
+		"format":              &b.config.Format,
+		"boot_wait":           &b.config.RawBootWait,
+		"shutdown_timeout":    &b.config.RawShutdownTimeout,
+		"ssh_wait_timeout":    &b.config.RawSSHWaitTimeout,
+		"accelerator":         &b.config.Accelerator,
+		"net_device":          &b.config.NetDevice,
+		"net_bridge":          &b.config.NetBridge,
+		"net_tap":             &b.config.NetTap,
+		"dhcp_leases_path":    &b.config.DHCPLeasesPath,
+		"disk_interface":      &b.config.DiskInterface,
+		"disk_cache":          &b.config.DiskCache,
+		"disk_discard":        &b.config.DiskDiscard,
+		"firmware":            &b.config.Firmware,
+		"firmware_vars":       &b.config.FirmwareVars,
+		"machine_type":        &b.config.MachineType,
+		"swtpm_binary":        &b.config.SWTPMBinary,
 	}
 
 	for n, ptr := range templates {
@@ -264,6 +331,65 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 			errs, errors.New("unrecognized disk interface type"))
 	}
 
+	if _, ok := diskCache[b.config.DiskCache]; !ok {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("unrecognized disk cache type"))
+	}
+
+	if _, ok := diskDiscard[b.config.DiskDiscard]; !ok {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("unrecognized disk discard type"))
+	}
+
+	if b.config.DiskCompression && b.config.Format != "qcow2" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("disk_compression is only supported with the 'qcow2' format"))
+	}
+
+	if b.config.DiskCompression && b.config.SkipCompaction {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("disk_compression can't be used with skip_compaction"))
+	}
+
+	for i, size := range b.config.DiskAdditionalSize {
+		if size == 0 {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("disk_additional_size[%d] must be greater than zero", i))
+		}
+	}
+
+	if b.config.Firmware != "" {
+		if _, err := os.Stat(b.config.Firmware); err != nil {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("firmware is invalid: %s", err))
+		}
+	}
+
+	if b.config.FirmwareVars != "" {
+		if b.config.Firmware == "" {
+			errs = packer.MultiErrorAppend(
+				errs, errors.New("firmware_vars requires firmware to be set"))
+		} else if _, err := os.Stat(b.config.FirmwareVars); err != nil {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("firmware_vars is invalid: %s", err))
+		}
+	}
+
+	if strings.ContainsAny(b.config.MachineType, ", ") {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("machine_type must be a single machine type name"))
+	}
+
+	if b.config.NetBridge != "" && b.config.NetTap != "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("Only one of net_bridge or net_tap can be specified"))
+	}
+
+	if b.config.DHCPLeasesPath != "" && b.config.NetBridge == "" && b.config.NetTap == "" {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("dhcp_leases_path can only be used with net_bridge or net_tap"))
+	}
+
 	if b.config.HTTPPortMin > b.config.HTTPPortMax {
 		errs = packer.MultiErrorAppend(
 			errs, errors.New("http_port_min must be less than http_port_max"))
@@ -365,6 +491,16 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 			errs, fmt.Errorf("vnc_port_min must be less than vnc_port_max"))
 	}
 
+	if b.config.RawVNCRecordInterval == "" {
+		b.config.RawVNCRecordInterval = "5s"
+	}
+
+	b.config.vncRecordInterval, err = time.ParseDuration(b.config.RawVNCRecordInterval)
+	if err != nil {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("Failed parsing vnc_record_interval: %s", err))
+	}
+
 	if b.config.QemuArgs == nil {
 		b.config.QemuArgs = make([][]string, 0)
 	}
@@ -395,14 +531,24 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&common.StepCreateFloppy{
 			Files: b.config.FloppyFiles,
 		},
+		new(stepPrepareFirmware),
 		new(stepCreateDisk),
 		new(stepHTTPServer),
 		new(stepForwardSSH),
+		new(stepConfigureNetwork),
 		new(stepConfigureVNC),
+		new(stepConfigureQMP),
+		new(stepRunTPM),
 		&stepRun{
 			BootDrive: "once=d",
 			Message:   "Starting VM, booting from CD-ROM",
 		},
+		&common.StepRecordVNC{
+			Path:      b.config.VNCRecord,
+			Interval:  b.config.vncRecordInterval,
+			OutputDir: b.config.OutputDir,
+			Debug:     b.config.PackerDebug,
+		},
 		&stepBootWait{},
 		&stepTypeBootCommand{},
 		&common.StepConnectSSH{
@@ -412,6 +558,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		},
 		new(common.StepProvision),
 		new(stepShutdown),
+		new(stepCompactDisk),
 	}
 
 	// Setup the state bag
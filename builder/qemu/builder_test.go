@@ -6,6 +6,7 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"time"
 )
 
 var testPem = `
@@ -90,6 +91,18 @@ func TestBuilderPrepare_Defaults(t *testing.T) {
 	if b.config.Format != "qcow2" {
 		t.Errorf("bad format: %s", b.config.Format)
 	}
+
+	if b.config.CPUs != 1 {
+		t.Errorf("bad cpus: %d", b.config.CPUs)
+	}
+
+	if b.config.Memory != 512 {
+		t.Errorf("bad memory: %d", b.config.Memory)
+	}
+
+	if b.config.MachineType != "pc-1.0" {
+		t.Errorf("bad machine type: %s", b.config.MachineType)
+	}
 }
 
 func TestBuilderPrepare_BootWait(t *testing.T) {
@@ -203,6 +216,163 @@ func TestBuilderPrepare_HTTPPort(t *testing.T) {
 	}
 }
 
+func TestBuilderPrepare_DiskCache(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test the default
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.DiskCache != "writeback" {
+		t.Fatalf("bad: %s", b.config.DiskCache)
+	}
+
+	// Test with a bad value
+	config["disk_cache"] = "illegal value"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test with a good one
+	config["disk_cache"] = "none"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
+
+func TestBuilderPrepare_DiskCompression(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test with a raw disk
+	config["disk_compression"] = true
+	config["format"] = "raw"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test with compaction skipped
+	config["format"] = "qcow2"
+	config["skip_compaction"] = true
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test with a good one
+	delete(config, "skip_compaction")
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
+
+func TestBuilderPrepare_DiskDiscard(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test with a bad value
+	config["disk_discard"] = "illegal value"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test with a good one
+	config["disk_discard"] = "unmap"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
+
+func TestBuilderPrepare_Firmware(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	code, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(code.Name())
+	code.Close()
+
+	vars, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(vars.Name())
+	vars.Close()
+
+	// Test with a nonexistent firmware
+	config["firmware"] = "/i/dont/exist"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test with vars but no firmware
+	delete(config, "firmware")
+	config["firmware_vars"] = vars.Name()
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test with a good one
+	config["firmware"] = code.Name()
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
+
 func TestBuilderPrepare_Format(t *testing.T) {
 	var b Builder
 	config := testConfig()
@@ -396,6 +566,68 @@ func TestBuilderPrepare_ISOUrl(t *testing.T) {
 	}
 }
 
+func TestBuilderPrepare_MachineType(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test with a bad value
+	config["machine_type"] = "q35,accel=tcg"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test with a good one
+	config["machine_type"] = "q35"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.MachineType != "q35" {
+		t.Fatalf("bad: %s", b.config.MachineType)
+	}
+}
+
+func TestBuilderPrepare_NetTap(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test with a bridge as well
+	config["net_bridge"] = "br0"
+	config["net_tap"] = "tap0"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test with a good one
+	delete(config, "net_bridge")
+	config["dhcp_leases_path"] = "/var/lib/misc/dnsmasq.leases"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.NetTap != "tap0" {
+		t.Fatalf("bad: %s", b.config.NetTap)
+	}
+}
+
 func TestBuilderPrepare_OutputDir(t *testing.T) {
 	var b Builder
 	config := testConfig()
@@ -662,3 +894,44 @@ func TestBuilderPrepare_QemuArgs(t *testing.T) {
 		t.Fatalf("bad: %#v", b.config.QemuArgs)
 	}
 }
+
+func TestBuilderPrepare_VNCRecordInterval(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test a default vnc_record_interval
+	delete(config, "vnc_record_interval")
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if b.config.vncRecordInterval != 5*time.Second {
+		t.Fatalf("bad value: %s", b.config.vncRecordInterval)
+	}
+
+	// Test with a bad vnc_record_interval
+	config["vnc_record_interval"] = "this is not good"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test with a good one
+	config["vnc_record_interval"] = "1s"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
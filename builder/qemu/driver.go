@@ -3,6 +3,7 @@ package qemu
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"github.com/mitchellh/multistep"
 	"io"
@@ -24,6 +25,19 @@ type Driver interface {
 	// Stop stops a running machine, forcefully.
 	Stop() error
 
+	// ConnectQMP connects to the QMP monitor socket of the running
+	// machine. The remaining QMP methods require a connected monitor.
+	ConnectQMP(socketPath string) error
+
+	// SystemPowerdown sends an ACPI power button event to the machine.
+	SystemPowerdown() error
+
+	// Status returns the run state of the machine as reported by QMP.
+	Status() (string, error)
+
+	// Screendump saves a screenshot of the machine display to the path.
+	Screendump(path string) error
+
 	// Qemu executes the given command via qemu-system-x86_64
 	Qemu(qemuArgs ...string) error
 
@@ -48,6 +62,7 @@ type QemuDriver struct {
 
 	vmCmd   *exec.Cmd
 	vmEndCh <-chan int
+	qmp     *qmpMonitor
 	lock    sync.Mutex
 }
 
@@ -55,6 +70,11 @@ func (d *QemuDriver) Stop() error {
 	d.lock.Lock()
 	defer d.lock.Unlock()
 
+	if d.qmp != nil {
+		d.qmp.Close()
+		d.qmp = nil
+	}
+
 	if d.vmCmd != nil {
 		if err := d.vmCmd.Process.Kill(); err != nil {
 			return err
@@ -64,6 +84,68 @@ func (d *QemuDriver) Stop() error {
 	return nil
 }
 
+func (d *QemuDriver) ConnectQMP(socketPath string) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.vmCmd == nil {
+		return errors.New("VM is not running")
+	}
+
+	if d.qmp != nil {
+		d.qmp.Close()
+		d.qmp = nil
+	}
+
+	m, err := newQMPMonitor(socketPath, 10*time.Second)
+	if err != nil {
+		return err
+	}
+
+	d.qmp = m
+	return nil
+}
+
+func (d *QemuDriver) SystemPowerdown() error {
+	m, err := d.monitor()
+	if err != nil {
+		return err
+	}
+
+	return m.SystemPowerdown()
+}
+
+func (d *QemuDriver) Status() (string, error) {
+	m, err := d.monitor()
+	if err != nil {
+		return "", err
+	}
+
+	return m.QueryStatus()
+}
+
+func (d *QemuDriver) Screendump(path string) error {
+	m, err := d.monitor()
+	if err != nil {
+		return err
+	}
+
+	return m.Screendump(path)
+}
+
+// monitor returns the connected QMP monitor, or an error if there
+// isn't one.
+func (d *QemuDriver) monitor() (*qmpMonitor, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.qmp == nil {
+		return nil, errors.New("QMP monitor is not connected")
+	}
+
+	return d.qmp, nil
+}
+
 func (d *QemuDriver) Qemu(qemuArgs ...string) error {
 	d.lock.Lock()
 	defer d.lock.Unlock()
@@ -113,6 +195,10 @@ func (d *QemuDriver) Qemu(qemuArgs ...string) error {
 
 		d.lock.Lock()
 		defer d.lock.Unlock()
+		if d.qmp != nil {
+			d.qmp.Close()
+			d.qmp = nil
+		}
 		d.vmCmd = nil
 		d.vmEndCh = nil
 	}()
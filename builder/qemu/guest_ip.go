@@ -0,0 +1,139 @@
+package qemu
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Interface to help find the IP address of a running virtual machine.
+type guestIPFinder interface {
+	GuestIP() (string, error)
+}
+
+// dnsmasqLeaseGuestLookup looks up the IP address of a guest using the
+// lease file of the dnsmasq DHCP server serving the bridge, such as the
+// one libvirt runs for "virbr0".
+type dnsmasqLeaseGuestLookup struct {
+	// Path to the dnsmasq lease file.
+	Path string
+
+	// MAC address of the guest.
+	MACAddress string
+}
+
+func (f *dnsmasqLeaseGuestLookup) GuestIP() (string, error) {
+	log.Printf("DHCP leases path: %s", f.Path)
+	fh, err := os.Open(f.Path)
+	if err != nil {
+		return "", err
+	}
+	defer fh.Close()
+
+	// Each line is "<expiry> <mac> <ip> <hostname> <client id>". If the
+	// guest has more than one lease, take the one that expires last.
+	var curIp string
+	var curExpiry int64
+
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+
+		if !strings.EqualFold(fields[1], f.MACAddress) {
+			continue
+		}
+
+		expiry, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			log.Printf("Ignoring lease with bad expiry: %s", fields[0])
+			continue
+		}
+
+		// An expiry of 0 means the lease is infinite.
+		if expiry == 0 || (curExpiry != 0 && expiry > curExpiry) || curIp == "" {
+			curIp = fields[2]
+			curExpiry = expiry
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	if curIp == "" {
+		return "", errors.New("IP not found for MAC in DHCP leases")
+	}
+
+	return curIp, nil
+}
+
+// arpGuestLookup looks up the IP address of a guest in the kernel's ARP
+// table, restricted to the bridge device that the guest is attached to.
+type arpGuestLookup struct {
+	// Path to the ARP table, usually /proc/net/arp.
+	Path string
+
+	// Device that the guest is connected to.
+	Device string
+
+	// MAC address of the guest.
+	MACAddress string
+}
+
+func (f *arpGuestLookup) GuestIP() (string, error) {
+	fh, err := os.Open(f.Path)
+	if err != nil {
+		return "", err
+	}
+	defer fh.Close()
+
+	// The format is a header line followed by lines of
+	// "IP address HW type Flags HW address Mask Device".
+	scanner := bufio.NewScanner(fh)
+	for first := true; scanner.Scan(); first = false {
+		fields := strings.Fields(scanner.Text())
+		if first || len(fields) < 6 {
+			continue
+		}
+
+		// Flags of 0x0 mark an incomplete entry.
+		if fields[2] == "0x0" {
+			continue
+		}
+
+		if strings.EqualFold(fields[3], f.MACAddress) && fields[5] == f.Device {
+			return fields[0], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", fmt.Errorf("IP not found for MAC in ARP table of %s", f.Device)
+}
+
+// multiGuestLookup tries each finder in turn and returns the first IP
+// address that is found.
+type multiGuestLookup []guestIPFinder
+
+func (fs multiGuestLookup) GuestIP() (string, error) {
+	errs := make([]string, 0, len(fs))
+	for _, f := range fs {
+		ip, err := f.GuestIP()
+		if err == nil && ip != "" {
+			return ip, nil
+		}
+
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	return "", fmt.Errorf("IP lookup failed: %s", strings.Join(errs, "; "))
+}
@@ -0,0 +1,116 @@
+package qemu
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func testGuestIPFile(t *testing.T, contents string) string {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := tf.Write([]byte(contents)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+
+	return tf.Name()
+}
+
+func TestDnsmasqLeaseGuestLookup_impl(t *testing.T) {
+	var _ guestIPFinder = new(dnsmasqLeaseGuestLookup)
+}
+
+func TestDnsmasqLeaseGuestLookup(t *testing.T) {
+	path := testGuestIPFile(t, testDnsmasqLeaseContents)
+	defer os.Remove(path)
+
+	finder := &dnsmasqLeaseGuestLookup{
+		Path:       path,
+		MACAddress: "52:54:00:12:34:56",
+	}
+
+	ip, err := finder.GuestIP()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if ip != "192.168.122.57" {
+		t.Fatalf("bad: %#v", ip)
+	}
+
+	finder.MACAddress = "52:54:00:00:00:00"
+	if _, err := finder.GuestIP(); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestArpGuestLookup_impl(t *testing.T) {
+	var _ guestIPFinder = new(arpGuestLookup)
+}
+
+func TestArpGuestLookup(t *testing.T) {
+	path := testGuestIPFile(t, testArpContents)
+	defer os.Remove(path)
+
+	finder := &arpGuestLookup{
+		Path:       path,
+		Device:     "virbr0",
+		MACAddress: "52:54:00:AB:CD:EF",
+	}
+
+	ip, err := finder.GuestIP()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if ip != "192.168.122.91" {
+		t.Fatalf("bad: %#v", ip)
+	}
+
+	// Incomplete entries are ignored
+	finder.MACAddress = "52:54:00:11:11:11"
+	if _, err := finder.GuestIP(); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestMultiGuestLookup(t *testing.T) {
+	path := testGuestIPFile(t, testArpContents)
+	defer os.Remove(path)
+
+	finder := multiGuestLookup{
+		&dnsmasqLeaseGuestLookup{
+			Path:       "/i/dont/exist",
+			MACAddress: "52:54:00:ab:cd:ef",
+		},
+		&arpGuestLookup{
+			Path:       path,
+			Device:     "virbr0",
+			MACAddress: "52:54:00:ab:cd:ef",
+		},
+	}
+
+	ip, err := finder.GuestIP()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if ip != "192.168.122.91" {
+		t.Fatalf("bad: %#v", ip)
+	}
+}
+
+const testDnsmasqLeaseContents = `1402950000 52:54:00:12:34:56 192.168.122.12 * 01:52:54:00:12:34:56
+1402956000 52:54:00:12:34:56 192.168.122.57 ubuntu 01:52:54:00:12:34:56
+1402953000 52:54:00:65:43:21 192.168.122.80 centos *
+`
+
+const testArpContents = `IP address       HW type     Flags       HW address            Mask     Device
+192.168.1.1      0x1         0x2         a0:63:91:00:00:01     *        eth0
+192.168.122.90   0x1         0x2         52:54:00:ab:cd:ef     *        virbr1
+192.168.122.91   0x1         0x2         52:54:00:ab:cd:ef     *        virbr0
+192.168.122.92   0x1         0x0         52:54:00:11:11:11     *        virbr0
+`
@@ -0,0 +1,174 @@
+package qemu
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// qmpMonitor is a minimal client for the QEMU Machine Protocol (QMP). It
+// talks to the monitor socket that qemu opens with the "-qmp" flag and
+// is used to control the VM gracefully instead of killing the process.
+type qmpMonitor struct {
+	conn net.Conn
+	dec  *json.Decoder
+	enc  *json.Encoder
+	lock sync.Mutex
+}
+
+// qmpResponse is a single message read from the QMP socket. A message
+// is either a command response (Return or Error set) or an asynchronous
+// event (Event set).
+type qmpResponse struct {
+	Event  string          `json:"event"`
+	Return json.RawMessage `json:"return"`
+	Error  *qmpError       `json:"error"`
+}
+
+type qmpError struct {
+	Class string `json:"class"`
+	Desc  string `json:"desc"`
+}
+
+func (e *qmpError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Class, e.Desc)
+}
+
+// qmpCommandTimeout is how long a single command may take, so that a
+// hung qemu doesn't block the build forever.
+var qmpCommandTimeout = 30 * time.Second
+
+type qmpCommand struct {
+	Execute   string      `json:"execute"`
+	Arguments interface{} `json:"arguments,omitempty"`
+}
+
+// newQMPMonitor connects to the QMP socket at the given path, retrying
+// until the timeout expires, and negotiates the capabilities so that
+// commands can be executed.
+func newQMPMonitor(path string, timeout time.Duration) (*qmpMonitor, error) {
+	var conn net.Conn
+	var err error
+
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err = net.Dial("unix", path)
+		if err == nil {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Error connecting to QMP socket: %s", err)
+		}
+
+		log.Printf("Waiting for QMP socket %s: %s", path, err)
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	m := &qmpMonitor{
+		conn: conn,
+		dec:  json.NewDecoder(conn),
+		enc:  json.NewEncoder(conn),
+	}
+
+	// The first thing qemu sends is the greeting banner.
+	var greeting struct {
+		QMP struct {
+			Version json.RawMessage `json:"version"`
+		} `json:"QMP"`
+	}
+	conn.SetDeadline(time.Now().Add(qmpCommandTimeout))
+	if err := m.dec.Decode(&greeting); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("Error reading QMP greeting: %s", err)
+	}
+	log.Printf("QMP greeting, version: %s", greeting.QMP.Version)
+
+	// Leave capabilities negotiation mode and enter command mode.
+	if _, err := m.Execute("qmp_capabilities", nil); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Execute runs the given QMP command and returns the raw value of its
+// "return" member. Asynchronous events received while waiting for the
+// response are logged and skipped.
+func (m *qmpMonitor) Execute(command string, args interface{}) (json.RawMessage, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	log.Printf("Executing QMP command: %s", command)
+	if err := m.conn.SetDeadline(time.Now().Add(qmpCommandTimeout)); err != nil {
+		return nil, fmt.Errorf("Error setting QMP deadline: %s", err)
+	}
+	defer m.conn.SetDeadline(time.Time{})
+
+	if err := m.enc.Encode(&qmpCommand{Execute: command, Arguments: args}); err != nil {
+		return nil, fmt.Errorf("Error sending QMP command '%s': %s", command, err)
+	}
+
+	for {
+		var resp qmpResponse
+		if err := m.dec.Decode(&resp); err != nil {
+			return nil, fmt.Errorf("Error reading QMP response to '%s': %s", command, err)
+		}
+
+		if resp.Event != "" {
+			log.Printf("QMP event: %s", resp.Event)
+			continue
+		}
+
+		if resp.Error != nil {
+			return nil, fmt.Errorf("QMP command '%s' failed: %s", command, resp.Error)
+		}
+
+		return resp.Return, nil
+	}
+}
+
+// SystemPowerdown sends an ACPI power button event to the guest.
+func (m *qmpMonitor) SystemPowerdown() error {
+	_, err := m.Execute("system_powerdown", nil)
+	return err
+}
+
+// QueryStatus returns the run state of the VM, such as "running",
+// "paused" or "shutdown".
+func (m *qmpMonitor) QueryStatus() (string, error) {
+	raw, err := m.Execute("query-status", nil)
+	if err != nil {
+		return "", err
+	}
+
+	var status struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return "", fmt.Errorf("Error decoding query-status response: %s", err)
+	}
+
+	if status.Status == "" {
+		return "", errors.New("query-status response did not include a status")
+	}
+
+	return status.Status, nil
+}
+
+// Screendump saves a PPM image of the VM display to the given path
+// on the host.
+func (m *qmpMonitor) Screendump(path string) error {
+	_, err := m.Execute("screendump", map[string]string{"filename": path})
+	return err
+}
+
+// Close closes the connection to the QMP socket.
+func (m *qmpMonitor) Close() error {
+	return m.conn.Close()
+}
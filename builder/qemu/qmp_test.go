@@ -0,0 +1,125 @@
+package qemu
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testQMPServer starts a fake QMP server on a unix socket that replies
+// to each command using the given handler. It returns the socket path.
+func testQMPServer(t *testing.T, handler func(cmd qmpCommand) string) string {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	path := filepath.Join(td, "qmp.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		os.RemoveAll(td)
+		t.Fatalf("err: %s", err)
+	}
+
+	go func() {
+		defer os.RemoveAll(td)
+		defer l.Close()
+
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.Write([]byte(`{"QMP": {"version": {"qemu": {"major": 2}}, "capabilities": []}}` + "\n"))
+
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadBytes('\n')
+			if err != nil {
+				return
+			}
+
+			var cmd qmpCommand
+			if err := json.Unmarshal(line, &cmd); err != nil {
+				return
+			}
+
+			if cmd.Execute == "qmp_capabilities" {
+				conn.Write([]byte(`{"return": {}}` + "\n"))
+				continue
+			}
+
+			conn.Write([]byte(handler(cmd) + "\n"))
+		}
+	}()
+
+	return path
+}
+
+func TestQMPMonitor_QueryStatus(t *testing.T) {
+	path := testQMPServer(t, func(cmd qmpCommand) string {
+		if cmd.Execute != "query-status" {
+			return `{"error": {"class": "CommandNotFound", "desc": "nope"}}`
+		}
+
+		return `{"event": "RTC_CHANGE", "data": {}}` + "\n" +
+			`{"return": {"status": "running", "running": true}}`
+	})
+
+	m, err := newQMPMonitor(path, 5*time.Second)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer m.Close()
+
+	status, err := m.QueryStatus()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if status != "running" {
+		t.Fatalf("bad: %s", status)
+	}
+}
+
+func TestQMPMonitor_Error(t *testing.T) {
+	path := testQMPServer(t, func(cmd qmpCommand) string {
+		return `{"error": {"class": "GenericError", "desc": "bad"}}`
+	})
+
+	m, err := newQMPMonitor(path, 5*time.Second)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer m.Close()
+
+	if err := m.SystemPowerdown(); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestQMPMonitor_Timeout(t *testing.T) {
+	defer func(d time.Duration) { qmpCommandTimeout = d }(qmpCommandTimeout)
+	qmpCommandTimeout = 100 * time.Millisecond
+
+	path := testQMPServer(t, func(cmd qmpCommand) string {
+		time.Sleep(time.Second)
+		return `{"return": {}}`
+	})
+
+	m, err := newQMPMonitor(path, 5*time.Second)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer m.Close()
+
+	if err := m.SystemPowerdown(); err == nil {
+		t.Fatal("should have error")
+	}
+}
@@ -6,12 +6,40 @@ import (
 	"github.com/mitchellh/multistep"
 	"github.com/mitchellh/packer/communicator/ssh"
 	"io/ioutil"
+	"log"
 	"os"
 )
 
 func sshAddress(state multistep.StateBag) (string, error) {
-	sshHostPort := state.Get("sshHostPort").(uint)
-	return fmt.Sprintf("127.0.0.1:%d", sshHostPort), nil
+	config := state.Get("config").(*config)
+
+	if config.NetBridge == "" && config.NetTap == "" {
+		sshHostPort := state.Get("sshHostPort").(uint)
+		return fmt.Sprintf("127.0.0.1:%d", sshHostPort), nil
+	}
+
+	mac := state.Get("guest_mac_address").(string)
+	finders := make(multiGuestLookup, 0, 2)
+	if config.DHCPLeasesPath != "" {
+		finders = append(finders, &dnsmasqLeaseGuestLookup{
+			Path:       config.DHCPLeasesPath,
+			MACAddress: mac,
+		})
+	}
+	finders = append(finders, &arpGuestLookup{
+		Path:       "/proc/net/arp",
+		Device:     state.Get("host_net_device").(string),
+		MACAddress: mac,
+	})
+
+	ipAddress, err := finders.GuestIP()
+	if err != nil {
+		log.Printf("IP lookup failed: %s", err)
+		return "", err
+	}
+
+	log.Printf("Detected IP: %s", ipAddress)
+	return fmt.Sprintf("%s:%d", ipAddress, config.SSHPort), nil
 }
 
 func sshConfig(state multistep.StateBag) (*gossh.ClientConfig, error) {
@@ -0,0 +1,66 @@
+package qemu
+
+import (
+	"fmt"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	"log"
+	"os"
+)
+
+// This step compacts the virtual disks by rewriting them with qemu-img,
+// which drops unused clusters and optionally compresses the image. It
+// is skipped if "skip_compaction" is true.
+//
+// Uses:
+//   config     *config
+//   disk_paths []string
+//   driver     Driver
+//   ui         packer.Ui
+//
+// Produces:
+//   <nothing>
+type stepCompactDisk struct{}
+
+func (s *stepCompactDisk) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*config)
+	diskPaths := state.Get("disk_paths").([]string)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	if config.SkipCompaction {
+		log.Println("Skipping disk compaction step...")
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Compacting the disk image")
+	for _, path := range diskPaths {
+		tempPath := path + ".compact"
+
+		command := []string{"convert", "-O", config.Format}
+		if config.DiskCompression {
+			command = append(command, "-c")
+		}
+		command = append(command, path, tempPath)
+
+		if err := driver.QemuImg(command...); err != nil {
+			os.Remove(tempPath)
+			err := fmt.Errorf("Error compacting disk: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		if err := os.Rename(tempPath, path); err != nil {
+			os.Remove(tempPath)
+			err := fmt.Errorf("Error replacing compacted disk: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCompactDisk) Cleanup(state multistep.StateBag) {}
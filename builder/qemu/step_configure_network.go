@@ -0,0 +1,122 @@
+package qemu
+
+import (
+	"crypto/rand"
+	"fmt"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// This step figures out the network settings of the VM. With user-mode
+// networking the host is always reachable at the qemu gateway address.
+// With a bridge or tap device, the guest gets a generated MAC address so
+// that its IP can be looked up later, and the host is reachable at the
+// address of the bridge, or of the tap device if it isn't on a bridge.
+//
+// Uses:
+//   config *config
+//   ui     packer.Ui
+//
+// Produces:
+//   host_ip string - The IP of the host as seen from the guest.
+//   host_net_device string - The host device the guest is reached
+//     through (bridge or tap only).
+//   guest_mac_address string - The MAC address of the guest (bridge or
+//     tap only).
+type stepConfigureNetwork struct{}
+
+func (stepConfigureNetwork) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*config)
+	ui := state.Get("ui").(packer.Ui)
+
+	if config.NetBridge == "" && config.NetTap == "" {
+		state.Put("host_ip", "10.0.2.2")
+		return multistep.ActionContinue
+	}
+
+	device := config.NetBridge
+	if config.NetTap != "" {
+		device = tapHostDevice("/sys/class/net", config.NetTap)
+	}
+
+	hostIP, err := deviceIP(device)
+	if err != nil {
+		err := fmt.Errorf("Error finding IP of %s: %s", device, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	mac, err := randomMACAddress()
+	if err != nil {
+		err := fmt.Errorf("Error generating MAC address: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if config.NetBridge != "" {
+		ui.Say(fmt.Sprintf("Attaching VM to bridge %s with MAC address %s", config.NetBridge, mac))
+	} else {
+		ui.Say(fmt.Sprintf("Attaching VM to tap %s with MAC address %s", config.NetTap, mac))
+	}
+	log.Printf("Host IP on %s: %s", device, hostIP)
+	state.Put("host_ip", hostIP)
+	state.Put("host_net_device", device)
+	state.Put("guest_mac_address", mac)
+
+	return multistep.ActionContinue
+}
+
+func (stepConfigureNetwork) Cleanup(multistep.StateBag) {}
+
+// tapHostDevice returns the bridge that the given tap device is a port
+// of, or the tap device itself if it isn't on a bridge. sysfs is the
+// path to the network devices in sysfs, usually /sys/class/net.
+func tapHostDevice(sysfs, tap string) string {
+	master, err := os.Readlink(filepath.Join(sysfs, tap, "master"))
+	if err != nil {
+		log.Printf("Tap %s isn't on a bridge: %s", tap, err)
+		return tap
+	}
+
+	return filepath.Base(master)
+}
+
+// deviceIP returns the first IPv4 address of the given network device.
+func deviceIP(name string) (string, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return "", err
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", err
+	}
+
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			if ip := ipNet.IP.To4(); ip != nil {
+				return ip.String(), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no IPv4 address found on %s", name)
+}
+
+// randomMACAddress returns a random MAC address in the range that qemu
+// uses for its guests.
+func randomMACAddress() (string, error) {
+	buf := make([]byte, 3)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("52:54:00:%02x:%02x:%02x", buf[0], buf[1], buf[2]), nil
+}
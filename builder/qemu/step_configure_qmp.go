@@ -0,0 +1,53 @@
+package qemu
+
+import (
+	"fmt"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// This step prepares the path of the QMP monitor socket so that the
+// builder can talk to the running VM.
+//
+// Uses:
+//   ui     packer.Ui
+//
+// Produces:
+//   qmp_socket_path string - The path of the QMP unix socket.
+type stepConfigureQMP struct {
+	tempDir string
+}
+
+func (s *stepConfigureQMP) Run(state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packer.Ui)
+
+	// The socket lives in its own temporary directory rather than the
+	// output directory so it doesn't end up in the artifact. Unix socket
+	// paths are also limited in length, so keep it short.
+	tempDir, err := ioutil.TempDir("", "packer-qmp")
+	if err != nil {
+		err := fmt.Errorf("Error creating QMP socket directory: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.tempDir = tempDir
+
+	socketPath := filepath.Join(tempDir, "qmp.sock")
+	log.Printf("QMP socket path: %s", socketPath)
+	state.Put("qmp_socket_path", socketPath)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepConfigureQMP) Cleanup(multistep.StateBag) {
+	if s.tempDir != "" {
+		if err := os.RemoveAll(s.tempDir); err != nil {
+			log.Printf("Error removing QMP socket directory: %s", err)
+		}
+	}
+}
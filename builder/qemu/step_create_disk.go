@@ -8,32 +8,54 @@ import (
 	"strings"
 )
 
-// This step creates the virtual disk that will be used as the
-// hard drive for the virtual machine.
+// This step creates the virtual disks that will be used as the
+// hard drives for the virtual machine.
+//
+// Uses:
+//   config *config
+//   driver Driver
+//   ui     packer.Ui
+//
+// Produces:
+//   disk_paths []string - The paths of all the disks, the boot disk first.
 type stepCreateDisk struct{}
 
 func (s *stepCreateDisk) Run(state multistep.StateBag) multistep.StepAction {
 	config := state.Get("config").(*config)
 	driver := state.Get("driver").(Driver)
 	ui := state.Get("ui").(packer.Ui)
-	path := filepath.Join(config.OutputDir, fmt.Sprintf("%s.%s", config.VMName,
-		strings.ToLower(config.Format)))
-
-	command := []string{
-		"create",
-		"-f", config.Format,
-		path,
-		fmt.Sprintf("%vM", config.DiskSize),
-	}
+
+	sizes := append([]uint{config.DiskSize}, config.DiskAdditionalSize...)
+	paths := make([]string, 0, len(sizes))
 
 	ui.Say("Creating hard drive...")
-	if err := driver.QemuImg(command...); err != nil {
-		err := fmt.Errorf("Error creating hard drive: %s", err)
-		state.Put("error", err)
-		ui.Error(err.Error())
-		return multistep.ActionHalt
+	for i, size := range sizes {
+		name := config.VMName
+		if i > 0 {
+			name = fmt.Sprintf("%s-%d", config.VMName, i)
+		}
+
+		path := filepath.Join(config.OutputDir,
+			fmt.Sprintf("%s.%s", name, strings.ToLower(config.Format)))
+
+		command := []string{
+			"create",
+			"-f", config.Format,
+			path,
+			fmt.Sprintf("%vM", size),
+		}
+
+		if err := driver.QemuImg(command...); err != nil {
+			err := fmt.Errorf("Error creating hard drive: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		paths = append(paths, path)
 	}
 
+	state.Put("disk_paths", paths)
 	return multistep.ActionContinue
 }
 
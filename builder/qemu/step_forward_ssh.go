@@ -21,6 +21,12 @@ func (s *stepForwardSSH) Run(state multistep.StateBag) multistep.StepAction {
 	config := state.Get("config").(*config)
 	ui := state.Get("ui").(packer.Ui)
 
+	// A bridged guest is reached directly on its own IP address.
+	if config.NetBridge != "" || config.NetTap != "" {
+		log.Println("VM is attached to a bridge or tap, not forwarding SSH port.")
+		return multistep.ActionContinue
+	}
+
 	log.Printf("Looking for available SSH port between %d and %d", config.SSHHostPortMin, config.SSHHostPortMax)
 	var sshHostPort uint
 	portRange := int(config.SSHHostPortMax - config.SSHHostPortMin)
@@ -0,0 +1,64 @@
+package qemu
+
+import (
+	"fmt"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// This step copies the UEFI vars file into the output directory so that
+// every build gets its own writable NVRAM, which is kept with the disk.
+//
+// Uses:
+//   config *config
+//   ui     packer.Ui
+//
+// Produces:
+//   firmware_vars_path string - The path of the writable vars file.
+type stepPrepareFirmware struct{}
+
+func (stepPrepareFirmware) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*config)
+	ui := state.Get("ui").(packer.Ui)
+
+	if config.FirmwareVars == "" {
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Copying UEFI firmware vars...")
+	path := filepath.Join(config.OutputDir, fmt.Sprintf("%s_VARS.fd", config.VMName))
+	if err := copyFile(config.FirmwareVars, path); err != nil {
+		err := fmt.Errorf("Error copying firmware vars: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("firmware_vars_path", path)
+	return multistep.ActionContinue
+}
+
+func (stepPrepareFirmware) Cleanup(multistep.StateBag) {}
+
+func copyFile(src, dst string) error {
+	srcF, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcF.Close()
+
+	dstF, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dstF, srcF); err != nil {
+		dstF.Close()
+		return err
+	}
+
+	return dstF.Close()
+}
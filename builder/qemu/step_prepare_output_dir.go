@@ -3,8 +3,11 @@ package qemu
 import (
 	"github.com/mitchellh/multistep"
 	"github.com/mitchellh/packer/packer"
+	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -35,6 +38,22 @@ func (stepPrepareOutputDir) Cleanup(state multistep.StateBag) {
 		config := state.Get("config").(*config)
 		ui := state.Get("ui").(packer.Ui)
 
+		// Keep the VNC recording and the screenshot of the failed build,
+		// if there are any
+		var keep []string
+		for _, key := range []string{"vnc_record_path", "screendump_path"} {
+			if path, ok := state.GetOk(key); ok {
+				keep = append(keep, path.(string))
+			}
+		}
+		if len(keep) > 0 {
+			ui.Say("Deleting output directory, except for the failure diagnostics...")
+			if err := removeAllExcept(config.OutputDir, keep); err != nil {
+				log.Printf("Error removing output dir: %s", err)
+			}
+			return
+		}
+
 		ui.Say("Deleting output directory...")
 		for i := 0; i < 5; i++ {
 			err := os.RemoveAll(config.OutputDir)
@@ -47,3 +66,43 @@ func (stepPrepareOutputDir) Cleanup(state multistep.StateBag) {
 		}
 	}
 }
+
+// removeAllExcept removes everything in dir except the paths in keep,
+// which must be inside dir.
+func removeAllExcept(dir string, keep []string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+
+		kept, inside := false, false
+		for _, k := range keep {
+			if path == filepath.Clean(k) {
+				kept = true
+			}
+			if strings.HasPrefix(k, path+string(filepath.Separator)) {
+				inside = true
+			}
+		}
+
+		if kept {
+			continue
+		}
+
+		if inside {
+			if err := removeAllExcept(path, keep); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
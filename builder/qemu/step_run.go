@@ -42,13 +42,42 @@ func (s *stepRun) Run(state multistep.StateBag) multistep.StepAction {
 		return multistep.ActionHalt
 	}
 
+	// Connect to the QMP monitor. The builder can still do its job
+	// without it, it just can't shut down or inspect the VM gracefully.
+	if socketPath, ok := state.GetOk("qmp_socket_path"); ok {
+		if err := driver.ConnectQMP(socketPath.(string)); err != nil {
+			ui.Message(fmt.Sprintf(
+				"WARNING: Could not connect to the QMP monitor: %s", err))
+		}
+	}
+
 	return multistep.ActionContinue
 }
 
 func (s *stepRun) Cleanup(state multistep.StateBag) {
+	config := state.Get("config").(*config)
 	driver := state.Get("driver").(Driver)
 	ui := state.Get("ui").(packer.Ui)
 
+	// If the build failed while the VM was still up, save what was on
+	// the screen. The output directory is deleted on failure, except
+	// for the screenshot.
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if halted && !cancelled {
+		if status, err := driver.Status(); err == nil && status != "shutdown" {
+			path := filepath.Join(config.OutputDir,
+				fmt.Sprintf("%s-failure.ppm", config.VMName))
+			if err := driver.Screendump(path); err != nil {
+				log.Printf("Error taking screendump: %s", err)
+			} else {
+				ui.Message(fmt.Sprintf(
+					"Saved a screenshot of the VM display to: %s", path))
+				state.Put("screendump_path", path)
+			}
+		}
+	}
+
 	if err := driver.Stop(); err != nil {
 		ui.Error(fmt.Sprintf("Error shutting down VM: %s", err))
 	}
@@ -56,16 +85,14 @@ func (s *stepRun) Cleanup(state multistep.StateBag) {
 
 func getCommandArgs(bootDrive string, state multistep.StateBag) ([]string, error) {
 	config := state.Get("config").(*config)
+	diskPaths := state.Get("disk_paths").([]string)
 	isoPath := state.Get("iso_path").(string)
 	vncPort := state.Get("vnc_port").(uint)
-	sshHostPort := state.Get("sshHostPort").(uint)
 	ui := state.Get("ui").(packer.Ui)
 
 	guiArgument := "sdl"
 	vnc := fmt.Sprintf("0.0.0.0:%d", vncPort-5900)
 	vmName := config.VMName
-	imgPath := filepath.Join(config.OutputDir,
-		fmt.Sprintf("%s.%s", vmName, strings.ToLower(config.Format)))
 
 	if config.Headless == true {
 		ui.Message("WARNING: The VM will be started in headless mode, as configured.\n" +
@@ -74,22 +101,79 @@ func getCommandArgs(bootDrive string, state multistep.StateBag) ([]string, error
 		guiArgument = "none"
 	}
 
-	defaultArgs := make(map[string]string)
-	defaultArgs["-name"] = vmName
-	defaultArgs["-machine"] = fmt.Sprintf("type=pc-1.0,accel=%s", config.Accelerator)
-	defaultArgs["-display"] = guiArgument
-	defaultArgs["-netdev"] = "user,id=user.0"
-	defaultArgs["-device"] = fmt.Sprintf("%s,netdev=user.0", config.NetDevice)
-	defaultArgs["-drive"] = fmt.Sprintf("file=%s,if=%s", imgPath, config.DiskInterface)
-	defaultArgs["-cdrom"] = isoPath
-	defaultArgs["-boot"] = bootDrive
-	defaultArgs["-m"] = "512m"
-	defaultArgs["-redir"] = fmt.Sprintf("tcp:%v::22", sshHostPort)
-	defaultArgs["-vnc"] = vnc
+	// Each switch may appear more than once on the qemu command line,
+	// so every default is a list of values.
+	defaultArgs := make(map[string][]string)
+	defaultArgs["-name"] = []string{vmName}
+	defaultArgs["-machine"] = []string{
+		fmt.Sprintf("type=%s,accel=%s", config.MachineType, config.Accelerator)}
+	defaultArgs["-display"] = []string{guiArgument}
+	if config.NetBridge == "" && config.NetTap == "" {
+		sshHostPort := state.Get("sshHostPort").(uint)
+		defaultArgs["-netdev"] = []string{"user,id=user.0"}
+		defaultArgs["-device"] = []string{fmt.Sprintf("%s,netdev=user.0", config.NetDevice)}
+		defaultArgs["-redir"] = []string{fmt.Sprintf("tcp:%v::22", sshHostPort)}
+	} else {
+		mac := state.Get("guest_mac_address").(string)
+		if config.NetBridge != "" {
+			// qemu uses qemu-bridge-helper to create a tap device on the
+			// bridge, so the helper must allow the bridge in bridge.conf.
+			defaultArgs["-netdev"] = []string{
+				fmt.Sprintf("bridge,id=user.0,br=%s", config.NetBridge)}
+		} else {
+			// The tap device is set up beforehand, so qemu must not run
+			// any scripts to configure it.
+			defaultArgs["-netdev"] = []string{fmt.Sprintf(
+				"tap,id=user.0,ifname=%s,script=no,downscript=no", config.NetTap)}
+		}
+		defaultArgs["-device"] = []string{
+			fmt.Sprintf("%s,netdev=user.0,mac=%s", config.NetDevice, mac)}
+	}
+	for _, diskPath := range diskPaths {
+		defaultArgs["-drive"] = append(defaultArgs["-drive"], fmt.Sprintf(
+			"file=%s,if=%s,format=%s,cache=%s,discard=%s",
+			diskPath, config.DiskInterface, config.Format,
+			config.DiskCache, config.DiskDiscard))
+	}
+	defaultArgs["-cdrom"] = []string{isoPath}
+	defaultArgs["-boot"] = []string{bootDrive}
+	defaultArgs["-m"] = []string{fmt.Sprintf("%dM", config.Memory)}
+	defaultArgs["-smp"] = []string{fmt.Sprintf("cpus=%d", config.CPUs)}
+	defaultArgs["-vnc"] = []string{vnc}
+
+	if socketPath, ok := state.GetOk("qmp_socket_path"); ok {
+		defaultArgs["-qmp"] = []string{
+			fmt.Sprintf("unix:%s,server,nowait", socketPath.(string))}
+	}
+
+	// The firmware and TPM arguments are added even if qemuargs
+	// overrides the same switches, since the build relies on them.
+	requiredArgs := make(map[string][]string)
+
+	// Boot from UEFI firmware. With a vars file the firmware is split
+	// into a read-only code image and the writable per-build NVRAM copy,
+	// otherwise it is a single image loaded as the BIOS.
+	if config.Firmware != "" {
+		if varsPath, ok := state.GetOk("firmware_vars_path"); ok {
+			requiredArgs["-drive"] = []string{
+				fmt.Sprintf("if=pflash,format=raw,readonly=on,file=%s", config.Firmware),
+				fmt.Sprintf("if=pflash,format=raw,file=%s", varsPath.(string))}
+		} else {
+			defaultArgs["-bios"] = []string{config.Firmware}
+		}
+	}
+
+	// Attach the emulated TPM that swtpm is serving.
+	if tpmSocketPath, ok := state.GetOk("tpm_socket_path"); ok {
+		requiredArgs["-chardev"] = []string{
+			fmt.Sprintf("socket,id=chrtpm,path=%s", tpmSocketPath.(string))}
+		requiredArgs["-tpmdev"] = []string{"emulator,id=tpm0,chardev=chrtpm"}
+		requiredArgs["-device"] = []string{"tpm-tis,tpmdev=tpm0"}
+	}
 
 	// Determine if we have a floppy disk to attach
 	if floppyPathRaw, ok := state.GetOk("floppy_path"); ok {
-		defaultArgs["-fda"] = floppyPathRaw.(string)
+		defaultArgs["-fda"] = []string{floppyPathRaw.(string)}
 	} else {
 		log.Println("Qemu Builder has no floppy files, not attaching a floppy.")
 	}
@@ -100,7 +184,7 @@ func getCommandArgs(bootDrive string, state multistep.StateBag) ([]string, error
 
 		httpPort := state.Get("http_port").(uint)
 		tplData := qemuArgsTemplateData{
-			"10.0.2.2",
+			state.Get("host_ip").(string),
 			httpPort,
 			config.HTTPDir,
 			config.OutputDir,
@@ -127,14 +211,16 @@ func getCommandArgs(bootDrive string, state multistep.StateBag) ([]string, error
 	}
 
 	// get any remaining missing default args from the default settings
-	for key := range defaultArgs {
+	for key, values := range defaultArgs {
 		if _, ok := inArgs[key]; !ok {
-			arg := make([]string, 1)
-			arg[0] = defaultArgs[key]
-			inArgs[key] = arg
+			inArgs[key] = values
 		}
 	}
 
+	for key, values := range requiredArgs {
+		inArgs[key] = append(inArgs[key], values...)
+	}
+
 	// Flatten to array of strings
 	outArgs := make([]string, 0)
 	for key, values := range inArgs {
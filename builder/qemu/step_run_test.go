@@ -0,0 +1,107 @@
+package qemu
+
+import (
+	"bytes"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	"strings"
+	"testing"
+)
+
+func testStepRunState(t *testing.T, raw map[string]interface{}) multistep.StateBag {
+	var b Builder
+	if _, err := b.Prepare(raw); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("config", &b.config)
+	state.Put("disk_paths", []string{"disk.qcow2"})
+	state.Put("host_ip", "10.0.2.2")
+	state.Put("http_port", uint(8080))
+	state.Put("iso_path", "boot.iso")
+	state.Put("sshHostPort", uint(2222))
+	state.Put("ui", &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	})
+	state.Put("vnc_port", uint(5901))
+	return state
+}
+
+// hasArg checks that the switch with the given value is on the command
+// line.
+func hasArg(args []string, key, value string) bool {
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == key && args[i+1] == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestStepRun_getCommandArgsRequired(t *testing.T) {
+	raw := testConfig()
+	raw["qemuargs"] = [][]interface{}{
+		[]interface{}{"-drive", "file=other.qcow2"},
+		[]interface{}{"-device", "virtio-rng-pci"},
+		[]interface{}{"-chardev", "stdio,id=serial0"},
+	}
+
+	state := testStepRunState(t, raw)
+	state.Get("config").(*config).Firmware = "OVMF_CODE.fd"
+	state.Put("firmware_vars_path", "vars.fd")
+	state.Put("tpm_socket_path", "tpm.sock")
+
+	args, err := getCommandArgs("once=d", state)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := [][2]string{
+		{"-drive", "file=other.qcow2"},
+		{"-device", "virtio-rng-pci"},
+		{"-chardev", "stdio,id=serial0"},
+		{"-drive", "if=pflash,format=raw,readonly=on,file=OVMF_CODE.fd"},
+		{"-drive", "if=pflash,format=raw,file=vars.fd"},
+		{"-chardev", "socket,id=chrtpm,path=tpm.sock"},
+		{"-tpmdev", "emulator,id=tpm0,chardev=chrtpm"},
+		{"-device", "tpm-tis,tpmdev=tpm0"},
+	}
+	for _, arg := range expected {
+		if !hasArg(args, arg[0], arg[1]) {
+			t.Fatalf("missing %s %s: %#v", arg[0], arg[1], args)
+		}
+	}
+
+	// The defaults of the overridden switches are gone
+	for i, arg := range args {
+		if arg == "-drive" && strings.HasPrefix(args[i+1], "file=disk.qcow2") {
+			t.Fatalf("bad: %#v", args)
+		}
+	}
+}
+
+func TestStepRun_getCommandArgsTap(t *testing.T) {
+	raw := testConfig()
+	raw["net_tap"] = "tap0"
+
+	state := testStepRunState(t, raw)
+	state.Put("guest_mac_address", "52:54:00:12:34:56")
+
+	args, err := getCommandArgs("once=d", state)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !hasArg(args, "-netdev", "tap,id=user.0,ifname=tap0,script=no,downscript=no") {
+		t.Fatalf("bad: %#v", args)
+	}
+	if !hasArg(args, "-device", "virtio-net,netdev=user.0,mac=52:54:00:12:34:56") {
+		t.Fatalf("bad: %#v", args)
+	}
+	if hasArg(args, "-redir", "tcp:2222::22") {
+		t.Fatalf("bad: %#v", args)
+	}
+}
@@ -0,0 +1,108 @@
+package qemu
+
+import (
+	"fmt"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// This step starts swtpm to emulate a TPM 2.0 device for the VM, if
+// it is enabled.
+//
+// Uses:
+//   config *config
+//   ui     packer.Ui
+//
+// Produces:
+//   tpm_socket_path string - The path of the swtpm control socket.
+type stepRunTPM struct {
+	cmd     *exec.Cmd
+	tempDir string
+}
+
+func (s *stepRunTPM) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*config)
+	ui := state.Get("ui").(packer.Ui)
+
+	if !config.SWTPM {
+		return multistep.ActionContinue
+	}
+
+	swtpmPath, err := exec.LookPath(config.SWTPMBinary)
+	if err != nil {
+		err := fmt.Errorf("Error finding swtpm: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	tempDir, err := ioutil.TempDir("", "packer-tpm")
+	if err != nil {
+		err := fmt.Errorf("Error creating TPM state directory: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.tempDir = tempDir
+
+	socketPath := filepath.Join(tempDir, "swtpm.sock")
+	args := []string{
+		"socket",
+		"--tpm2",
+		"--tpmstate", fmt.Sprintf("dir=%s", tempDir),
+		"--ctrl", fmt.Sprintf("type=unixio,path=%s", socketPath),
+	}
+
+	ui.Say("Starting TPM emulator...")
+	log.Printf("Executing %s: %#v", swtpmPath, args)
+	s.cmd = exec.Command(swtpmPath, args...)
+	if err := s.cmd.Start(); err != nil {
+		s.cmd = nil
+		err := fmt.Errorf("Error starting swtpm: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// Wait for swtpm to create its socket before qemu tries to use it
+	for i := 0; ; i++ {
+		if _, err := os.Stat(socketPath); err == nil {
+			break
+		}
+
+		if i >= 50 {
+			err := fmt.Errorf("Timeout waiting for swtpm socket: %s", socketPath)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	state.Put("tpm_socket_path", socketPath)
+	return multistep.ActionContinue
+}
+
+func (s *stepRunTPM) Cleanup(state multistep.StateBag) {
+	if s.cmd != nil {
+		// swtpm exits on its own when qemu disconnects, this is just
+		// to be sure it doesn't outlive the build.
+		if err := s.cmd.Process.Kill(); err != nil {
+			log.Printf("Error killing swtpm: %s", err)
+		}
+		s.cmd.Wait()
+	}
+
+	if s.tempDir != "" {
+		if err := os.RemoveAll(s.tempDir); err != nil {
+			log.Printf("Error removing TPM state directory: %s", err)
+		}
+	}
+}
@@ -22,6 +22,10 @@ import (
 //   <nothing>
 type stepShutdown struct{}
 
+// acpiShutdownTimeout is how long to wait for the guest to react to the
+// ACPI power button event once the shutdown command has timed out.
+const acpiShutdownTimeout = 1 * time.Minute
+
 func (s *stepShutdown) Run(state multistep.StateBag) multistep.StepAction {
 	comm := state.Get("communicator").(packer.Communicator)
 	config := state.Get("config").(*config)
@@ -39,15 +43,18 @@ func (s *stepShutdown) Run(state multistep.StateBag) multistep.StepAction {
 			return multistep.ActionHalt
 		}
 
-		// Start the goroutine that will time out our graceful attempt
-		cancelCh := make(chan struct{}, 1)
-		go func() {
-			defer close(cancelCh)
-			<-time.After(config.shutdownTimeout)
-		}()
-
 		log.Printf("Waiting max %s for shutdown to complete", config.shutdownTimeout)
-		if ok := driver.WaitForShutdown(cancelCh); !ok {
+		if ok := waitForShutdown(driver, config.shutdownTimeout); !ok {
+			// The guest ignored the shutdown command, so press the ACPI
+			// power button through QMP before giving up.
+			ui.Say("Shutdown command timed out, sending ACPI power button event...")
+			if err := driver.SystemPowerdown(); err != nil {
+				log.Printf("Error sending system_powerdown: %s", err)
+			} else if waitForShutdown(driver, acpiShutdownTimeout) {
+				log.Println("VM shut down.")
+				return multistep.ActionContinue
+			}
+
 			err := errors.New("Timeout while waiting for machine to shut down.")
 			state.Put("error", err)
 			ui.Error(err.Error())
@@ -68,3 +75,15 @@ func (s *stepShutdown) Run(state multistep.StateBag) multistep.StepAction {
 }
 
 func (s *stepShutdown) Cleanup(state multistep.StateBag) {}
+
+// waitForShutdown waits up to the given timeout for the VM to exit and
+// returns whether it did.
+func waitForShutdown(driver Driver, timeout time.Duration) bool {
+	cancelCh := make(chan struct{}, 1)
+	go func() {
+		defer close(cancelCh)
+		<-time.After(timeout)
+	}()
+
+	return driver.WaitForShutdown(cancelCh)
+}
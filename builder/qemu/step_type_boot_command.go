@@ -50,7 +50,9 @@ func (s *stepTypeBootCommand) Run(state multistep.StateBag) multistep.StepAction
 	}
 	defer nc.Close()
 
-	c, err := vnc.Client(nc, &vnc.ClientConfig{Exclusive: true})
+	// Share the connection if the screen is being recorded, since an
+	// exclusive one would disconnect the recorder.
+	c, err := vnc.Client(nc, &vnc.ClientConfig{Exclusive: config.VNCRecord == ""})
 	if err != nil {
 		err := fmt.Errorf("Error handshaking with VNC: %s", err)
 		state.Put("error", err)
@@ -62,7 +64,7 @@ func (s *stepTypeBootCommand) Run(state multistep.StateBag) multistep.StepAction
 	log.Printf("Connected to VNC desktop: %s", c.DesktopName)
 
 	tplData := &bootCommandTemplateData{
-		"10.0.2.2",
+		state.Get("host_ip").(string),
 		httpPort,
 		config.VMName,
 	}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/mitchellh/packer/packer"
 )
@@ -12,15 +13,89 @@ import (
 const BuilderId = "mitchellh.virtualbox"
 
 // Artifact is the result of running the VirtualBox builder, namely a set
-// of files associated with the resulting machine.
+// of files associated with the resulting machine, and the machine itself
+// if it was kept registered with VirtualBox.
 type artifact struct {
 	dir string
 	f   []string
+
+	driver Driver
+	vmName string
 }
 
 // NewArtifact returns a VirtualBox artifact containing the files
 // in the given directory.
 func NewArtifact(dir string) (packer.Artifact, error) {
+	files, err := artifactFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &artifact{
+		dir: dir,
+		f:   files,
+	}, nil
+}
+
+// NewRegisteredArtifact returns a VirtualBox artifact for a VM that was
+// left registered with VirtualBox, along with the files of its export at
+// exportPath, if it was exported. The disks of the registered VM are in
+// the same directory but aren't part of the files, since they are still
+// in use. Destroying the artifact unregisters and deletes the VM.
+func NewRegisteredArtifact(driver Driver, vmName, dir, exportPath string) (packer.Artifact, error) {
+	var files []string
+	if exportPath != "" {
+		var err error
+		files, err = exportFiles(exportPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &artifact{
+		dir:    dir,
+		f:      files,
+		driver: driver,
+		vmName: vmName,
+	}, nil
+}
+
+// exportFiles returns the files that make up the export at the given
+// path: an OVA, or an OVF with the files it references and its manifest
+// and certificate, if there are any.
+func exportFiles(exportPath string) ([]string, error) {
+	if strings.ToLower(filepath.Ext(exportPath)) == ".ova" {
+		return []string{exportPath}, nil
+	}
+
+	f, err := os.Open(exportPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	d, err := parseOVF(f)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(exportPath)
+	files := []string{exportPath}
+	for _, name := range d.Files() {
+		files = append(files, filepath.Join(dir, name))
+	}
+
+	base := strings.TrimSuffix(exportPath, filepath.Ext(exportPath))
+	for _, path := range []string{base + ".mf", base + ".cert"} {
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+
+	return files, nil
+}
+
+func artifactFiles(dir string) ([]string, error) {
 	files := make([]string, 0, 5)
 	visit := func(path string, info os.FileInfo, err error) error {
 		if !info.IsDir() {
@@ -34,10 +109,7 @@ func NewArtifact(dir string) (packer.Artifact, error) {
 		return nil, err
 	}
 
-	return &artifact{
-		dir: dir,
-		f:   files,
-	}, nil
+	return files, nil
 }
 
 func (*artifact) BuilderId() string {
@@ -48,14 +120,33 @@ func (a *artifact) Files() []string {
 	return a.f
 }
 
-func (*artifact) Id() string {
+func (a *artifact) Id() string {
+	if a.vmName != "" {
+		return a.vmName
+	}
+
 	return "VM"
 }
 
 func (a *artifact) String() string {
-	return fmt.Sprintf("VM files in directory: %s", a.dir)
+	if a.vmName == "" {
+		return fmt.Sprintf("VM files in directory: %s", a.dir)
+	}
+
+	if len(a.f) == 0 {
+		return fmt.Sprintf("VM registered with VirtualBox: %s", a.vmName)
+	}
+
+	return fmt.Sprintf(
+		"VM registered with VirtualBox: %s, files in directory: %s", a.vmName, a.dir)
 }
 
 func (a *artifact) Destroy() error {
+	if a.driver != nil {
+		if err := a.driver.Delete(a.vmName); err != nil {
+			return err
+		}
+	}
+
 	return os.RemoveAll(a.dir)
 }
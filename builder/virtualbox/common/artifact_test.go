@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/mitchellh/packer/packer"
@@ -41,3 +42,74 @@ func TestNewArtifact(t *testing.T) {
 		t.Fatalf("should length 1: %d", len(a.Files()))
 	}
 }
+
+func TestNewRegisteredArtifact(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	driver := new(DriverMock)
+	a, err := NewRegisteredArtifact(driver, "foo", td, "")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if a.Id() != "foo" {
+		t.Fatalf("bad: %#v", a.Id())
+	}
+	if len(a.Files()) != 0 {
+		t.Fatalf("should be empty: %#v", a.Files())
+	}
+
+	if err := a.Destroy(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if driver.DeleteName != "foo" {
+		t.Fatalf("bad: %#v", driver.DeleteName)
+	}
+}
+
+func TestNewRegisteredArtifact_export(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	ovf := `<?xml version="1.0"?>
+<Envelope xmlns="http://schemas.dmtf.org/ovf/envelope/1">
+  <References>
+    <File href="foo-disk1.vmdk" id="file1"/>
+  </References>
+</Envelope>
+`
+	files := map[string]string{
+		"foo.ovf":        ovf,
+		"foo-disk1.vmdk": "",
+		"foo.mf":         "",
+		"foo.vdi":        "",
+	}
+	for name, contents := range files {
+		err := ioutil.WriteFile(filepath.Join(td, name), []byte(contents), 0644)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	a, err := NewRegisteredArtifact(new(DriverMock), "foo", td, filepath.Join(td, "foo.ovf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The disk of the registered VM isn't included
+	expected := []string{
+		filepath.Join(td, "foo.ovf"),
+		filepath.Join(td, "foo-disk1.vmdk"),
+		filepath.Join(td, "foo.mf"),
+	}
+	if !reflect.DeepEqual(a.Files(), expected) {
+		t.Fatalf("bad: %#v", a.Files())
+	}
+}
@@ -16,12 +16,23 @@ import (
 // versions out of the builder steps, so sometimes the methods are
 // extremely specific.
 type Driver interface {
+	// CloneVM creates and registers a new VM named name from the given
+	// snapshot of the source VM. If linked is true, the new VM uses
+	// differencing disks based on the snapshot instead of full copies.
+	CloneVM(name, source, snapshot string, linked bool) error
+
 	// Create a SATA controller.
 	CreateSATAController(vm string, controller string) error
 
+	// CreateSnapshot takes a snapshot of the VM with the given name.
+	CreateSnapshot(vm string, snapshot string) error
+
 	// Delete a VM by name
 	Delete(string) error
 
+	// DeleteSnapshot deletes the named snapshot of a VM.
+	DeleteSnapshot(vm string, snapshot string) error
+
 	// Import a VM
 	Import(string, string, string) error
 
@@ -15,6 +15,21 @@ type VBox42Driver struct {
 	VBoxManagePath string
 }
 
+func (d *VBox42Driver) CloneVM(name, source, snapshot string, linked bool) error {
+	args := []string{
+		"clonevm", source,
+		"--snapshot", snapshot,
+		"--name", name,
+		"--register",
+	}
+
+	if linked {
+		args = append(args, "--options", "link")
+	}
+
+	return d.VBoxManage(args...)
+}
+
 func (d *VBox42Driver) CreateSATAController(vmName string, name string) error {
 	version, err := d.Version()
 	if err != nil {
@@ -36,10 +51,18 @@ func (d *VBox42Driver) CreateSATAController(vmName string, name string) error {
 	return d.VBoxManage(command...)
 }
 
+func (d *VBox42Driver) CreateSnapshot(vmName string, name string) error {
+	return d.VBoxManage("snapshot", vmName, "take", name)
+}
+
 func (d *VBox42Driver) Delete(name string) error {
 	return d.VBoxManage("unregistervm", name, "--delete")
 }
 
+func (d *VBox42Driver) DeleteSnapshot(vmName string, name string) error {
+	return d.VBoxManage("snapshot", vmName, "delete", name)
+}
+
 func (d *VBox42Driver) Iso() (string, error) {
 	var stdout bytes.Buffer
 
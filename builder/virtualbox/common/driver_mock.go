@@ -5,14 +5,31 @@ import "sync"
 type DriverMock struct {
 	sync.Mutex
 
+	CloneVMCalled   bool
+	CloneVMName     string
+	CloneVMSource   string
+	CloneVMSnapshot string
+	CloneVMLinked   bool
+	CloneVMErr      error
+
 	CreateSATAControllerVM         string
 	CreateSATAControllerController string
 	CreateSATAControllerErr        error
 
+	CreateSnapshotCalled bool
+	CreateSnapshotVM     string
+	CreateSnapshotName   string
+	CreateSnapshotErr    error
+
 	DeleteCalled bool
 	DeleteName   string
 	DeleteErr    error
 
+	DeleteSnapshotCalled bool
+	DeleteSnapshotVM     string
+	DeleteSnapshotName   string
+	DeleteSnapshotErr    error
+
 	ImportCalled bool
 	ImportName   string
 	ImportPath   string
@@ -43,18 +60,41 @@ type DriverMock struct {
 	VersionErr    error
 }
 
+func (d *DriverMock) CloneVM(name, source, snapshot string, linked bool) error {
+	d.CloneVMCalled = true
+	d.CloneVMName = name
+	d.CloneVMSource = source
+	d.CloneVMSnapshot = snapshot
+	d.CloneVMLinked = linked
+	return d.CloneVMErr
+}
+
 func (d *DriverMock) CreateSATAController(vm string, controller string) error {
 	d.CreateSATAControllerVM = vm
 	d.CreateSATAControllerController = vm
 	return d.CreateSATAControllerErr
 }
 
+func (d *DriverMock) CreateSnapshot(vm string, name string) error {
+	d.CreateSnapshotCalled = true
+	d.CreateSnapshotVM = vm
+	d.CreateSnapshotName = name
+	return d.CreateSnapshotErr
+}
+
 func (d *DriverMock) Delete(name string) error {
 	d.DeleteCalled = true
 	d.DeleteName = name
 	return d.DeleteErr
 }
 
+func (d *DriverMock) DeleteSnapshot(vm string, name string) error {
+	d.DeleteSnapshotCalled = true
+	d.DeleteSnapshotVM = vm
+	d.DeleteSnapshotName = name
+	return d.DeleteSnapshotErr
+}
+
 func (d *DriverMock) Import(name, path, opts string) error {
 	d.ImportCalled = true
 	d.ImportName = name
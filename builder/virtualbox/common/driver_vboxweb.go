@@ -0,0 +1,1213 @@
+package common
+
+import (
+	"bytes"
+	gossh "code.google.com/p/go.crypto/ssh"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/mitchellh/packer/communicator/ssh"
+	"github.com/mitchellh/packer/packer"
+	"log"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VBoxWebDriver talks to a remote VirtualBox host through the VirtualBox
+// web service (vboxwebsrv). The VBoxManage commands that the builder
+// steps issue are translated into web service calls, and local files
+// such as ISOs and floppies are uploaded to the host over SSH.
+//
+// Only the VBoxManage commands that Packer itself uses are supported.
+type VBoxWebDriver struct {
+	Host        string
+	Port        uint
+	Username    string
+	Password    string
+	SSHPort     uint
+	SSHUsername string
+	SSHPassword string
+	RemoteDir   string
+
+	client    *vboxwebClient
+	vbox      string
+	comm      packer.Communicator
+	remoteDir string
+	uploaded  map[string]string
+}
+
+func (d *VBoxWebDriver) CloneVM(name, source, snapshot string, linked bool) error {
+	args := []string{
+		"clonevm", source,
+		"--snapshot", snapshot,
+		"--name", name,
+		"--register",
+	}
+
+	if linked {
+		args = append(args, "--options", "link")
+	}
+
+	return d.VBoxManage(args...)
+}
+
+func (d *VBoxWebDriver) CreateSATAController(vmName string, name string) error {
+	return d.VBoxManage(
+		"storagectl", vmName,
+		"--name", name,
+		"--add", "sata",
+		"--portcount", "1")
+}
+
+func (d *VBoxWebDriver) CreateSnapshot(vmName string, name string) error {
+	return d.VBoxManage("snapshot", vmName, "take", name)
+}
+
+func (d *VBoxWebDriver) Delete(name string) error {
+	return d.VBoxManage("unregistervm", name, "--delete")
+}
+
+func (d *VBoxWebDriver) DeleteSnapshot(vmName string, name string) error {
+	return d.VBoxManage("snapshot", vmName, "delete", name)
+}
+
+func (d *VBoxWebDriver) Import(name, srcPath, opts string) error {
+	remotePath, err := d.uploadAppliance(srcPath)
+	if err != nil {
+		return err
+	}
+
+	appliance, err := d.client.CallOne("IVirtualBox_createAppliance",
+		soapArg{"_this", d.vbox})
+	if err != nil {
+		return err
+	}
+
+	progress, err := d.client.CallOne("IAppliance_read",
+		soapArg{"_this", appliance}, soapArg{"file", remotePath})
+	if err != nil {
+		return err
+	}
+	if err := d.waitProgress(progress); err != nil {
+		return err
+	}
+
+	if _, err := d.client.Call("IAppliance_interpret",
+		soapArg{"_this", appliance}); err != nil {
+		return err
+	}
+
+	descs, err := d.client.Call("IAppliance_getVirtualSystemDescriptions",
+		soapArg{"_this", appliance})
+	if err != nil {
+		return err
+	}
+	if len(descs["returnval"]) == 0 {
+		return errors.New("Appliance does not contain a virtual system")
+	}
+	vsys := descs["returnval"][0]
+
+	// Override the name of the VM, the same as "--vsys 0 --vmname".
+	desc, err := d.client.Call("IVirtualSystemDescription_getDescription",
+		soapArg{"_this", vsys})
+	if err != nil {
+		return err
+	}
+
+	types := desc["types"]
+	vboxValues := desc["VBoxValues"]
+	extraValues := desc["extraConfigValues"]
+	enabled := make([]string, len(types))
+	for i, t := range types {
+		enabled[i] = "true"
+		if t == "Name" && i < len(vboxValues) {
+			vboxValues[i] = name
+		}
+	}
+
+	if _, err := d.client.Call("IVirtualSystemDescription_setFinalValues",
+		soapArg{"_this", vsys},
+		soapArg{"enabled", enabled},
+		soapArg{"VBoxValues", vboxValues},
+		soapArg{"extraConfigValues", extraValues}); err != nil {
+		return err
+	}
+
+	importOpts := make([]string, 0, 1)
+	for _, opt := range strings.Split(opts, ",") {
+		switch strings.TrimSpace(opt) {
+		case "":
+		case "keepallmacs":
+			importOpts = append(importOpts, "KeepAllMACs")
+		case "keepnatmacs":
+			importOpts = append(importOpts, "KeepNATMACs")
+		default:
+			return fmt.Errorf("Unsupported import option: %s", opt)
+		}
+	}
+
+	progress, err = d.client.CallOne("IAppliance_importMachines",
+		soapArg{"_this", appliance}, soapArg{"options", importOpts})
+	if err != nil {
+		return err
+	}
+
+	return d.waitProgress(progress)
+}
+
+func (d *VBoxWebDriver) Iso() (string, error) {
+	// The builder expects a local path that it can upload or attach, and
+	// the host's ISO isn't one, so the ISO is downloaded instead.
+	return "", errors.New(
+		"The Guest Additions ISO of a remote VirtualBox host can't be used, it will be downloaded.")
+}
+
+func (d *VBoxWebDriver) IsRunning(name string) (bool, error) {
+	machine, err := d.findMachine(name)
+	if err != nil {
+		return false, err
+	}
+
+	state, err := d.client.CallOne("IMachine_getState", soapArg{"_this", machine})
+	if err != nil {
+		return false, err
+	}
+
+	// We consider "Stopping" and "Paused" to still be running, the same
+	// as the local driver does.
+	switch state {
+	case "Running", "Stopping", "Paused":
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (d *VBoxWebDriver) Stop(name string) error {
+	if err := d.VBoxManage("controlvm", name, "poweroff"); err != nil {
+		return err
+	}
+
+	// We sleep here for a little bit to let the session "unlock"
+	time.Sleep(2 * time.Second)
+
+	return nil
+}
+
+func (d *VBoxWebDriver) SuppressMessages() error {
+	// There is no GUI on the remote host to suppress messages in.
+	return nil
+}
+
+func (d *VBoxWebDriver) VBoxManage(args ...string) error {
+	log.Printf("Executing VBoxManage over vboxwebsrv: %#v", args)
+	if len(args) == 0 {
+		return errors.New("No VBoxManage command given")
+	}
+
+	cmd := parseVBoxManageArgs(args[1:])
+	switch args[0] {
+	case "clonevm":
+		return d.cloneVM(cmd)
+	case "controlvm":
+		return d.controlVM(cmd)
+	case "createhd":
+		return d.createHD(cmd)
+	case "createvm":
+		return d.createVM(cmd)
+	case "export":
+		return d.export(cmd)
+	case "modifyvm":
+		return d.modifyVM(cmd)
+	case "setextradata":
+		return d.setExtraData(cmd)
+	case "snapshot":
+		return d.snapshot(cmd)
+	case "startvm":
+		return d.startVM(cmd)
+	case "storageattach":
+		return d.storageAttach(cmd)
+	case "storagectl":
+		return d.storageCtl(cmd)
+	case "unregistervm":
+		return d.unregisterVM(cmd)
+	}
+
+	return fmt.Errorf("VBoxManage command '%s' is not supported with vboxwebsrv", args[0])
+}
+
+func (d *VBoxWebDriver) Verify() error {
+	d.client = &vboxwebClient{
+		URL: fmt.Sprintf("http://%s:%d/", d.Host, d.Port),
+	}
+
+	vbox, err := d.client.CallOne("IWebsessionManager_logon",
+		soapArg{"username", d.Username}, soapArg{"password", d.Password})
+	if err != nil {
+		return fmt.Errorf("Error logging on to vboxwebsrv: %s", err)
+	}
+	d.vbox = vbox
+
+	if err := d.connect(); err != nil {
+		return fmt.Errorf("Error connecting to VirtualBox host over SSH: %s", err)
+	}
+
+	// Create the remote directory and resolve it to an absolute path,
+	// since VirtualBox requires absolute paths.
+	stdout, err := d.ssh(fmt.Sprintf("mkdir -p '%s' && cd '%s' && pwd", d.RemoteDir, d.RemoteDir))
+	if err != nil {
+		return err
+	}
+	d.remoteDir = strings.TrimSpace(stdout.String())
+	d.uploaded = make(map[string]string)
+
+	log.Printf("Remote directory on VirtualBox host: %s", d.remoteDir)
+	return nil
+}
+
+func (d *VBoxWebDriver) Version() (string, error) {
+	versionOutput, err := d.client.CallOne("IVirtualBox_getVersion",
+		soapArg{"_this", d.vbox})
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("vboxwebsrv version output: %s", versionOutput)
+	versionRe := regexp.MustCompile("[^.0-9]")
+	matches := versionRe.Split(versionOutput, 2)
+	if len(matches) == 0 || matches[0] == "" {
+		return "", fmt.Errorf("No version found: %s", versionOutput)
+	}
+
+	log.Printf("VirtualBox version: %s", matches[0])
+	return matches[0], nil
+}
+
+//-------------------------------------------------------------------
+// RemoteDriver implementation
+//-------------------------------------------------------------------
+
+func (d *VBoxWebDriver) HostAddress() string {
+	return d.Host
+}
+
+func (d *VBoxWebDriver) HostIP() (string, error) {
+	conn, err := net.Dial("tcp", net.JoinHostPort(d.Host, strconv.Itoa(int(d.Port))))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	host, _, err := net.SplitHostPort(conn.LocalAddr().String())
+	return host, err
+}
+
+func (d *VBoxWebDriver) UploadFile(localPath string) (string, error) {
+	absPath, err := filepath.Abs(localPath)
+	if err != nil {
+		return "", err
+	}
+
+	if remotePath, ok := d.uploaded[absPath]; ok {
+		return remotePath, nil
+	}
+
+	remotePath := d.remoteFilePath(localPath)
+	log.Printf("Uploading %s to VirtualBox host: %s", localPath, remotePath)
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if err := d.comm.Upload(remotePath, f); err != nil {
+		return "", err
+	}
+
+	d.uploaded[absPath] = remotePath
+	return remotePath, nil
+}
+
+//-------------------------------------------------------------------
+// VBoxManage command translation
+//-------------------------------------------------------------------
+
+func (d *VBoxWebDriver) cloneVM(cmd *vboxManageCommand) error {
+	name := cmd.Flag("name")
+	if len(cmd.Args) < 1 || name == "" {
+		return errors.New("clonevm requires a VM and --name")
+	}
+
+	source, err := d.findMachine(cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	// Clone the state of the machine in the snapshot, which is what
+	// linked clones have to be based on.
+	if snapshotName := cmd.Flag("snapshot"); snapshotName != "" {
+		snapshot, err := d.client.CallOne("IMachine_findSnapshot",
+			soapArg{"_this", source}, soapArg{"nameOrId", snapshotName})
+		if err != nil {
+			return err
+		}
+
+		source, err = d.client.CallOne("ISnapshot_getMachine", soapArg{"_this", snapshot})
+		if err != nil {
+			return err
+		}
+	}
+
+	osType, err := d.client.CallOne("IMachine_getOSTypeId", soapArg{"_this", source})
+	if err != nil {
+		return err
+	}
+
+	target, err := d.client.CallOne("IVirtualBox_createMachine",
+		soapArg{"_this", d.vbox},
+		soapArg{"settingsFile", ""},
+		soapArg{"name", name},
+		soapArg{"groups", []string{"/"}},
+		soapArg{"osTypeId", osType},
+		soapArg{"flags", ""})
+	if err != nil {
+		return err
+	}
+
+	var options []string
+	if cmd.Flag("options") == "link" {
+		options = append(options, "Link")
+	}
+
+	progress, err := d.client.CallOne("IMachine_cloneTo",
+		soapArg{"_this", source},
+		soapArg{"target", target},
+		soapArg{"mode", "MachineState"},
+		soapArg{"options", options})
+	if err != nil {
+		return err
+	}
+
+	if err := d.waitProgress(progress); err != nil {
+		return err
+	}
+
+	if _, err := d.client.Call("IMachine_saveSettings", soapArg{"_this", target}); err != nil {
+		return err
+	}
+
+	if cmd.HasFlag("register") {
+		_, err = d.client.Call("IVirtualBox_registerMachine",
+			soapArg{"_this", d.vbox}, soapArg{"machine", target})
+	}
+
+	return err
+}
+
+func (d *VBoxWebDriver) controlVM(cmd *vboxManageCommand) error {
+	if len(cmd.Args) < 2 {
+		return errors.New("controlvm requires a VM and an action")
+	}
+
+	return d.withLockedMachine(cmd.Args[0], "Shared", func(session, _ string) error {
+		console, err := d.client.CallOne("ISession_getConsole", soapArg{"_this", session})
+		if err != nil {
+			return err
+		}
+
+		switch cmd.Args[1] {
+		case "poweroff":
+			progress, err := d.client.CallOne("IConsole_powerDown", soapArg{"_this", console})
+			if err != nil {
+				return err
+			}
+			return d.waitProgress(progress)
+		case "keyboardputscancode":
+			keyboard, err := d.client.CallOne("IConsole_getKeyboard", soapArg{"_this", console})
+			if err != nil {
+				return err
+			}
+
+			codes := make([]string, 0, len(cmd.Args)-2)
+			for _, code := range cmd.Args[2:] {
+				value, err := strconv.ParseUint(code, 16, 8)
+				if err != nil {
+					return fmt.Errorf("Invalid scancode '%s': %s", code, err)
+				}
+				codes = append(codes, strconv.FormatUint(value, 10))
+			}
+
+			_, err = d.client.Call("IKeyboard_putScancodes",
+				soapArg{"_this", keyboard}, soapArg{"scancodes", codes})
+			return err
+		}
+
+		return fmt.Errorf("controlvm action '%s' is not supported with vboxwebsrv", cmd.Args[1])
+	})
+}
+
+func (d *VBoxWebDriver) createHD(cmd *vboxManageCommand) error {
+	filename := cmd.Flag("filename")
+	size, err := strconv.ParseUint(cmd.Flag("size"), 10, 64)
+	if filename == "" || err != nil {
+		return errors.New("createhd requires --filename and a numeric --size")
+	}
+
+	format := cmd.Flag("format")
+	if format == "" {
+		format = "VDI"
+	}
+
+	variant := cmd.Flag("variant")
+	if variant == "" {
+		variant = "Standard"
+	}
+
+	medium, err := d.client.CallOne("IVirtualBox_createHardDisk",
+		soapArg{"_this", d.vbox},
+		soapArg{"format", format},
+		soapArg{"location", d.remoteFilePath(filename)})
+	if err != nil {
+		return err
+	}
+
+	// VBoxManage takes the size in megabytes, the API in bytes.
+	progress, err := d.client.CallOne("IMedium_createBaseStorage",
+		soapArg{"_this", medium},
+		soapArg{"logicalSize", size * 1024 * 1024},
+		soapArg{"variant", []string{variant}})
+	if err != nil {
+		return err
+	}
+
+	return d.waitProgress(progress)
+}
+
+func (d *VBoxWebDriver) createVM(cmd *vboxManageCommand) error {
+	name := cmd.Flag("name")
+	if name == "" {
+		return errors.New("createvm requires --name")
+	}
+
+	machine, err := d.client.CallOne("IVirtualBox_createMachine",
+		soapArg{"_this", d.vbox},
+		soapArg{"settingsFile", ""},
+		soapArg{"name", name},
+		soapArg{"groups", []string{"/"}},
+		soapArg{"osTypeId", cmd.Flag("ostype")},
+		soapArg{"flags", ""})
+	if err != nil {
+		return err
+	}
+
+	if _, err := d.client.Call("IMachine_saveSettings", soapArg{"_this", machine}); err != nil {
+		return err
+	}
+
+	if cmd.HasFlag("register") {
+		_, err = d.client.Call("IVirtualBox_registerMachine",
+			soapArg{"_this", d.vbox}, soapArg{"machine", machine})
+	}
+
+	return err
+}
+
+func (d *VBoxWebDriver) export(cmd *vboxManageCommand) error {
+	if len(cmd.Args) < 1 {
+		return errors.New("export requires a VM")
+	}
+
+	output := cmd.Flag("output")
+	if output == "" {
+		return errors.New("export requires --output")
+	}
+
+	options := make([]string, 0, 2)
+	for _, f := range cmd.Flags {
+		switch f.Name {
+		case "output":
+		case "manifest":
+			options = append(options, "CreateManifest")
+		case "iso":
+			options = append(options, "ExportDVDImages")
+		default:
+			return fmt.Errorf("export option '--%s' is not supported with vboxwebsrv", f.Name)
+		}
+	}
+
+	machine, err := d.findMachine(cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	appliance, err := d.client.CallOne("IVirtualBox_createAppliance", soapArg{"_this", d.vbox})
+	if err != nil {
+		return err
+	}
+
+	remoteOutput := d.remoteFilePath(output)
+	if _, err := d.client.Call("IMachine_exportTo",
+		soapArg{"_this", machine},
+		soapArg{"appliance", appliance},
+		soapArg{"location", remoteOutput}); err != nil {
+		return err
+	}
+
+	progress, err := d.client.CallOne("IAppliance_write",
+		soapArg{"_this", appliance},
+		soapArg{"format", "ovf-1.0"},
+		soapArg{"options", options},
+		soapArg{"path", remoteOutput})
+	if err != nil {
+		return err
+	}
+	if err := d.waitProgress(progress); err != nil {
+		return err
+	}
+
+	// Bring the exported files back so they end up in the local output
+	// directory like with a local build.
+	files, err := d.client.Call("IAppliance_getDisks", soapArg{"_this", appliance})
+	if err != nil {
+		return err
+	}
+
+	remoteFiles := []string{remoteOutput}
+	if strings.HasSuffix(remoteOutput, ".ovf") {
+		for _, disk := range files["returnval"] {
+			// Each disk is described as "id\tsize\tcapacity\tformat\thref\t..."
+			parts := strings.Split(disk, "\t")
+			if len(parts) > 4 && parts[4] != "" {
+				remoteFiles = append(remoteFiles, path.Join(path.Dir(remoteOutput), parts[4]))
+			}
+		}
+
+		if cmd.HasFlag("manifest") {
+			remoteFiles = append(remoteFiles,
+				strings.TrimSuffix(remoteOutput, ".ovf")+".mf")
+		}
+	}
+
+	for _, remoteFile := range remoteFiles {
+		localFile := filepath.Join(filepath.Dir(output), path.Base(remoteFile))
+		if err := d.download(remoteFile, localFile); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *VBoxWebDriver) modifyVM(cmd *vboxManageCommand) error {
+	if len(cmd.Args) < 1 {
+		return errors.New("modifyvm requires a VM")
+	}
+
+	return d.withLockedMachine(cmd.Args[0], "Write", func(_, machine string) error {
+		for _, f := range cmd.Flags {
+			if err := d.modifyVMFlag(machine, f); err != nil {
+				return err
+			}
+		}
+
+		_, err := d.client.Call("IMachine_saveSettings", soapArg{"_this", machine})
+		return err
+	})
+}
+
+func (d *VBoxWebDriver) modifyVMFlag(machine string, f vboxManageFlag) error {
+	value := f.Value()
+
+	switch {
+	case f.Name == "memory":
+		_, err := d.client.Call("IMachine_setMemorySize",
+			soapArg{"_this", machine}, soapArg{"memorySize", value})
+		return err
+	case f.Name == "cpus":
+		_, err := d.client.Call("IMachine_setCPUCount",
+			soapArg{"_this", machine}, soapArg{"CPUCount", value})
+		return err
+	case f.Name == "vram":
+		_, err := d.client.Call("IMachine_setVRAMSize",
+			soapArg{"_this", machine}, soapArg{"VRAMSize", value})
+		return err
+	case f.Name == "ioapic":
+		bios, err := d.client.CallOne("IMachine_getBIOSSettings", soapArg{"_this", machine})
+		if err != nil {
+			return err
+		}
+		_, err = d.client.Call("IBIOSSettings_setIOAPICEnabled",
+			soapArg{"_this", bios}, soapArg{"IOAPICEnabled", value == "on"})
+		return err
+	case strings.HasPrefix(f.Name, "boot"):
+		position, err := strconv.Atoi(strings.TrimPrefix(f.Name, "boot"))
+		if err != nil {
+			break
+		}
+		device, ok := vboxwebBootDevices[value]
+		if !ok {
+			return fmt.Errorf("Unknown boot device: %s", value)
+		}
+		_, err = d.client.Call("IMachine_setBootOrder",
+			soapArg{"_this", machine},
+			soapArg{"position", position},
+			soapArg{"device", device})
+		return err
+	case f.Name == "natpf1":
+		adapter, err := d.client.CallOne("IMachine_getNetworkAdapter",
+			soapArg{"_this", machine}, soapArg{"slot", 0})
+		if err != nil {
+			return err
+		}
+		nat, err := d.client.CallOne("INetworkAdapter_getNATEngine", soapArg{"_this", adapter})
+		if err != nil {
+			return err
+		}
+
+		if value == "delete" && len(f.Values) > 1 {
+			_, err = d.client.Call("INATEngine_removeRedirect",
+				soapArg{"_this", nat}, soapArg{"name", f.Values[1]})
+			return err
+		}
+
+		// The rule is "name,proto,hostip,hostport,guestip,guestport".
+		rule := strings.Split(value, ",")
+		if len(rule) != 6 {
+			return fmt.Errorf("Invalid port forwarding rule: %s", value)
+		}
+
+		_, err = d.client.Call("INATEngine_addRedirect",
+			soapArg{"_this", nat},
+			soapArg{"name", rule[0]},
+			soapArg{"proto", strings.ToUpper(rule[1])},
+			soapArg{"hostIP", rule[2]},
+			soapArg{"hostPort", rule[3]},
+			soapArg{"guestIP", rule[4]},
+			soapArg{"guestPort", rule[5]})
+		return err
+	}
+
+	return fmt.Errorf("modifyvm option '--%s' is not supported with vboxwebsrv", f.Name)
+}
+
+func (d *VBoxWebDriver) setExtraData(cmd *vboxManageCommand) error {
+	if len(cmd.Args) < 2 {
+		return errors.New("setextradata requires a target and a key")
+	}
+
+	value := ""
+	if len(cmd.Args) > 2 {
+		value = cmd.Args[2]
+	}
+
+	target := d.vbox
+	method := "IVirtualBox_setExtraData"
+	if cmd.Args[0] != "global" {
+		machine, err := d.findMachine(cmd.Args[0])
+		if err != nil {
+			return err
+		}
+
+		target = machine
+		method = "IMachine_setExtraData"
+	}
+
+	_, err := d.client.Call(method,
+		soapArg{"_this", target},
+		soapArg{"key", cmd.Args[1]},
+		soapArg{"value", value})
+	return err
+}
+
+func (d *VBoxWebDriver) snapshot(cmd *vboxManageCommand) error {
+	if len(cmd.Args) < 3 {
+		return errors.New("snapshot requires a VM, an action and a snapshot name")
+	}
+
+	vmName, action, name := cmd.Args[0], cmd.Args[1], cmd.Args[2]
+	return d.withLockedMachine(vmName, "Shared", func(session, machine string) error {
+		console, err := d.client.CallOne("ISession_getConsole", soapArg{"_this", session})
+		if err != nil {
+			return err
+		}
+
+		var progress string
+		switch action {
+		case "take":
+			progress, err = d.client.CallOne("IConsole_takeSnapshot",
+				soapArg{"_this", console},
+				soapArg{"name", name},
+				soapArg{"description", ""})
+		case "delete":
+			var snapshot, id string
+			snapshot, err = d.client.CallOne("IMachine_findSnapshot",
+				soapArg{"_this", machine}, soapArg{"nameOrId", name})
+			if err != nil {
+				return err
+			}
+
+			id, err = d.client.CallOne("ISnapshot_getId", soapArg{"_this", snapshot})
+			if err != nil {
+				return err
+			}
+
+			progress, err = d.client.CallOne("IConsole_deleteSnapshot",
+				soapArg{"_this", console}, soapArg{"id", id})
+		default:
+			return fmt.Errorf("snapshot action '%s' is not supported with vboxwebsrv", action)
+		}
+		if err != nil {
+			return err
+		}
+
+		return d.waitProgress(progress)
+	})
+}
+
+func (d *VBoxWebDriver) startVM(cmd *vboxManageCommand) error {
+	if len(cmd.Args) < 1 {
+		return errors.New("startvm requires a VM")
+	}
+
+	machine, err := d.findMachine(cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	session, err := d.client.CallOne("IWebsessionManager_getSessionObject",
+		soapArg{"refIVirtualBox", d.vbox})
+	if err != nil {
+		return err
+	}
+
+	vmType := cmd.Flag("type")
+	if vmType == "" {
+		vmType = "headless"
+	}
+
+	progress, err := d.client.CallOne("IMachine_launchVMProcess",
+		soapArg{"_this", machine},
+		soapArg{"session", session},
+		soapArg{"type", vmType},
+		soapArg{"environment", ""})
+	if err != nil {
+		return err
+	}
+
+	if err := d.waitProgress(progress); err != nil {
+		return err
+	}
+
+	_, err = d.client.Call("ISession_unlockMachine", soapArg{"_this", session})
+	return err
+}
+
+func (d *VBoxWebDriver) storageAttach(cmd *vboxManageCommand) error {
+	if len(cmd.Args) < 1 {
+		return errors.New("storageattach requires a VM")
+	}
+
+	controller := cmd.Flag("storagectl")
+	port := cmd.Flag("port")
+	device := cmd.Flag("device")
+	if device == "" {
+		device = "0"
+	}
+
+	mediumType, ok := vboxwebDeviceTypes[cmd.Flag("type")]
+	medium := cmd.Flag("medium")
+	if !ok && medium != "none" {
+		return fmt.Errorf("Unknown storage type: %s", cmd.Flag("type"))
+	}
+
+	return d.withLockedMachine(cmd.Args[0], "Write", func(_, machine string) error {
+		var err error
+		switch medium {
+		case "none":
+			_, err = d.client.Call("IMachine_detachDevice",
+				soapArg{"_this", machine},
+				soapArg{"name", controller},
+				soapArg{"controllerPort", port},
+				soapArg{"device", device})
+		default:
+			mediumRef := ""
+			if medium != "emptydrive" {
+				mediumRef, err = d.openMedium(medium, mediumType)
+				if err != nil {
+					return err
+				}
+			}
+
+			_, err = d.client.Call("IMachine_attachDevice",
+				soapArg{"_this", machine},
+				soapArg{"name", controller},
+				soapArg{"controllerPort", port},
+				soapArg{"device", device},
+				soapArg{"type", mediumType},
+				soapArg{"medium", mediumRef})
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = d.client.Call("IMachine_saveSettings", soapArg{"_this", machine})
+		return err
+	})
+}
+
+func (d *VBoxWebDriver) storageCtl(cmd *vboxManageCommand) error {
+	if len(cmd.Args) < 1 {
+		return errors.New("storagectl requires a VM")
+	}
+
+	name := cmd.Flag("name")
+	return d.withLockedMachine(cmd.Args[0], "Write", func(_, machine string) error {
+		if cmd.HasFlag("remove") {
+			if _, err := d.client.Call("IMachine_removeStorageController",
+				soapArg{"_this", machine}, soapArg{"name", name}); err != nil {
+				return err
+			}
+		} else {
+			bus, ok := vboxwebStorageBuses[cmd.Flag("add")]
+			if !ok {
+				return fmt.Errorf("Unknown storage controller type: %s", cmd.Flag("add"))
+			}
+
+			controller, err := d.client.CallOne("IMachine_addStorageController",
+				soapArg{"_this", machine},
+				soapArg{"name", name},
+				soapArg{"connectionType", bus})
+			if err != nil {
+				return err
+			}
+
+			portCount := cmd.Flag("portcount")
+			if portCount == "" {
+				portCount = cmd.Flag("sataportcount")
+			}
+
+			if portCount != "" {
+				if _, err := d.client.Call("IStorageController_setPortCount",
+					soapArg{"_this", controller},
+					soapArg{"portCount", portCount}); err != nil {
+					return err
+				}
+			}
+		}
+
+		_, err := d.client.Call("IMachine_saveSettings", soapArg{"_this", machine})
+		return err
+	})
+}
+
+func (d *VBoxWebDriver) unregisterVM(cmd *vboxManageCommand) error {
+	if len(cmd.Args) < 1 {
+		return errors.New("unregistervm requires a VM")
+	}
+
+	machine, err := d.findMachine(cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	media, err := d.client.Call("IMachine_unregister",
+		soapArg{"_this", machine},
+		soapArg{"cleanupMode", "DetachAllReturnHardDisksOnly"})
+	if err != nil {
+		return err
+	}
+
+	if !cmd.HasFlag("delete") {
+		return nil
+	}
+
+	progress, err := d.client.CallOne("IMachine_deleteConfig",
+		soapArg{"_this", machine},
+		soapArg{"media", media["returnval"]})
+	if err != nil {
+		return err
+	}
+
+	return d.waitProgress(progress)
+}
+
+//-------------------------------------------------------------------
+// Helpers
+//-------------------------------------------------------------------
+
+var vboxwebBootDevices = map[string]string{
+	"none":   "Null",
+	"floppy": "Floppy",
+	"dvd":    "DVD",
+	"disk":   "HardDisk",
+	"net":    "Network",
+}
+
+var vboxwebDeviceTypes = map[string]string{
+	"dvddrive": "DVD",
+	"fdd":      "Floppy",
+	"hdd":      "HardDisk",
+}
+
+var vboxwebStorageBuses = map[string]string{
+	"floppy": "Floppy",
+	"ide":    "IDE",
+	"sas":    "SAS",
+	"sata":   "SATA",
+	"scsi":   "SCSI",
+}
+
+func (d *VBoxWebDriver) findMachine(name string) (string, error) {
+	return d.client.CallOne("IVirtualBox_findMachine",
+		soapArg{"_this", d.vbox}, soapArg{"nameOrId", name})
+}
+
+// withLockedMachine locks the named machine with a new session and calls
+// the function with the session and the mutable machine of the session.
+func (d *VBoxWebDriver) withLockedMachine(name, lockType string, f func(session, machine string) error) error {
+	machine, err := d.findMachine(name)
+	if err != nil {
+		return err
+	}
+
+	session, err := d.client.CallOne("IWebsessionManager_getSessionObject",
+		soapArg{"refIVirtualBox", d.vbox})
+	if err != nil {
+		return err
+	}
+
+	if _, err := d.client.Call("IMachine_lockMachine",
+		soapArg{"_this", machine},
+		soapArg{"session", session},
+		soapArg{"lockType", lockType}); err != nil {
+		return err
+	}
+	defer d.client.Call("ISession_unlockMachine", soapArg{"_this", session})
+
+	mutable, err := d.client.CallOne("ISession_getMachine", soapArg{"_this", session})
+	if err != nil {
+		return err
+	}
+
+	return f(session, mutable)
+}
+
+// openMedium opens the medium at the given local path, uploading it to
+// the host if it is a local file.
+func (d *VBoxWebDriver) openMedium(localPath, deviceType string) (string, error) {
+	remotePath := d.remoteFilePath(localPath)
+	if _, err := os.Stat(localPath); err == nil {
+		remotePath, err = d.UploadFile(localPath)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	accessMode := "ReadOnly"
+	if deviceType == "HardDisk" {
+		accessMode = "ReadWrite"
+	}
+
+	return d.client.CallOne("IVirtualBox_openMedium",
+		soapArg{"_this", d.vbox},
+		soapArg{"location", remotePath},
+		soapArg{"deviceType", deviceType},
+		soapArg{"accessMode", accessMode},
+		soapArg{"forceNewUuid", false})
+}
+
+// waitProgress waits for the given progress object to complete and
+// returns its error, if any.
+func (d *VBoxWebDriver) waitProgress(progress string) error {
+	if _, err := d.client.Call("IProgress_waitForCompletion",
+		soapArg{"_this", progress}, soapArg{"timeout", -1}); err != nil {
+		return err
+	}
+
+	code, err := d.client.CallOne("IProgress_getResultCode", soapArg{"_this", progress})
+	if err != nil {
+		return err
+	}
+
+	if code == "0" {
+		return nil
+	}
+
+	info, err := d.client.CallOne("IProgress_getErrorInfo", soapArg{"_this", progress})
+	if err != nil {
+		return fmt.Errorf("VirtualBox operation failed with code %s", code)
+	}
+
+	text, err := d.client.CallOne("IVirtualBoxErrorInfo_getText", soapArg{"_this", info})
+	if err != nil {
+		return fmt.Errorf("VirtualBox operation failed with code %s", code)
+	}
+
+	return fmt.Errorf("VirtualBox operation failed: %s", text)
+}
+
+// uploadAppliance uploads an OVA, or an OVF along with the files that it
+// references, and returns the remote path of the OVF/OVA.
+func (d *VBoxWebDriver) uploadAppliance(srcPath string) (string, error) {
+	if strings.ToLower(filepath.Ext(srcPath)) == ".ovf" {
+		f, err := os.Open(srcPath)
+		if err != nil {
+			return "", err
+		}
+
+		var envelope struct {
+			Files []struct {
+				Href string `xml:"href,attr"`
+			} `xml:"References>File"`
+		}
+		err = xml.NewDecoder(f).Decode(&envelope)
+		f.Close()
+		if err != nil {
+			return "", fmt.Errorf("Error reading OVF: %s", err)
+		}
+
+		for _, file := range envelope.Files {
+			if _, err := d.UploadFile(filepath.Join(filepath.Dir(srcPath), file.Href)); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return d.UploadFile(srcPath)
+}
+
+// remoteFilePath returns where the given local file lives on the host.
+func (d *VBoxWebDriver) remoteFilePath(localPath string) string {
+	return path.Join(d.remoteDir, filepath.Base(localPath))
+}
+
+func (d *VBoxWebDriver) connect() error {
+	address := fmt.Sprintf("%s:%d", d.Host, d.SSHPort)
+
+	auth := []gossh.AuthMethod{
+		gossh.Password(d.SSHPassword),
+		gossh.KeyboardInteractive(
+			ssh.PasswordKeyboardInteractive(d.SSHPassword)),
+	}
+
+	sshConfig := &ssh.Config{
+		Connection: ssh.ConnectFunc("tcp", address),
+		SSHConfig: &gossh.ClientConfig{
+			User: d.SSHUsername,
+			Auth: auth,
+		},
+		NoPty: true,
+	}
+
+	comm, err := ssh.New(address, sshConfig)
+	if err != nil {
+		return err
+	}
+
+	d.comm = comm
+	return nil
+}
+
+func (d *VBoxWebDriver) download(remotePath, localPath string) error {
+	log.Printf("Downloading %s from VirtualBox host to %s", remotePath, localPath)
+	f, err := os.Create(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return d.comm.Download(remotePath, f)
+}
+
+func (d *VBoxWebDriver) ssh(command string) (*bytes.Buffer, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := &packer.RemoteCmd{
+		Command: command,
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+	}
+
+	if err := d.comm.Start(cmd); err != nil {
+		return nil, err
+	}
+
+	cmd.Wait()
+
+	if cmd.ExitStatus != 0 {
+		return nil, fmt.Errorf("'%s'\n\nStdout: %s\n\nStderr: %s",
+			cmd.Command, stdout.String(), stderr.String())
+	}
+
+	return &stdout, nil
+}
+
+// vboxManageCommand is a parsed VBoxManage command line: the positional
+// arguments followed by "--flag value..." pairs.
+type vboxManageCommand struct {
+	Args  []string
+	Flags []vboxManageFlag
+}
+
+type vboxManageFlag struct {
+	Name   string
+	Values []string
+}
+
+// Value returns the first value of the flag, or "" if it has none.
+func (f vboxManageFlag) Value() string {
+	if len(f.Values) == 0 {
+		return ""
+	}
+
+	return f.Values[0]
+}
+
+func parseVBoxManageArgs(args []string) *vboxManageCommand {
+	cmd := new(vboxManageCommand)
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--") {
+			cmd.Flags = append(cmd.Flags, vboxManageFlag{Name: arg[2:]})
+			continue
+		}
+
+		if len(cmd.Flags) == 0 {
+			cmd.Args = append(cmd.Args, arg)
+			continue
+		}
+
+		last := &cmd.Flags[len(cmd.Flags)-1]
+		last.Values = append(last.Values, arg)
+	}
+
+	return cmd
+}
+
+// Flag returns the first value of the named flag.
+func (c *vboxManageCommand) Flag(name string) string {
+	for _, f := range c.Flags {
+		if f.Name == name {
+			return f.Value()
+		}
+	}
+
+	return ""
+}
+
+// HasFlag returns whether the named flag was given.
+func (c *vboxManageCommand) HasFlag(name string) bool {
+	for _, f := range c.Flags {
+		if f.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
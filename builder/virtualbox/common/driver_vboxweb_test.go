@@ -0,0 +1,238 @@
+package common
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// testVBoxWebServer is a local stand-in for vboxwebsrv. It records the
+// methods that are called and answers each with the canned return
+// values, or an empty response for methods without any.
+type testVBoxWebServer struct {
+	Calls   []string
+	Args    []map[string][]string
+	Returns map[string]string
+}
+
+func (s *testVBoxWebServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var envelope struct {
+		Body struct {
+			Call struct {
+				XMLName xml.Name
+				Args    []struct {
+					XMLName xml.Name
+					Value   string `xml:",chardata"`
+				} `xml:",any"`
+			} `xml:",any"`
+		} `xml:"Body"`
+	}
+	if err := xml.NewDecoder(r.Body).Decode(&envelope); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	method := envelope.Body.Call.XMLName.Local
+	args := make(map[string][]string)
+	for _, arg := range envelope.Body.Call.Args {
+		args[arg.XMLName.Local] = append(args[arg.XMLName.Local], arg.Value)
+	}
+
+	s.Calls = append(s.Calls, method)
+	s.Args = append(s.Args, args)
+
+	if method == "IFail_fail" {
+		fmt.Fprint(w, `<?xml version="1.0"?><SOAP-ENV:Envelope xmlns:SOAP-ENV="http://schemas.xmlsoap.org/soap/envelope/"><SOAP-ENV:Body><SOAP-ENV:Fault><faultcode>SOAP-ENV:Client</faultcode><faultstring>VirtualBox error: nope</faultstring></SOAP-ENV:Fault></SOAP-ENV:Body></SOAP-ENV:Envelope>`)
+		return
+	}
+
+	fmt.Fprintf(w, `<?xml version="1.0"?><SOAP-ENV:Envelope xmlns:SOAP-ENV="http://schemas.xmlsoap.org/soap/envelope/" xmlns:vbox="http://www.virtualbox.org/"><SOAP-ENV:Body><vbox:%sResponse>`, method)
+	if ret, ok := s.Returns[method]; ok {
+		fmt.Fprintf(w, "<returnval>%s</returnval>", ret)
+	}
+	fmt.Fprintf(w, "</vbox:%sResponse></SOAP-ENV:Body></SOAP-ENV:Envelope>", method)
+}
+
+func testVBoxWebDriver(t *testing.T, returns map[string]string) (*VBoxWebDriver, *testVBoxWebServer, func()) {
+	server := &testVBoxWebServer{Returns: returns}
+	ts := httptest.NewServer(server)
+
+	driver := &VBoxWebDriver{
+		Host:      "vbox.example.com",
+		client:    &vboxwebClient{URL: ts.URL},
+		vbox:      "vbox-ref",
+		remoteDir: "/home/packer/packer-foo",
+		uploaded:  make(map[string]string),
+	}
+
+	return driver, server, ts.Close
+}
+
+func TestVBoxWebDriver_impl(t *testing.T) {
+	var _ Driver = new(VBoxWebDriver)
+	var _ RemoteDriver = new(VBoxWebDriver)
+}
+
+func TestVBoxWebClient_fault(t *testing.T) {
+	driver, _, closeFn := testVBoxWebDriver(t, nil)
+	defer closeFn()
+
+	_, err := driver.client.Call("IFail_fail")
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestVBoxWebDriver_Version(t *testing.T) {
+	driver, server, closeFn := testVBoxWebDriver(t, map[string]string{
+		"IVirtualBox_getVersion": "4.3.12_Ubuntu",
+	})
+	defer closeFn()
+
+	version, err := driver.Version()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if version != "4.3.12" {
+		t.Fatalf("bad: %s", version)
+	}
+
+	if server.Args[0]["_this"][0] != "vbox-ref" {
+		t.Fatalf("bad: %#v", server.Args[0])
+	}
+}
+
+func TestVBoxWebDriver_IsRunning(t *testing.T) {
+	driver, server, closeFn := testVBoxWebDriver(t, map[string]string{
+		"IVirtualBox_findMachine": "machine-ref",
+		"IMachine_getState":       "Paused",
+	})
+	defer closeFn()
+
+	running, err := driver.IsRunning("foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !running {
+		t.Fatal("should be running")
+	}
+
+	if server.Args[0]["nameOrId"][0] != "foo" {
+		t.Fatalf("bad: %#v", server.Args[0])
+	}
+
+	server.Returns["IMachine_getState"] = "PoweredOff"
+	running, err = driver.IsRunning("foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if running {
+		t.Fatal("should not be running")
+	}
+}
+
+func TestVBoxWebDriver_VBoxManageCreateVM(t *testing.T) {
+	driver, server, closeFn := testVBoxWebDriver(t, map[string]string{
+		"IVirtualBox_createMachine": "machine-ref",
+	})
+	defer closeFn()
+
+	err := driver.VBoxManage(
+		"createvm", "--name", "foo", "--ostype", "Ubuntu_64", "--register")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{
+		"IVirtualBox_createMachine",
+		"IMachine_saveSettings",
+		"IVirtualBox_registerMachine",
+	}
+	if !reflect.DeepEqual(server.Calls, expected) {
+		t.Fatalf("bad: %#v", server.Calls)
+	}
+
+	create := server.Args[0]
+	if !reflect.DeepEqual(create["groups"], []string{"/"}) || create["osTypeId"][0] != "Ubuntu_64" {
+		t.Fatalf("bad: %#v", create)
+	}
+}
+
+func TestVBoxWebDriver_VBoxManageModifyVM(t *testing.T) {
+	driver, server, closeFn := testVBoxWebDriver(t, map[string]string{
+		"IVirtualBox_findMachine":             "machine-ref",
+		"IWebsessionManager_getSessionObject": "session-ref",
+		"ISession_getMachine":                 "mutable-ref",
+		"IMachine_getNetworkAdapter":          "adapter-ref",
+		"INetworkAdapter_getNATEngine":        "nat-ref",
+	})
+	defer closeFn()
+
+	err := driver.VBoxManage(
+		"modifyvm", "foo",
+		"--memory", "1024",
+		"--natpf1", "packerssh,tcp,,2222,,22")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{
+		"IVirtualBox_findMachine",
+		"IWebsessionManager_getSessionObject",
+		"IMachine_lockMachine",
+		"ISession_getMachine",
+		"IMachine_setMemorySize",
+		"IMachine_getNetworkAdapter",
+		"INetworkAdapter_getNATEngine",
+		"INATEngine_addRedirect",
+		"IMachine_saveSettings",
+		"ISession_unlockMachine",
+	}
+	if !reflect.DeepEqual(server.Calls, expected) {
+		t.Fatalf("bad: %#v", server.Calls)
+	}
+
+	memory := server.Args[4]
+	if memory["_this"][0] != "mutable-ref" || memory["memorySize"][0] != "1024" {
+		t.Fatalf("bad: %#v", memory)
+	}
+
+	// The forwarded port must listen on all of the host's addresses
+	redirect := server.Args[7]
+	if redirect["hostIP"][0] != "" || redirect["hostPort"][0] != "2222" || redirect["proto"][0] != "TCP" {
+		t.Fatalf("bad: %#v", redirect)
+	}
+}
+
+func TestVBoxWebDriver_VBoxManageUnsupported(t *testing.T) {
+	driver, _, closeFn := testVBoxWebDriver(t, nil)
+	defer closeFn()
+
+	if err := driver.VBoxManage("clonehd", "foo", "bar"); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestParseVBoxManageArgs(t *testing.T) {
+	cmd := parseVBoxManageArgs([]string{
+		"foo", "--natpf1", "delete", "packerssh", "--register",
+	})
+
+	if !reflect.DeepEqual(cmd.Args, []string{"foo"}) {
+		t.Fatalf("bad: %#v", cmd.Args)
+	}
+
+	if !reflect.DeepEqual(cmd.Flags[0].Values, []string{"delete", "packerssh"}) {
+		t.Fatalf("bad: %#v", cmd.Flags)
+	}
+
+	if !cmd.HasFlag("register") || cmd.Flag("register") != "" {
+		t.Fatalf("bad: %#v", cmd.Flags)
+	}
+}
@@ -4,10 +4,25 @@ import (
 	"errors"
 	"fmt"
 	"github.com/mitchellh/packer/packer"
+	"os"
 )
 
 type ExportConfig struct {
-	Format string `mapstruture:"format"`
+	Format string `mapstructure:"format"`
+
+	// These edit the OVF descriptor after the VM is exported.
+	OVFProduct        string `mapstructure:"ovf_product"`
+	OVFProductURL     string `mapstructure:"ovf_product_url"`
+	OVFVendor         string `mapstructure:"ovf_vendor"`
+	OVFVendorURL      string `mapstructure:"ovf_vendor_url"`
+	OVFVersion        string `mapstructure:"ovf_version"`
+	OVFRemoveNICs     bool   `mapstructure:"ovf_remove_nics"`
+	OVFDiskController string `mapstructure:"ovf_disk_controller"`
+
+	// These configure the manifest of the exported files.
+	ManifestDigest string `mapstructure:"manifest_digest"`
+	SigningCert    string `mapstructure:"signing_certificate"`
+	SigningKey     string `mapstructure:"signing_key"`
 }
 
 func (c *ExportConfig) Prepare(t *packer.ConfigTemplate) []error {
@@ -16,7 +31,16 @@ func (c *ExportConfig) Prepare(t *packer.ConfigTemplate) []error {
 	}
 
 	templates := map[string]*string{
-		"format": &c.Format,
+		"format":              &c.Format,
+		"ovf_product":         &c.OVFProduct,
+		"ovf_product_url":     &c.OVFProductURL,
+		"ovf_vendor":          &c.OVFVendor,
+		"ovf_vendor_url":      &c.OVFVendorURL,
+		"ovf_version":         &c.OVFVersion,
+		"ovf_disk_controller": &c.OVFDiskController,
+		"manifest_digest":     &c.ManifestDigest,
+		"signing_certificate": &c.SigningCert,
+		"signing_key":         &c.SigningKey,
 	}
 
 	errs := make([]error, 0)
@@ -33,5 +57,53 @@ func (c *ExportConfig) Prepare(t *packer.ConfigTemplate) []error {
 			errors.New("invalid format, only 'ovf' or 'ova' are allowed"))
 	}
 
+	if c.OVFDiskController != "" {
+		if _, ok := ovfDiskControllers[c.OVFDiskController]; !ok {
+			errs = append(errs, errors.New(
+				"invalid ovf_disk_controller, only 'ide', 'sata' or 'scsi' are allowed"))
+		}
+	}
+
+	// The manifest is only rewritten if the descriptor is edited or
+	// signed, or if a digest is asked for explicitly.
+	if c.ManifestDigest == "" && c.EditOVF() {
+		c.ManifestDigest = "sha256"
+	}
+
+	if c.ManifestDigest != "" {
+		if _, ok := manifestDigests[c.ManifestDigest]; !ok {
+			errs = append(errs, errors.New(
+				"invalid manifest_digest, only 'sha1' or 'sha256' are allowed"))
+		}
+	}
+
+	if (c.SigningCert == "") != (c.SigningKey == "") {
+		errs = append(errs, errors.New(
+			"signing_certificate and signing_key must be specified together"))
+	}
+
+	for _, path := range []string{c.SigningCert, c.SigningKey} {
+		if path == "" {
+			continue
+		}
+
+		if _, err := os.Stat(path); err != nil {
+			errs = append(errs, fmt.Errorf("Error reading %s: %s", path, err))
+		}
+	}
+
 	return errs
 }
+
+// EditOVF returns whether the exported OVF descriptor is modified or
+// signed after the export.
+func (c *ExportConfig) EditOVF() bool {
+	return c.OVFProduct != "" ||
+		c.OVFProductURL != "" ||
+		c.OVFVendor != "" ||
+		c.OVFVendorURL != "" ||
+		c.OVFVersion != "" ||
+		c.OVFRemoveNICs ||
+		c.OVFDiskController != "" ||
+		c.SigningCert != ""
+}
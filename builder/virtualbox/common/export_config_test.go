@@ -1,6 +1,8 @@
 package common
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
 )
 
@@ -32,3 +34,107 @@ func TestExportConfigPrepare_BootWait(t *testing.T) {
 		t.Fatalf("should not have error: %s", errs)
 	}
 }
+
+func TestExportConfigPrepare_OVFDiskController(t *testing.T) {
+	var c *ExportConfig
+	var errs []error
+
+	// Bad
+	c = new(ExportConfig)
+	c.OVFDiskController = "floppy"
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) == 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	// Good
+	c = new(ExportConfig)
+	c.OVFDiskController = "scsi"
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) > 0 {
+		t.Fatalf("should not have error: %s", errs)
+	}
+}
+
+func TestExportConfigPrepare_ManifestDigest(t *testing.T) {
+	var c *ExportConfig
+	var errs []error
+
+	// Default with nothing to edit
+	c = new(ExportConfig)
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) > 0 {
+		t.Fatalf("should not have error: %s", errs)
+	}
+	if c.ManifestDigest != "" {
+		t.Fatalf("bad: %s", c.ManifestDigest)
+	}
+
+	// Default when editing
+	c = new(ExportConfig)
+	c.OVFRemoveNICs = true
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) > 0 {
+		t.Fatalf("should not have error: %s", errs)
+	}
+	if c.ManifestDigest != "sha256" {
+		t.Fatalf("bad: %s", c.ManifestDigest)
+	}
+
+	// Bad
+	c = new(ExportConfig)
+	c.ManifestDigest = "md5"
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) == 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	// Good
+	c = new(ExportConfig)
+	c.ManifestDigest = "sha1"
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) > 0 {
+		t.Fatalf("should not have error: %s", errs)
+	}
+}
+
+func TestExportConfigPrepare_Signing(t *testing.T) {
+	var c *ExportConfig
+	var errs []error
+
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	// Certificate without a key
+	c = new(ExportConfig)
+	c.SigningCert = tf.Name()
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) == 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	// Missing file
+	c = new(ExportConfig)
+	c.SigningCert = tf.Name()
+	c.SigningKey = tf.Name() + ".missing"
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) == 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	// Good
+	c = new(ExportConfig)
+	c.SigningCert = tf.Name()
+	c.SigningKey = tf.Name()
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) > 0 {
+		t.Fatalf("should not have error: %s", errs)
+	}
+	if c.ManifestDigest != "sha256" {
+		t.Fatalf("bad: %s", c.ManifestDigest)
+	}
+}
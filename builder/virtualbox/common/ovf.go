@@ -0,0 +1,514 @@
+package common
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ovfElement is an element of an OVF descriptor. Descriptors are edited
+// as a tree of these rather than through encoding/xml structs so that
+// everything we don't touch, such as the VirtualBox specific sections,
+// is written back as it was, namespace prefixes included.
+type ovfElement struct {
+	// Name is the raw name of the element: Space holds the namespace
+	// prefix as written in the document, not the namespace URL.
+	Name xml.Name
+	Attr []xml.Attr
+
+	// Children holds *ovfElement, xml.CharData, xml.Comment,
+	// xml.ProcInst and xml.Directive values in document order.
+	Children []interface{}
+}
+
+// ovfDescriptor is a parsed OVF descriptor.
+type ovfDescriptor struct {
+	// prolog holds what comes before the root element, such as the XML
+	// declaration.
+	prolog []interface{}
+	root   *ovfElement
+}
+
+// OVF resource types of the virtual hardware items that we edit.
+const (
+	ovfResourceIDE  = "5"
+	ovfResourceSCSI = "6"
+	ovfResourceNIC  = "10"
+	ovfResourceDisk = "17"
+	ovfResourceSATA = "20"
+)
+
+// ovfDiskControllers maps the values of ovf_disk_controller to the OVF
+// resource type and subtype of the controller.
+var ovfDiskControllers = map[string][2]string{
+	"ide":  {ovfResourceIDE, "PIIX4"},
+	"sata": {ovfResourceSATA, "AHCI"},
+	"scsi": {ovfResourceSCSI, "lsilogic"},
+}
+
+func parseOVF(r io.Reader) (*ovfDescriptor, error) {
+	d := new(ovfDescriptor)
+	dec := xml.NewDecoder(r)
+
+	var stack []*ovfElement
+	for {
+		token, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing OVF: %s", err)
+		}
+
+		var node interface{}
+		switch t := token.(type) {
+		case xml.StartElement:
+			e := &ovfElement{Name: t.Name, Attr: t.Copy().Attr}
+			if len(stack) == 0 {
+				if d.root != nil {
+					return nil, errors.New("Error parsing OVF: multiple root elements")
+				}
+				d.root = e
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, e)
+			}
+			stack = append(stack, e)
+			continue
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, errors.New("Error parsing OVF: unexpected end element")
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		case xml.CharData:
+			node = t.Copy()
+		case xml.Comment:
+			node = t.Copy()
+		case xml.ProcInst:
+			node = t.Copy()
+		case xml.Directive:
+			node = t.Copy()
+		}
+
+		if len(stack) > 0 {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, node)
+		} else if d.root == nil {
+			d.prolog = append(d.prolog, node)
+		}
+	}
+
+	if d.root == nil {
+		return nil, errors.New("Error parsing OVF: no root element")
+	}
+
+	if len(stack) > 0 {
+		return nil, errors.New("Error parsing OVF: unexpected end of document")
+	}
+
+	return d, nil
+}
+
+// Write writes the descriptor as XML.
+func (d *ovfDescriptor) Write(w io.Writer) error {
+	var buf bytes.Buffer
+	for _, node := range d.prolog {
+		writeOVFNode(&buf, node)
+	}
+	writeOVFNode(&buf, d.root)
+	buf.WriteString("\n")
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func writeOVFNode(buf *bytes.Buffer, node interface{}) {
+	switch n := node.(type) {
+	case *ovfElement:
+		buf.WriteString("<" + ovfRawName(n.Name))
+		for _, attr := range n.Attr {
+			buf.WriteString(" " + ovfRawName(attr.Name) + `="`)
+			buf.WriteString(ovfAttrEscaper.Replace(attr.Value))
+			buf.WriteString(`"`)
+		}
+
+		if len(n.Children) == 0 {
+			buf.WriteString("/>")
+			return
+		}
+
+		buf.WriteString(">")
+		for _, child := range n.Children {
+			writeOVFNode(buf, child)
+		}
+		buf.WriteString("</" + ovfRawName(n.Name) + ">")
+	case xml.CharData:
+		buf.WriteString(ovfTextEscaper.Replace(string(n)))
+	case xml.Comment:
+		buf.WriteString("<!--")
+		buf.Write(n)
+		buf.WriteString("-->")
+	case xml.ProcInst:
+		buf.WriteString("<?" + n.Target)
+		if len(n.Inst) > 0 {
+			buf.WriteString(" ")
+			buf.Write(n.Inst)
+		}
+		buf.WriteString("?>")
+	case xml.Directive:
+		buf.WriteString("<!")
+		buf.Write(n)
+		buf.WriteString(">")
+	}
+}
+
+// These escape text and attribute values. Unlike xml.EscapeText they
+// leave newlines alone, so that the formatting of the document is kept.
+var ovfTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+var ovfAttrEscaper = strings.NewReplacer(
+	"&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;",
+	"\n", "&#xA;", "\r", "&#xD;", "\t", "&#x9;")
+
+func ovfRawName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+
+	return name.Space + ":" + name.Local
+}
+
+// Child returns the first child element with the given local name, or
+// nil if there is none.
+func (e *ovfElement) Child(local string) *ovfElement {
+	for _, child := range e.Children {
+		if c, ok := child.(*ovfElement); ok && c.Name.Local == local {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// Elements returns the child elements with the given local name.
+func (e *ovfElement) Elements(local string) []*ovfElement {
+	var result []*ovfElement
+	for _, child := range e.Children {
+		if c, ok := child.(*ovfElement); ok && c.Name.Local == local {
+			result = append(result, c)
+		}
+	}
+
+	return result
+}
+
+// Text returns the character data of the element.
+func (e *ovfElement) Text() string {
+	var buf bytes.Buffer
+	for _, child := range e.Children {
+		if data, ok := child.(xml.CharData); ok {
+			buf.Write(data)
+		}
+	}
+
+	return strings.TrimSpace(buf.String())
+}
+
+// SetText replaces the content of the element with the given text.
+func (e *ovfElement) SetText(text string) {
+	e.Children = []interface{}{xml.CharData(text)}
+}
+
+// AttrValue returns the value of the attribute with the given local name.
+func (e *ovfElement) AttrValue(local string) string {
+	for _, attr := range e.Attr {
+		if attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+
+	return ""
+}
+
+// Remove removes the given child element, along with the whitespace
+// that precedes it.
+func (e *ovfElement) Remove(child *ovfElement) {
+	for i, c := range e.Children {
+		if c != child {
+			continue
+		}
+
+		start := i
+		if i > 0 {
+			if data, ok := e.Children[i-1].(xml.CharData); ok && len(bytes.TrimSpace(data)) == 0 {
+				start = i - 1
+			}
+		}
+
+		e.Children = append(e.Children[:start], e.Children[i+1:]...)
+		return
+	}
+}
+
+// InsertBefore inserts the new child element before the given one, or
+// at the end if before is nil. The indentation of the surrounding
+// elements is reused.
+func (e *ovfElement) InsertBefore(child, before *ovfElement) {
+	indent := e.childIndent()
+
+	index := len(e.Children)
+	if before != nil {
+		for i, c := range e.Children {
+			if c == before {
+				index = i
+				break
+			}
+		}
+
+		// Put the new element after the whitespace that indents the
+		// element we insert before, and indent that element again.
+		if index > 0 {
+			if _, ok := e.Children[index-1].(xml.CharData); ok {
+				index--
+			}
+		}
+		e.insertAt(index, indent, child)
+		return
+	}
+
+	// At the end, the last child is usually the whitespace before the
+	// closing tag.
+	if index > 0 {
+		if data, ok := e.Children[index-1].(xml.CharData); ok && len(bytes.TrimSpace(data)) == 0 {
+			index--
+		}
+	}
+	e.insertAt(index, indent, child)
+}
+
+func (e *ovfElement) insertAt(index int, indent xml.CharData, child *ovfElement) {
+	nodes := []interface{}{indent, child}
+	if len(indent) == 0 {
+		nodes = nodes[1:]
+	}
+
+	rest := append([]interface{}{}, e.Children[index:]...)
+	e.Children = append(append(e.Children[:index], nodes...), rest...)
+}
+
+// childIndent returns the whitespace that precedes the child elements.
+func (e *ovfElement) childIndent() xml.CharData {
+	for i, c := range e.Children {
+		if _, ok := c.(*ovfElement); ok && i > 0 {
+			if data, ok := e.Children[i-1].(xml.CharData); ok && len(bytes.TrimSpace(data)) == 0 {
+				return data.Copy()
+			}
+		}
+	}
+
+	return nil
+}
+
+// virtualSystem returns the first virtual system of the descriptor.
+func (d *ovfDescriptor) virtualSystem() (*ovfElement, error) {
+	vs := d.root.Child("VirtualSystem")
+	if vs == nil {
+		if collection := d.root.Child("VirtualSystemCollection"); collection != nil {
+			vs = collection.Child("VirtualSystem")
+		}
+	}
+
+	if vs == nil {
+		return nil, errors.New("OVF descriptor has no VirtualSystem")
+	}
+
+	return vs, nil
+}
+
+// hardwareItems returns the items of the virtual hardware section.
+func (d *ovfDescriptor) hardwareItems() (*ovfElement, []*ovfElement, error) {
+	vs, err := d.virtualSystem()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	section := vs.Child("VirtualHardwareSection")
+	if section == nil {
+		return nil, nil, errors.New("OVF descriptor has no VirtualHardwareSection")
+	}
+
+	return section, section.Elements("Item"), nil
+}
+
+func ovfItemValue(item *ovfElement, local string) string {
+	if e := item.Child(local); e != nil {
+		return e.Text()
+	}
+
+	return ""
+}
+
+// Files returns the names of the files referenced by the descriptor.
+func (d *ovfDescriptor) Files() []string {
+	var files []string
+	if refs := d.root.Child("References"); refs != nil {
+		for _, file := range refs.Elements("File") {
+			if href := file.AttrValue("href"); href != "" {
+				files = append(files, href)
+			}
+		}
+	}
+
+	return files
+}
+
+// SetProduct sets the fields of the product section of the virtual
+// system, creating it if needed. Empty values are left unchanged.
+func (d *ovfDescriptor) SetProduct(product, productURL, vendor, vendorURL, version string) error {
+	vs, err := d.virtualSystem()
+	if err != nil {
+		return err
+	}
+
+	prefix := vs.Name.Space
+	section := vs.Child("ProductSection")
+	if section == nil {
+		info := &ovfElement{
+			Name:     xml.Name{Space: prefix, Local: "Info"},
+			Children: []interface{}{xml.CharData("Meta-information about the installed software")},
+		}
+
+		// Indent the new section one level deeper than its siblings so
+		// the elements added below line up.
+		section = &ovfElement{Name: xml.Name{Space: prefix, Local: "ProductSection"}}
+		section.Children = []interface{}{info}
+		if indent := vs.childIndent(); len(indent) > 0 {
+			section.Children = []interface{}{
+				xml.CharData(string(indent) + "  "), info, indent,
+			}
+		}
+
+		// The product section comes after the operating system section
+		// in VirtualBox exports, before the hardware.
+		vs.InsertBefore(section, vs.Child("VirtualHardwareSection"))
+	}
+
+	// These have to be in this order according to the OVF schema.
+	order := []string{"Info", "Product", "Vendor", "Version", "FullVersion", "ProductUrl", "VendorUrl"}
+	values := map[string]string{
+		"Product":     product,
+		"ProductUrl":  productURL,
+		"Vendor":      vendor,
+		"VendorUrl":   vendorURL,
+		"Version":     version,
+		"FullVersion": version,
+	}
+
+	for i, local := range order {
+		value := values[local]
+		if value == "" {
+			continue
+		}
+
+		if e := section.Child(local); e != nil {
+			e.SetText(value)
+			continue
+		}
+
+		// Insert it before the next element in the schema order.
+		var before *ovfElement
+		for _, next := range order[i+1:] {
+			if before = section.Child(next); before != nil {
+				break
+			}
+		}
+
+		section.InsertBefore(&ovfElement{
+			Name:     xml.Name{Space: prefix, Local: local},
+			Children: []interface{}{xml.CharData(value)},
+		}, before)
+	}
+
+	return nil
+}
+
+// RemoveNetworkAdapters removes the network adapters of the virtual
+// system along with the network section, since the networks of the
+// host the VM was built on are meaningless elsewhere.
+func (d *ovfDescriptor) RemoveNetworkAdapters() error {
+	section, items, err := d.hardwareItems()
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if ovfItemValue(item, "ResourceType") == ovfResourceNIC {
+			section.Remove(item)
+		}
+	}
+
+	if network := d.root.Child("NetworkSection"); network != nil {
+		d.root.Remove(network)
+	}
+
+	return nil
+}
+
+// SetDiskController changes the type of the controllers that the disks
+// are attached to. Other controllers, such as one that only has a CD-ROM
+// drive, are left alone.
+func (d *ovfDescriptor) SetDiskController(controller string) error {
+	kind, ok := ovfDiskControllers[controller]
+	if !ok {
+		return fmt.Errorf("Unknown disk controller: %s", controller)
+	}
+
+	_, items, err := d.hardwareItems()
+	if err != nil {
+		return err
+	}
+
+	parents := make(map[string]bool)
+	for _, item := range items {
+		if ovfItemValue(item, "ResourceType") == ovfResourceDisk {
+			parents[ovfItemValue(item, "Parent")] = true
+		}
+	}
+
+	count := 0
+	for _, item := range items {
+		if !parents[ovfItemValue(item, "InstanceID")] {
+			continue
+		}
+
+		resourceType := item.Child("ResourceType")
+		if resourceType == nil {
+			return fmt.Errorf(
+				"Disk controller %s has no ResourceType", ovfItemValue(item, "InstanceID"))
+		}
+		resourceType.SetText(kind[0])
+
+		subtype := item.Child("ResourceSubType")
+		if subtype == nil {
+			subtype = &ovfElement{
+				Name: xml.Name{Space: resourceType.Name.Space, Local: "ResourceSubType"},
+			}
+
+			// The resource allocation elements are in alphabetical order.
+			item.InsertBefore(subtype, resourceType)
+		}
+		subtype.SetText(kind[1])
+
+		for _, local := range []string{"Caption", "ElementName"} {
+			if e := item.Child(local); e != nil {
+				e.SetText(fmt.Sprintf("%sController%d", controller, count))
+			}
+		}
+		count++
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package common
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// manifestDigests maps the values of manifest_digest to the hash used and
+// its name in the manifest.
+var manifestDigests = map[string]struct {
+	Name string
+	Hash crypto.Hash
+	New  func() hash.Hash
+}{
+	"sha1":   {"SHA1", crypto.SHA1, sha1.New},
+	"sha256": {"SHA256", crypto.SHA256, sha256.New},
+}
+
+// writeManifest writes an OVF manifest to the given path with the digest
+// of each of the given files, which are relative to the directory of the
+// manifest.
+func writeManifest(path string, files []string, digest string) error {
+	d, ok := manifestDigests[digest]
+	if !ok {
+		return fmt.Errorf("Unknown manifest digest: %s", digest)
+	}
+
+	var buf bytes.Buffer
+	dir := filepath.Dir(path)
+	for _, name := range files {
+		sum, err := fileDigest(filepath.Join(dir, name), d.New())
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(&buf, "%s(%s)= %x\n", d.Name, name, sum)
+	}
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// signManifest signs the manifest at the given path with the private key
+// and writes the signature and the certificate to the certificate file
+// next to it, which has the same name with a ".cert" extension.
+func signManifest(path, certPath, keyPath, digest string) (string, error) {
+	d, ok := manifestDigests[digest]
+	if !ok {
+		return "", fmt.Errorf("Unknown manifest digest: %s", digest)
+	}
+
+	cert, certPEM, err := readCertificate(certPath)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := readPrivateKey(keyPath)
+	if err != nil {
+		return "", err
+	}
+
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok || pub.N.Cmp(key.N) != 0 || pub.E != key.E {
+		return "", errors.New("signing_key doesn't match signing_certificate")
+	}
+
+	sum, err := fileDigest(path, d.New())
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, d.Hash, sum)
+	if err != nil {
+		return "", fmt.Errorf("Error signing manifest: %s", err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s(%s)= %x\n", d.Name, filepath.Base(path), signature)
+	buf.Write(certPEM)
+
+	result := path[:len(path)-len(filepath.Ext(path))] + ".cert"
+	if err := ioutil.WriteFile(result, buf.Bytes(), 0644); err != nil {
+		return "", err
+	}
+
+	return result, nil
+}
+
+func fileDigest(path string, h hash.Hash) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+
+	return h.Sum(nil), nil
+}
+
+// readCertificate reads a PEM encoded X.509 certificate and returns it
+// along with its PEM encoding.
+func readCertificate(path string) (*x509.Certificate, []byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, nil, fmt.Errorf("No PEM encoded certificate found in %s", path)
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error parsing certificate %s: %s", path, err)
+	}
+
+	return cert, pem.EncodeToMemory(block), nil
+}
+
+// readPrivateKey reads a PEM encoded RSA private key, in either PKCS #1
+// or PKCS #8 form.
+func readPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("No PEM encoded private key found in %s", path)
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("Private key in %s is not an RSA key", path)
+		}
+
+		return rsaKey, nil
+	}
+
+	return nil, fmt.Errorf("Unsupported private key type in %s: %s", path, block.Type)
+}
@@ -0,0 +1,179 @@
+package common
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testManifestDir(t *testing.T) string {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, name := range []string{"packer.ovf", "packer-disk1.vmdk"} {
+		err := ioutil.WriteFile(filepath.Join(td, name), []byte(name), 0644)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	return td
+}
+
+// testSigningKey writes a new RSA key and a self-signed certificate for
+// it to the given directory and returns their paths.
+func testSigningKey(t *testing.T, dir string) (string, string, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "packer"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	certPath := filepath.Join(dir, "cert.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := ioutil.WriteFile(certPath, certPEM, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	keyPath := filepath.Join(dir, "key.pem")
+	keyPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+	if err := ioutil.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return certPath, keyPath, key
+}
+
+func TestWriteManifest(t *testing.T) {
+	td := testManifestDir(t)
+	defer os.RemoveAll(td)
+
+	path := filepath.Join(td, "packer.mf")
+	err := writeManifest(path, []string{"packer.ovf", "packer-disk1.vmdk"}, "sha256")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	lines := strings.Split(string(data), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("bad: %s", data)
+	}
+
+	sum := sha256.Sum256([]byte("packer.ovf"))
+	expected := "SHA256(packer.ovf)= " + hex.EncodeToString(sum[:])
+	if lines[0] != expected {
+		t.Fatalf("bad: %s", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "SHA256(packer-disk1.vmdk)= ") {
+		t.Fatalf("bad: %s", lines[1])
+	}
+
+	if err := writeManifest(path, nil, "md5"); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestSignManifest(t *testing.T) {
+	td := testManifestDir(t)
+	defer os.RemoveAll(td)
+
+	path := filepath.Join(td, "packer.mf")
+	if err := writeManifest(path, []string{"packer.ovf"}, "sha256"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	certPath, keyPath, key := testSigningKey(t, td)
+	result, err := signManifest(path, certPath, keyPath, "sha256")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if result != filepath.Join(td, "packer.cert") {
+		t.Fatalf("bad: %s", result)
+	}
+
+	data, err := ioutil.ReadFile(result)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The first line is the signature, followed by the certificate
+	parts := strings.SplitN(string(data), "\n", 2)
+	prefix := "SHA256(packer.mf)= "
+	if !strings.HasPrefix(parts[0], prefix) {
+		t.Fatalf("bad: %s", parts[0])
+	}
+	if !strings.HasPrefix(parts[1], "-----BEGIN CERTIFICATE-----") {
+		t.Fatalf("bad: %s", parts[1])
+	}
+
+	signature, err := hex.DecodeString(strings.TrimPrefix(parts[0], prefix))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	manifest, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	sum := sha256.Sum256(manifest)
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, sum[:], signature); err != nil {
+		t.Fatalf("bad signature: %s", err)
+	}
+}
+
+func TestSignManifest_keyMismatch(t *testing.T) {
+	td := testManifestDir(t)
+	defer os.RemoveAll(td)
+
+	path := filepath.Join(td, "packer.mf")
+	if err := writeManifest(path, []string{"packer.ovf"}, "sha256"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	certPath, _, _ := testSigningKey(t, td)
+
+	otherDir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(otherDir)
+	_, keyPath, _ := testSigningKey(t, otherDir)
+
+	if _, err := signManifest(path, certPath, keyPath, "sha256"); err == nil {
+		t.Fatal("should have error")
+	}
+}
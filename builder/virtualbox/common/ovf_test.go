@@ -0,0 +1,209 @@
+package common
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const testOVF = `<?xml version="1.0"?>
+<Envelope ovf:version="1.0" xml:lang="en-US" xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vssd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData" xmlns:vbox="http://www.virtualbox.org/ovf/machine">
+  <References>
+    <File ovf:href="packer-disk1.vmdk" ovf:id="file1"/>
+  </References>
+  <NetworkSection>
+    <Info>Logical networks used in the package</Info>
+    <Network ovf:name="NAT">
+      <Description>Logical network used by this appliance.</Description>
+    </Network>
+  </NetworkSection>
+  <VirtualSystem ovf:id="packer">
+    <Info>A virtual machine</Info>
+    <OperatingSystemSection ovf:id="96">
+      <Info>The kind of installed guest operating system</Info>
+      <vbox:OSType ovf:required="false">Debian_64</vbox:OSType>
+    </OperatingSystemSection>
+    <VirtualHardwareSection>
+      <Info>Virtual hardware requirements for a virtual machine</Info>
+      <Item>
+        <rasd:Address>0</rasd:Address>
+        <rasd:Caption>ideController0</rasd:Caption>
+        <rasd:ElementName>ideController0</rasd:ElementName>
+        <rasd:InstanceID>3</rasd:InstanceID>
+        <rasd:ResourceSubType>PIIX4</rasd:ResourceSubType>
+        <rasd:ResourceType>5</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:Caption>sataController0</rasd:Caption>
+        <rasd:ElementName>sataController0</rasd:ElementName>
+        <rasd:InstanceID>4</rasd:InstanceID>
+        <rasd:ResourceSubType>AHCI</rasd:ResourceSubType>
+        <rasd:ResourceType>20</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:AutomaticAllocation>true</rasd:AutomaticAllocation>
+        <rasd:Caption>Ethernet adapter on 'NAT'</rasd:Caption>
+        <rasd:Connection>NAT</rasd:Connection>
+        <rasd:ElementName>Ethernet adapter on 'NAT'</rasd:ElementName>
+        <rasd:InstanceID>5</rasd:InstanceID>
+        <rasd:ResourceSubType>E1000</rasd:ResourceSubType>
+        <rasd:ResourceType>10</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:AddressOnParent>0</rasd:AddressOnParent>
+        <rasd:Caption>disk1</rasd:Caption>
+        <rasd:ElementName>disk1</rasd:ElementName>
+        <rasd:HostResource>/disk/vmdisk1</rasd:HostResource>
+        <rasd:InstanceID>6</rasd:InstanceID>
+        <rasd:Parent>4</rasd:Parent>
+        <rasd:ResourceType>17</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:AddressOnParent>0</rasd:AddressOnParent>
+        <rasd:AutomaticAllocation>true</rasd:AutomaticAllocation>
+        <rasd:Caption>cdrom1</rasd:Caption>
+        <rasd:ElementName>cdrom1</rasd:ElementName>
+        <rasd:InstanceID>7</rasd:InstanceID>
+        <rasd:Parent>3</rasd:Parent>
+        <rasd:ResourceType>15</rasd:ResourceType>
+      </Item>
+    </VirtualHardwareSection>
+    <vbox:Machine ovf:required="false" version="1.12-linux" uuid="{e3b0c442-98fc-1c14-9afb-f4c8996fb924}" name="packer">
+      <ovf:Info>Complete VirtualBox machine configuration in VirtualBox format</ovf:Info>
+      <Hardware version="2"/>
+    </vbox:Machine>
+  </VirtualSystem>
+</Envelope>
+`
+
+func testOVFDescriptor(t *testing.T) *ovfDescriptor {
+	d, err := parseOVF(strings.NewReader(testOVF))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return d
+}
+
+func testOVFString(t *testing.T, d *ovfDescriptor) string {
+	var buf bytes.Buffer
+	if err := d.Write(&buf); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return buf.String()
+}
+
+func TestParseOVF_roundTrip(t *testing.T) {
+	d := testOVFDescriptor(t)
+	if result := testOVFString(t, d); result != testOVF {
+		t.Fatalf("bad:\n%s", result)
+	}
+}
+
+func TestParseOVF_bad(t *testing.T) {
+	if _, err := parseOVF(strings.NewReader("<Envelope>")); err == nil {
+		t.Fatal("should have error")
+	}
+
+	if _, err := parseOVF(strings.NewReader("")); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestOVFDescriptorFiles(t *testing.T) {
+	d := testOVFDescriptor(t)
+	files := d.Files()
+	if len(files) != 1 || files[0] != "packer-disk1.vmdk" {
+		t.Fatalf("bad: %#v", files)
+	}
+}
+
+func TestOVFDescriptorSetProduct(t *testing.T) {
+	d := testOVFDescriptor(t)
+	if err := d.SetProduct("Packer", "", "HashiCorp", "http://www.packer.io", "1.0"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := `    <ProductSection>
+      <Info>Meta-information about the installed software</Info>
+      <Product>Packer</Product>
+      <Vendor>HashiCorp</Vendor>
+      <Version>1.0</Version>
+      <FullVersion>1.0</FullVersion>
+      <VendorUrl>http://www.packer.io</VendorUrl>
+    </ProductSection>
+    <VirtualHardwareSection>`
+
+	result := testOVFString(t, d)
+	if !strings.Contains(result, expected) {
+		t.Fatalf("bad:\n%s", result)
+	}
+
+	// Setting it again updates the existing section
+	if err := d.SetProduct("", "http://www.packer.io/docs", "", "", "1.1"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected = `    <ProductSection>
+      <Info>Meta-information about the installed software</Info>
+      <Product>Packer</Product>
+      <Vendor>HashiCorp</Vendor>
+      <Version>1.1</Version>
+      <FullVersion>1.1</FullVersion>
+      <ProductUrl>http://www.packer.io/docs</ProductUrl>
+      <VendorUrl>http://www.packer.io</VendorUrl>
+    </ProductSection>`
+
+	result = testOVFString(t, d)
+	if !strings.Contains(result, expected) {
+		t.Fatalf("bad:\n%s", result)
+	}
+}
+
+func TestOVFDescriptorRemoveNetworkAdapters(t *testing.T) {
+	d := testOVFDescriptor(t)
+	if err := d.RemoveNetworkAdapters(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	result := testOVFString(t, d)
+	if strings.Contains(result, "NetworkSection") {
+		t.Fatalf("bad:\n%s", result)
+	}
+	if strings.Contains(result, "<rasd:ResourceType>10</rasd:ResourceType>") {
+		t.Fatalf("bad:\n%s", result)
+	}
+	if !strings.Contains(result, "      </Item>\n      <Item>\n        <rasd:AddressOnParent>0") {
+		t.Fatalf("bad:\n%s", result)
+	}
+}
+
+func TestOVFDescriptorSetDiskController(t *testing.T) {
+	d := testOVFDescriptor(t)
+	if err := d.SetDiskController("scsi"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := `      <Item>
+        <rasd:Caption>scsiController0</rasd:Caption>
+        <rasd:ElementName>scsiController0</rasd:ElementName>
+        <rasd:InstanceID>4</rasd:InstanceID>
+        <rasd:ResourceSubType>lsilogic</rasd:ResourceSubType>
+        <rasd:ResourceType>6</rasd:ResourceType>
+      </Item>`
+
+	result := testOVFString(t, d)
+	if !strings.Contains(result, expected) {
+		t.Fatalf("bad:\n%s", result)
+	}
+
+	// The IDE controller only has the CD-ROM drive on it
+	if !strings.Contains(result, "<rasd:ResourceType>5</rasd:ResourceType>") {
+		t.Fatalf("bad:\n%s", result)
+	}
+
+	if err := d.SetDiskController("floppy"); err == nil {
+		t.Fatal("should have error")
+	}
+}
@@ -0,0 +1,86 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mitchellh/packer/common"
+	"github.com/mitchellh/packer/packer"
+)
+
+// RemoteConfig configures building on a remote VirtualBox host rather
+// than on the machine that Packer runs on.
+type RemoteConfig struct {
+	RemoteType        string `mapstructure:"remote_type"`
+	RemoteHost        string `mapstructure:"remote_host"`
+	RemotePort        uint   `mapstructure:"remote_port"`
+	RemoteUser        string `mapstructure:"remote_username"`
+	RemotePassword    string `mapstructure:"remote_password"`
+	RemoteSSHPort     uint   `mapstructure:"remote_ssh_port"`
+	RemoteSSHUser     string `mapstructure:"remote_ssh_username"`
+	RemoteSSHPassword string `mapstructure:"remote_ssh_password"`
+	RemoteDir         string `mapstructure:"remote_directory"`
+}
+
+func (c *RemoteConfig) Prepare(t *packer.ConfigTemplate, pc *common.PackerConfig) []error {
+	if c.RemoteType == "" {
+		return nil
+	}
+
+	if c.RemotePort == 0 {
+		c.RemotePort = 18083
+	}
+
+	if c.RemoteSSHPort == 0 {
+		c.RemoteSSHPort = 22
+	}
+
+	if c.RemoteDir == "" {
+		c.RemoteDir = fmt.Sprintf("packer-%s", pc.PackerBuildName)
+	}
+
+	templates := map[string]*string{
+		"remote_type":         &c.RemoteType,
+		"remote_host":         &c.RemoteHost,
+		"remote_username":     &c.RemoteUser,
+		"remote_password":     &c.RemotePassword,
+		"remote_ssh_username": &c.RemoteSSHUser,
+		"remote_ssh_password": &c.RemoteSSHPassword,
+		"remote_directory":    &c.RemoteDir,
+	}
+
+	errs := make([]error, 0)
+	for n, ptr := range templates {
+		var err error
+		*ptr, err = t.Process(*ptr, nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Error processing %s: %s", n, err))
+		}
+	}
+
+	if c.RemoteType != "vboxwebsrv" {
+		errs = append(errs,
+			errors.New("invalid remote_type, only 'vboxwebsrv' is allowed"))
+	}
+
+	if c.RemoteHost == "" {
+		errs = append(errs,
+			errors.New("remote_host must be specified with remote_type"))
+	}
+
+	// The SSH credentials default to those of the web service, since
+	// vboxwebsrv usually authenticates against the host's users.
+	if c.RemoteSSHUser == "" {
+		c.RemoteSSHUser = c.RemoteUser
+	}
+
+	if c.RemoteSSHPassword == "" {
+		c.RemoteSSHPassword = c.RemotePassword
+	}
+
+	if c.RemoteSSHUser == "" {
+		errs = append(errs,
+			errors.New("remote_username or remote_ssh_username must be specified with remote_type"))
+	}
+
+	return errs
+}
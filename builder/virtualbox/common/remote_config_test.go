@@ -0,0 +1,68 @@
+package common
+
+import (
+	"github.com/mitchellh/packer/common"
+	"testing"
+)
+
+func testRemoteConfig() *RemoteConfig {
+	return &RemoteConfig{
+		RemoteType: "vboxwebsrv",
+		RemoteHost: "vbox.example.com",
+		RemoteUser: "packer",
+	}
+}
+
+func TestRemoteConfigPrepare_empty(t *testing.T) {
+	c := new(RemoteConfig)
+	errs := c.Prepare(testConfigTemplate(t), &common.PackerConfig{})
+	if len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+
+	if c.RemotePort != 0 {
+		t.Fatalf("bad: %d", c.RemotePort)
+	}
+}
+
+func TestRemoteConfigPrepare_defaults(t *testing.T) {
+	c := testRemoteConfig()
+	errs := c.Prepare(testConfigTemplate(t), &common.PackerConfig{PackerBuildName: "foo"})
+	if len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+
+	if c.RemotePort != 18083 {
+		t.Fatalf("bad: %d", c.RemotePort)
+	}
+
+	if c.RemoteSSHPort != 22 {
+		t.Fatalf("bad: %d", c.RemoteSSHPort)
+	}
+
+	if c.RemoteSSHUser != "packer" {
+		t.Fatalf("bad: %s", c.RemoteSSHUser)
+	}
+
+	if c.RemoteDir != "packer-foo" {
+		t.Fatalf("bad: %s", c.RemoteDir)
+	}
+}
+
+func TestRemoteConfigPrepare_RemoteType(t *testing.T) {
+	c := testRemoteConfig()
+	c.RemoteType = "bad"
+	errs := c.Prepare(testConfigTemplate(t), &common.PackerConfig{})
+	if len(errs) == 0 {
+		t.Fatal("should have error")
+	}
+}
+
+func TestRemoteConfigPrepare_RemoteHost(t *testing.T) {
+	c := testRemoteConfig()
+	c.RemoteHost = ""
+	errs := c.Prepare(testConfigTemplate(t), &common.PackerConfig{})
+	if len(errs) == 0 {
+		t.Fatal("should have error")
+	}
+}
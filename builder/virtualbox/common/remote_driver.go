@@ -0,0 +1,50 @@
+package common
+
+import (
+	"fmt"
+)
+
+// A RemoteDriver is a Driver that manages VMs on a VirtualBox host other
+// than the machine that Packer runs on.
+type RemoteDriver interface {
+	Driver
+
+	// HostAddress returns the address of the VirtualBox host. Ports
+	// forwarded to the guest are reachable on this address.
+	HostAddress() string
+
+	// HostIP returns the IP address of the machine Packer runs on, as
+	// seen from the VirtualBox host.
+	HostIP() (string, error)
+
+	// UploadFile uploads a local file to the VirtualBox host and returns
+	// its path on the host.
+	UploadFile(string) (string, error)
+}
+
+// NewRemoteDriver returns the remote driver for the given configuration,
+// or an error if it couldn't be initialized.
+func NewRemoteDriver(config *RemoteConfig) (Driver, error) {
+	var driver RemoteDriver
+	switch config.RemoteType {
+	case "vboxwebsrv":
+		driver = &VBoxWebDriver{
+			Host:        config.RemoteHost,
+			Port:        config.RemotePort,
+			Username:    config.RemoteUser,
+			Password:    config.RemotePassword,
+			SSHPort:     config.RemoteSSHPort,
+			SSHUsername: config.RemoteSSHUser,
+			SSHPassword: config.RemoteSSHPassword,
+			RemoteDir:   config.RemoteDir,
+		}
+	default:
+		return nil, fmt.Errorf("Unknown remote_type: %s", config.RemoteType)
+	}
+
+	if err := driver.Verify(); err != nil {
+		return nil, err
+	}
+
+	return driver, nil
+}
@@ -11,7 +11,14 @@ import (
 
 func SSHAddress(state multistep.StateBag) (string, error) {
 	sshHostPort := state.Get("sshHostPort").(uint)
-	return fmt.Sprintf("127.0.0.1:%d", sshHostPort), nil
+
+	// On a remote host the forwarded port is on the host, not here.
+	host := "127.0.0.1"
+	if remote, ok := state.Get("driver").(RemoteDriver); ok {
+		host = remote.HostAddress()
+	}
+
+	return fmt.Sprintf("%s:%d", host, sshHostPort), nil
 }
 
 func SSHConfigFunc(config SSHConfig) func(multistep.StateBag) (*gossh.ClientConfig, error) {
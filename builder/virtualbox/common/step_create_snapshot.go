@@ -0,0 +1,43 @@
+package common
+
+import (
+	"fmt"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// This step takes a snapshot of the provisioned VM so that it can be
+// reverted to later. It does nothing if no snapshot name is given.
+//
+// Uses:
+//   driver Driver
+//   ui     packer.Ui
+//   vmName string
+//
+// Produces:
+//   <nothing>
+type StepCreateSnapshot struct {
+	Name string
+}
+
+func (s *StepCreateSnapshot) Run(state multistep.StateBag) multistep.StepAction {
+	if s.Name == "" {
+		return multistep.ActionContinue
+	}
+
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+	vmName := state.Get("vmName").(string)
+
+	ui.Say(fmt.Sprintf("Creating snapshot: %s", s.Name))
+	if err := driver.CreateSnapshot(vmName, s.Name); err != nil {
+		err := fmt.Errorf("Error creating snapshot: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepCreateSnapshot) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,242 @@
+package common
+
+import (
+	"archive/tar"
+	"fmt"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// This step edits the OVF descriptor of the exported VM, regenerates
+// the manifest and signs it, as configured. OVA files are unpacked and
+// packed again around the edit.
+//
+// Uses:
+//   exportPath string
+//   ui         packer.Ui
+//
+// Produces:
+//   <nothing>
+type StepEditOVF struct {
+	Config *ExportConfig
+}
+
+func (s *StepEditOVF) Run(state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packer.Ui)
+
+	if !s.Config.EditOVF() && s.Config.ManifestDigest == "" {
+		return multistep.ActionContinue
+	}
+
+	// There's nothing to edit if the export was skipped.
+	raw, ok := state.GetOk("exportPath")
+	if !ok {
+		return multistep.ActionContinue
+	}
+	exportPath := raw.(string)
+
+	ui.Say("Editing exported OVF...")
+
+	var err error
+	if strings.ToLower(filepath.Ext(exportPath)) == ".ova" {
+		err = s.editOVA(exportPath)
+	} else {
+		_, err = s.editOVF(exportPath)
+	}
+
+	if err != nil {
+		err := fmt.Errorf("Error editing OVF: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepEditOVF) Cleanup(state multistep.StateBag) {}
+
+// editOVF edits the descriptor at the given path and writes the manifest
+// and certificate next to it. It returns the names of the files that make
+// up the appliance, in the order they belong in an OVA.
+func (s *StepEditOVF) editOVF(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := parseOVF(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	c := s.Config
+	if c.OVFProduct != "" || c.OVFProductURL != "" || c.OVFVendor != "" ||
+		c.OVFVendorURL != "" || c.OVFVersion != "" {
+		err := d.SetProduct(
+			c.OVFProduct, c.OVFProductURL, c.OVFVendor, c.OVFVendorURL, c.OVFVersion)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.OVFRemoveNICs {
+		if err := d.RemoveNetworkAdapters(); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.OVFDiskController != "" {
+		if err := d.SetDiskController(c.OVFDiskController); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err = os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.Write(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	ovfName := filepath.Base(path)
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	files := append([]string{ovfName}, d.Files()...)
+
+	log.Printf("Writing manifest with %s digests", c.ManifestDigest)
+	mfPath := base + ".mf"
+	if err := writeManifest(mfPath, files, c.ManifestDigest); err != nil {
+		return nil, err
+	}
+
+	result := []string{ovfName, filepath.Base(mfPath)}
+	if c.SigningCert != "" {
+		log.Printf("Signing manifest with %s", c.SigningCert)
+		certPath, err := signManifest(mfPath, c.SigningCert, c.SigningKey, c.ManifestDigest)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, filepath.Base(certPath))
+	}
+
+	return append(result, d.Files()...), nil
+}
+
+// editOVA unpacks the OVA at the given path, edits it, and packs it
+// again in place.
+func (s *StepEditOVF) editOVA(path string) error {
+	dir, err := ioutil.TempDir(filepath.Dir(path), "ova")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	// Unpack the OVA. The descriptor is the first file in it.
+	var ovfName string
+	if err := untar(path, dir, func(name string) {
+		if ovfName == "" {
+			ovfName = name
+		}
+	}); err != nil {
+		return err
+	}
+
+	if ovfName == "" {
+		return fmt.Errorf("No OVF descriptor in %s", path)
+	}
+
+	files, err := s.editOVF(filepath.Join(dir, ovfName))
+	if err != nil {
+		return err
+	}
+
+	return tarFiles(path, dir, files)
+}
+
+func untar(path, dir string, visit func(string)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := tar.NewReader(f)
+	for {
+		hdr, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// The files of an appliance are all at the top level.
+		name := filepath.Base(hdr.Name)
+		visit(name)
+
+		out, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(out, r)
+		out.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func tarFiles(path, dir string, files []string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := tar.NewWriter(f)
+	for _, name := range files {
+		if err := tarFile(w, filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+
+	return w.Close()
+}
+
+func tarFile(w *tar.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+
+	if err := w.WriteHeader(hdr); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,80 @@
+package common
+
+import (
+	"github.com/mitchellh/multistep"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStepEditOVF_impl(t *testing.T) {
+	var _ multistep.Step = new(StepEditOVF)
+}
+
+func TestStepEditOVF(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	ovfPath := filepath.Join(td, "packer.ovf")
+	if err := ioutil.WriteFile(ovfPath, []byte(testOVF), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	diskPath := filepath.Join(td, "packer-disk1.vmdk")
+	if err := ioutil.WriteFile(diskPath, []byte("disk"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state := testState(t)
+	state.Put("exportPath", ovfPath)
+
+	config := &ExportConfig{OVFRemoveNICs: true}
+	if errs := config.Prepare(testConfigTemplate(t)); len(errs) > 0 {
+		t.Fatalf("errs: %s", errs)
+	}
+	step := &StepEditOVF{Config: config}
+
+	// Test the run
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+
+	data, err := ioutil.ReadFile(ovfPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if strings.Contains(string(data), "NetworkSection") {
+		t.Fatalf("bad: %s", data)
+	}
+
+	data, err = ioutil.ReadFile(filepath.Join(td, "packer.mf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !strings.HasPrefix(string(data), "SHA256(packer.ovf)= ") {
+		t.Fatalf("bad: %s", data)
+	}
+	if !strings.Contains(string(data), "SHA256(packer-disk1.vmdk)= ") {
+		t.Fatalf("bad: %s", data)
+	}
+}
+
+func TestStepEditOVF_noExport(t *testing.T) {
+	state := testState(t)
+	step := &StepEditOVF{Config: &ExportConfig{OVFRemoveNICs: true}}
+
+	// Test the run
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+}
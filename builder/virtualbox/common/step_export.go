@@ -20,6 +20,7 @@ type StepExport struct {
 	Format     string
 	OutputDir  string
 	ExportOpts []string
+	SkipExport bool
 }
 
 func (s *StepExport) Run(state multistep.StateBag) multistep.StepAction {
@@ -44,6 +45,11 @@ func (s *StepExport) Run(state multistep.StateBag) multistep.StepAction {
 		return multistep.ActionHalt
 	}
 
+	if s.SkipExport {
+		ui.Say("Skipping export of virtual machine...")
+		return multistep.ActionContinue
+	}
+
 	// Export the VM to an OVF
 	outputPath := filepath.Join(s.OutputDir, vmName+"."+s.Format)
 
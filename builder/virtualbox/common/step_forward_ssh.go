@@ -7,6 +7,9 @@ import (
 	"log"
 	"math/rand"
 	"net"
+	"strconv"
+	"syscall"
+	"time"
 )
 
 // This step adds a NAT port forwarding definition so that SSH is available
@@ -31,7 +34,6 @@ func (s *StepForwardSSH) Run(state multistep.StateBag) multistep.StepAction {
 
 	log.Printf("Looking for available SSH port between %d and %d",
 		s.HostPortMin, s.HostPortMax)
-	var sshHostPort uint
 	var offset uint = 0
 
 	portRange := int(s.HostPortMax - s.HostPortMin)
@@ -40,22 +42,47 @@ func (s *StepForwardSSH) Run(state multistep.StateBag) multistep.StepAction {
 		offset = uint(rand.Intn(portRange))
 	}
 
-	for {
-		sshHostPort = offset + s.HostPortMin
-		log.Printf("Trying port: %d", sshHostPort)
-		l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", sshHostPort))
-		if err == nil {
-			defer l.Close()
-			break
+	// On a remote host the port must be free on that host, and the rule
+	// must listen on all of its addresses for Packer to reach it.
+	remote, isRemote := driver.(RemoteDriver)
+	hostIP := "127.0.0.1"
+	if isRemote {
+		hostIP = ""
+	}
+
+	var sshHostPort uint
+	for i := 0; i <= portRange; i++ {
+		port := s.HostPortMin + (offset+uint(i))%uint(portRange+1)
+		log.Printf("Trying port: %d", port)
+		if isRemote {
+			if remotePortFree(remote.HostAddress(), port) {
+				sshHostPort = port
+				break
+			}
+		} else {
+			l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+			if err == nil {
+				defer l.Close()
+				sshHostPort = port
+				break
+			}
 		}
 	}
 
+	if sshHostPort == 0 {
+		err := fmt.Errorf("No free port found for SSH between %d and %d",
+			s.HostPortMin, s.HostPortMax)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
 	// Create a forwarded port mapping to the VM
 	ui.Say(fmt.Sprintf("Creating forwarded port mapping for SSH (host port %d)", sshHostPort))
 	command := []string{
 		"modifyvm", vmName,
 		"--natpf1",
-		fmt.Sprintf("packerssh,tcp,127.0.0.1,%d,,%d", sshHostPort, s.GuestPort),
+		fmt.Sprintf("packerssh,tcp,%s,%d,,%d", hostIP, sshHostPort, s.GuestPort),
 	}
 	if err := driver.VBoxManage(command...); err != nil {
 		err := fmt.Errorf("Error creating port forwarding rule: %s", err)
@@ -71,3 +98,22 @@ func (s *StepForwardSSH) Run(state multistep.StateBag) multistep.StepAction {
 }
 
 func (s *StepForwardSSH) Cleanup(state multistep.StateBag) {}
+
+// remotePortFree checks if nothing listens on the given port of a remote
+// host, which is the case if connecting to it is refused.
+func remotePortFree(host string, port uint) bool {
+	address := net.JoinHostPort(host, strconv.Itoa(int(port)))
+	conn, err := net.DialTimeout("tcp", address, 1*time.Second)
+	if err == nil {
+		log.Printf("Port %d in use on %s", port, host)
+		conn.Close()
+		return false
+	}
+
+	if e, ok := err.(*net.OpError); ok && e.Err == syscall.ECONNREFUSED {
+		return true
+	}
+
+	log.Printf("Port %d on %s can't be checked: %s", port, host, err)
+	return false
+}
@@ -0,0 +1,157 @@
+package common
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// vboxwebClient is a minimal SOAP client for the VirtualBox web service
+// (vboxwebsrv). Every call is a method of the form "IInterface_method"
+// whose arguments are simple values or managed object references, so
+// requests are built by hand instead of from the WSDL.
+type vboxwebClient struct {
+	URL    string
+	Client *http.Client
+}
+
+// soapArg is a single named argument of a SOAP call. The value may be a
+// string, an integer, a bool or a slice of strings, which is encoded as
+// a repeated element.
+type soapArg struct {
+	Name  string
+	Value interface{}
+}
+
+// Call calls the given method and returns the values of the output
+// parameters by name. The return value of the method is "returnval", and
+// parameters that are arrays have one value per element.
+func (c *vboxwebClient) Call(method string, args ...soapArg) (map[string][]string, error) {
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0" encoding="UTF-8"?>`)
+	body.WriteString(`<SOAP-ENV:Envelope xmlns:SOAP-ENV="http://schemas.xmlsoap.org/soap/envelope/" xmlns:vbox="http://www.virtualbox.org/">`)
+	body.WriteString(`<SOAP-ENV:Body>`)
+	fmt.Fprintf(&body, "<vbox:%s>", method)
+	for _, arg := range args {
+		switch v := arg.Value.(type) {
+		case []string:
+			for _, item := range v {
+				writeSOAPElement(&body, arg.Name, item)
+			}
+		case string:
+			writeSOAPElement(&body, arg.Name, v)
+		case bool:
+			writeSOAPElement(&body, arg.Name, strconv.FormatBool(v))
+		default:
+			writeSOAPElement(&body, arg.Name, fmt.Sprintf("%v", v))
+		}
+	}
+	fmt.Fprintf(&body, "</vbox:%s>", method)
+	body.WriteString(`</SOAP-ENV:Body></SOAP-ENV:Envelope>`)
+
+	log.Printf("vboxwebsrv call: %s", method)
+	req, err := http.NewRequest("POST", c.URL, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	req.Header.Set("SOAPAction", `""`)
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error calling %s: %s", method, err)
+	}
+	defer resp.Body.Close()
+
+	result, err := decodeSOAPResponse(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Error calling %s: %s", method, err)
+	}
+
+	return result, nil
+}
+
+// CallOne calls the given method and returns its single return value.
+func (c *vboxwebClient) CallOne(method string, args ...soapArg) (string, error) {
+	result, err := c.Call(method, args...)
+	if err != nil {
+		return "", err
+	}
+
+	values := result["returnval"]
+	if len(values) == 0 {
+		return "", fmt.Errorf("No value returned from %s", method)
+	}
+
+	return values[0], nil
+}
+
+func writeSOAPElement(w io.Writer, name, value string) {
+	fmt.Fprintf(w, "<%s>", name)
+	xml.EscapeText(w, []byte(value))
+	fmt.Fprintf(w, "</%s>", name)
+}
+
+// decodeSOAPResponse reads a SOAP response envelope and returns the
+// text of every element of the response message by name, or the fault
+// string as an error.
+func decodeSOAPResponse(r io.Reader) (map[string][]string, error) {
+	dec := xml.NewDecoder(r)
+
+	// The elements we want are nested as Envelope > Body > Response > out.
+	const outDepth = 4
+
+	result := make(map[string][]string)
+	var text bytes.Buffer
+	var depth int
+	var inFault bool
+	var faultString string
+
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if t.Name.Local == "Fault" {
+				inFault = true
+			}
+			text.Reset()
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if inFault && t.Name.Local == "faultstring" {
+				faultString = strings.TrimSpace(text.String())
+			} else if !inFault && depth == outDepth {
+				result[t.Name.Local] = append(result[t.Name.Local], text.String())
+			}
+			depth--
+			text.Reset()
+		}
+	}
+
+	if inFault {
+		if faultString == "" {
+			faultString = "unknown SOAP fault"
+		}
+		return nil, fmt.Errorf("vboxwebsrv: %s", faultString)
+	}
+
+	return result, nil
+}
@@ -24,6 +24,7 @@ type config struct {
 	vboxcommon.ExportOpts           `mapstructure:",squash"`
 	vboxcommon.FloppyConfig         `mapstructure:",squash"`
 	vboxcommon.OutputConfig         `mapstructure:",squash"`
+	vboxcommon.RemoteConfig         `mapstructure:",squash"`
 	vboxcommon.RunConfig            `mapstructure:",squash"`
 	vboxcommon.ShutdownConfig       `mapstructure:",squash"`
 	vboxcommon.SSHConfig            `mapstructure:",squash"`
@@ -45,6 +46,9 @@ type config struct {
 	ISOChecksum          string   `mapstructure:"iso_checksum"`
 	ISOChecksumType      string   `mapstructure:"iso_checksum_type"`
 	ISOUrls              []string `mapstructure:"iso_urls"`
+	KeepRegistered       bool     `mapstructure:"keep_registered"`
+	SkipExport           bool     `mapstructure:"skip_export"`
+	SnapshotName         string   `mapstructure:"snapshot_name"`
 	VMName               string   `mapstructure:"vm_name"`
 
 	RawSingleISOUrl string `mapstructure:"iso_url"`
@@ -71,6 +75,8 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	errs = packer.MultiErrorAppend(errs, b.config.FloppyConfig.Prepare(b.config.tpl)...)
 	errs = packer.MultiErrorAppend(
 		errs, b.config.OutputConfig.Prepare(b.config.tpl, &b.config.PackerConfig)...)
+	errs = packer.MultiErrorAppend(
+		errs, b.config.RemoteConfig.Prepare(b.config.tpl, &b.config.PackerConfig)...)
 	errs = packer.MultiErrorAppend(errs, b.config.RunConfig.Prepare(b.config.tpl)...)
 	errs = packer.MultiErrorAppend(errs, b.config.ShutdownConfig.Prepare(b.config.tpl)...)
 	errs = packer.MultiErrorAppend(errs, b.config.SSHConfig.Prepare(b.config.tpl)...)
@@ -121,6 +127,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		"iso_checksum":           &b.config.ISOChecksum,
 		"iso_checksum_type":      &b.config.ISOChecksumType,
 		"iso_url":                &b.config.RawSingleISOUrl,
+		"snapshot_name":          &b.config.SnapshotName,
 		"vm_name":                &b.config.VMName,
 	}
 
@@ -233,6 +240,11 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		b.config.GuestAdditionsSHA256 = strings.ToLower(b.config.GuestAdditionsSHA256)
 	}
 
+	if b.config.SnapshotName != "" && !b.config.KeepRegistered {
+		errs = packer.MultiErrorAppend(errs,
+			errors.New("snapshot_name can only be used with keep_registered"))
+	}
+
 	// Warnings
 	if b.config.ISOChecksumType == "none" {
 		warnings = append(warnings,
@@ -246,6 +258,12 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 				"will forcibly halt the virtual machine, which may result in data loss.")
 	}
 
+	if b.config.SkipExport && !b.config.KeepRegistered {
+		warnings = append(warnings,
+			"skip_export is set without keep_registered, so the build will not\n"+
+				"produce anything: the virtual machine is deleted at the end of the build.")
+	}
+
 	if errs != nil && len(errs.Errors) > 0 {
 		return warnings, errs
 	}
@@ -255,7 +273,13 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 
 func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
 	// Create the driver that we'll use to communicate with VirtualBox
-	driver, err := vboxcommon.NewDriver()
+	var driver vboxcommon.Driver
+	var err error
+	if b.config.RemoteType != "" {
+		driver, err = vboxcommon.NewRemoteDriver(&b.config.RemoteConfig)
+	} else {
+		driver, err = vboxcommon.NewDriver()
+	}
 	if err != nil {
 		return nil, fmt.Errorf("Failed creating VirtualBox driver: %s", err)
 	}
@@ -327,10 +351,17 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Commands: b.config.VBoxManagePost,
 			Tpl:      b.config.tpl,
 		},
+		&vboxcommon.StepCreateSnapshot{
+			Name: b.config.SnapshotName,
+		},
 		&vboxcommon.StepExport{
 			Format:     b.config.Format,
 			OutputDir:  b.config.OutputDir,
 			ExportOpts: b.config.ExportOpts.ExportOpts,
+			SkipExport: b.config.SkipExport,
+		},
+		&vboxcommon.StepEditOVF{
+			Config: &b.config.ExportConfig,
 		},
 	}
 
@@ -368,6 +399,17 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		return nil, errors.New("Build was halted.")
 	}
 
+	if b.config.KeepRegistered {
+		// Nothing is exported with skip_export
+		exportPath := ""
+		if raw, ok := state.GetOk("exportPath"); ok {
+			exportPath = raw.(string)
+		}
+
+		return vboxcommon.NewRegisteredArtifact(
+			driver, state.Get("vmName").(string), b.config.OutputDir, exportPath)
+	}
+
 	return vboxcommon.NewArtifact(b.config.OutputDir)
 }
 
@@ -40,8 +40,24 @@ func (s *stepTypeBootCommand) Run(state multistep.StateBag) multistep.StepAction
 	ui := state.Get("ui").(packer.Ui)
 	vmName := state.Get("vmName").(string)
 
+	// The guest reaches the host through the NAT gateway. On a remote
+	// VirtualBox host that is the remote host, which has to go on to
+	// the machine Packer runs on.
+	hostIP := "10.0.2.2"
+	if remote, ok := driver.(vboxcommon.RemoteDriver); ok {
+		var err error
+		hostIP, err = remote.HostIP()
+		if err != nil {
+			err := fmt.Errorf("Error detecting host IP: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+	log.Printf("Host IP for the VM: %s", hostIP)
+
 	tplData := &bootCommandTemplateData{
-		"10.0.2.2",
+		hostIP,
 		httpPort,
 		config.VMName,
 	}
@@ -33,7 +33,13 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 // a VirtualBox appliance.
 func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
 	// Create the driver that we'll use to communicate with VirtualBox
-	driver, err := vboxcommon.NewDriver()
+	var driver vboxcommon.Driver
+	var err error
+	if b.config.RemoteType != "" {
+		driver, err = vboxcommon.NewRemoteDriver(&b.config.RemoteConfig)
+	} else {
+		driver, err = vboxcommon.NewDriver()
+	}
 	if err != nil {
 		return nil, fmt.Errorf("Failed creating VirtualBox driver: %s", err)
 	}
@@ -62,11 +68,26 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			GuestAdditionsSHA256: b.config.GuestAdditionsSHA256,
 			Tpl:                  b.config.tpl,
 		},
-		&StepImport{
-			Name:       b.config.VMName,
-			SourcePath: b.config.SourcePath,
-			ImportOpts: b.config.ImportOpts,
-		},
+	}
+
+	// Either clone the source VM, or import the appliance.
+	if b.config.SourceVM != "" {
+		steps = append(steps, &StepCloneVM{
+			Name:           b.config.VMName,
+			SourceVM:       b.config.SourceVM,
+			Linked:         b.config.LinkedClone,
+			KeepRegistered: b.config.KeepRegistered,
+		})
+	} else {
+		steps = append(steps, &StepImport{
+			Name:           b.config.VMName,
+			SourcePath:     b.config.SourcePath,
+			ImportOpts:     b.config.ImportOpts,
+			KeepRegistered: b.config.KeepRegistered,
+		})
+	}
+
+	steps = append(steps,
 		&vboxcommon.StepAttachGuestAdditions{
 			GuestAdditionsMode: b.config.GuestAdditionsMode,
 		},
@@ -107,12 +128,19 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Commands: b.config.VBoxManagePost,
 			Tpl:      b.config.tpl,
 		},
+		&vboxcommon.StepCreateSnapshot{
+			Name: b.config.SnapshotName,
+		},
 		&vboxcommon.StepExport{
 			Format:     b.config.Format,
 			OutputDir:  b.config.OutputDir,
 			ExportOpts: b.config.ExportOpts.ExportOpts,
+			SkipExport: b.config.SkipExport,
 		},
-	}
+		&vboxcommon.StepEditOVF{
+			Config: &b.config.ExportConfig,
+		},
+	)
 
 	// Run the steps.
 	if b.config.PackerDebug {
@@ -139,6 +167,17 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		return nil, errors.New("Build was halted.")
 	}
 
+	if b.config.KeepRegistered {
+		// Nothing is exported with skip_export
+		exportPath := ""
+		if raw, ok := state.GetOk("exportPath"); ok {
+			exportPath = raw.(string)
+		}
+
+		return vboxcommon.NewRegisteredArtifact(
+			driver, state.Get("vmName").(string), b.config.OutputDir, exportPath)
+	}
+
 	return vboxcommon.NewArtifact(b.config.OutputDir)
 }
 
@@ -17,6 +17,7 @@ type Config struct {
 	vboxcommon.ExportOpts           `mapstructure:",squash"`
 	vboxcommon.FloppyConfig         `mapstructure:",squash"`
 	vboxcommon.OutputConfig         `mapstructure:",squash"`
+	vboxcommon.RemoteConfig         `mapstructure:",squash"`
 	vboxcommon.RunConfig            `mapstructure:",squash"`
 	vboxcommon.SSHConfig            `mapstructure:",squash"`
 	vboxcommon.ShutdownConfig       `mapstructure:",squash"`
@@ -25,10 +26,15 @@ type Config struct {
 	vboxcommon.VBoxVersionConfig    `mapstructure:",squash"`
 
 	SourcePath           string `mapstructure:"source_path"`
+	SourceVM             string `mapstructure:"source_vm"`
 	GuestAdditionsMode   string `mapstructure:"guest_additions_mode"`
 	GuestAdditionsPath   string `mapstructure:"guest_additions_path"`
 	GuestAdditionsURL    string `mapstructure:"guest_additions_url"`
 	GuestAdditionsSHA256 string `mapstructure:"guest_additions_sha256"`
+	KeepRegistered       bool   `mapstructure:"keep_registered"`
+	LinkedClone          bool   `mapstructure:"linked_clone"`
+	SkipExport           bool   `mapstructure:"skip_export"`
+	SnapshotName         string `mapstructure:"snapshot_name"`
 	VMName               string `mapstructure:"vm_name"`
 	ImportOpts           string `mapstructure:"import_opts"`
 
@@ -66,6 +72,7 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	errs = packer.MultiErrorAppend(errs, c.ExportOpts.Prepare(c.tpl)...)
 	errs = packer.MultiErrorAppend(errs, c.FloppyConfig.Prepare(c.tpl)...)
 	errs = packer.MultiErrorAppend(errs, c.OutputConfig.Prepare(c.tpl, &c.PackerConfig)...)
+	errs = packer.MultiErrorAppend(errs, c.RemoteConfig.Prepare(c.tpl, &c.PackerConfig)...)
 	errs = packer.MultiErrorAppend(errs, c.RunConfig.Prepare(c.tpl)...)
 	errs = packer.MultiErrorAppend(errs, c.ShutdownConfig.Prepare(c.tpl)...)
 	errs = packer.MultiErrorAppend(errs, c.SSHConfig.Prepare(c.tpl)...)
@@ -77,6 +84,8 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		"guest_additions_mode":   &c.GuestAdditionsMode,
 		"guest_additions_sha256": &c.GuestAdditionsSHA256,
 		"source_path":            &c.SourcePath,
+		"source_vm":              &c.SourceVM,
+		"snapshot_name":          &c.SnapshotName,
 		"vm_name":                &c.VMName,
 		"import_opts":            &c.ImportOpts,
 	}
@@ -90,15 +99,29 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		}
 	}
 
-	if c.SourcePath == "" {
-		errs = packer.MultiErrorAppend(errs, fmt.Errorf("source_path is required"))
-	} else {
+	if c.SourcePath == "" && c.SourceVM == "" {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("One of source_path or source_vm is required"))
+	} else if c.SourcePath != "" && c.SourceVM != "" {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("Only one of source_path or source_vm can be specified"))
+	} else if c.SourcePath != "" {
 		if _, err := os.Stat(c.SourcePath); err != nil {
 			errs = packer.MultiErrorAppend(errs,
 				fmt.Errorf("source_path is invalid: %s", err))
 		}
 	}
 
+	if c.LinkedClone && c.SourceVM == "" {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("linked_clone can only be used with source_vm"))
+	}
+
+	if c.SnapshotName != "" && !c.KeepRegistered {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("snapshot_name can only be used with keep_registered"))
+	}
+
 	validates := map[string]*string{
 		"guest_additions_path": &c.GuestAdditionsPath,
 		"guest_additions_url":  &c.GuestAdditionsURL,
@@ -142,6 +165,12 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 				"will forcibly halt the virtual machine, which may result in data loss.")
 	}
 
+	if c.SkipExport && !c.KeepRegistered {
+		warnings = append(warnings,
+			"skip_export is set without keep_registered, so the build will not\n"+
+				"produce anything: the virtual machine is deleted at the end of the build.")
+	}
+
 	// Check for any errors.
 	if errs != nil && len(errs.Errors) > 0 {
 		return nil, warnings, errs
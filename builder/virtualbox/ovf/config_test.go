@@ -83,3 +83,44 @@ func TestNewConfig_shutdown_timeout(t *testing.T) {
 	_, warns, errs = NewConfig(c)
 	testConfigOk(t, warns, errs)
 }
+
+func TestNewConfig_sourceVM(t *testing.T) {
+	// Good
+	c := testConfig(t)
+	c["source_vm"] = "foo"
+	c["linked_clone"] = true
+	_, warns, errs := NewConfig(c)
+	testConfigOk(t, warns, errs)
+
+	// Bad, both source_vm and source_path
+	tf := getTempFile(t)
+	defer os.Remove(tf.Name())
+
+	c["source_path"] = tf.Name()
+	_, warns, errs = NewConfig(c)
+	testConfigErr(t, warns, errs)
+
+	// Bad, linked_clone without source_vm
+	c = testConfig(t)
+	c["source_path"] = tf.Name()
+	c["linked_clone"] = true
+	_, warns, errs = NewConfig(c)
+	testConfigErr(t, warns, errs)
+}
+
+func TestNewConfig_snapshotName(t *testing.T) {
+	c := testConfig(t)
+	tf := getTempFile(t)
+	defer os.Remove(tf.Name())
+
+	// Bad, without keep_registered
+	c["source_path"] = tf.Name()
+	c["snapshot_name"] = "foo"
+	_, warns, errs := NewConfig(c)
+	testConfigErr(t, warns, errs)
+
+	// Good
+	c["keep_registered"] = true
+	_, warns, errs = NewConfig(c)
+	testConfigOk(t, warns, errs)
+}
@@ -0,0 +1,91 @@
+package ovf
+
+import (
+	"fmt"
+	"github.com/mitchellh/multistep"
+	vboxcommon "github.com/mitchellh/packer/builder/virtualbox/common"
+	"github.com/mitchellh/packer/packer"
+)
+
+// This step creates the VM to build by cloning a VM that is already
+// registered with VirtualBox. A snapshot of the source VM is taken so
+// that the clone can be linked to it.
+//
+// Uses:
+//   driver vboxcommon.Driver
+//   ui     packer.Ui
+//
+// Produces:
+//   vmName string - The name of the cloned VM.
+type StepCloneVM struct {
+	Name           string
+	SourceVM       string
+	Linked         bool
+	KeepRegistered bool
+
+	vmName       string
+	snapshotName string
+}
+
+func (s *StepCloneVM) Run(state multistep.StateBag) multistep.StepAction {
+	driver := state.Get("driver").(vboxcommon.Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	snapshotName := fmt.Sprintf("packer-%s", s.Name)
+	ui.Say(fmt.Sprintf("Taking snapshot of source VM: %s", s.SourceVM))
+	if err := driver.CreateSnapshot(s.SourceVM, snapshotName); err != nil {
+		err := fmt.Errorf("Error taking snapshot of source VM: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.snapshotName = snapshotName
+
+	if s.Linked {
+		ui.Say("Creating linked clone of source VM...")
+	} else {
+		ui.Say("Cloning source VM...")
+	}
+
+	if err := driver.CloneVM(s.Name, s.SourceVM, snapshotName, s.Linked); err != nil {
+		err := fmt.Errorf("Error cloning VM: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	s.vmName = s.Name
+	state.Put("vmName", s.Name)
+	return multistep.ActionContinue
+}
+
+func (s *StepCloneVM) Cleanup(state multistep.StateBag) {
+	if s.snapshotName == "" {
+		return
+	}
+
+	driver := state.Get("driver").(vboxcommon.Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	// The snapshot has to stay around as long as the clone does, since
+	// a linked clone's disks are based on it.
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if s.KeepRegistered && !cancelled && !halted {
+		ui.Say("Keeping the cloned VM registered (keep_registered)")
+		return
+	}
+
+	if s.vmName != "" {
+		ui.Say("Unregistering and deleting cloned VM...")
+		if err := driver.Delete(s.vmName); err != nil {
+			ui.Error(fmt.Sprintf("Error deleting VM: %s", err))
+			return
+		}
+	}
+
+	ui.Say("Deleting snapshot of source VM...")
+	if err := driver.DeleteSnapshot(s.SourceVM, s.snapshotName); err != nil {
+		ui.Error(fmt.Sprintf("Error deleting snapshot: %s", err))
+	}
+}
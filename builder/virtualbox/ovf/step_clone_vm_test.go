@@ -0,0 +1,89 @@
+package ovf
+
+import (
+	"github.com/mitchellh/multistep"
+	vboxcommon "github.com/mitchellh/packer/builder/virtualbox/common"
+	"testing"
+)
+
+func TestStepCloneVM_impl(t *testing.T) {
+	var _ multistep.Step = new(StepCloneVM)
+}
+
+func TestStepCloneVM(t *testing.T) {
+	state := testState(t)
+	step := new(StepCloneVM)
+	step.Name = "bar"
+	step.SourceVM = "foo"
+	step.Linked = true
+
+	driver := state.Get("driver").(*vboxcommon.DriverMock)
+
+	// Test the run
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+
+	// Test driver
+	if driver.CreateSnapshotVM != "foo" {
+		t.Fatalf("bad: %#v", driver.CreateSnapshotVM)
+	}
+	if !driver.CloneVMCalled {
+		t.Fatal("clone should be called")
+	}
+	if driver.CloneVMName != "bar" || driver.CloneVMSource != "foo" {
+		t.Fatalf("bad: %#v %#v", driver.CloneVMName, driver.CloneVMSource)
+	}
+	if driver.CloneVMSnapshot != driver.CreateSnapshotName {
+		t.Fatalf("bad: %#v", driver.CloneVMSnapshot)
+	}
+	if !driver.CloneVMLinked {
+		t.Fatal("clone should be linked")
+	}
+
+	// Test output state
+	if name, ok := state.GetOk("vmName"); !ok {
+		t.Fatal("vmName should be set")
+	} else if name != "bar" {
+		t.Fatalf("bad: %#v", name)
+	}
+
+	// Test cleanup
+	step.Cleanup(state)
+	if driver.DeleteName != "bar" {
+		t.Fatalf("bad: %#v", driver.DeleteName)
+	}
+	if driver.DeleteSnapshotVM != "foo" {
+		t.Fatalf("bad: %#v", driver.DeleteSnapshotVM)
+	}
+}
+
+func TestStepCloneVM_keepRegistered(t *testing.T) {
+	state := testState(t)
+	step := new(StepCloneVM)
+	step.Name = "bar"
+	step.SourceVM = "foo"
+	step.KeepRegistered = true
+
+	driver := state.Get("driver").(*vboxcommon.DriverMock)
+
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// A successful build keeps the clone and its snapshot
+	step.Cleanup(state)
+	if driver.DeleteCalled || driver.DeleteSnapshotCalled {
+		t.Fatal("should not delete")
+	}
+
+	// A failed build cleans up anyways
+	state.Put(multistep.StateHalted, true)
+	step.Cleanup(state)
+	if !driver.DeleteCalled || !driver.DeleteSnapshotCalled {
+		t.Fatal("should delete")
+	}
+}
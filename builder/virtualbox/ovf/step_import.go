@@ -9,9 +9,10 @@ import (
 
 // This step imports an OVF VM into VirtualBox.
 type StepImport struct {
-	Name       string
-	SourcePath string
-	ImportOpts string
+	Name           string
+	SourcePath     string
+	ImportOpts     string
+	KeepRegistered bool
 
 	vmName string
 }
@@ -41,6 +42,13 @@ func (s *StepImport) Cleanup(state multistep.StateBag) {
 	driver := state.Get("driver").(vboxcommon.Driver)
 	ui := state.Get("ui").(packer.Ui)
 
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if s.KeepRegistered && !cancelled && !halted {
+		ui.Say("Keeping the imported VM registered (keep_registered)")
+		return
+	}
+
 	ui.Say("Unregistering and deleting imported VM...")
 	if err := driver.Delete(s.vmName); err != nil {
 		ui.Error(fmt.Sprintf("Error deleting VM: %s", err))
@@ -0,0 +1,115 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// BuilderIdESX is the ID of artifacts that are left on an ESX host.
+const BuilderIdESX = "mitchellh.vmware-esx"
+
+// dirArtifact is the result of a build whose output directory may be on
+// a remote host.
+type dirArtifact struct {
+	builderId string
+	dir       OutputDir
+	f         []string
+
+	// vmName is set if the VM itself is the result of the build, because
+	// it was kept registered or the export was skipped.
+	vmName string
+
+	// driver and vmxPath are set if the VM was kept registered on a
+	// remote host, so that it can be unregistered when destroyed.
+	driver  RemoteDriver
+	vmxPath string
+}
+
+// NewArtifact returns the artifact of a build from the state of its
+// steps. If the VM was exported to the local machine, these are the
+// exported files. Otherwise these are the files in the output directory,
+// or the VM itself if the export is skipped.
+//
+// Uses:
+//   dir        OutputDir
+//   driver     Driver
+//   export_dir string (optional)
+//   vmx_path   string
+func NewArtifact(state multistep.StateBag, config *ExportConfig, vmName string) (packer.Artifact, error) {
+	if exportDir, ok := state.GetOk("export_dir"); ok {
+		return NewLocalArtifact(exportDir.(string))
+	}
+
+	dir := state.Get("dir").(OutputDir)
+	driver := state.Get("driver").(Driver)
+	remoteDriver, remote := driver.(RemoteDriver)
+
+	a := &dirArtifact{
+		builderId: BuilderId,
+		dir:       dir,
+	}
+
+	if remote {
+		a.builderId = BuilderIdESX
+	}
+
+	if !config.SkipExport {
+		files, err := dir.ListFiles()
+		if err != nil {
+			return nil, err
+		}
+
+		a.f = files
+	}
+
+	if config.KeepRegistered || config.SkipExport {
+		a.vmName = vmName
+	}
+
+	if remote && config.KeepRegistered {
+		a.driver = remoteDriver
+		a.vmxPath = state.Get("vmx_path").(string)
+	}
+
+	return a, nil
+}
+
+func (a *dirArtifact) BuilderId() string {
+	return a.builderId
+}
+
+func (a *dirArtifact) Files() []string {
+	return a.f
+}
+
+func (a *dirArtifact) Id() string {
+	if a.vmName != "" {
+		return a.vmName
+	}
+
+	return "VM"
+}
+
+func (a *dirArtifact) String() string {
+	if a.driver != nil {
+		return fmt.Sprintf("VM '%s' registered in directory: %s", a.vmName, a.dir)
+	}
+
+	if a.vmName != "" {
+		return fmt.Sprintf("VM '%s' in directory: %s", a.vmName, a.dir)
+	}
+
+	return fmt.Sprintf("VM files in directory: %s", a.dir)
+}
+
+func (a *dirArtifact) Destroy() error {
+	if a.driver != nil {
+		if err := a.driver.Unregister(a.vmxPath); err != nil {
+			return err
+		}
+	}
+
+	return a.dir.RemoveAll()
+}
@@ -0,0 +1,122 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mitchellh/packer/packer"
+)
+
+func TestDirArtifact_impl(t *testing.T) {
+	var _ packer.Artifact = new(dirArtifact)
+}
+
+func testArtifactState(t *testing.T) (string, *LocalOutputDir) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(td, "foo.vmx"), []byte("foo"), 0644)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	dir := new(LocalOutputDir)
+	dir.SetOutputDir(td)
+	return td, dir
+}
+
+func TestNewArtifact(t *testing.T) {
+	td, dir := testArtifactState(t)
+	defer os.RemoveAll(td)
+
+	state := testState(t)
+	state.Put("dir", dir)
+
+	a, err := NewArtifact(state, new(ExportConfig), "foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if a.BuilderId() != BuilderId {
+		t.Fatalf("bad: %#v", a.BuilderId())
+	}
+	if a.Id() != "VM" {
+		t.Fatalf("bad: %#v", a.Id())
+	}
+	if len(a.Files()) != 1 {
+		t.Fatalf("should length 1: %d", len(a.Files()))
+	}
+}
+
+func TestNewArtifact_skipExport(t *testing.T) {
+	td, dir := testArtifactState(t)
+	defer os.RemoveAll(td)
+
+	state := testState(t)
+	state.Put("dir", dir)
+
+	a, err := NewArtifact(state, &ExportConfig{SkipExport: true}, "foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if a.Id() != "foo" {
+		t.Fatalf("bad: %#v", a.Id())
+	}
+	if len(a.Files()) != 0 {
+		t.Fatalf("should be empty: %#v", a.Files())
+	}
+}
+
+func TestNewArtifact_keepRegistered(t *testing.T) {
+	td, dir := testArtifactState(t)
+	defer os.RemoveAll(td)
+
+	driver := new(RemoteDriverMock)
+	state := testState(t)
+	state.Put("dir", dir)
+	state.Put("driver", driver)
+	state.Put("vmx_path", "foo.vmx")
+
+	a, err := NewArtifact(state, &ExportConfig{KeepRegistered: true}, "foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if a.BuilderId() != BuilderIdESX {
+		t.Fatalf("bad: %#v", a.BuilderId())
+	}
+	if a.Id() != "foo" {
+		t.Fatalf("bad: %#v", a.Id())
+	}
+
+	if err := a.Destroy(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !driver.UnregisterCalled || driver.UnregisterPath != "foo.vmx" {
+		t.Fatal("should unregister the VM")
+	}
+}
+
+func TestNewArtifact_export(t *testing.T) {
+	td, dir := testArtifactState(t)
+	defer os.RemoveAll(td)
+
+	state := testState(t)
+	state.Put("dir", dir)
+	state.Put("driver", new(RemoteDriverMock))
+	state.Put("export_dir", td)
+
+	a, err := NewArtifact(state, new(ExportConfig), "foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if a.BuilderId() != BuilderId {
+		t.Fatalf("bad: %#v", a.BuilderId())
+	}
+}
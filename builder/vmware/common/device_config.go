@@ -0,0 +1,120 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mitchellh/packer/packer"
+)
+
+// The network adapter types that VMware supports.
+var networkAdapterTypes = []string{
+	"e1000", "e1000e", "vlance", "vmxnet", "vmxnet2", "vmxnet3",
+}
+
+// DeviceConfig configures the virtual hardware of the VM on top of what
+// the VMX file already has.
+type DeviceConfig struct {
+	AdditionalDiskSize []uint `mapstructure:"disk_additional_size"`
+	CDROMAdapterType   string `mapstructure:"cdrom_adapter_type"`
+	NetworkAdapterType string `mapstructure:"network_adapter_type"`
+	Serial             string `mapstructure:"serial"`
+}
+
+func (c *DeviceConfig) Prepare(t *packer.ConfigTemplate) []error {
+	templates := map[string]*string{
+		"cdrom_adapter_type":   &c.CDROMAdapterType,
+		"network_adapter_type": &c.NetworkAdapterType,
+		"serial":               &c.Serial,
+	}
+
+	errs := make([]error, 0)
+	for n, ptr := range templates {
+		var err error
+		*ptr, err = t.Process(*ptr, nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Error processing %s: %s", n, err))
+		}
+	}
+
+	for i, size := range c.AdditionalDiskSize {
+		if size == 0 {
+			errs = append(errs, fmt.Errorf(
+				"disk_additional_size[%d] must be greater than zero", i))
+		}
+	}
+
+	c.CDROMAdapterType = strings.ToLower(c.CDROMAdapterType)
+	switch c.CDROMAdapterType {
+	case "", "ide", "sata", "scsi":
+	default:
+		errs = append(errs, errors.New(
+			"cdrom_adapter_type must be one of 'ide', 'sata' or 'scsi'"))
+	}
+
+	if c.NetworkAdapterType != "" {
+		c.NetworkAdapterType = strings.ToLower(c.NetworkAdapterType)
+		valid := false
+		for _, t := range networkAdapterTypes {
+			if c.NetworkAdapterType == t {
+				valid = true
+				break
+			}
+		}
+
+		if !valid {
+			errs = append(errs, fmt.Errorf(
+				"network_adapter_type must be one of: %s",
+				strings.Join(networkAdapterTypes, ", ")))
+		}
+	}
+
+	if _, err := c.SerialPort(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// SerialPort returns the first serial port as configured by the serial
+// setting, which is one of "FILE:<path>", "DEVICE:<path>",
+// "PIPE:<path>[,client|server]" or "NONE". It returns nil if the port is
+// to be removed or left as it is.
+func (c *DeviceConfig) SerialPort() (*VMXSerialPort, error) {
+	if c.Serial == "" || strings.ToUpper(c.Serial) == "NONE" {
+		return nil, nil
+	}
+
+	parts := strings.SplitN(c.Serial, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("serial is invalid: %s", c.Serial)
+	}
+
+	port := &VMXSerialPort{FileName: parts[1]}
+	switch strings.ToUpper(parts[0]) {
+	case "FILE":
+		port.FileType = "file"
+	case "DEVICE":
+		port.FileType = "device"
+	case "PIPE":
+		port.FileType = "pipe"
+		port.PipeEndpoint = "server"
+
+		pipe := strings.SplitN(parts[1], ",", 2)
+		if len(pipe) == 2 {
+			port.FileName = pipe[0]
+			port.PipeEndpoint = strings.ToLower(pipe[1])
+		}
+
+		if port.PipeEndpoint != "client" && port.PipeEndpoint != "server" {
+			return nil, fmt.Errorf(
+				"serial pipe endpoint must be 'client' or 'server': %s", c.Serial)
+		}
+	default:
+		return nil, fmt.Errorf(
+			"serial must start with FILE, DEVICE or PIPE: %s", c.Serial)
+	}
+
+	return port, nil
+}
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestDeviceConfigPrepare(t *testing.T) {
+	c := new(DeviceConfig)
+	c.AdditionalDiskSize = []uint{1024}
+	c.CDROMAdapterType = "SATA"
+	c.NetworkAdapterType = "VMXNET3"
+	c.Serial = "FILE:/tmp/serial.log"
+	errs := c.Prepare(testConfigTemplate(t))
+	if len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+
+	if c.CDROMAdapterType != "sata" {
+		t.Fatalf("bad: %s", c.CDROMAdapterType)
+	}
+	if c.NetworkAdapterType != "vmxnet3" {
+		t.Fatalf("bad: %s", c.NetworkAdapterType)
+	}
+}
+
+func TestDeviceConfigPrepare_bad(t *testing.T) {
+	cases := []*DeviceConfig{
+		{AdditionalDiskSize: []uint{0}},
+		{CDROMAdapterType: "floppy"},
+		{NetworkAdapterType: "rtl8139"},
+		{Serial: "TCP:localhost:2000"},
+		{Serial: "FILE:"},
+		{Serial: "PIPE:/tmp/serial.pipe,both"},
+	}
+
+	for _, c := range cases {
+		if errs := c.Prepare(testConfigTemplate(t)); len(errs) == 0 {
+			t.Fatalf("should have error: %#v", c)
+		}
+	}
+}
+
+func TestDeviceConfigSerialPort(t *testing.T) {
+	cases := []struct {
+		Serial   string
+		Expected *VMXSerialPort
+	}{
+		{"", nil},
+		{"none", nil},
+		{"FILE:/tmp/serial.log", &VMXSerialPort{FileType: "file", FileName: "/tmp/serial.log"}},
+		{"device:/dev/ttyS0", &VMXSerialPort{FileType: "device", FileName: "/dev/ttyS0"}},
+		{"PIPE:/tmp/serial.pipe", &VMXSerialPort{
+			FileType:     "pipe",
+			FileName:     "/tmp/serial.pipe",
+			PipeEndpoint: "server",
+		}},
+		{"PIPE:/tmp/serial.pipe,client", &VMXSerialPort{
+			FileType:     "pipe",
+			FileName:     "/tmp/serial.pipe",
+			PipeEndpoint: "client",
+		}},
+	}
+
+	for _, tc := range cases {
+		c := &DeviceConfig{Serial: tc.Serial}
+		port, err := c.SerialPort()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if (port == nil) != (tc.Expected == nil) {
+			t.Fatalf("bad: %s %#v", tc.Serial, port)
+		}
+		if port != nil && *port != *tc.Expected {
+			t.Fatalf("bad: %s %#v", tc.Serial, port)
+		}
+	}
+}
@@ -16,8 +16,10 @@ import (
 type Driver interface {
 	// Clone clones the VMX and the disk to the destination path. The
 	// destination is a path to the VMX file. The disk will be copied
-	// to that same directory.
-	Clone(dst string, src string) error
+	// to that same directory. If linked is true, a linked clone is made
+	// that shares the disks of the source instead. If a snapshot is
+	// given, the clone is made from that snapshot of the source.
+	Clone(dst string, src string, linked bool, snapshot string) error
 
 	// CompactDisk compacts a virtual disk.
 	CompactDisk(string) error
@@ -25,6 +27,10 @@ type Driver interface {
 	// CreateDisk creates a virtual disk with the given size.
 	CreateDisk(string, string, string) error
 
+	// CreateSnapshot takes a snapshot with the given name of the VM
+	// specified by the path to the VMX given.
+	CreateSnapshot(string, string) error
+
 	// Checks if the VMX file at the given path is running.
 	IsRunning(string) (bool, error)
 
@@ -63,6 +69,36 @@ type Driver interface {
 func NewDriver(dconfig *DriverConfig, config *SSHConfig) (Driver, error) {
 	drivers := []Driver{}
 
+	switch dconfig.RemoteType {
+	case "esx5":
+		drivers = []Driver{
+			&ESX5Driver{
+				Host:      dconfig.RemoteHost,
+				Port:      dconfig.RemotePort,
+				Username:  dconfig.RemoteUser,
+				Password:  dconfig.RemotePassword,
+				Datastore: dconfig.RemoteDatastore,
+				SSHConfig: config,
+			},
+		}
+
+		return verifyDrivers(drivers)
+	case "vsphere":
+		drivers = []Driver{
+			&VSphereDriver{
+				Host:      dconfig.RemoteHost,
+				Port:      dconfig.RemotePort,
+				Username:  dconfig.RemoteUser,
+				Password:  dconfig.RemotePassword,
+				Datastore: dconfig.RemoteDatastore,
+				Insecure:  dconfig.RemoteInsecure,
+				SSHConfig: config,
+			},
+		}
+
+		return verifyDrivers(drivers)
+	}
+
 	switch runtime.GOOS {
 	case "darwin":
 		drivers = []Driver{
@@ -106,6 +142,11 @@ func NewDriver(dconfig *DriverConfig, config *SSHConfig) (Driver, error) {
 		return nil, fmt.Errorf("can't find driver for OS: %s", runtime.GOOS)
 	}
 
+	return verifyDrivers(drivers)
+}
+
+// verifyDrivers returns the first of the given drivers that verifies.
+func verifyDrivers(drivers []Driver) (Driver, error) {
 	errs := ""
 	for _, driver := range drivers {
 		err := driver.Verify()
@@ -175,3 +216,18 @@ func compareVersions(versionFound string, versionWanted string) error {
 
 	return nil
 }
+
+// cloneArgs returns the arguments of "vmrun clone" that follow the source
+// and destination paths.
+func cloneArgs(linked bool, snapshot string) []string {
+	args := []string{"full"}
+	if linked {
+		args[0] = "linked"
+	}
+
+	if snapshot != "" {
+		args = append(args, "-snapshot="+snapshot)
+	}
+
+	return args
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/mitchellh/packer/packer"
@@ -8,6 +9,14 @@ import (
 
 type DriverConfig struct {
 	FusionAppPath string `mapstructure:"fusion_app_path"`
+
+	RemoteType      string `mapstructure:"remote_type"`
+	RemoteDatastore string `mapstructure:"remote_datastore"`
+	RemoteHost      string `mapstructure:"remote_host"`
+	RemoteInsecure  bool   `mapstructure:"remote_insecure"`
+	RemotePort      uint   `mapstructure:"remote_port"`
+	RemoteUser      string `mapstructure:"remote_username"`
+	RemotePassword  string `mapstructure:"remote_password"`
 }
 
 func (c *DriverConfig) Prepare(t *packer.ConfigTemplate) []error {
@@ -15,8 +24,21 @@ func (c *DriverConfig) Prepare(t *packer.ConfigTemplate) []error {
 		c.FusionAppPath = "/Applications/VMware Fusion.app"
 	}
 
+	if c.RemoteUser == "" {
+		c.RemoteUser = "root"
+	}
+
+	if c.RemoteDatastore == "" {
+		c.RemoteDatastore = "datastore1"
+	}
+
 	templates := map[string]*string{
-		"fusion_app_path": &c.FusionAppPath,
+		"fusion_app_path":  &c.FusionAppPath,
+		"remote_type":      &c.RemoteType,
+		"remote_host":      &c.RemoteHost,
+		"remote_datastore": &c.RemoteDatastore,
+		"remote_username":  &c.RemoteUser,
+		"remote_password":  &c.RemotePassword,
 	}
 
 	var err error
@@ -28,5 +50,23 @@ func (c *DriverConfig) Prepare(t *packer.ConfigTemplate) []error {
 		}
 	}
 
+	// The vSphere API is served over HTTPS, the ESX driver uses SSH.
+	if c.RemotePort == 0 {
+		c.RemotePort = 22
+		if c.RemoteType == "vsphere" {
+			c.RemotePort = 443
+		}
+	}
+
+	switch c.RemoteType {
+	case "":
+	case "esx5", "vsphere":
+		if c.RemoteHost == "" {
+			errs = append(errs, errors.New("remote_host must be specified"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("Unknown remote_type: %s", c.RemoteType))
+	}
+
 	return errs
 }
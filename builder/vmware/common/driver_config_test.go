@@ -28,3 +28,54 @@ func TestDriverConfigPrepare(t *testing.T) {
 		t.Fatalf("bad value: %s", c.FusionAppPath)
 	}
 }
+
+func TestDriverConfigPrepare_Remote(t *testing.T) {
+	var c *DriverConfig
+
+	// Defaults
+	c = new(DriverConfig)
+	c.RemoteType = "esx5"
+	c.RemoteHost = "esx.example.com"
+	errs := c.Prepare(testConfigTemplate(t))
+	if len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+	if c.RemoteUser != "root" {
+		t.Fatalf("bad value: %s", c.RemoteUser)
+	}
+	if c.RemoteDatastore != "datastore1" {
+		t.Fatalf("bad value: %s", c.RemoteDatastore)
+	}
+	if c.RemotePort != 22 {
+		t.Fatalf("bad value: %d", c.RemotePort)
+	}
+
+	// vSphere defaults to HTTPS
+	c = new(DriverConfig)
+	c.RemoteType = "vsphere"
+	c.RemoteHost = "vcenter.example.com"
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+	if c.RemotePort != 443 {
+		t.Fatalf("bad value: %d", c.RemotePort)
+	}
+
+	// No host
+	c = new(DriverConfig)
+	c.RemoteType = "esx5"
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) == 0 {
+		t.Fatal("should have error")
+	}
+
+	// Unknown type
+	c = new(DriverConfig)
+	c.RemoteType = "foo"
+	c.RemoteHost = "esx.example.com"
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) == 0 {
+		t.Fatal("should have error")
+	}
+}
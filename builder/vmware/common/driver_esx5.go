@@ -1,4 +1,4 @@
-package iso
+package common
 
 import (
 	"bufio"
@@ -28,14 +28,86 @@ type ESX5Driver struct {
 	Username  string
 	Password  string
 	Datastore string
+	SSHConfig *SSHConfig
 
 	comm      packer.Communicator
 	outputDir string
 	vmId      string
 }
 
-func (d *ESX5Driver) Clone(dst, src string) error {
-	return errors.New("Cloning is not supported with the ESX driver.")
+// Clone copies the VM on the datastore at the source path to the output
+// directory. The disks of full clones are copied with vmkfstools, from
+// the state they were in when the snapshot was taken if one is given.
+// Linked clones copy only the delta disks of the current snapshot of the
+// source and point them at its parent disks, which are shared with the
+// source, so they include any changes made since that snapshot.
+func (d *ESX5Driver) Clone(dst, src string, linked bool, snapshot string) error {
+	srcVmx := d.sourcePath(src)
+	srcDir := filepath.Dir(srcVmx)
+	dstVmx := d.datastorePath(dst)
+	dstDir := filepath.Dir(dstVmx)
+
+	contents, err := d.run(nil, "cat", srcVmx)
+	if err != nil {
+		return err
+	}
+	vmxData := ParseVMX(contents)
+
+	// The disk files of the snapshot, by device
+	var snapshotDisks map[string]string
+	if snapshot != "" {
+		contents, err := d.run(nil, "cat", strings.TrimSuffix(srcVmx, ".vmx")+".vmsd")
+		if err != nil {
+			return fmt.Errorf("Error reading snapshots of %s: %s", src, err)
+		}
+		vmsd := ParseVMX(contents)
+
+		if linked {
+			if err := checkCurrentSnapshot(vmsd, snapshot); err != nil {
+				return err
+			}
+		} else {
+			snapshotDisks, err = snapshotDiskFiles(vmsd, snapshot)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := d.mkdir(dstDir); err != nil {
+		return err
+	}
+
+	for _, disk := range ParseVMXDevices(vmxData).Disks {
+		srcDisk := disk.FileName
+		if file, ok := snapshotDisks[disk.Key()]; ok {
+			srcDisk = file
+		}
+		if !filepath.IsAbs(srcDisk) {
+			srcDisk = filepath.Join(srcDir, srcDisk)
+		}
+		dstDisk := filepath.Join(dstDir, filepath.Base(srcDisk))
+
+		if linked {
+			err = d.cloneDeltaDisk(dstDisk, srcDisk)
+		} else {
+			err = d.sh("vmkfstools", "-i", srcDisk, "-d", "thin", dstDisk)
+		}
+		if err != nil {
+			return fmt.Errorf("Error cloning disk %s: %s", srcDisk, err)
+		}
+
+		vmxData[disk.Key()+".filename"] = filepath.Base(dstDisk)
+	}
+
+	// The source may be registered, so the clone gets its own identity.
+	delete(vmxData, "uuid.bios")
+	delete(vmxData, "uuid.location")
+	delete(vmxData, "vc.uuid")
+	vmxData["displayname"] = strings.TrimSuffix(filepath.Base(dst), ".vmx")
+
+	_, err = d.ssh("cat > "+dstVmx, strings.NewReader(EncodeVMX(vmxData)))
+	return err
 }
 
 func (d *ESX5Driver) CompactDisk(diskPathLocal string) error {
@@ -47,6 +119,10 @@ func (d *ESX5Driver) CreateDisk(diskPathLocal string, size string, typeId string
 	return d.sh("vmkfstools", "-c", size, "-d", typeId, "-a", "lsilogic", diskPath)
 }
 
+func (d *ESX5Driver) CreateSnapshot(vmxPathLocal string, name string) error {
+	return d.sh("vim-cmd", "vmsvc/snapshot.create", d.vmId, name)
+}
+
 func (d *ESX5Driver) IsRunning(string) (bool, error) {
 	state, err := d.run(nil, "vim-cmd", "vmsvc/power.getstate", d.vmId)
 	if err != nil {
@@ -76,6 +152,16 @@ func (d *ESX5Driver) Register(vmxPathLocal string) error {
 	return nil
 }
 
+func (d *ESX5Driver) Download(src, dst string) error {
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return d.comm.Download(d.datastorePath(src), f)
+}
+
 func (d *ESX5Driver) SuppressMessages(vmxPath string) error {
 	return nil
 }
@@ -168,18 +254,22 @@ func (d *ESX5Driver) VNCAddress(portMin, portMax uint) (string, uint) {
 }
 
 func (d *ESX5Driver) SSHAddress(state multistep.StateBag) (string, error) {
-	config := state.Get("config").(*config)
-
 	if address, ok := state.GetOk("vm_address"); ok {
 		return address.(string), nil
 	}
 
+	// The VM is listed by its display name.
+	vmxData, err := ReadVMX(state.Get("vmx_path").(string))
+	if err != nil {
+		return "", err
+	}
+
 	r, err := d.esxcli("network", "vm", "list")
 	if err != nil {
 		return "", err
 	}
 
-	record, err := r.find("Name", config.VMName)
+	record, err := r.find("Name", vmxData["displayname"])
 	if err != nil {
 		return "", err
 	}
@@ -202,7 +292,7 @@ func (d *ESX5Driver) SSHAddress(state multistep.StateBag) (string, error) {
 		return "", errors.New("VM network port found, but no IP address")
 	}
 
-	address := fmt.Sprintf("%s:%d", record["IPAddress"], config.SSHPort)
+	address := fmt.Sprintf("%s:%d", record["IPAddress"], d.SSHConfig.SSHPort)
 	state.Put("vm_address", address)
 	return address, nil
 }
@@ -259,6 +349,42 @@ func (d *ESX5Driver) String() string {
 	return d.outputDir
 }
 
+// sourcePath returns the path on the host of a VM to clone, which is
+// either absolute or relative to the datastore.
+func (d *ESX5Driver) sourcePath(path string) string {
+	if strings.HasPrefix(path, "/vmfs/") {
+		return path
+	}
+
+	return filepath.Join("/vmfs/volumes", d.Datastore, path)
+}
+
+// cloneDeltaDisk copies a delta disk along with its extents and makes
+// the parent of the copy the parent disk of the original.
+func (d *ESX5Driver) cloneDeltaDisk(dst, src string) error {
+	descriptor, err := d.run(nil, "cat", src)
+	if err != nil {
+		return err
+	}
+
+	descriptor, extents, err := linkedDiskDescriptor(descriptor, filepath.Dir(src))
+	if err != nil {
+		return fmt.Errorf("%s: %s", src, err)
+	}
+
+	for _, extent := range extents {
+		err := d.sh("cp",
+			filepath.Join(filepath.Dir(src), extent),
+			filepath.Join(filepath.Dir(dst), extent))
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = d.ssh("cat > "+dst, strings.NewReader(descriptor))
+	return err
+}
+
 func (d *ESX5Driver) datastorePath(path string) string {
 	baseDir := filepath.Base(filepath.Dir(path))
 	return filepath.Join("/vmfs/volumes", d.Datastore, baseDir, filepath.Base(path))
@@ -434,3 +560,99 @@ func (r *esxcliReader) find(key, val string) (map[string]string, error) {
 		}
 	}
 }
+
+// linkedDiskDescriptor rewrites the descriptor of a delta disk in the
+// given directory so that its parent is referred to by absolute path,
+// and returns it along with the names of its extent files. Disks that
+// aren't deltas of a snapshot can't be shared by a linked clone.
+func linkedDiskDescriptor(descriptor, dir string) (string, []string, error) {
+	var extents []string
+	hasParent := false
+
+	lines := strings.Split(descriptor, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+
+		if strings.HasPrefix(line, "parentFileNameHint=") {
+			parent := strings.Trim(strings.TrimPrefix(line, "parentFileNameHint="), `"`)
+			if !filepath.IsAbs(parent) {
+				parent = filepath.Join(dir, parent)
+			}
+
+			lines[i] = fmt.Sprintf(`parentFileNameHint="%s"`, parent)
+			hasParent = true
+			continue
+		}
+
+		// Extent lines look like: RW 8388608 VMFSSPARSE "disk-000001-delta.vmdk"
+		fields := strings.Fields(line)
+		if len(fields) >= 4 && (fields[0] == "RW" || fields[0] == "RDONLY") {
+			extents = append(extents, strings.Trim(fields[3], `"`))
+		}
+	}
+
+	if !hasParent {
+		return "", nil, errors.New(
+			"not a delta disk, linked clones require a snapshot of the source VM")
+	}
+
+	return strings.Join(lines, "\n"), extents, nil
+}
+
+// findSnapshot returns the key prefix, such as "snapshot0", of the
+// snapshot with the given name in the given VMSD data.
+func findSnapshot(vmsd map[string]string, name string) (string, error) {
+	for k, v := range vmsd {
+		if strings.HasSuffix(k, ".displayname") && v == name {
+			return strings.TrimSuffix(k, ".displayname"), nil
+		}
+	}
+
+	return "", fmt.Errorf("The source VM has no snapshot '%s'", name)
+}
+
+// checkCurrentSnapshot verifies that the current snapshot in the given
+// VMSD data has the given name. Linked clones on ESX share the disks of
+// the current snapshot only.
+func checkCurrentSnapshot(vmsd map[string]string, name string) error {
+	prefix, err := findSnapshot(vmsd, name)
+	if err != nil {
+		return err
+	}
+
+	current := vmsd["snapshot.current"]
+	if vmsd[prefix+".uid"] == current {
+		return nil
+	}
+
+	currentName := ""
+	for k, v := range vmsd {
+		if strings.HasSuffix(k, ".uid") && v == current {
+			currentName = vmsd[strings.TrimSuffix(k, ".uid")+".displayname"]
+		}
+	}
+
+	return fmt.Errorf(
+		"The current snapshot of the source VM is '%s', not '%s'. Linked\n"+
+			"clones can only be made of the current snapshot on ESX.",
+		currentName, name)
+}
+
+// snapshotDiskFiles returns the disk files of the snapshot with the given
+// name in the given VMSD data by device, such as "scsi0:0". Each file is
+// the disk chain as it was when the snapshot was taken.
+func snapshotDiskFiles(vmsd map[string]string, name string) (map[string]string, error) {
+	prefix, err := findSnapshot(vmsd, name)
+	if err != nil {
+		return nil, err
+	}
+
+	disks := make(map[string]string)
+	for k, v := range vmsd {
+		if strings.HasPrefix(k, prefix+".disk") && strings.HasSuffix(k, ".node") {
+			disks[v] = vmsd[strings.TrimSuffix(k, ".node")+".filename"]
+		}
+	}
+
+	return disks, nil
+}
@@ -0,0 +1,131 @@
+package common
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestESX5Driver_implDriver(t *testing.T) {
+	var _ Driver = new(ESX5Driver)
+}
+
+func TestESX5Driver_implOutputDir(t *testing.T) {
+	var _ OutputDir = new(ESX5Driver)
+}
+
+func TestESX5Driver_implRemoteDriver(t *testing.T) {
+	var _ RemoteDriver = new(ESX5Driver)
+}
+
+func TestESX5Driver_HostIP(t *testing.T) {
+	expected_host := "127.0.0.1"
+
+	//create mock SSH server
+	listen, _ := net.Listen("tcp", fmt.Sprintf("%s:0", expected_host))
+	port := listen.Addr().(*net.TCPAddr).Port
+	defer listen.Close()
+
+	driver := ESX5Driver{Host: "localhost", Port: uint(port)}
+
+	if host, _ := driver.HostIP(); host != expected_host {
+		t.Error(fmt.Sprintf("Expected string, %s but got %s", expected_host, host))
+	}
+}
+
+const testDeltaDescriptor = `# Disk DescriptorFile
+version=1
+CID=fffffffe
+parentCID=fffffffe
+createType="vmfsSparse"
+parentFileNameHint="base.vmdk"
+# Extent description
+RW 16777216 VMFSSPARSE "base-000001-delta.vmdk"
+
+# The Disk Data Base
+#DDB
+
+ddb.longContentID = "8f7a1c4bd1a3bb6f2bcb2b6ffffffffe"`
+
+func TestLinkedDiskDescriptor(t *testing.T) {
+	result, extents, err := linkedDiskDescriptor(testDeltaDescriptor, "/vmfs/volumes/datastore1/base")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !strings.Contains(result, `parentFileNameHint="/vmfs/volumes/datastore1/base/base.vmdk"`) {
+		t.Fatalf("bad: %s", result)
+	}
+	if !strings.Contains(result, `RW 16777216 VMFSSPARSE "base-000001-delta.vmdk"`) {
+		t.Fatalf("bad: %s", result)
+	}
+
+	if len(extents) != 1 || extents[0] != "base-000001-delta.vmdk" {
+		t.Fatalf("bad: %#v", extents)
+	}
+}
+
+func TestLinkedDiskDescriptor_noParent(t *testing.T) {
+	descriptor := strings.Replace(testDeltaDescriptor, `parentFileNameHint="base.vmdk"`, "", 1)
+	if _, _, err := linkedDiskDescriptor(descriptor, "/vmfs/volumes/datastore1/base"); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestCheckCurrentSnapshot(t *testing.T) {
+	vmsd := ParseVMX(`
+.encoding = "UTF-8"
+snapshot.lastUID = "2"
+snapshot.current = "2"
+snapshot0.uid = "1"
+snapshot0.displayName = "installed"
+snapshot1.uid = "2"
+snapshot1.parent = "1"
+snapshot1.displayName = "provisioned"
+`)
+
+	if err := checkCurrentSnapshot(vmsd, "provisioned"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := checkCurrentSnapshot(vmsd, "installed"); err == nil {
+		t.Fatal("should have error")
+	}
+
+	if err := checkCurrentSnapshot(map[string]string{}, "installed"); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestSnapshotDiskFiles(t *testing.T) {
+	vmsd := ParseVMX(`
+.encoding = "UTF-8"
+snapshot.lastUID = "2"
+snapshot.current = "2"
+snapshot0.uid = "1"
+snapshot0.displayName = "installed"
+snapshot0.disk0.fileName = "disk.vmdk"
+snapshot0.disk0.node = "scsi0:0"
+snapshot1.uid = "2"
+snapshot1.parent = "1"
+snapshot1.displayName = "provisioned"
+snapshot1.disk0.fileName = "disk-000001.vmdk"
+snapshot1.disk0.node = "scsi0:0"
+`)
+
+	disks, err := snapshotDiskFiles(vmsd, "installed")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]string{"scsi0:0": "disk.vmdk"}
+	if !reflect.DeepEqual(disks, expected) {
+		t.Fatalf("bad: %#v", disks)
+	}
+
+	if _, err := snapshotDiskFiles(vmsd, "missing"); err == nil {
+		t.Fatal("should have error")
+	}
+}
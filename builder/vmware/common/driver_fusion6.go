@@ -16,11 +16,11 @@ type Fusion6Driver struct {
 	Fusion5Driver
 }
 
-func (d *Fusion6Driver) Clone(dst, src string) error {
-	cmd := exec.Command(d.vmrunPath(),
-		"-T", "fusion",
-		"clone", src, dst,
-		"full")
+func (d *Fusion6Driver) Clone(dst, src string, linked bool, snapshot string) error {
+	args := []string{"-T", "fusion", "clone", src, dst}
+	args = append(args, cloneArgs(linked, snapshot)...)
+
+	cmd := exec.Command(d.vmrunPath(), args...)
 	if _, _, err := runAndLog(cmd); err != nil {
 		return err
 	}
@@ -9,10 +9,12 @@ import (
 type DriverMock struct {
 	sync.Mutex
 
-	CloneCalled bool
-	CloneDst    string
-	CloneSrc    string
-	CloneErr    error
+	CloneCalled   bool
+	CloneDst      string
+	CloneSrc      string
+	CloneLinked   bool
+	CloneSnapshot string
+	CloneErr      error
 
 	CompactDiskCalled bool
 	CompactDiskPath   string
@@ -24,6 +26,11 @@ type DriverMock struct {
 	CreateDiskTypeId string
 	CreateDiskErr    error
 
+	CreateSnapshotCalled bool
+	CreateSnapshotPath   string
+	CreateSnapshotName   string
+	CreateSnapshotErr    error
+
 	IsRunningCalled bool
 	IsRunningPath   string
 	IsRunningResult bool
@@ -62,10 +69,12 @@ type DriverMock struct {
 	VerifyErr    error
 }
 
-func (d *DriverMock) Clone(dst string, src string) error {
+func (d *DriverMock) Clone(dst string, src string, linked bool, snapshot string) error {
 	d.CloneCalled = true
 	d.CloneDst = dst
 	d.CloneSrc = src
+	d.CloneLinked = linked
+	d.CloneSnapshot = snapshot
 	return d.CloneErr
 }
 
@@ -83,6 +92,13 @@ func (d *DriverMock) CreateDisk(output string, size string, typeId string) error
 	return d.CreateDiskErr
 }
 
+func (d *DriverMock) CreateSnapshot(path string, name string) error {
+	d.CreateSnapshotCalled = true
+	d.CreateSnapshotPath = path
+	d.CreateSnapshotName = name
+	return d.CreateSnapshotErr
+}
+
 func (d *DriverMock) IsRunning(path string) (bool, error) {
 	d.Lock()
 	defer d.Unlock()
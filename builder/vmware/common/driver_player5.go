@@ -22,7 +22,7 @@ type Player5LinuxDriver struct {
 	SSHConfig *SSHConfig
 }
 
-func (d *Player5LinuxDriver) Clone(dst, src string) error {
+func (d *Player5LinuxDriver) Clone(dst, src string, linked bool, snapshot string) error {
 	return errors.New("Cloning is not supported with Player 5. Please use Player 6+.")
 }
 
@@ -75,6 +75,10 @@ func (d *Player5LinuxDriver) CreateDisk(output string, size string, type_id stri
 	return nil
 }
 
+func (d *Player5LinuxDriver) CreateSnapshot(vmxPath string, name string) error {
+	return errors.New("Snapshots are not supported with VMware Player.")
+}
+
 func (d *Player5LinuxDriver) IsRunning(vmxPath string) (bool, error) {
 	vmxPath, err := filepath.Abs(vmxPath)
 	if err != nil {
@@ -0,0 +1,26 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCloneArgs(t *testing.T) {
+	cases := []struct {
+		Linked   bool
+		Snapshot string
+		Expected []string
+	}{
+		{false, "", []string{"full"}},
+		{true, "", []string{"linked"}},
+		{true, "base", []string{"linked", "-snapshot=base"}},
+		{false, "base", []string{"full", "-snapshot=base"}},
+	}
+
+	for _, tc := range cases {
+		args := cloneArgs(tc.Linked, tc.Snapshot)
+		if !reflect.DeepEqual(args, tc.Expected) {
+			t.Fatalf("bad: %#v", args)
+		}
+	}
+}
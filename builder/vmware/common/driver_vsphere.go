@@ -0,0 +1,642 @@
+package common
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mitchellh/multistep"
+)
+
+// VSphereDriver talks to an ESXi or vCenter host through the vSphere API
+// to build virtual machines. Unlike the ESX5Driver, it needs neither SSH
+// on the host nor the GuestIPHack setting: the guest IP is reported by
+// VMware Tools. This driver can only manage one machine at a time.
+type VSphereDriver struct {
+	Host      string
+	Port      uint
+	Username  string
+	Password  string
+	Datastore string
+	Insecure  bool
+	SSHConfig *SSHConfig
+
+	client     *vimClient
+	datacenter moRef
+	dcName     string
+	datastore  moRef
+	browser    moRef
+	vmFolder   moRef
+	pool       moRef
+	outputDir  string
+	vm         moRef
+}
+
+// Clone copies the VM on the datastore at the source path to the output
+// directory, the same way the ESX5Driver does, but with the datastore
+// file and disk managers of the API.
+func (d *VSphereDriver) Clone(dst, src string, linked bool, snapshot string) error {
+	srcVmx := d.sourcePath(src)
+	srcDir := filepath.Dir(srcVmx)
+	dstVmx := d.datastorePath(dst)
+	dstDir := filepath.Dir(dstVmx)
+
+	contents, err := d.readFile(srcVmx)
+	if err != nil {
+		return err
+	}
+	vmxData := ParseVMX(contents)
+
+	if snapshot != "" {
+		vmsd, err := d.readFile(strings.TrimSuffix(srcVmx, ".vmx") + ".vmsd")
+		if err != nil {
+			return fmt.Errorf("Error reading snapshots of %s: %s", src, err)
+		}
+
+		if err := checkCurrentSnapshot(ParseVMX(vmsd), snapshot); err != nil {
+			return err
+		}
+	}
+
+	if err := d.mkdir(dstDir); err != nil {
+		return err
+	}
+
+	for _, disk := range ParseVMXDevices(vmxData).Disks {
+		srcDisk := disk.FileName
+		if !filepath.IsAbs(srcDisk) {
+			srcDisk = filepath.Join(srcDir, srcDisk)
+		}
+		dstDisk := filepath.Join(dstDir, filepath.Base(srcDisk))
+
+		if linked {
+			err = d.cloneDeltaDisk(dstDisk, srcDisk)
+		} else {
+			_, err = d.client.Task("CopyVirtualDisk_Task", d.client.content.VirtualDiskManager, []vimParam{
+				{"sourceName", d.dsPath(srcDisk)},
+				{"sourceDatacenter", d.datacenter},
+				{"destName", d.dsPath(dstDisk)},
+				{"destDatacenter", d.datacenter},
+				{"destSpec", &vimVirtualDiskSpec{
+					XSIType:     "VirtualDiskSpec",
+					AdapterType: "lsiLogic",
+					DiskType:    "thin",
+				}},
+				{"force", false},
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("Error cloning disk %s: %s", srcDisk, err)
+		}
+
+		vmxData[disk.Key()+".filename"] = filepath.Base(dstDisk)
+	}
+
+	// The source may be registered, so the clone gets its own identity.
+	delete(vmxData, "uuid.bios")
+	delete(vmxData, "uuid.location")
+	delete(vmxData, "vc.uuid")
+	vmxData["displayname"] = strings.TrimSuffix(filepath.Base(dst), ".vmx")
+
+	return d.writeFile(dstVmx, []byte(EncodeVMX(vmxData)))
+}
+
+func (d *VSphereDriver) CompactDisk(diskPathLocal string) error {
+	return nil
+}
+
+func (d *VSphereDriver) CreateDisk(diskPathLocal string, size string, typeId string) error {
+	sizeMB, err := strconv.ParseUint(strings.TrimSuffix(size, "M"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("Invalid disk size: %s", size)
+	}
+
+	_, err = d.client.Task("CreateVirtualDisk_Task", d.client.content.VirtualDiskManager, []vimParam{
+		{"name", d.dsPath(d.datastorePath(diskPathLocal))},
+		{"datacenter", d.datacenter},
+		{"spec", &vimVirtualDiskSpec{
+			XSIType:     "FileBackedVirtualDiskSpec",
+			AdapterType: "lsiLogic",
+			DiskType:    vsphereDiskType(typeId),
+			CapacityKb:  sizeMB * 1024,
+		}},
+	})
+	return err
+}
+
+func (d *VSphereDriver) CreateSnapshot(vmxPathLocal string, name string) error {
+	_, err := d.client.Task("CreateSnapshot_Task", d.vm, []vimParam{
+		{"name", name},
+		{"memory", false},
+		{"quiesce", false},
+	})
+	return err
+}
+
+func (d *VSphereDriver) IsRunning(string) (bool, error) {
+	state, err := d.client.Property(d.vm, "runtime.powerState")
+	if err != nil {
+		return false, err
+	}
+	return state.String() == "poweredOn", nil
+}
+
+func (d *VSphereDriver) Start(vmxPathLocal string, headless bool) error {
+	_, err := d.client.Task("PowerOnVM_Task", d.vm, nil)
+	return err
+}
+
+func (d *VSphereDriver) Stop(vmxPathLocal string) error {
+	_, err := d.client.Task("PowerOffVM_Task", d.vm, nil)
+	return err
+}
+
+func (d *VSphereDriver) Register(vmxPathLocal string) error {
+	vmxPath := filepath.Join(d.outputDir, filepath.Base(vmxPathLocal))
+	if err := d.upload(vmxPath, vmxPathLocal); err != nil {
+		return err
+	}
+
+	result, err := d.client.Task("RegisterVM_Task", d.vmFolder, []vimParam{
+		{"path", d.dsPath(vmxPath)},
+		{"asTemplate", false},
+		{"pool", d.pool},
+	})
+	if err != nil {
+		return err
+	}
+
+	d.vm = result.Ref()
+	return nil
+}
+
+func (d *VSphereDriver) Download(src, dst string) error {
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ds, path := splitDatastorePath(d.datastorePath(src))
+	return d.client.Download(d.dcName, ds, path, f)
+}
+
+func (d *VSphereDriver) SuppressMessages(vmxPath string) error {
+	return nil
+}
+
+func (d *VSphereDriver) Unregister(vmxPathLocal string) error {
+	_, err := d.client.Call("UnregisterVM", d.vm, nil)
+	return err
+}
+
+func (d *VSphereDriver) UploadISO(localPath string) (string, error) {
+	cacheRoot, _ := filepath.Abs(".")
+	targetFile, err := filepath.Rel(cacheRoot, localPath)
+	if err != nil {
+		return "", err
+	}
+
+	finalPath := d.datastorePath(targetFile)
+	if err := d.mkdir(filepath.Dir(finalPath)); err != nil {
+		return "", err
+	}
+
+	if err := d.upload(finalPath, localPath); err != nil {
+		return "", err
+	}
+
+	return finalPath, nil
+}
+
+func (d *VSphereDriver) ToolsIsoPath(string) string {
+	return ""
+}
+
+func (d *VSphereDriver) ToolsInstall() error {
+	_, err := d.client.Call("MountToolsInstaller", d.vm, nil)
+	return err
+}
+
+func (d *VSphereDriver) DhcpLeasesPath(string) string {
+	return ""
+}
+
+func (d *VSphereDriver) Verify() error {
+	checks := []func() error{
+		d.connect,
+		d.findInventory,
+	}
+
+	for _, check := range checks {
+		if err := check(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *VSphereDriver) HostIP() (string, error) {
+	conn, err := net.Dial("tcp", net.JoinHostPort(d.Host, strconv.FormatUint(uint64(d.Port), 10)))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	host, _, err := net.SplitHostPort(conn.LocalAddr().String())
+	return host, err
+}
+
+// VNCAddress returns the first port in the range that isn't configured
+// for VNC by any other VM on the host.
+func (d *VSphereDriver) VNCAddress(portMin, portMax uint) (string, uint) {
+	used, err := d.usedVNCPorts()
+	if err != nil {
+		log.Printf("Error looking up the VNC ports in use: %s", err)
+		return d.Host, 0
+	}
+
+	for port := portMin; port <= portMax; port++ {
+		if !used[port] {
+			return d.Host, port
+		}
+
+		log.Printf("VNC port %d in use", port)
+	}
+
+	return d.Host, 0
+}
+
+func (d *VSphereDriver) SSHAddress(state multistep.StateBag) (string, error) {
+	if address, ok := state.GetOk("vm_address"); ok {
+		return address.(string), nil
+	}
+
+	props, err := d.client.Properties(d.vm, "guest.ipAddress")
+	if err != nil {
+		return "", err
+	}
+
+	ip, ok := props["guest.ipAddress"]
+	if !ok || ip.String() == "" {
+		return "", errors.New("VM has no IP address yet, waiting for VMware Tools")
+	}
+
+	address := net.JoinHostPort(ip.String(), strconv.FormatUint(uint64(d.SSHConfig.SSHPort), 10))
+	state.Put("vm_address", address)
+	return address, nil
+}
+
+//-------------------------------------------------------------------
+// OutputDir implementation
+//-------------------------------------------------------------------
+
+func (d *VSphereDriver) DirExists() (bool, error) {
+	_, err := d.searchDatastore(d.outputDir)
+	return err == nil, nil
+}
+
+func (d *VSphereDriver) ListFiles() ([]string, error) {
+	files, err := d.searchDatastore(d.outputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0, len(files))
+	for _, f := range files {
+		result = append(result, filepath.Join(d.outputDir, f))
+	}
+
+	return result, nil
+}
+
+func (d *VSphereDriver) MkdirAll() error {
+	return d.mkdir(d.outputDir)
+}
+
+func (d *VSphereDriver) Remove(path string) error {
+	return d.deleteFile(path)
+}
+
+func (d *VSphereDriver) RemoveAll() error {
+	return d.deleteFile(d.outputDir)
+}
+
+func (d *VSphereDriver) SetOutputDir(path string) {
+	d.outputDir = d.datastorePath(path)
+}
+
+func (d *VSphereDriver) String() string {
+	return d.outputDir
+}
+
+// sourcePath returns the path on the host of a VM to clone, which is
+// either absolute or relative to the datastore.
+func (d *VSphereDriver) sourcePath(path string) string {
+	if strings.HasPrefix(path, "/vmfs/") {
+		return path
+	}
+
+	return filepath.Join("/vmfs/volumes", d.Datastore, path)
+}
+
+func (d *VSphereDriver) datastorePath(path string) string {
+	baseDir := filepath.Base(filepath.Dir(path))
+	return filepath.Join("/vmfs/volumes", d.Datastore, baseDir, filepath.Base(path))
+}
+
+// dsPath turns a path on the host into a datastore path of the API, such
+// as "[datastore1] dir/file.vmx".
+func (d *VSphereDriver) dsPath(path string) string {
+	ds, rel := splitDatastorePath(path)
+	return fmt.Sprintf("[%s] %s", ds, rel)
+}
+
+// cloneDeltaDisk copies a delta disk along with its extents and makes
+// the parent of the copy the parent disk of the original.
+func (d *VSphereDriver) cloneDeltaDisk(dst, src string) error {
+	descriptor, err := d.readFile(src)
+	if err != nil {
+		return err
+	}
+
+	descriptor, extents, err := linkedDiskDescriptor(descriptor, filepath.Dir(src))
+	if err != nil {
+		return fmt.Errorf("%s: %s", src, err)
+	}
+
+	for _, extent := range extents {
+		_, err := d.client.Task("CopyDatastoreFile_Task", d.client.content.FileManager, []vimParam{
+			{"sourceName", d.dsPath(filepath.Join(filepath.Dir(src), extent))},
+			{"sourceDatacenter", d.datacenter},
+			{"destinationName", d.dsPath(filepath.Join(filepath.Dir(dst), extent))},
+			{"destinationDatacenter", d.datacenter},
+			{"force", false},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return d.writeFile(dst, []byte(descriptor))
+}
+
+func (d *VSphereDriver) connect() error {
+	d.client = newVimClient(d.Host, d.Port, d.Insecure)
+	if err := d.client.Login(d.Username, d.Password); err != nil {
+		return err
+	}
+
+	about := d.client.content.About
+	log.Printf("Connected to %s (%s, API %s)", about.FullName, about.APIType, about.APIVersion)
+	return nil
+}
+
+// findInventory looks up the datacenter, folder, resource pool and
+// datastore the VM is created in. The first datacenter and the first
+// host or cluster in it are used, which on ESXi are the only ones.
+func (d *VSphereDriver) findInventory() error {
+	children, err := d.client.Property(d.client.content.RootFolder, "childEntity")
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range children.Refs() {
+		if ref.Type == "Datacenter" {
+			d.datacenter = ref
+			break
+		}
+	}
+	if d.datacenter.Value == "" {
+		return errors.New("No datacenter found")
+	}
+
+	props, err := d.client.Properties(d.datacenter, "name", "vmFolder", "hostFolder", "datastore")
+	if err != nil {
+		return err
+	}
+	for _, p := range []string{"name", "vmFolder", "hostFolder"} {
+		if _, ok := props[p]; !ok {
+			return fmt.Errorf("Datacenter %s has no %s", d.datacenter.Value, p)
+		}
+	}
+	d.dcName = props["name"].String()
+	d.vmFolder = props["vmFolder"].Ref()
+
+	if ds, ok := props["datastore"]; ok {
+		for _, ref := range ds.Refs() {
+			name, err := d.client.Property(ref, "name")
+			if err != nil {
+				return err
+			}
+
+			if name.String() == d.Datastore {
+				d.datastore = ref
+				break
+			}
+		}
+	}
+	if d.datastore.Value == "" {
+		return fmt.Errorf("Datastore not found: %s", d.Datastore)
+	}
+
+	browser, err := d.client.Property(d.datastore, "browser")
+	if err != nil {
+		return err
+	}
+	d.browser = browser.Ref()
+
+	hosts, err := d.client.Property(props["hostFolder"].Ref(), "childEntity")
+	if err != nil {
+		return err
+	}
+	for _, ref := range hosts.Refs() {
+		if ref.Type == "ComputeResource" || ref.Type == "ClusterComputeResource" {
+			pool, err := d.client.Property(ref, "resourcePool")
+			if err != nil {
+				return err
+			}
+
+			d.pool = pool.Ref()
+			break
+		}
+	}
+	if d.pool.Value == "" {
+		return errors.New("No host or cluster found")
+	}
+
+	return nil
+}
+
+// usedVNCPorts returns the VNC ports configured for the VMs in the folder
+// the VM is created in.
+func (d *VSphereDriver) usedVNCPorts() (map[uint]bool, error) {
+	children, err := d.client.Property(d.vmFolder, "childEntity")
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[uint]bool)
+	for _, ref := range children.Refs() {
+		if ref.Type != "VirtualMachine" {
+			continue
+		}
+
+		props, err := d.client.Properties(ref, "config.extraConfig")
+		if err != nil {
+			return nil, err
+		}
+
+		extraConfig, ok := props["config.extraConfig"]
+		if !ok {
+			continue
+		}
+
+		var options struct {
+			Values []struct {
+				Key   string `xml:"key"`
+				Value string `xml:"value"`
+			} `xml:"OptionValue"`
+		}
+		if err := extraConfig.Decode(&options); err != nil {
+			return nil, err
+		}
+
+		for _, o := range options.Values {
+			if strings.ToLower(o.Key) != "remotedisplay.vnc.port" {
+				continue
+			}
+
+			if port, err := strconv.ParseUint(o.Value, 10, 0); err == nil {
+				used[uint(port)] = true
+			}
+		}
+	}
+
+	return used, nil
+}
+
+// searchDatastore returns the names of the files in a directory.
+func (d *VSphereDriver) searchDatastore(dir string) ([]string, error) {
+	result, err := d.client.Task("SearchDatastore_Task", d.browser, []vimParam{
+		{"datastorePath", d.dsPath(dir)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var search struct {
+		Files []vimValue `xml:"file"`
+	}
+	if err := result.Decode(&search); err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(search.Files))
+	for _, f := range search.Files {
+		if f.XSIType == "FolderFileInfo" {
+			continue
+		}
+
+		var info struct {
+			Path string `xml:"path"`
+		}
+		if err := f.Decode(&info); err != nil {
+			return nil, err
+		}
+
+		files = append(files, info.Path)
+	}
+
+	return files, nil
+}
+
+func (d *VSphereDriver) mkdir(path string) error {
+	_, err := d.client.Call("MakeDirectory", d.client.content.FileManager, []vimParam{
+		{"name", d.dsPath(path)},
+		{"datacenter", d.datacenter},
+		{"createParentDirectories", true},
+	})
+	if isVimFault(err, "FileAlreadyExists") {
+		return nil
+	}
+	return err
+}
+
+func (d *VSphereDriver) deleteFile(path string) error {
+	_, err := d.client.Task("DeleteDatastoreFile_Task", d.client.content.FileManager, []vimParam{
+		{"name", d.dsPath(path)},
+		{"datacenter", d.datacenter},
+	})
+	return err
+}
+
+func (d *VSphereDriver) readFile(path string) (string, error) {
+	var buf bytes.Buffer
+	ds, rel := splitDatastorePath(path)
+	if err := d.client.Download(d.dcName, ds, rel, &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (d *VSphereDriver) writeFile(path string, data []byte) error {
+	ds, rel := splitDatastorePath(path)
+	return d.client.Upload(d.dcName, ds, rel, bytes.NewReader(data), int64(len(data)))
+}
+
+func (d *VSphereDriver) upload(dst, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	ds, rel := splitDatastorePath(dst)
+	return d.client.Upload(d.dcName, ds, rel, f, fi.Size())
+}
+
+type vimVirtualDiskSpec struct {
+	XSIType     string `xml:"xsi:type,attr"`
+	DiskType    string `xml:"diskType"`
+	AdapterType string `xml:"adapterType"`
+	CapacityKb  uint64 `xml:"capacityKb,omitempty"`
+}
+
+// splitDatastorePath splits a path such as "/vmfs/volumes/ds/dir/file"
+// into the name of the datastore and the path within it.
+func splitDatastorePath(path string) (string, string) {
+	path = strings.TrimPrefix(filepath.ToSlash(path), "/vmfs/volumes/")
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) < 2 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// vsphereDiskType returns the API disk type for the disk types that
+// vmkfstools takes, as in disk_type_id.
+func vsphereDiskType(typeId string) string {
+	switch strings.ToLower(typeId) {
+	case "zeroedthick":
+		return "preallocated"
+	case "eagerzeroedthick":
+		return "eagerZeroedThick"
+	case "thin":
+		return "thin"
+	}
+
+	return typeId
+}
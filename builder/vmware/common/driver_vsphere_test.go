@@ -0,0 +1,806 @@
+package common
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/multistep"
+)
+
+func TestVSphereDriver_implDriver(t *testing.T) {
+	var _ Driver = new(VSphereDriver)
+}
+
+func TestVSphereDriver_implOutputDir(t *testing.T) {
+	var _ OutputDir = new(VSphereDriver)
+}
+
+func TestVSphereDriver_implRemoteDriver(t *testing.T) {
+	var _ RemoteDriver = new(VSphereDriver)
+}
+
+func TestVSphereDriver_Verify(t *testing.T) {
+	sim := newVSphereSim()
+	server := httptest.NewTLSServer(sim)
+	defer server.Close()
+
+	// Good
+	d := testVSphereDriver(t, server)
+	if err := d.Verify(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if d.dcName != "ha-datacenter" {
+		t.Fatalf("bad: %s", d.dcName)
+	}
+	if d.pool.Value != "ha-root-pool" {
+		t.Fatalf("bad: %#v", d.pool)
+	}
+	if d.browser.Value != "datastoreBrowser-datastore1" {
+		t.Fatalf("bad: %#v", d.browser)
+	}
+
+	// Bad password
+	d = testVSphereDriver(t, server)
+	d.Password = "wrong"
+	if err := d.Verify(); err == nil || !strings.Contains(err.Error(), "incorrect user name or password") {
+		t.Fatalf("should fail to log in: %s", err)
+	}
+
+	// Unknown datastore
+	d = testVSphereDriver(t, server)
+	d.Datastore = "datastore2"
+	if err := d.Verify(); err == nil {
+		t.Fatal("should fail to find the datastore")
+	}
+
+	// Certificate isn't trusted
+	d = testVSphereDriver(t, server)
+	d.Insecure = false
+	if err := d.Verify(); err == nil {
+		t.Fatal("should fail to verify the certificate")
+	}
+}
+
+func TestVSphereDriver_build(t *testing.T) {
+	sim := newVSphereSim()
+	server := httptest.NewTLSServer(sim)
+	defer server.Close()
+
+	d := testVSphereDriver(t, server)
+	if err := d.Verify(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	d.client.PollInterval = time.Millisecond
+
+	// Output directory
+	d.SetOutputDir("output-foo")
+	if d.String() != "/vmfs/volumes/datastore1/output-foo" {
+		t.Fatalf("bad: %s", d.String())
+	}
+	if exists, _ := d.DirExists(); exists {
+		t.Fatal("should not exist")
+	}
+	if err := d.MkdirAll(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := d.MkdirAll(); err != nil {
+		t.Fatalf("should be able to create an existing dir: %s", err)
+	}
+	if exists, _ := d.DirExists(); !exists {
+		t.Fatal("should exist")
+	}
+
+	// Disk
+	if err := d.CreateDisk("output-foo/disk.vmdk", "40000M", "zeroedthick"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	spec := sim.diskSpecs["datastore1/output-foo/disk.vmdk"]
+	if spec.DiskType != "preallocated" || spec.CapacityKb != 40000*1024 {
+		t.Fatalf("bad: %#v", spec)
+	}
+
+	// ISO
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	iso := filepath.Join(td, "os.iso")
+	if err := ioutil.WriteFile(iso, []byte("iso"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	isoPath, err := d.UploadISO(iso)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !strings.HasPrefix(isoPath, "/vmfs/volumes/datastore1/") {
+		t.Fatalf("bad: %s", isoPath)
+	}
+	ds, rel := splitDatastorePath(isoPath)
+	if string(sim.files[ds+"/"+rel]) != "iso" {
+		t.Fatalf("should upload the ISO: %#v", sim.files)
+	}
+
+	// Register and run
+	vmxPath := filepath.Join(td, "foo.vmx")
+	if err := WriteVMX(vmxPath, map[string]string{"displayname": "foo"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := d.Register(vmxPath); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, ok := sim.files["datastore1/output-foo/foo.vmx"]; !ok {
+		t.Fatal("should upload the VMX")
+	}
+	if d.vm.Type != "VirtualMachine" || sim.vms[d.vm.Value] == nil {
+		t.Fatalf("bad: %#v", d.vm)
+	}
+
+	state := new(multistep.BasicStateBag)
+	if _, err := d.SSHAddress(state); err == nil {
+		t.Fatal("should have no address before starting")
+	}
+
+	if err := d.Start(vmxPath, true); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if running, err := d.IsRunning(vmxPath); err != nil || !running {
+		t.Fatalf("should be running: %s", err)
+	}
+
+	address, err := d.SSHAddress(state)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if address != "10.0.0.2:22" {
+		t.Fatalf("bad: %s", address)
+	}
+
+	if err := d.ToolsInstall(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !sim.vms[d.vm.Value].ToolsMounted {
+		t.Fatal("should mount the tools")
+	}
+
+	if err := d.Stop(vmxPath); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if running, _ := d.IsRunning(vmxPath); running {
+		t.Fatal("should not be running")
+	}
+
+	if err := d.CreateSnapshot(vmxPath, "done"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if snapshots := sim.vms[d.vm.Value].Snapshots; len(snapshots) != 1 || snapshots[0] != "done" {
+		t.Fatalf("bad: %#v", snapshots)
+	}
+
+	// Files
+	files, err := d.ListFiles()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []string{
+		"/vmfs/volumes/datastore1/output-foo/disk.vmdk",
+		"/vmfs/volumes/datastore1/output-foo/foo.vmx",
+	}
+	sort.Strings(files)
+	if strings.Join(files, ",") != strings.Join(expected, ",") {
+		t.Fatalf("bad: %#v", files)
+	}
+
+	local := filepath.Join(td, "download.vmx")
+	if err := d.Download("output-foo/foo.vmx", local); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if data, _ := ReadVMX(local); data["displayname"] != "foo" {
+		t.Fatalf("bad: %#v", data)
+	}
+
+	// Cleanup
+	vm := d.vm.Value
+	if err := d.Unregister(vmxPath); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if sim.vms[vm] != nil {
+		t.Fatal("should unregister")
+	}
+
+	if err := d.Remove(files[0]); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := d.RemoveAll(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if exists, _ := d.DirExists(); exists {
+		t.Fatal("should be removed")
+	}
+}
+
+func TestVSphereDriver_Clone(t *testing.T) {
+	sim := newVSphereSim()
+	sim.dirs["datastore1/base"] = true
+	sim.files["datastore1/base/base.vmx"] = []byte(`
+displayName = "base"
+uuid.bios = "56 4d"
+scsi0.present = "TRUE"
+scsi0:0.present = "TRUE"
+scsi0:0.fileName = "base-000001.vmdk"
+`)
+	sim.files["datastore1/base/base.vmsd"] = []byte(`
+snapshot.current = "1"
+snapshot0.uid = "1"
+snapshot0.displayName = "installed"
+`)
+	sim.files["datastore1/base/base-000001.vmdk"] = []byte(testDeltaDescriptor)
+	sim.files["datastore1/base/base-000001-delta.vmdk"] = []byte("delta")
+
+	server := httptest.NewTLSServer(sim)
+	defer server.Close()
+
+	d := testVSphereDriver(t, server)
+	if err := d.Verify(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	d.client.PollInterval = time.Millisecond
+
+	// Full clone
+	if err := d.Clone("output-full/full.vmx", "base/base.vmx", false, ""); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, ok := sim.files["datastore1/output-full/base-000001.vmdk"]; !ok {
+		t.Fatal("should copy the disk")
+	}
+	vmx := ParseVMX(string(sim.files["datastore1/output-full/full.vmx"]))
+	if vmx["displayname"] != "full" {
+		t.Fatalf("bad: %#v", vmx)
+	}
+	if _, ok := vmx["uuid.bios"]; ok {
+		t.Fatal("should remove the uuid")
+	}
+
+	// Linked clone of a snapshot that isn't the current one
+	err := d.Clone("output-linked/linked.vmx", "/vmfs/volumes/datastore1/base/base.vmx", true, "provisioned")
+	if err == nil {
+		t.Fatal("should fail for a snapshot that isn't current")
+	}
+
+	// Linked clone of the current snapshot
+	err = d.Clone("output-linked/linked.vmx", "/vmfs/volumes/datastore1/base/base.vmx", true, "installed")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(sim.files["datastore1/output-linked/base-000001-delta.vmdk"]) != "delta" {
+		t.Fatal("should copy the delta extent")
+	}
+	descriptor := string(sim.files["datastore1/output-linked/base-000001.vmdk"])
+	if !strings.Contains(descriptor, `parentFileNameHint="/vmfs/volumes/datastore1/base/base.vmdk"`) {
+		t.Fatalf("bad: %s", descriptor)
+	}
+}
+
+func TestVSphereDriver_VNCAddress(t *testing.T) {
+	sim := newVSphereSim()
+	sim.vms["vm-1"] = &vsphereSimVM{Name: "other", PowerState: "poweredOn", VNCPort: "5900"}
+
+	server := httptest.NewTLSServer(sim)
+	defer server.Close()
+
+	d := testVSphereDriver(t, server)
+	if err := d.Verify(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	host, port := d.VNCAddress(5900, 5902)
+	if host != d.Host {
+		t.Fatalf("bad: %s", host)
+	}
+	if port != 5901 {
+		t.Fatalf("bad: %d", port)
+	}
+
+	if _, port := d.VNCAddress(5900, 5900); port != 0 {
+		t.Fatalf("bad: %d", port)
+	}
+}
+
+func TestSplitDatastorePath(t *testing.T) {
+	ds, path := splitDatastorePath("/vmfs/volumes/datastore1/output/foo.vmx")
+	if ds != "datastore1" || path != "output/foo.vmx" {
+		t.Fatalf("bad: %s %s", ds, path)
+	}
+
+	ds, path = splitDatastorePath("/vmfs/volumes/datastore1")
+	if ds != "datastore1" || path != "" {
+		t.Fatalf("bad: %s %s", ds, path)
+	}
+}
+
+func TestVSphereDiskType(t *testing.T) {
+	cases := map[string]string{
+		"zeroedthick":      "preallocated",
+		"eagerzeroedthick": "eagerZeroedThick",
+		"thin":             "thin",
+		"sesparse":         "sesparse",
+	}
+
+	for typeId, expected := range cases {
+		if actual := vsphereDiskType(typeId); actual != expected {
+			t.Fatalf("%s: %s", typeId, actual)
+		}
+	}
+}
+
+func testVSphereDriver(t *testing.T, server *httptest.Server) *VSphereDriver {
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	host, portStr, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	port, _ := strconv.Atoi(portStr)
+
+	return &VSphereDriver{
+		Host:      host,
+		Port:      uint(port),
+		Username:  "root",
+		Password:  "secret",
+		Datastore: "datastore1",
+		Insecure:  true,
+		SSHConfig: &SSHConfig{SSHPort: 22},
+	}
+}
+
+// vsphereSim simulates the parts of the vSphere API of an ESXi host that
+// the VSphereDriver uses: the SOAP methods on /sdk and the datastore
+// file transfers on /folder.
+type vsphereSim struct {
+	sync.Mutex
+
+	dirs      map[string]bool
+	files     map[string][]byte
+	diskSpecs map[string]vimVirtualDiskSpec
+	tasks     map[string]*vsphereSimTask
+	vms       map[string]*vsphereSimVM
+	nextId    int
+}
+
+type vsphereSimVM struct {
+	Name         string
+	PowerState   string
+	IP           string
+	Snapshots    []string
+	ToolsMounted bool
+	VNCPort      string
+}
+
+type vsphereSimTask struct {
+	polls  int
+	result string
+	fault  string
+}
+
+const vsphereSimSession = "vsphere-sim-session"
+
+func newVSphereSim() *vsphereSim {
+	return &vsphereSim{
+		dirs:      map[string]bool{"datastore1": true},
+		files:     make(map[string][]byte),
+		diskSpecs: make(map[string]vimVirtualDiskSpec),
+		tasks:     make(map[string]*vsphereSimTask),
+		vms:       make(map[string]*vsphereSimVM),
+	}
+}
+
+func (s *vsphereSim) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	if strings.HasPrefix(r.URL.Path, "/folder/") {
+		s.serveFile(w, r)
+		return
+	}
+
+	method, params, err := parseVSphereSimRequest(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	if method != "RetrieveServiceContent" && method != "Login" {
+		if c, err := r.Cookie("vmware_soap_session"); err != nil || c.Value != vsphereSimSession {
+			s.fault(w, "NotAuthenticated", "The session is not authenticated.")
+			return
+		}
+	}
+
+	param := func(name string) string {
+		if v, ok := params[name]; ok {
+			return v.String()
+		}
+		return ""
+	}
+
+	switch method {
+	case "RetrieveServiceContent":
+		s.respond(w, method, `<returnval>`+
+			`<rootFolder type="Folder">ha-folder-root</rootFolder>`+
+			`<propertyCollector type="PropertyCollector">ha-property-collector</propertyCollector>`+
+			`<about><fullName>VMware ESXi 5.5.0 (simulated)</fullName><apiType>HostAgent</apiType><apiVersion>5.5</apiVersion></about>`+
+			`<sessionManager type="SessionManager">ha-sessionmgr</sessionManager>`+
+			`<fileManager type="FileManager">ha-nfc-file-manager</fileManager>`+
+			`<virtualDiskManager type="VirtualDiskManager">ha-vdiskmanager</virtualDiskManager>`+
+			`</returnval>`)
+	case "Login":
+		if param("userName") != "root" || param("password") != "secret" {
+			s.fault(w, "InvalidLogin", "Cannot complete login due to an incorrect user name or password.")
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{Name: "vmware_soap_session", Value: vsphereSimSession})
+		s.respond(w, method, `<returnval><key>session</key><userName>root</userName></returnval>`)
+	case "RetrieveProperties":
+		var spec vimPropertyFilterSpec
+		if err := params["specSet"].Decode(&spec); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+
+		obj := spec.ObjectSet.Obj
+		body := fmt.Sprintf(`<returnval><obj type="%s">%s</obj>`, obj.Type, obj.Value)
+		for _, path := range spec.PropSet.PathSet {
+			if val, ok := s.property(obj, path); ok {
+				body += fmt.Sprintf(`<propSet><name>%s</name>%s</propSet>`, path, val)
+			}
+		}
+		s.respond(w, method, body+`</returnval>`)
+	case "MakeDirectory":
+		key := vsphereSimKey(param("name"))
+		if s.dirs[key] {
+			s.fault(w, "FileAlreadyExists", "Cannot complete file creation operation.")
+			return
+		}
+
+		for dir := key; dir != "." && dir != "/"; dir = filepath.Dir(dir) {
+			s.dirs[dir] = true
+		}
+		s.respond(w, method, "")
+	case "SearchDatastore_Task":
+		key := vsphereSimKey(param("datastorePath"))
+		if !s.dirs[key] {
+			s.task(w, method, "", "FileNotFound")
+			return
+		}
+
+		result := `<result xsi:type="HostDatastoreBrowserSearchResults">`
+		for dir, _ := range s.dirs {
+			if filepath.Dir(dir) == key {
+				result += fmt.Sprintf(`<file xsi:type="FolderFileInfo"><path>%s</path></file>`, filepath.Base(dir))
+			}
+		}
+		for file, _ := range s.files {
+			if filepath.Dir(file) == key {
+				result += fmt.Sprintf(`<file xsi:type="FileInfo"><path>%s</path></file>`, filepath.Base(file))
+			}
+		}
+		s.task(w, method, result+`</result>`, "")
+	case "DeleteDatastoreFile_Task":
+		key := vsphereSimKey(param("name"))
+		if _, ok := s.files[key]; ok {
+			delete(s.files, key)
+		} else if s.dirs[key] {
+			for file, _ := range s.files {
+				if strings.HasPrefix(file, key+"/") {
+					delete(s.files, file)
+				}
+			}
+			for dir, _ := range s.dirs {
+				if dir == key || strings.HasPrefix(dir, key+"/") {
+					delete(s.dirs, dir)
+				}
+			}
+		} else {
+			s.task(w, method, "", "FileNotFound")
+			return
+		}
+		s.task(w, method, "", "")
+	case "CopyDatastoreFile_Task", "CopyVirtualDisk_Task":
+		src := vsphereSimKey(param("sourceName"))
+		dst := vsphereSimKey(param("destinationName") + param("destName"))
+		data, ok := s.files[src]
+		if !ok {
+			s.task(w, method, "", "FileNotFound")
+			return
+		}
+		if _, ok := s.files[dst]; ok {
+			s.task(w, method, "", "FileAlreadyExists")
+			return
+		}
+
+		s.files[dst] = data
+		s.task(w, method, "", "")
+	case "CreateVirtualDisk_Task":
+		var spec vimVirtualDiskSpec
+		if err := params["spec"].Decode(&spec); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+
+		key := vsphereSimKey(param("name"))
+		s.files[key] = []byte("disk")
+		s.diskSpecs[key] = spec
+		s.task(w, method, "", "")
+	case "RegisterVM_Task":
+		data, ok := s.files[vsphereSimKey(param("path"))]
+		if !ok {
+			s.task(w, method, "", "NotFound")
+			return
+		}
+
+		vmx := ParseVMX(string(data))
+		s.nextId++
+		id := fmt.Sprintf("vm-%d", s.nextId)
+		s.vms[id] = &vsphereSimVM{
+			Name:       vmx["displayname"],
+			PowerState: "poweredOff",
+			VNCPort:    vmx["remotedisplay.vnc.port"],
+		}
+		s.task(w, method, fmt.Sprintf(
+			`<result type="VirtualMachine" xsi:type="ManagedObjectReference">%s</result>`, id), "")
+	default:
+		vm, ok := s.vms[params["_this"].Ref().Value]
+		if !ok {
+			s.fault(w, "ManagedObjectNotFound", "The object has already been deleted or has not been completely created")
+			return
+		}
+
+		switch method {
+		case "PowerOnVM_Task":
+			vm.PowerState = "poweredOn"
+			vm.IP = "10.0.0.2"
+			s.task(w, method, "", "")
+		case "PowerOffVM_Task":
+			vm.PowerState = "poweredOff"
+			vm.IP = ""
+			s.task(w, method, "", "")
+		case "CreateSnapshot_Task":
+			vm.Snapshots = append(vm.Snapshots, param("name"))
+			s.task(w, method, "", "")
+		case "MountToolsInstaller":
+			vm.ToolsMounted = true
+			s.respond(w, method, "")
+		case "UnregisterVM":
+			delete(s.vms, params["_this"].Ref().Value)
+			s.respond(w, method, "")
+		default:
+			s.fault(w, "MethodNotFound", "Unknown method "+method)
+		}
+	}
+}
+
+func (s *vsphereSim) serveFile(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie("vmware_soap_session"); err != nil || c.Value != vsphereSimSession {
+		http.Error(w, "Unauthorized", 401)
+		return
+	}
+
+	if r.URL.Query().Get("dcPath") != "ha-datacenter" {
+		http.NotFound(w, r)
+		return
+	}
+
+	key := r.URL.Query().Get("dsName") + "/" + strings.TrimPrefix(r.URL.Path, "/folder/")
+	switch r.Method {
+	case "GET":
+		data, ok := s.files[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	case "PUT":
+		if !s.dirs[filepath.Dir(key)] {
+			http.NotFound(w, r)
+			return
+		}
+
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		s.files[key] = data
+		w.WriteHeader(201)
+	default:
+		http.Error(w, "Method not allowed", 405)
+	}
+}
+
+func (s *vsphereSim) property(obj moRef, path string) (string, bool) {
+	ref := func(t, v string) string {
+		return fmt.Sprintf(`<val type="%s" xsi:type="ManagedObjectReference">%s</val>`, t, v)
+	}
+	refs := func(refs ...moRef) string {
+		result := `<val xsi:type="ArrayOfManagedObjectReference">`
+		for _, r := range refs {
+			result += fmt.Sprintf(`<ManagedObjectReference type="%s">%s</ManagedObjectReference>`, r.Type, r.Value)
+		}
+		return result + `</val>`
+	}
+	str := func(v string) string {
+		return fmt.Sprintf(`<val xsi:type="xsd:string">%s</val>`, v)
+	}
+
+	switch obj.Type + "/" + obj.Value + "/" + path {
+	case "Folder/ha-folder-root/childEntity":
+		return refs(moRef{"Datacenter", "ha-datacenter"}), true
+	case "Datacenter/ha-datacenter/name":
+		return str("ha-datacenter"), true
+	case "Datacenter/ha-datacenter/vmFolder":
+		return ref("Folder", "ha-folder-vm"), true
+	case "Datacenter/ha-datacenter/hostFolder":
+		return ref("Folder", "ha-folder-host"), true
+	case "Datacenter/ha-datacenter/datastore":
+		return refs(moRef{"Datastore", "datastore-1"}), true
+	case "Datastore/datastore-1/name":
+		return str("datastore1"), true
+	case "Datastore/datastore-1/browser":
+		return ref("HostDatastoreBrowser", "datastoreBrowser-datastore1"), true
+	case "Folder/ha-folder-host/childEntity":
+		return refs(moRef{"ComputeResource", "ha-compute-res"}), true
+	case "ComputeResource/ha-compute-res/resourcePool":
+		return ref("ResourcePool", "ha-root-pool"), true
+	case "Folder/ha-folder-vm/childEntity":
+		ids := make([]string, 0, len(s.vms))
+		for id, _ := range s.vms {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		vms := make([]moRef, len(ids))
+		for i, id := range ids {
+			vms[i] = moRef{"VirtualMachine", id}
+		}
+		return refs(vms...), true
+	}
+
+	if obj.Type == "Task" {
+		task, ok := s.tasks[obj.Value]
+		if !ok || path != "info" {
+			return "", false
+		}
+
+		// Tasks are running when they are first looked at.
+		task.polls++
+		if task.polls == 1 {
+			return `<val xsi:type="TaskInfo"><state>running</state></val>`, true
+		}
+		if task.fault != "" {
+			return fmt.Sprintf(`<val xsi:type="TaskInfo"><state>error</state>`+
+				`<error><fault xsi:type="%s"></fault><localizedMessage>%s</localizedMessage></error></val>`,
+				task.fault, task.fault), true
+		}
+		return `<val xsi:type="TaskInfo"><state>success</state>` + task.result + `</val>`, true
+	}
+
+	vm, ok := s.vms[obj.Value]
+	if obj.Type != "VirtualMachine" || !ok {
+		return "", false
+	}
+
+	switch path {
+	case "runtime.powerState":
+		return str(vm.PowerState), true
+	case "guest.ipAddress":
+		return str(vm.IP), vm.IP != ""
+	case "config.extraConfig":
+		result := `<val xsi:type="ArrayOfOptionValue">`
+		if vm.VNCPort != "" {
+			result += fmt.Sprintf(`<OptionValue xsi:type="OptionValue"><key>RemoteDisplay.vnc.port</key>`+
+				`<value xsi:type="xsd:string">%s</value></OptionValue>`, vm.VNCPort)
+		}
+		return result + `</val>`, true
+	}
+
+	return "", false
+}
+
+func (s *vsphereSim) task(w http.ResponseWriter, method, result, fault string) {
+	s.nextId++
+	id := fmt.Sprintf("task-%d", s.nextId)
+	s.tasks[id] = &vsphereSimTask{result: result, fault: fault}
+	s.respond(w, method, fmt.Sprintf(`<returnval type="Task">%s</returnval>`, id))
+}
+
+func (s *vsphereSim) respond(w http.ResponseWriter, method, body string) {
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	fmt.Fprintf(w, `%s<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"`+
+		` xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">`+
+		`<soapenv:Body><%sResponse xmlns="urn:vim25">%s</%sResponse></soapenv:Body></soapenv:Envelope>`,
+		xml.Header, method, body, method)
+}
+
+func (s *vsphereSim) fault(w http.ResponseWriter, fault, message string) {
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.WriteHeader(500)
+	fmt.Fprintf(w, `%s<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"`+
+		` xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"><soapenv:Body><soapenv:Fault>`+
+		`<faultcode>ServerFaultCode</faultcode><faultstring>%s</faultstring>`+
+		`<detail><%sFault xmlns="urn:vim25" xsi:type="%s"></%sFault></detail>`+
+		`</soapenv:Fault></soapenv:Body></soapenv:Envelope>`,
+		xml.Header, message, fault, fault, fault)
+}
+
+// parseVSphereSimRequest returns the method of a SOAP request and its
+// parameters, including _this.
+func parseVSphereSimRequest(r io.Reader) (string, map[string]*vimValue, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	method := ""
+	params := make(map[string]*vimValue)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, err
+		}
+
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case se.Name.Local == "Envelope" || se.Name.Local == "Body":
+		case method == "":
+			method = se.Name.Local
+		default:
+			v := new(vimValue)
+			if err := dec.DecodeElement(v, &se); err != nil {
+				return "", nil, err
+			}
+			params[se.Name.Local] = v
+		}
+	}
+
+	if method == "" {
+		return "", nil, fmt.Errorf("no method in request: %s", data)
+	}
+
+	return method, params, nil
+}
+
+// vsphereSimKey turns a datastore path such as "[datastore1] dir/file"
+// into the key of the file in the simulator.
+func vsphereSimKey(path string) string {
+	path = strings.TrimPrefix(path, "[")
+	path = strings.Replace(path, "] ", "/", 1)
+	return strings.TrimSuffix(strings.TrimSuffix(path, "]"), "/")
+}
@@ -13,11 +13,11 @@ type Workstation10Driver struct {
 	Workstation9Driver
 }
 
-func (d *Workstation10Driver) Clone(dst, src string) error {
-	cmd := exec.Command(d.Workstation9Driver.VmrunPath,
-		"-T", "ws",
-		"clone", src, dst,
-		"full")
+func (d *Workstation10Driver) Clone(dst, src string, linked bool, snapshot string) error {
+	args := []string{"-T", "ws", "clone", src, dst}
+	args = append(args, cloneArgs(linked, snapshot)...)
+
+	cmd := exec.Command(d.Workstation9Driver.VmrunPath, args...)
 
 	if _, _, err := runAndLog(cmd); err != nil {
 		return err
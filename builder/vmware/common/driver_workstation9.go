@@ -22,7 +22,7 @@ type Workstation9Driver struct {
 	SSHConfig *SSHConfig
 }
 
-func (d *Workstation9Driver) Clone(dst, src string) error {
+func (d *Workstation9Driver) Clone(dst, src string, linked bool, snapshot string) error {
 	return errors.New("Cloning is not supported with VMWare WS version 9. Please use VMWare WS version 10, or greater.")
 }
 
@@ -49,6 +49,15 @@ func (d *Workstation9Driver) CreateDisk(output string, size string, type_id stri
 	return nil
 }
 
+func (d *Workstation9Driver) CreateSnapshot(vmxPath string, name string) error {
+	cmd := exec.Command(d.VmrunPath, "-T", "ws", "snapshot", vmxPath, name)
+	if _, _, err := runAndLog(cmd); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (d *Workstation9Driver) IsRunning(vmxPath string) (bool, error) {
 	vmxPath, err := filepath.Abs(vmxPath)
 	if err != nil {
@@ -0,0 +1,47 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mitchellh/packer/packer"
+)
+
+// ExportConfig configures what the result of a build is: exported
+// files, or the VM itself left in place for further use.
+type ExportConfig struct {
+	Format         string `mapstructure:"format"`
+	KeepRegistered bool   `mapstructure:"keep_registered"`
+	OVFToolPath    string `mapstructure:"ovftool_path"`
+	SkipExport     bool   `mapstructure:"skip_export"`
+	SnapshotName   string `mapstructure:"snapshot_name"`
+}
+
+func (c *ExportConfig) Prepare(t *packer.ConfigTemplate) []error {
+	if c.OVFToolPath == "" {
+		c.OVFToolPath = "ovftool"
+	}
+
+	templates := map[string]*string{
+		"format":        &c.Format,
+		"ovftool_path":  &c.OVFToolPath,
+		"snapshot_name": &c.SnapshotName,
+	}
+
+	errs := make([]error, 0)
+	for n, ptr := range templates {
+		var err error
+		*ptr, err = t.Process(*ptr, nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Error processing %s: %s", n, err))
+		}
+	}
+
+	switch c.Format {
+	case "", "ovf", "ova", "vmx":
+	default:
+		errs = append(errs,
+			errors.New("format must be one of 'ovf', 'ova' or 'vmx'"))
+	}
+
+	return errs
+}
@@ -0,0 +1,52 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestExportConfigPrepare(t *testing.T) {
+	c := new(ExportConfig)
+	c.SnapshotName = `{{"foo"}}-bar`
+	errs := c.Prepare(testConfigTemplate(t))
+	if len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+
+	if c.SnapshotName != "foo-bar" {
+		t.Fatalf("bad: %s", c.SnapshotName)
+	}
+}
+
+func TestExportConfigPrepare_badTemplate(t *testing.T) {
+	c := new(ExportConfig)
+	c.SnapshotName = "{{"
+	errs := c.Prepare(testConfigTemplate(t))
+	if len(errs) == 0 {
+		t.Fatal("should have error")
+	}
+}
+
+func TestExportConfigPrepare_format(t *testing.T) {
+	c := new(ExportConfig)
+	errs := c.Prepare(testConfigTemplate(t))
+	if len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+	if c.OVFToolPath != "ovftool" {
+		t.Fatalf("bad: %s", c.OVFToolPath)
+	}
+
+	for _, format := range []string{"ovf", "ova", "vmx"} {
+		c = new(ExportConfig)
+		c.Format = format
+		if errs := c.Prepare(testConfigTemplate(t)); len(errs) > 0 {
+			t.Fatalf("err: %#v", errs)
+		}
+	}
+
+	c = new(ExportConfig)
+	c.Format = "vmdk"
+	if errs := c.Prepare(testConfigTemplate(t)); len(errs) == 0 {
+		t.Fatal("should have error")
+	}
+}
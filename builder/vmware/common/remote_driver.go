@@ -1,11 +1,13 @@
-package iso
-
-import (
-	vmwcommon "github.com/mitchellh/packer/builder/vmware/common"
-)
+package common
 
+// RemoteDriver is a Driver for a remote host, where the output directory
+// and the VM are on a datastore of the host.
 type RemoteDriver interface {
-	vmwcommon.Driver
+	Driver
+
+	// Download copies a file in the output directory on the remote side
+	// to the given local path.
+	Download(string, string) error
 
 	// UploadISO uploads a local ISO to the remote side and returns the
 	// new path that should be used in the VMX along with an error if it
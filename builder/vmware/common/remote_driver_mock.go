@@ -1,11 +1,17 @@
-package iso
+package common
 
 import (
-	vmwcommon "github.com/mitchellh/packer/builder/vmware/common"
+	"io/ioutil"
 )
 
 type RemoteDriverMock struct {
-	vmwcommon.DriverMock
+	DriverMock
+
+	DownloadCalled bool
+	DownloadSrc    string
+	DownloadDst    string
+	DownloadData   string
+	DownloadErr    error
 
 	UploadISOCalled bool
 	UploadISOPath   string
@@ -21,6 +27,18 @@ type RemoteDriverMock struct {
 	UnregisterErr    error
 }
 
+func (d *RemoteDriverMock) Download(src, dst string) error {
+	d.DownloadCalled = true
+	d.DownloadSrc = src
+	d.DownloadDst = dst
+	if d.DownloadErr != nil {
+		return d.DownloadErr
+	}
+
+	// Write the contents so that the downloaded file can be read.
+	return ioutil.WriteFile(dst, []byte(d.DownloadData), 0644)
+}
+
 func (d *RemoteDriverMock) UploadISO(path string) (string, error) {
 	d.UploadISOCalled = true
 	d.UploadISOPath = path
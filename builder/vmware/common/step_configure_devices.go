@@ -0,0 +1,170 @@
+package common
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// This step configures the devices of the VM as given by the device
+// configuration: it creates and attaches the additional disks, moves the
+// CD-ROM drives, sets the network adapter type and the serial port.
+//
+// Uses:
+//   driver   Driver
+//   ui       packer.Ui
+//   vmx_path string
+//
+// Produces:
+//   <nothing>
+type StepConfigureDevices struct {
+	Config     *DeviceConfig
+	DiskName   string
+	DiskTypeId string
+	OutputDir  string
+}
+
+func (s *StepConfigureDevices) Run(state multistep.StateBag) multistep.StepAction {
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+	vmxPath := state.Get("vmx_path").(string)
+
+	vmxData, err := ReadVMX(vmxPath)
+	if err != nil {
+		err := fmt.Errorf("Error reading VMX file: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if err := s.configure(driver, ui, vmxData); err != nil {
+		err := fmt.Errorf("Error configuring devices: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if err := WriteVMX(vmxPath, vmxData); err != nil {
+		err := fmt.Errorf("Error writing VMX file: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepConfigureDevices) Cleanup(state multistep.StateBag) {}
+
+func (s *StepConfigureDevices) configure(driver Driver, ui packer.Ui, vmxData map[string]string) error {
+	devices := ParseVMXDevices(vmxData)
+
+	if len(s.Config.AdditionalDiskSize) > 0 {
+		ui.Say("Creating additional disks...")
+
+		// The additional disks go on the same adapter as the first disk.
+		adapter := "scsi"
+		if len(devices.Disks) > 0 {
+			adapter = devices.Disks[0].Adapter
+		}
+
+		typeId := s.DiskTypeId
+		if typeId == "" {
+			typeId = "1"
+		}
+
+		for i, size := range s.Config.AdditionalDiskSize {
+			name := fmt.Sprintf("%s-%d.vmdk", s.DiskName, i+1)
+			path := filepath.Join(s.OutputDir, name)
+			if err := driver.CreateDisk(path, fmt.Sprintf("%dM", size), typeId); err != nil {
+				return fmt.Errorf("Error creating disk %s: %s", name, err)
+			}
+
+			slot, err := devices.FreeSlot(adapter)
+			if err != nil {
+				return err
+			}
+
+			log.Printf("Attaching %s to %s", name, slot.Key())
+			enableVMXController(vmxData, slot)
+			devices.Disks = append(devices.Disks, &VMXDisk{
+				VMXSlot:  slot,
+				FileName: name,
+			})
+		}
+	}
+
+	if adapter := s.Config.CDROMAdapterType; adapter != "" {
+		for _, cdrom := range devices.CDROMs {
+			if cdrom.Adapter == adapter {
+				continue
+			}
+
+			slot, err := devices.FreeSlot(adapter)
+			if err != nil {
+				return err
+			}
+
+			log.Printf("Moving CD-ROM drive from %s to %s", cdrom.Key(), slot.Key())
+			RenameVMXDevice(vmxData, cdrom.Key(), slot.Key())
+			enableVMXController(vmxData, slot)
+			cdrom.VMXSlot = slot
+		}
+	}
+
+	if s.Config.NetworkAdapterType != "" {
+		for _, nic := range devices.NetworkAdapters {
+			nic.VirtualDev = s.Config.NetworkAdapterType
+		}
+	}
+
+	if s.Config.Serial != "" {
+		port, err := s.Config.SerialPort()
+		if err != nil {
+			return err
+		}
+
+		// Replace the first serial port, or remove it for "NONE".
+		RemoveVMXDevice(vmxData, "serial0")
+		ports := make([]*VMXSerialPort, 0, len(devices.SerialPorts))
+		for _, p := range devices.SerialPorts {
+			if p.Index != 0 {
+				ports = append(ports, p)
+			}
+		}
+
+		if port != nil {
+			ports = append(ports, port)
+		}
+
+		devices.SerialPorts = ports
+	}
+
+	devices.Encode(vmxData)
+	return nil
+}
+
+// enableVMXController makes sure the controller of the given slot is
+// present. IDE controllers are always there.
+func enableVMXController(vmxData map[string]string, slot VMXSlot) {
+	if slot.Adapter == "ide" {
+		return
+	}
+
+	key := fmt.Sprintf("%s%d", slot.Adapter, slot.Bus)
+	if vmxBool(vmxData[key+".present"]) {
+		return
+	}
+
+	vmxData[key+".present"] = "TRUE"
+	if slot.Adapter == "scsi" {
+		if _, ok := vmxData[key+".virtualdev"]; !ok {
+			vmxData[key+".virtualdev"] = "lsilogic"
+		}
+	}
+
+	log.Printf("Enabling controller %s", key)
+}
@@ -0,0 +1,135 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mitchellh/multistep"
+)
+
+func TestStepConfigureDevices_impl(t *testing.T) {
+	var _ multistep.Step = new(StepConfigureDevices)
+}
+
+func TestStepConfigureDevices(t *testing.T) {
+	state := testState(t)
+	step := &StepConfigureDevices{
+		Config: &DeviceConfig{
+			AdditionalDiskSize: []uint{1024},
+			CDROMAdapterType:   "sata",
+			NetworkAdapterType: "vmxnet3",
+			Serial:             "PIPE:/tmp/packer.pipe",
+		},
+		DiskName:  "disk",
+		OutputDir: "/tmp/output",
+	}
+
+	vmxPath := testVMXFile(t)
+	defer os.Remove(vmxPath)
+	if err := ioutil.WriteFile(vmxPath, []byte(testVMXDevices), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	state.Put("vmx_path", vmxPath)
+
+	driver := state.Get("driver").(*DriverMock)
+
+	// Test the run
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+
+	// Test the driver
+	if !driver.CreateDiskCalled {
+		t.Fatal("should've called")
+	}
+	if driver.CreateDiskOutput != filepath.Join("/tmp/output", "disk-1.vmdk") {
+		t.Fatalf("bad: %s", driver.CreateDiskOutput)
+	}
+	if driver.CreateDiskSize != "1024M" {
+		t.Fatalf("bad: %s", driver.CreateDiskSize)
+	}
+	if driver.CreateDiskTypeId != "1" {
+		t.Fatalf("bad: %s", driver.CreateDiskTypeId)
+	}
+
+	// Test the resulting data
+	vmxData, err := ReadVMX(vmxPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := []struct {
+		Key   string
+		Value string
+	}{
+		// The additional disk goes after the existing ones
+		{"scsi0:2.present", "TRUE"},
+		{"scsi0:2.filename", "disk-1.vmdk"},
+
+		// The CD-ROM is moved to a new SATA controller
+		{"ide1:0.present", ""},
+		{"sata0.present", "TRUE"},
+		{"sata0:0.present", "TRUE"},
+		{"sata0:0.devicetype", "cdrom-image"},
+		{"sata0:0.filename", "/tmp/packer.iso"},
+
+		// All the network adapters are changed
+		{"ethernet0.virtualdev", "vmxnet3"},
+		{"ethernet1.virtualdev", "vmxnet3"},
+
+		// The first serial port is replaced
+		{"serial0.filetype", "pipe"},
+		{"serial0.filename", "/tmp/packer.pipe"},
+		{"serial0.pipe.endpoint", "server"},
+		{"serial1.filetype", "pipe"},
+	}
+
+	for _, tc := range cases {
+		if tc.Value == "" {
+			if _, ok := vmxData[tc.Key]; ok {
+				t.Fatalf("should not have key: %s", tc.Key)
+			}
+		} else {
+			if vmxData[tc.Key] != tc.Value {
+				t.Fatalf("bad: %s %#v", tc.Key, vmxData[tc.Key])
+			}
+		}
+	}
+}
+
+func TestStepConfigureDevices_serialNone(t *testing.T) {
+	state := testState(t)
+	step := &StepConfigureDevices{
+		Config: &DeviceConfig{Serial: "NONE"},
+	}
+
+	vmxPath := testVMXFile(t)
+	defer os.Remove(vmxPath)
+	if err := ioutil.WriteFile(vmxPath, []byte(testVMXDevices), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	state.Put("vmx_path", vmxPath)
+
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	vmxData, err := ReadVMX(vmxPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, ok := vmxData["serial0.present"]; ok {
+		t.Fatal("should not have serial0")
+	}
+	if _, ok := vmxData["serial1.present"]; !ok {
+		t.Fatal("should have serial1")
+	}
+	if driver := state.Get("driver").(*DriverMock); driver.CreateDiskCalled {
+		t.Fatal("should not create disks")
+	}
+}
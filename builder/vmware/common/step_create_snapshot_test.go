@@ -0,0 +1,56 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/mitchellh/multistep"
+)
+
+func TestStepCreateSnapshot_impl(t *testing.T) {
+	var _ multistep.Step = new(StepCreateSnapshot)
+}
+
+func TestStepCreateSnapshot(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateSnapshot)
+	step.Name = "bar"
+
+	state.Put("vmx_path", "foo")
+
+	driver := state.Get("driver").(*DriverMock)
+
+	// Test the run
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+
+	// Test the driver
+	if !driver.CreateSnapshotCalled {
+		t.Fatal("should've called")
+	}
+	if driver.CreateSnapshotPath != "foo" {
+		t.Fatalf("bad: %#v", driver.CreateSnapshotPath)
+	}
+	if driver.CreateSnapshotName != "bar" {
+		t.Fatalf("bad: %#v", driver.CreateSnapshotName)
+	}
+}
+
+func TestStepCreateSnapshot_noName(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateSnapshot)
+
+	driver := state.Get("driver").(*DriverMock)
+
+	// Test the run
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if driver.CreateSnapshotCalled {
+		t.Fatal("should not call")
+	}
+}
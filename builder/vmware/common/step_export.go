@@ -0,0 +1,128 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+)
+
+// This step exports the VM from the remote host to the local output
+// directory with ovftool, so that the result of the build can be used
+// by post-processors. Local builds have nothing to export.
+//
+// Uses:
+//   driver Driver
+//   ui     packer.Ui
+//
+// Produces:
+//   export_dir string - The local directory the VM was exported to.
+type StepExport struct {
+	Format         string
+	OutputDir      string
+	OVFToolPath    string
+	RemoteHost     string
+	RemotePassword string
+	RemoteUser     string
+	SkipExport     bool
+	VMName         string
+
+	created bool
+}
+
+func (s *StepExport) Run(state multistep.StateBag) multistep.StepAction {
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	if s.Format == "" || s.SkipExport {
+		return multistep.ActionContinue
+	}
+
+	if _, ok := driver.(RemoteDriver); !ok {
+		return multistep.ActionContinue
+	}
+
+	if _, err := os.Stat(s.OutputDir); os.IsNotExist(err) {
+		if err := os.MkdirAll(s.OutputDir, 0755); err != nil {
+			err := fmt.Errorf("Error creating output directory: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		s.created = true
+	}
+
+	source := &url.URL{
+		Scheme: "vi",
+		User:   url.UserPassword(s.RemoteUser, s.RemotePassword),
+		Host:   s.RemoteHost,
+		Path:   s.VMName,
+	}
+
+	args := ovftoolArgs(s.Format, source.String(), s.target())
+
+	// Don't log the password.
+	source.User = url.UserPassword(s.RemoteUser, "****")
+	log.Printf("Executing: %s %s", s.OVFToolPath,
+		strings.Join(ovftoolArgs(s.Format, source.String(), s.target()), " "))
+
+	ui.Say(fmt.Sprintf("Exporting virtual machine to %s...", s.Format))
+	var out bytes.Buffer
+	cmd := exec.Command(s.OVFToolPath, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		err := fmt.Errorf("Error exporting virtual machine: %s\n\n%s", err, out.String())
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	log.Printf("ovftool output: %s", out.String())
+	state.Put("export_dir", s.OutputDir)
+	return multistep.ActionContinue
+}
+
+func (s *StepExport) Cleanup(state multistep.StateBag) {
+	if !s.created {
+		return
+	}
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if cancelled || halted {
+		ui := state.Get("ui").(packer.Ui)
+		ui.Say("Deleting exported files...")
+		if err := os.RemoveAll(s.OutputDir); err != nil {
+			ui.Error(fmt.Sprintf("Error deleting exported files: %s", err))
+		}
+	}
+}
+
+// target returns the path ovftool exports to. VMX exports are put in a
+// directory named after the VM within it.
+func (s *StepExport) target() string {
+	if s.Format == "vmx" {
+		return s.OutputDir
+	}
+
+	return filepath.Join(s.OutputDir, s.VMName+"."+s.Format)
+}
+
+func ovftoolArgs(format, source, target string) []string {
+	return []string{
+		"--noSSLVerify=true",
+		"--skipManifestCheck",
+		"-tt=" + format,
+		source,
+		target,
+	}
+}
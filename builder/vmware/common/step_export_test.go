@@ -0,0 +1,116 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mitchellh/multistep"
+)
+
+func TestStepExport_impl(t *testing.T) {
+	var _ multistep.Step = new(StepExport)
+}
+
+func TestStepExport_skip(t *testing.T) {
+	state := testState(t)
+	state.Put("driver", new(RemoteDriverMock))
+	step := new(StepExport)
+
+	// Test without a format
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("export_dir"); ok {
+		t.Fatal("should NOT export")
+	}
+
+	// Test with skip_export
+	step.Format = "ova"
+	step.SkipExport = true
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("export_dir"); ok {
+		t.Fatal("should NOT export")
+	}
+
+	// Test with a local driver
+	state.Put("driver", new(DriverMock))
+	step.SkipExport = false
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("export_dir"); ok {
+		t.Fatal("should NOT export")
+	}
+}
+
+func TestStepExport_error(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	state := testState(t)
+	state.Put("driver", new(RemoteDriverMock))
+	step := &StepExport{
+		Format:      "ovf",
+		OutputDir:   filepath.Join(td, "output"),
+		OVFToolPath: filepath.Join(td, "ovftool"),
+		VMName:      "foo",
+	}
+
+	// Test the run, ovftool doesn't exist
+	if action := step.Run(state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+	if _, err := os.Stat(step.OutputDir); err != nil {
+		t.Fatalf("should create the output dir: %s", err)
+	}
+
+	// Test the cleanup removes the output dir
+	state.Put(multistep.StateHalted, true)
+	step.Cleanup(state)
+	if _, err := os.Stat(step.OutputDir); !os.IsNotExist(err) {
+		t.Fatalf("should remove the output dir: %s", err)
+	}
+}
+
+func TestStepExport_target(t *testing.T) {
+	step := &StepExport{
+		Format:    "ova",
+		OutputDir: "output",
+		VMName:    "foo",
+	}
+
+	if target := step.target(); target != filepath.Join("output", "foo.ova") {
+		t.Fatalf("bad: %s", target)
+	}
+
+	step.Format = "vmx"
+	if target := step.target(); target != "output" {
+		t.Fatalf("bad: %s", target)
+	}
+}
+
+func TestOvftoolArgs(t *testing.T) {
+	args := ovftoolArgs("ovf", "vi://root@esxi/foo", "output/foo.ovf")
+	expected := []string{
+		"--noSSLVerify=true",
+		"--skipManifestCheck",
+		"-tt=ovf",
+		"vi://root@esxi/foo",
+		"output/foo.ovf",
+	}
+
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad: %#v", args)
+	}
+}
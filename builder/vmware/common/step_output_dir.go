@@ -3,6 +3,8 @@ package common
 import (
 	"fmt"
 	"log"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/mitchellh/multistep"
@@ -60,6 +62,16 @@ func (s *StepOutputDir) Cleanup(state multistep.StateBag) {
 		dir := state.Get("dir").(OutputDir)
 		ui := state.Get("ui").(packer.Ui)
 
+		// Keep the VNC recording of the failed build, if there is one
+		if path, ok := state.GetOk("vnc_record_path"); ok {
+			keep := filepath.Clean(path.(string))
+			if strings.HasPrefix(keep, filepath.Clean(dir.String())+string(filepath.Separator)) {
+				ui.Say("Deleting output directory, except for the VNC recording...")
+				s.removeAllExcept(dir, keep)
+				return
+			}
+		}
+
 		ui.Say("Deleting output directory...")
 		for i := 0; i < 5; i++ {
 			err := dir.RemoveAll()
@@ -72,3 +84,24 @@ func (s *StepOutputDir) Cleanup(state multistep.StateBag) {
 		}
 	}
 }
+
+// removeAllExcept removes every file in the output directory except those
+// that are keep or inside of it.
+func (s *StepOutputDir) removeAllExcept(dir OutputDir, keep string) {
+	files, err := dir.ListFiles()
+	if err != nil {
+		log.Printf("Error listing output dir: %s", err)
+		return
+	}
+
+	for _, file := range files {
+		file = filepath.Clean(file)
+		if file == keep || strings.HasPrefix(file, keep+string(filepath.Separator)) {
+			continue
+		}
+
+		if err := dir.Remove(file); err != nil {
+			log.Printf("Error removing output file: %s", err)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/mitchellh/multistep"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -151,3 +152,36 @@ func TestStepOutputDir_halt(t *testing.T) {
 		t.Fatal("directory should not exist")
 	}
 }
+
+func TestStepOutputDir_keepVNCRecording(t *testing.T) {
+	state := testState(t)
+	step := new(StepOutputDir)
+
+	dir := testOutputDir(t)
+	state.Put("dir", dir)
+
+	// Test the run
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	disk := filepath.Join(dir.dir, "disk.vmdk")
+	recording := filepath.Join(dir.dir, "vnc.fbs")
+	for _, path := range []string{disk, recording} {
+		if err := ioutil.WriteFile(path, []byte("foo"), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	// Test the cleanup keeps the recording
+	state.Put(multistep.StateHalted, true)
+	state.Put("vnc_record_path", recording)
+	step.Cleanup(state)
+	if _, err := os.Stat(recording); err != nil {
+		t.Fatalf("should keep the recording: %s", err)
+	}
+	if _, err := os.Stat(disk); !os.IsNotExist(err) {
+		t.Fatal("should remove the disk")
+	}
+	os.RemoveAll(dir.dir)
+}
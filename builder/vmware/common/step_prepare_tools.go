@@ -15,7 +15,7 @@ type StepPrepareTools struct {
 func (c *StepPrepareTools) Run(state multistep.StateBag) multistep.StepAction {
 	driver := state.Get("driver").(Driver)
 
-	if c.RemoteType == "esx5" {
+	if c.RemoteType != "" {
 		return multistep.ActionContinue
 	}
 
@@ -1,19 +1,20 @@
-package iso
+package common
 
 import (
 	"fmt"
 
 	"github.com/mitchellh/multistep"
-	vmwcommon "github.com/mitchellh/packer/builder/vmware/common"
 	"github.com/mitchellh/packer/packer"
 )
 
 type StepRegister struct {
+	KeepRegistered bool
+
 	registeredPath string
 }
 
 func (s *StepRegister) Run(state multistep.StateBag) multistep.StepAction {
-	driver := state.Get("driver").(vmwcommon.Driver)
+	driver := state.Get("driver").(Driver)
 	ui := state.Get("ui").(packer.Ui)
 	vmxPath := state.Get("vmx_path").(string)
 
@@ -37,9 +38,16 @@ func (s *StepRegister) Cleanup(state multistep.StateBag) {
 		return
 	}
 
-	driver := state.Get("driver").(vmwcommon.Driver)
+	driver := state.Get("driver").(Driver)
 	ui := state.Get("ui").(packer.Ui)
 
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if s.KeepRegistered && !cancelled && !halted {
+		ui.Say("Keeping virtual machine registered (keep_registered)")
+		return
+	}
+
 	if remoteDriver, ok := driver.(RemoteDriver); ok {
 		ui.Say("Unregistering virtual machine...")
 		if err := remoteDriver.Unregister(s.registeredPath); err != nil {
@@ -1,4 +1,4 @@
-package iso
+package common
 
 import (
 	"github.com/mitchellh/multistep"
@@ -63,3 +63,31 @@ func TestStepRegister_remoteDriver(t *testing.T) {
 		t.Fatal("should unregister proper path")
 	}
 }
+
+func TestStepRegister_keepRegistered(t *testing.T) {
+	state := testState(t)
+	step := new(StepRegister)
+	step.KeepRegistered = true
+
+	driver := new(RemoteDriverMock)
+	state.Put("driver", driver)
+	state.Put("vmx_path", "foo")
+
+	// Test the run
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// cleanup after a successful build keeps the VM
+	step.Cleanup(state)
+	if driver.UnregisterCalled {
+		t.Fatal("unregister should not be called")
+	}
+
+	// cleanup after a failed build unregisters it anyways
+	state.Put(multistep.StateHalted, true)
+	step.Cleanup(state)
+	if !driver.UnregisterCalled {
+		t.Fatal("unregister should be called")
+	}
+}
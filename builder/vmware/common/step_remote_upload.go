@@ -1,22 +1,21 @@
-package iso
+package common
 
 import (
 	"fmt"
 	"github.com/mitchellh/multistep"
-	vmwcommon "github.com/mitchellh/packer/builder/vmware/common"
 	"github.com/mitchellh/packer/packer"
 	"log"
 )
 
-// stepRemoteUpload uploads some thing from the state bag to a remote driver
+// StepRemoteUpload uploads some thing from the state bag to a remote driver
 // (if it can) and stores that new remote path into the state bag.
-type stepRemoteUpload struct {
+type StepRemoteUpload struct {
 	Key     string
 	Message string
 }
 
-func (s *stepRemoteUpload) Run(state multistep.StateBag) multistep.StepAction {
-	driver := state.Get("driver").(vmwcommon.Driver)
+func (s *StepRemoteUpload) Run(state multistep.StateBag) multistep.StepAction {
+	driver := state.Get("driver").(Driver)
 	ui := state.Get("ui").(packer.Ui)
 
 	remote, ok := driver.(RemoteDriver)
@@ -43,5 +42,5 @@ func (s *stepRemoteUpload) Run(state multistep.StateBag) multistep.StepAction {
 	return multistep.ActionContinue
 }
 
-func (s *stepRemoteUpload) Cleanup(state multistep.StateBag) {
+func (s *StepRemoteUpload) Cleanup(state multistep.StateBag) {
 }
@@ -21,7 +21,7 @@ type StepUploadTools struct {
 func (c *StepUploadTools) Run(state multistep.StateBag) multistep.StepAction {
 	driver := state.Get("driver").(Driver)
 
-	if c.RemoteType == "esx5" {
+	if c.RemoteType != "" {
 		if err := driver.ToolsInstall(); err != nil {
 			state.Put("error", fmt.Errorf("Couldn't mount VMware tools ISO."))
 		}
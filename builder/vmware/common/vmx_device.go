@@ -0,0 +1,335 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The storage adapters and the number of buses and units each of them
+// supports. Unit 7 of SCSI buses is reserved for the controller itself.
+var vmxStorageAdapters = map[string][2]int{
+	"ide":  {2, 2},
+	"nvme": {4, 15},
+	"sata": {4, 30},
+	"scsi": {4, 16},
+}
+
+// VMXSlot is the position of a storage device, such as "scsi0:1".
+type VMXSlot struct {
+	Adapter string
+	Bus     int
+	Unit    int
+}
+
+// Key returns the prefix of the VMX keys of the device in the slot.
+func (s VMXSlot) Key() string {
+	return fmt.Sprintf("%s%d:%d", s.Adapter, s.Bus, s.Unit)
+}
+
+// VMXDisk is a virtual disk attached to a storage adapter.
+type VMXDisk struct {
+	VMXSlot
+
+	FileName string
+	Mode     string
+}
+
+// VMXCDROM is a CD-ROM drive attached to a storage adapter, either
+// backed by an ISO image or by a drive of the host.
+type VMXCDROM struct {
+	VMXSlot
+
+	DeviceType string
+	FileName   string
+}
+
+// VMXNetworkAdapter is an ethernet adapter, such as "ethernet0".
+type VMXNetworkAdapter struct {
+	Index int
+
+	AddressType    string
+	Address        string
+	ConnectionType string
+	VirtualDev     string
+}
+
+// VMXSerialPort is a serial port, such as "serial0", connected to a file,
+// a device or a named pipe of the host.
+type VMXSerialPort struct {
+	Index int
+
+	FileType     string
+	FileName     string
+	PipeEndpoint string
+	Yield        bool
+}
+
+// VMXParallelPort is a parallel port, such as "parallel0", connected to
+// a file or a device of the host.
+type VMXParallelPort struct {
+	Index int
+
+	FileType      string
+	FileName      string
+	Bidirectional bool
+}
+
+// VMXUSB is the USB configuration of the VM.
+type VMXUSB struct {
+	Present bool
+	EHCI    bool
+	XHCI    bool
+}
+
+// VMXDevices is a typed view of the devices in VMX data as returned by
+// ParseVMX. Only the keys that are modeled here are changed when the
+// devices are encoded again, so any other setting of a device is kept.
+type VMXDevices struct {
+	Disks           []*VMXDisk
+	CDROMs          []*VMXCDROM
+	NetworkAdapters []*VMXNetworkAdapter
+	SerialPorts     []*VMXSerialPort
+	ParallelPorts   []*VMXParallelPort
+	USB             *VMXUSB
+}
+
+var (
+	vmxStorageRe  = regexp.MustCompile(`^(ide|nvme|sata|scsi)(\d+):(\d+)\.present$`)
+	vmxIndexedRe  = regexp.MustCompile(`^(ethernet|parallel|serial)(\d+)\.present$`)
+	vmxCDROMTypes = []string{"atapi-cdrom", "cdrom-image", "cdrom-raw"}
+)
+
+// ParseVMXDevices reads the devices that are present in the given VMX
+// data. The keys are expected to be lowercase, as ParseVMX returns them.
+func ParseVMXDevices(data map[string]string) *VMXDevices {
+	d := new(VMXDevices)
+
+	keys := make([]string, 0, len(data))
+	for k, _ := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if !vmxBool(data[k]) {
+			continue
+		}
+
+		if m := vmxStorageRe.FindStringSubmatch(k); m != nil {
+			bus, _ := strconv.Atoi(m[2])
+			unit, _ := strconv.Atoi(m[3])
+			slot := VMXSlot{Adapter: m[1], Bus: bus, Unit: unit}
+			prefix := slot.Key() + "."
+
+			deviceType := data[prefix+"devicetype"]
+			if isVMXCDROMType(deviceType) {
+				d.CDROMs = append(d.CDROMs, &VMXCDROM{
+					VMXSlot:    slot,
+					DeviceType: deviceType,
+					FileName:   data[prefix+"filename"],
+				})
+			} else {
+				d.Disks = append(d.Disks, &VMXDisk{
+					VMXSlot:  slot,
+					FileName: data[prefix+"filename"],
+					Mode:     data[prefix+"mode"],
+				})
+			}
+
+			continue
+		}
+
+		m := vmxIndexedRe.FindStringSubmatch(k)
+		if m == nil {
+			continue
+		}
+
+		index, _ := strconv.Atoi(m[2])
+		prefix := fmt.Sprintf("%s%d.", m[1], index)
+		switch m[1] {
+		case "ethernet":
+			d.NetworkAdapters = append(d.NetworkAdapters, &VMXNetworkAdapter{
+				Index:          index,
+				AddressType:    data[prefix+"addresstype"],
+				Address:        data[prefix+"address"],
+				ConnectionType: data[prefix+"connectiontype"],
+				VirtualDev:     data[prefix+"virtualdev"],
+			})
+		case "parallel":
+			d.ParallelPorts = append(d.ParallelPorts, &VMXParallelPort{
+				Index:         index,
+				FileType:      data[prefix+"filetype"],
+				FileName:      data[prefix+"filename"],
+				Bidirectional: vmxBool(data[prefix+"bidirectional"]),
+			})
+		case "serial":
+			d.SerialPorts = append(d.SerialPorts, &VMXSerialPort{
+				Index:        index,
+				FileType:     data[prefix+"filetype"],
+				FileName:     data[prefix+"filename"],
+				PipeEndpoint: data[prefix+"pipe.endpoint"],
+				Yield:        vmxBool(data[prefix+"trynorxloss"]),
+			})
+		}
+	}
+
+	if _, ok := data["usb.present"]; ok {
+		d.USB = &VMXUSB{
+			Present: vmxBool(data["usb.present"]),
+			EHCI:    vmxBool(data["ehci.present"]),
+			XHCI:    vmxBool(data["usb_xhci.present"]),
+		}
+	}
+
+	return d
+}
+
+// Encode sets the keys of the devices in the given VMX data. Devices
+// that were removed from the lists must be removed from the data with
+// RemoveVMXDevice.
+func (d *VMXDevices) Encode(data map[string]string) {
+	for _, disk := range d.Disks {
+		prefix := disk.Key() + "."
+		data[prefix+"present"] = "TRUE"
+		setVMXString(data, prefix+"filename", disk.FileName)
+		setVMXString(data, prefix+"mode", disk.Mode)
+	}
+
+	for _, cdrom := range d.CDROMs {
+		prefix := cdrom.Key() + "."
+		data[prefix+"present"] = "TRUE"
+		setVMXString(data, prefix+"devicetype", cdrom.DeviceType)
+		setVMXString(data, prefix+"filename", cdrom.FileName)
+	}
+
+	for _, nic := range d.NetworkAdapters {
+		prefix := fmt.Sprintf("ethernet%d.", nic.Index)
+		data[prefix+"present"] = "TRUE"
+		setVMXString(data, prefix+"addresstype", nic.AddressType)
+		setVMXString(data, prefix+"address", nic.Address)
+		setVMXString(data, prefix+"connectiontype", nic.ConnectionType)
+		setVMXString(data, prefix+"virtualdev", nic.VirtualDev)
+	}
+
+	for _, port := range d.SerialPorts {
+		prefix := fmt.Sprintf("serial%d.", port.Index)
+		data[prefix+"present"] = "TRUE"
+		setVMXString(data, prefix+"filetype", port.FileType)
+		setVMXString(data, prefix+"filename", port.FileName)
+		setVMXString(data, prefix+"pipe.endpoint", port.PipeEndpoint)
+		setVMXBool(data, prefix+"tryNoRxLoss", port.Yield)
+	}
+
+	for _, port := range d.ParallelPorts {
+		prefix := fmt.Sprintf("parallel%d.", port.Index)
+		data[prefix+"present"] = "TRUE"
+		setVMXString(data, prefix+"filetype", port.FileType)
+		setVMXString(data, prefix+"filename", port.FileName)
+		setVMXBool(data, prefix+"bidirectional", port.Bidirectional)
+	}
+
+	if d.USB != nil {
+		data["usb.present"] = vmxBoolString(d.USB.Present)
+		setVMXBool(data, "ehci.present", d.USB.EHCI)
+		setVMXBool(data, "usb_xhci.present", d.USB.XHCI)
+	}
+}
+
+// FreeSlot returns the first slot of the given storage adapter that
+// isn't used by a disk or a CD-ROM drive.
+func (d *VMXDevices) FreeSlot(adapter string) (VMXSlot, error) {
+	limits, ok := vmxStorageAdapters[adapter]
+	if !ok {
+		return VMXSlot{}, fmt.Errorf("Unknown storage adapter: %s", adapter)
+	}
+
+	used := make(map[VMXSlot]bool)
+	for _, disk := range d.Disks {
+		used[disk.VMXSlot] = true
+	}
+	for _, cdrom := range d.CDROMs {
+		used[cdrom.VMXSlot] = true
+	}
+
+	for bus := 0; bus < limits[0]; bus++ {
+		for unit := 0; unit < limits[1]; unit++ {
+			if adapter == "scsi" && unit == 7 {
+				continue
+			}
+
+			slot := VMXSlot{Adapter: adapter, Bus: bus, Unit: unit}
+			if !used[slot] {
+				return slot, nil
+			}
+		}
+	}
+
+	return VMXSlot{}, fmt.Errorf("No free slot on the %s adapters", adapter)
+}
+
+// RemoveVMXDevice removes all the keys of the device with the given
+// prefix, such as "serial0" or "ide1:0", from the VMX data.
+func RemoveVMXDevice(data map[string]string, key string) {
+	prefix := strings.ToLower(key) + "."
+	for k, _ := range data {
+		if strings.HasPrefix(k, prefix) {
+			delete(data, k)
+		}
+	}
+}
+
+// RenameVMXDevice moves all the keys of a device to a new prefix, such
+// as when a CD-ROM drive is moved from "ide1:0" to "sata0:1".
+func RenameVMXDevice(data map[string]string, from, to string) {
+	prefix := strings.ToLower(from) + "."
+	newPrefix := strings.ToLower(to) + "."
+	for k, v := range data {
+		if strings.HasPrefix(k, prefix) {
+			delete(data, k)
+			data[newPrefix+k[len(prefix):]] = v
+		}
+	}
+}
+
+func isVMXCDROMType(deviceType string) bool {
+	deviceType = strings.ToLower(deviceType)
+	for _, t := range vmxCDROMTypes {
+		if deviceType == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+func vmxBool(value string) bool {
+	return strings.ToLower(value) == "true"
+}
+
+func vmxBoolString(value bool) string {
+	if value {
+		return "TRUE"
+	}
+
+	return "FALSE"
+}
+
+// setVMXString sets a key unless the value is empty, in which case the
+// key is left as it is.
+func setVMXString(data map[string]string, key, value string) {
+	if value != "" {
+		data[strings.ToLower(key)] = value
+	}
+}
+
+// setVMXBool sets a boolean key if it is true or already set, so that
+// settings that were never there aren't added.
+func setVMXBool(data map[string]string, key string, value bool) {
+	key = strings.ToLower(key)
+	if _, ok := data[key]; ok || value {
+		data[key] = vmxBoolString(value)
+	}
+}
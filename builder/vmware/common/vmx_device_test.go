@@ -0,0 +1,164 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseVMXDevices(t *testing.T) {
+	d := ParseVMXDevices(ParseVMX(testVMXDevices))
+
+	disks := []*VMXDisk{
+		{VMXSlot: VMXSlot{"scsi", 0, 0}, FileName: "disk.vmdk"},
+		{
+			VMXSlot:  VMXSlot{"scsi", 0, 1},
+			FileName: "data.vmdk",
+			Mode:     "independent-persistent",
+		},
+	}
+	if !reflect.DeepEqual(d.Disks, disks) {
+		t.Fatalf("bad: %#v", d.Disks)
+	}
+
+	cdroms := []*VMXCDROM{
+		{
+			VMXSlot:    VMXSlot{"ide", 1, 0},
+			DeviceType: "cdrom-image",
+			FileName:   "/tmp/packer.iso",
+		},
+	}
+	if !reflect.DeepEqual(d.CDROMs, cdroms) {
+		t.Fatalf("bad: %#v", d.CDROMs)
+	}
+
+	nics := []*VMXNetworkAdapter{
+		{
+			Index:          0,
+			AddressType:    "generated",
+			ConnectionType: "nat",
+			VirtualDev:     "e1000",
+		},
+		{
+			Index:          1,
+			AddressType:    "static",
+			Address:        "00:50:56:00:00:01",
+			ConnectionType: "hostonly",
+		},
+	}
+	if !reflect.DeepEqual(d.NetworkAdapters, nics) {
+		t.Fatalf("bad: %#v", d.NetworkAdapters)
+	}
+
+	serials := []*VMXSerialPort{
+		{Index: 0, FileType: "file", FileName: "/tmp/serial.log"},
+		{
+			Index:        1,
+			FileType:     "pipe",
+			FileName:     "/tmp/serial.pipe",
+			PipeEndpoint: "client",
+			Yield:        true,
+		},
+	}
+	if !reflect.DeepEqual(d.SerialPorts, serials) {
+		t.Fatalf("bad: %#v", d.SerialPorts)
+	}
+
+	parallels := []*VMXParallelPort{
+		{Index: 0, FileType: "device", FileName: "/dev/parport0", Bidirectional: true},
+	}
+	if !reflect.DeepEqual(d.ParallelPorts, parallels) {
+		t.Fatalf("bad: %#v", d.ParallelPorts)
+	}
+
+	usb := &VMXUSB{Present: true, EHCI: true}
+	if !reflect.DeepEqual(d.USB, usb) {
+		t.Fatalf("bad: %#v", d.USB)
+	}
+}
+
+func TestVMXDevicesEncode_roundTrip(t *testing.T) {
+	data := ParseVMX(testVMXDevices)
+	ParseVMXDevices(data).Encode(data)
+
+	if result := EncodeVMX(data); result != testVMXDevices {
+		t.Fatalf("bad:\n%s", result)
+	}
+
+	// Encoding into empty data only writes the modeled keys, which
+	// parse into the same devices again.
+	empty := make(map[string]string)
+	ParseVMXDevices(ParseVMX(testVMXDevices)).Encode(empty)
+
+	expected := ParseVMXDevices(ParseVMX(testVMXDevices))
+	if result := ParseVMXDevices(empty); !reflect.DeepEqual(result, expected) {
+		t.Fatalf("bad:\n%s", EncodeVMX(empty))
+	}
+}
+
+func TestVMXDevicesFreeSlot(t *testing.T) {
+	d := ParseVMXDevices(ParseVMX(testVMXDevices))
+
+	cases := []struct {
+		Adapter string
+		Slot    VMXSlot
+	}{
+		{"scsi", VMXSlot{"scsi", 0, 2}},
+		{"ide", VMXSlot{"ide", 0, 0}},
+		{"sata", VMXSlot{"sata", 0, 0}},
+	}
+
+	for _, tc := range cases {
+		slot, err := d.FreeSlot(tc.Adapter)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if slot != tc.Slot {
+			t.Fatalf("bad: %s %#v", tc.Adapter, slot)
+		}
+	}
+
+	// Unit 7 is reserved for the SCSI controller
+	for i := 2; i < 7; i++ {
+		d.Disks = append(d.Disks, &VMXDisk{VMXSlot: VMXSlot{"scsi", 0, i}})
+	}
+	slot, err := d.FreeSlot("scsi")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if slot.Key() != "scsi0:8" {
+		t.Fatalf("bad: %s", slot.Key())
+	}
+
+	if _, err := d.FreeSlot("floppy"); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestRemoveVMXDevice(t *testing.T) {
+	data := ParseVMX(testVMXDevices)
+	RemoveVMXDevice(data, "serial1")
+
+	for _, k := range []string{"serial1.filename", "serial1.present", "serial1.pipe.endpoint"} {
+		if _, ok := data[k]; ok {
+			t.Fatalf("should not have key: %s", k)
+		}
+	}
+	if _, ok := data["serial0.present"]; !ok {
+		t.Fatal("should have serial0")
+	}
+}
+
+func TestRenameVMXDevice(t *testing.T) {
+	data := ParseVMX(testVMXDevices)
+	RenameVMXDevice(data, "ide1:0", "sata0:1")
+
+	if _, ok := data["ide1:0.present"]; ok {
+		t.Fatal("should not have ide1:0")
+	}
+	if data["sata0:1.filename"] != "/tmp/packer.iso" {
+		t.Fatalf("bad: %#v", data)
+	}
+	if data["sata0:1.devicetype"] != "cdrom-image" {
+		t.Fatalf("bad: %#v", data)
+	}
+}
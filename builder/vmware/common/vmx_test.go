@@ -37,3 +37,53 @@ config.version = "8"
 		t.Errorf("invalid results: %s", result)
 	}
 }
+
+// testVMXDevices is a VMX file with one device of each kind that the
+// typed device layer knows about, as VMware Workstation writes them.
+const testVMXDevices = `.encoding = "UTF-8"
+config.version = "8"
+displayname = "packer"
+ehci.present = "TRUE"
+ethernet0.addresstype = "generated"
+ethernet0.connectiontype = "nat"
+ethernet0.pcislotnumber = "33"
+ethernet0.present = "TRUE"
+ethernet0.virtualdev = "e1000"
+ethernet0.wakeonpcktrcv = "FALSE"
+ethernet1.address = "00:50:56:00:00:01"
+ethernet1.addresstype = "static"
+ethernet1.connectiontype = "hostonly"
+ethernet1.present = "TRUE"
+floppy0.present = "FALSE"
+ide1:0.devicetype = "cdrom-image"
+ide1:0.filename = "/tmp/packer.iso"
+ide1:0.present = "TRUE"
+parallel0.bidirectional = "TRUE"
+parallel0.filename = "/dev/parport0"
+parallel0.filetype = "device"
+parallel0.present = "TRUE"
+scsi0.present = "TRUE"
+scsi0.virtualdev = "lsilogic"
+scsi0:0.filename = "disk.vmdk"
+scsi0:0.present = "TRUE"
+scsi0:0.redo = ""
+scsi0:1.filename = "data.vmdk"
+scsi0:1.mode = "independent-persistent"
+scsi0:1.present = "TRUE"
+serial0.filename = "/tmp/serial.log"
+serial0.filetype = "file"
+serial0.present = "TRUE"
+serial1.filename = "/tmp/serial.pipe"
+serial1.filetype = "pipe"
+serial1.pipe.endpoint = "client"
+serial1.present = "TRUE"
+serial1.trynorxloss = "TRUE"
+usb.present = "TRUE"
+`
+
+func TestParseVMX_roundTrip(t *testing.T) {
+	data := ParseVMX(testVMXDevices)
+	if result := EncodeVMX(data); result != testVMXDevices {
+		t.Fatalf("bad:\n%s", result)
+	}
+}
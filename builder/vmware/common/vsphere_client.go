@@ -0,0 +1,464 @@
+// The methods of the vSphere web services API that the vSphere driver
+// uses are here. The API is SOAP, which both ESXi and vCenter serve, but
+// only a handful of its methods are needed, so they are encoded by hand
+// in place of a proper client library.
+
+package common
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// moRef is a reference to a managed object, such as a VM or a task.
+type moRef struct {
+	Type  string
+	Value string
+}
+
+func (r moRef) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "type"}, Value: r.Type})
+	return e.EncodeElement(r.Value, start)
+}
+
+func (r *moRef) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, a := range start.Attr {
+		// The type of the reference has no namespace, unlike xsi:type.
+		if a.Name.Local == "type" && a.Name.Space == "" {
+			r.Type = a.Value
+		}
+	}
+
+	var value string
+	if err := d.DecodeElement(&value, &start); err != nil {
+		return err
+	}
+	r.Value = strings.TrimSpace(value)
+	return nil
+}
+
+// vimValue is a value of any type as returned by the API, such as the
+// value of a property or the result of a task. It is decoded on demand.
+type vimValue struct {
+	XSIType string
+	RefType string
+
+	inner []byte
+}
+
+func (v *vimValue) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, a := range start.Attr {
+		if a.Name.Local != "type" {
+			continue
+		}
+
+		if a.Name.Space == "" {
+			v.RefType = a.Value
+		} else {
+			v.XSIType = a.Value
+		}
+	}
+
+	var raw struct {
+		Inner []byte `xml:",innerxml"`
+	}
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		return err
+	}
+	v.inner = raw.Inner
+	return nil
+}
+
+// Decode decodes the contents of the value into the given struct.
+func (v *vimValue) Decode(out interface{}) error {
+	data := make([]byte, 0, len(v.inner)+7)
+	data = append(data, "<v>"...)
+	data = append(data, v.inner...)
+	data = append(data, "</v>"...)
+	return xml.Unmarshal(data, out)
+}
+
+// String returns a simple value, such as a string or a number.
+func (v *vimValue) String() string {
+	var s string
+	if err := v.Decode(&s); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(s)
+}
+
+// Ref returns a value that is a managed object reference.
+func (v *vimValue) Ref() moRef {
+	return moRef{Type: v.RefType, Value: v.String()}
+}
+
+// Refs returns a value that is an array of managed object references.
+func (v *vimValue) Refs() []moRef {
+	var refs struct {
+		Refs []moRef `xml:"ManagedObjectReference"`
+	}
+	v.Decode(&refs)
+	return refs.Refs
+}
+
+// vimFault is the error of a failed call.
+type vimFault struct {
+	Method  string
+	Message string
+	Detail  string
+}
+
+func (f *vimFault) Error() string {
+	return fmt.Sprintf("%s: %s", f.Method, f.Message)
+}
+
+// isVimFault checks if the error is a fault of the given type, such as
+// "FileAlreadyExists".
+func isVimFault(err error, name string) bool {
+	if f, ok := err.(*vimFault); ok {
+		return strings.Contains(f.Detail, name)
+	}
+
+	return false
+}
+
+type vimParam struct {
+	Name  string
+	Value interface{}
+}
+
+type vimServiceContent struct {
+	About struct {
+		FullName   string `xml:"fullName"`
+		APIType    string `xml:"apiType"`
+		APIVersion string `xml:"apiVersion"`
+	} `xml:"about"`
+
+	FileManager        moRef `xml:"fileManager"`
+	PropertyCollector  moRef `xml:"propertyCollector"`
+	RootFolder         moRef `xml:"rootFolder"`
+	SessionManager     moRef `xml:"sessionManager"`
+	VirtualDiskManager moRef `xml:"virtualDiskManager"`
+}
+
+type vimPropertyFilterSpec struct {
+	PropSet struct {
+		Type    string   `xml:"type"`
+		PathSet []string `xml:"pathSet"`
+	} `xml:"propSet"`
+	ObjectSet struct {
+		Obj moRef `xml:"obj"`
+	} `xml:"objectSet"`
+}
+
+type vimTaskInfo struct {
+	State  string   `xml:"state"`
+	Result vimValue `xml:"result"`
+	Error  struct {
+		LocalizedMessage string `xml:"localizedMessage"`
+		Fault            struct {
+			Inner string `xml:",innerxml"`
+		} `xml:"fault"`
+	} `xml:"error"`
+}
+
+// vimClient talks to the API of an ESXi or vCenter host. The session
+// is kept in a cookie, which is also used to transfer files to and from
+// the datastores.
+type vimClient struct {
+	URL          *url.URL
+	PollInterval time.Duration
+
+	client  *http.Client
+	content vimServiceContent
+}
+
+func newVimClient(host string, port uint, insecure bool) *vimClient {
+	jar, _ := cookiejar.New(nil)
+
+	return &vimClient{
+		URL: &url.URL{
+			Scheme: "https",
+			Host:   net.JoinHostPort(host, strconv.FormatUint(uint64(port), 10)),
+			Path:   "/sdk",
+		},
+		PollInterval: 1 * time.Second,
+		client: &http.Client{
+			Jar: jar,
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{
+					InsecureSkipVerify: insecure,
+				},
+			},
+		},
+	}
+}
+
+// Login starts a session on the host.
+func (c *vimClient) Login(username, password string) error {
+	var res struct {
+		Returnval vimServiceContent `xml:"returnval"`
+	}
+	instance := moRef{Type: "ServiceInstance", Value: "ServiceInstance"}
+	if err := c.call("RetrieveServiceContent", instance, nil, &res); err != nil {
+		return err
+	}
+	c.content = res.Returnval
+
+	return c.call("Login", c.content.SessionManager, []vimParam{
+		{"userName", username},
+		{"password", password},
+	}, nil)
+}
+
+// Properties returns the given properties of a managed object. Properties
+// that aren't set aren't in the result.
+func (c *vimClient) Properties(obj moRef, props ...string) (map[string]*vimValue, error) {
+	spec := new(vimPropertyFilterSpec)
+	spec.PropSet.Type = obj.Type
+	spec.PropSet.PathSet = props
+	spec.ObjectSet.Obj = obj
+
+	var res struct {
+		Returnval []struct {
+			PropSet []struct {
+				Name string   `xml:"name"`
+				Val  vimValue `xml:"val"`
+			} `xml:"propSet"`
+		} `xml:"returnval"`
+	}
+	err := c.call("RetrieveProperties", c.content.PropertyCollector, []vimParam{
+		{"specSet", spec},
+	}, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]*vimValue)
+	for _, content := range res.Returnval {
+		for i := range content.PropSet {
+			result[content.PropSet[i].Name] = &content.PropSet[i].Val
+		}
+	}
+
+	return result, nil
+}
+
+// Property returns a single property of a managed object, or an error if
+// it isn't set.
+func (c *vimClient) Property(obj moRef, prop string) (*vimValue, error) {
+	props, err := c.Properties(obj, prop)
+	if err != nil {
+		return nil, err
+	}
+
+	v, ok := props[prop]
+	if !ok {
+		return nil, fmt.Errorf("%s %s has no %s", obj.Type, obj.Value, prop)
+	}
+
+	return v, nil
+}
+
+// Call calls a method that returns a result right away.
+func (c *vimClient) Call(method string, this moRef, params []vimParam) (*vimValue, error) {
+	var res struct {
+		Returnval vimValue `xml:"returnval"`
+	}
+	if err := c.call(method, this, params, &res); err != nil {
+		return nil, err
+	}
+
+	return &res.Returnval, nil
+}
+
+// Task calls a method that starts a task and waits for it to complete.
+// The result of the task is returned.
+func (c *vimClient) Task(method string, this moRef, params []vimParam) (*vimValue, error) {
+	var res struct {
+		Returnval moRef `xml:"returnval"`
+	}
+	if err := c.call(method, this, params, &res); err != nil {
+		return nil, err
+	}
+
+	task := res.Returnval
+	log.Printf("Waiting for %s task %s", method, task.Value)
+	for {
+		v, err := c.Property(task, "info")
+		if err != nil {
+			return nil, err
+		}
+
+		var info vimTaskInfo
+		if err := v.Decode(&info); err != nil {
+			return nil, err
+		}
+
+		switch info.State {
+		case "success":
+			return &info.Result, nil
+		case "error":
+			return nil, &vimFault{
+				Method:  method,
+				Message: info.Error.LocalizedMessage,
+				Detail:  info.Error.Fault.Inner,
+			}
+		}
+
+		time.Sleep(c.PollInterval)
+	}
+}
+
+// Upload writes a file on a datastore.
+func (c *vimClient) Upload(dc, ds, path string, r io.Reader, size int64) error {
+	req, err := http.NewRequest("PUT", c.fileURL(dc, ds, path), r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 && resp.StatusCode != 201 {
+		return fmt.Errorf("Error uploading [%s] %s: %s", ds, path, resp.Status)
+	}
+
+	return nil
+}
+
+// Download reads a file on a datastore.
+func (c *vimClient) Download(dc, ds, path string, w io.Writer) error {
+	resp, err := c.client.Get(c.fileURL(dc, ds, path))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("Error downloading [%s] %s: %s", ds, path, resp.Status)
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func (c *vimClient) fileURL(dc, ds, path string) string {
+	u := *c.URL
+	u.Path = "/folder/" + strings.TrimLeft(path, "/")
+	u.RawQuery = url.Values{
+		"dcPath": []string{dc},
+		"dsName": []string{ds},
+	}.Encode()
+	return u.String()
+}
+
+func (c *vimClient) call(method string, this moRef, params []vimParam, result interface{}) error {
+	var body bytes.Buffer
+	body.WriteString(xml.Header)
+	body.WriteString(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"` +
+		` xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"><soapenv:Body>`)
+
+	enc := xml.NewEncoder(&body)
+	start := xml.StartElement{Name: xml.Name{Space: "urn:vim25", Local: method}}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := enc.EncodeElement(this, xml.StartElement{Name: xml.Name{Local: "_this"}}); err != nil {
+		return err
+	}
+	for _, p := range params {
+		err := enc.EncodeElement(p.Value, xml.StartElement{Name: xml.Name{Local: p.Name}})
+		if err != nil {
+			return err
+		}
+	}
+	if err := enc.EncodeToken(start.End()); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+	body.WriteString(`</soapenv:Body></soapenv:Envelope>`)
+
+	req, err := http.NewRequest("POST", c.URL.String(), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", "urn:vim25/5.5")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	// Find the response, or the fault, within the body of the envelope.
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("%s: bad response (%s): %s", method, resp.Status, err)
+		}
+
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch se.Name.Local {
+		case "Envelope", "Header", "Body":
+			continue
+		case "Fault":
+			var fault struct {
+				String string `xml:"faultstring"`
+				Detail struct {
+					Inner string `xml:",innerxml"`
+				} `xml:"detail"`
+			}
+			if err := dec.DecodeElement(&fault, &se); err != nil {
+				return err
+			}
+
+			return &vimFault{
+				Method:  method,
+				Message: fault.String,
+				Detail:  fault.Detail.Inner,
+			}
+		}
+
+		if resp.StatusCode != 200 {
+			return fmt.Errorf("%s: %s", method, resp.Status)
+		}
+
+		if result == nil {
+			return nil
+		}
+
+		return dec.DecodeElement(result, &se)
+	}
+}
@@ -1,33 +0,0 @@
-package iso
-
-import (
-	"fmt"
-)
-
-// Artifact is the result of running the VMware builder, namely a set
-// of files associated with the resulting machine.
-type Artifact struct {
-	builderId string
-	dir       OutputDir
-	f         []string
-}
-
-func (a *Artifact) BuilderId() string {
-	return a.builderId
-}
-
-func (a *Artifact) Files() []string {
-	return a.f
-}
-
-func (*Artifact) Id() string {
-	return "VM"
-}
-
-func (a *Artifact) String() string {
-	return fmt.Sprintf("VM files in directory: %s", a.dir)
-}
-
-func (a *Artifact) Destroy() error {
-	return a.dir.RemoveAll()
-}
@@ -1,14 +0,0 @@
-package iso
-
-import (
-	"github.com/mitchellh/packer/packer"
-	"testing"
-)
-
-func TestArtifact_Impl(t *testing.T) {
-	var raw interface{}
-	raw = &Artifact{}
-	if _, ok := raw.(packer.Artifact); !ok {
-		t.Fatal("Artifact must be a proper artifact")
-	}
-}
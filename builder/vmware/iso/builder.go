@@ -15,8 +15,6 @@ import (
 	"time"
 )
 
-const BuilderIdESX = "mitchellh.vmware-esx"
-
 type Builder struct {
 	config config
 	runner multistep.Runner
@@ -24,7 +22,9 @@ type Builder struct {
 
 type config struct {
 	common.PackerConfig      `mapstructure:",squash"`
+	vmwcommon.DeviceConfig   `mapstructure:",squash"`
 	vmwcommon.DriverConfig   `mapstructure:",squash"`
+	vmwcommon.ExportConfig   `mapstructure:",squash"`
 	vmwcommon.OutputConfig   `mapstructure:",squash"`
 	vmwcommon.RunConfig      `mapstructure:",squash"`
 	vmwcommon.ShutdownConfig `mapstructure:",squash"`
@@ -49,15 +49,12 @@ type config struct {
 	VMXTemplatePath string   `mapstructure:"vmx_template_path"`
 	VNCPortMin      uint     `mapstructure:"vnc_port_min"`
 	VNCPortMax      uint     `mapstructure:"vnc_port_max"`
+	VNCRecord       string   `mapstructure:"vnc_record"`
 
-	RemoteType      string `mapstructure:"remote_type"`
-	RemoteDatastore string `mapstructure:"remote_datastore"`
-	RemoteHost      string `mapstructure:"remote_host"`
-	RemotePort      uint   `mapstructure:"remote_port"`
-	RemoteUser      string `mapstructure:"remote_username"`
-	RemotePassword  string `mapstructure:"remote_password"`
+	RawSingleISOUrl      string `mapstructure:"iso_url"`
+	RawVNCRecordInterval string `mapstructure:"vnc_record_interval"`
 
-	RawSingleISOUrl string `mapstructure:"iso_url"`
+	vncRecordInterval time.Duration
 
 	tpl *packer.ConfigTemplate
 }
@@ -76,7 +73,9 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 
 	// Accumulate any errors
 	errs := common.CheckUnusedConfig(md)
+	errs = packer.MultiErrorAppend(errs, b.config.DeviceConfig.Prepare(b.config.tpl)...)
 	errs = packer.MultiErrorAppend(errs, b.config.DriverConfig.Prepare(b.config.tpl)...)
+	errs = packer.MultiErrorAppend(errs, b.config.ExportConfig.Prepare(b.config.tpl)...)
 	errs = packer.MultiErrorAppend(errs,
 		b.config.OutputConfig.Prepare(b.config.tpl, &b.config.PackerConfig)...)
 	errs = packer.MultiErrorAppend(errs, b.config.RunConfig.Prepare(b.config.tpl)...)
@@ -98,7 +97,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		// Default is growable virtual disk split in 2GB files.
 		b.config.DiskTypeId = "1"
 
-		if b.config.RemoteType == "esx5" {
+		if b.config.RemoteType != "" {
 			b.config.DiskTypeId = "zeroedthick"
 		}
 	}
@@ -131,33 +130,18 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		b.config.VNCPortMax = 6000
 	}
 
-	if b.config.RemoteUser == "" {
-		b.config.RemoteUser = "root"
-	}
-
-	if b.config.RemoteDatastore == "" {
-		b.config.RemoteDatastore = "datastore1"
-	}
-
-	if b.config.RemotePort == 0 {
-		b.config.RemotePort = 22
-	}
-
 	// Errors
 	templates := map[string]*string{
-		"disk_name":         &b.config.DiskName,
-		"guest_os_type":     &b.config.GuestOSType,
-		"http_directory":    &b.config.HTTPDir,
-		"iso_checksum":      &b.config.ISOChecksum,
-		"iso_checksum_type": &b.config.ISOChecksumType,
-		"iso_url":           &b.config.RawSingleISOUrl,
-		"vm_name":           &b.config.VMName,
-		"vmx_template_path": &b.config.VMXTemplatePath,
-		"remote_type":       &b.config.RemoteType,
-		"remote_host":       &b.config.RemoteHost,
-		"remote_datastore":  &b.config.RemoteDatastore,
-		"remote_user":       &b.config.RemoteUser,
-		"remote_password":   &b.config.RemotePassword,
+		"disk_name":           &b.config.DiskName,
+		"guest_os_type":       &b.config.GuestOSType,
+		"http_directory":      &b.config.HTTPDir,
+		"iso_checksum":        &b.config.ISOChecksum,
+		"iso_checksum_type":   &b.config.ISOChecksumType,
+		"iso_url":             &b.config.RawSingleISOUrl,
+		"vm_name":             &b.config.VMName,
+		"vmx_template_path":   &b.config.VMXTemplatePath,
+		"vnc_record":          &b.config.VNCRecord,
+		"vnc_record_interval": &b.config.RawVNCRecordInterval,
 	}
 
 	for n, ptr := range templates {
@@ -252,12 +236,19 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 			errs, fmt.Errorf("vnc_port_min must be less than vnc_port_max"))
 	}
 
-	// Remote configuration validation
-	if b.config.RemoteType != "" {
-		if b.config.RemoteHost == "" {
-			errs = packer.MultiErrorAppend(errs,
-				fmt.Errorf("remote_host must be specified"))
-		}
+	if b.config.RawVNCRecordInterval == "" {
+		b.config.RawVNCRecordInterval = "5s"
+	}
+
+	b.config.vncRecordInterval, err = time.ParseDuration(b.config.RawVNCRecordInterval)
+	if err != nil {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("Failed parsing vnc_record_interval: %s", err))
+	}
+
+	if b.config.Format != "" && b.config.RemoteType == "" {
+		errs = packer.MultiErrorAppend(errs,
+			errors.New("format is only supported for builds with a remote_type."))
 	}
 
 	// Warnings
@@ -273,6 +264,12 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 				"will forcibly halt the virtual machine, which may result in data loss.")
 	}
 
+	if b.config.KeepRegistered && b.config.RemoteType == "" {
+		warnings = append(warnings,
+			"keep_registered only applies to builds with a remote_type. Local VMs\n"+
+				"are always left in the output directory.")
+	}
+
 	if errs != nil && len(errs.Errors) > 0 {
 		return warnings, errs
 	}
@@ -281,15 +278,15 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 }
 
 func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
-	driver, err := NewDriver(&b.config)
+	driver, err := vmwcommon.NewDriver(&b.config.DriverConfig, &b.config.SSHConfig)
 	if err != nil {
 		return nil, fmt.Errorf("Failed creating VMware driver: %s", err)
 	}
 
 	// Determine the output dir implementation
-	var dir OutputDir
+	var dir vmwcommon.OutputDir
 	switch d := driver.(type) {
-	case OutputDir:
+	case vmwcommon.OutputDir:
 		dir = d
 	default:
 		dir = new(vmwcommon.LocalOutputDir)
@@ -326,28 +323,42 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&common.StepCreateFloppy{
 			Files: b.config.FloppyFiles,
 		},
-		&stepRemoteUpload{
+		&vmwcommon.StepRemoteUpload{
 			Key:     "floppy_path",
 			Message: "Uploading Floppy to remote machine...",
 		},
-		&stepRemoteUpload{
+		&vmwcommon.StepRemoteUpload{
 			Key:     "iso_path",
 			Message: "Uploading ISO to remote machine...",
 		},
 		&stepCreateDisk{},
 		&stepCreateVMX{},
+		&vmwcommon.StepConfigureDevices{
+			Config:     &b.config.DeviceConfig,
+			DiskName:   b.config.DiskName,
+			DiskTypeId: b.config.DiskTypeId,
+			OutputDir:  b.config.OutputDir,
+		},
 		&vmwcommon.StepConfigureVMX{
 			CustomData: b.config.VMXData,
 		},
 		&vmwcommon.StepSuppressMessages{},
 		&stepHTTPServer{},
 		&stepConfigureVNC{},
-		&StepRegister{},
+		&vmwcommon.StepRegister{
+			KeepRegistered: b.config.KeepRegistered,
+		},
 		&vmwcommon.StepRun{
 			BootWait:           b.config.BootWait,
 			DurationBeforeStop: 5 * time.Second,
 			Headless:           b.config.Headless,
 		},
+		&common.StepRecordVNC{
+			Path:      b.config.VNCRecord,
+			Interval:  b.config.vncRecordInterval,
+			OutputDir: b.config.OutputDir,
+			Debug:     b.config.PackerDebug,
+		},
 		&stepTypeBootCommand{},
 		&common.StepConnectSSH{
 			SSHAddress:     driver.SSHAddress,
@@ -374,6 +385,19 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&vmwcommon.StepCompactDisk{
 			Skip: b.config.SkipCompaction,
 		},
+		&vmwcommon.StepCreateSnapshot{
+			Name: b.config.SnapshotName,
+		},
+		&vmwcommon.StepExport{
+			Format:         b.config.Format,
+			OutputDir:      b.config.OutputDir,
+			OVFToolPath:    b.config.OVFToolPath,
+			RemoteHost:     b.config.RemoteHost,
+			RemotePassword: b.config.RemotePassword,
+			RemoteUser:     b.config.RemoteUser,
+			SkipExport:     b.config.SkipExport,
+			VMName:         b.config.VMName,
+		},
 	}
 
 	// Run!
@@ -402,23 +426,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		return nil, errors.New("Build was halted.")
 	}
 
-	// Compile the artifact list
-	files, err := state.Get("dir").(OutputDir).ListFiles()
-	if err != nil {
-		return nil, err
-	}
-
-	// Set the proper builder ID
-	builderId := vmwcommon.BuilderId
-	if b.config.RemoteType != "" {
-		builderId = BuilderIdESX
-	}
-
-	return &Artifact{
-		builderId: builderId,
-		dir:       dir,
-		f:         files,
-	}, nil
+	return vmwcommon.NewArtifact(state, &b.config.ExportConfig, b.config.VMName)
 }
 
 func (b *Builder) Cancel() {
@@ -143,6 +143,39 @@ func TestBuilderPrepare_Defaults(t *testing.T) {
 	}
 }
 
+func TestBuilderPrepare_Devices(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Bad
+	config["network_adapter_type"] = "bad"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Good
+	config["disk_additional_size"] = []uint{1024, 2048}
+	config["cdrom_adapter_type"] = "sata"
+	config["network_adapter_type"] = "vmxnet3"
+	config["serial"] = "FILE:/tmp/serial.log"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if len(b.config.AdditionalDiskSize) != 2 {
+		t.Fatalf("bad: %#v", b.config.AdditionalDiskSize)
+	}
+}
+
 func TestBuilderPrepare_DiskSize(t *testing.T) {
 	var b Builder
 	config := testConfig()
@@ -332,6 +365,56 @@ func TestBuilderPrepare_ISOUrl(t *testing.T) {
 	}
 }
 
+func TestBuilderPrepare_Format(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test without a remote_type
+	config["format"] = "ova"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test with a remote_type
+	config["remote_type"] = "esx5"
+	config["remote_host"] = "esxi.example.com"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if b.config.Format != "ova" {
+		t.Fatalf("bad: %s", b.config.Format)
+	}
+}
+
+func TestBuilderPrepare_KeepRegistered(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test without a remote_type
+	config["keep_registered"] = true
+	warns, err := b.Prepare(config)
+	if len(warns) == 0 {
+		t.Fatal("should have warning")
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	if !b.config.KeepRegistered {
+		t.Fatal("should keep registered")
+	}
+}
+
 func TestBuilderPrepare_OutputDir(t *testing.T) {
 	var b Builder
 	config := testConfig()
@@ -503,3 +586,44 @@ func TestBuilderPrepare_VNCPort(t *testing.T) {
 		t.Fatalf("should not have error: %s", err)
 	}
 }
+
+func TestBuilderPrepare_VNCRecordInterval(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Test a default vnc_record_interval
+	delete(config, "vnc_record_interval")
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if b.config.vncRecordInterval != 5*time.Second {
+		t.Fatalf("bad value: %s", b.config.vncRecordInterval)
+	}
+
+	// Test with a bad vnc_record_interval
+	config["vnc_record_interval"] = "this is not good"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Test with a good one
+	config["vnc_record_interval"] = "1s"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
@@ -54,7 +54,9 @@ func (s *stepTypeBootCommand) Run(state multistep.StateBag) multistep.StepAction
 	}
 	defer nc.Close()
 
-	c, err := vnc.Client(nc, &vnc.ClientConfig{Exclusive: true})
+	// Share the connection if the screen is being recorded, since an
+	// exclusive one would disconnect the recorder.
+	c, err := vnc.Client(nc, &vnc.ClientConfig{Exclusive: config.VNCRecord == ""})
 	if err != nil {
 		err := fmt.Errorf("Error handshaking with VNC: %s", err)
 		state.Put("error", err)
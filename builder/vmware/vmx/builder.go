@@ -38,8 +38,14 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		return nil, fmt.Errorf("Failed creating VMware driver: %s", err)
 	}
 
-	// Setup the directory
-	dir := new(vmwcommon.LocalOutputDir)
+	// Determine the output dir implementation
+	var dir vmwcommon.OutputDir
+	switch d := driver.(type) {
+	case vmwcommon.OutputDir:
+		dir = d
+	default:
+		dir = new(vmwcommon.LocalOutputDir)
+	}
 	dir.SetOutputDir(b.config.OutputDir)
 
 	// Set up the state.
@@ -62,15 +68,29 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&common.StepCreateFloppy{
 			Files: b.config.FloppyFiles,
 		},
+		&vmwcommon.StepRemoteUpload{
+			Key:     "floppy_path",
+			Message: "Uploading Floppy to remote machine...",
+		},
 		&StepCloneVMX{
+			Linked:    b.config.Linked,
 			OutputDir: b.config.OutputDir,
 			Path:      b.config.SourcePath,
+			Snapshot:  b.config.SourceSnapshot,
 			VMName:    b.config.VMName,
 		},
+		&vmwcommon.StepConfigureDevices{
+			Config:    &b.config.DeviceConfig,
+			DiskName:  b.config.VMName,
+			OutputDir: b.config.OutputDir,
+		},
 		&vmwcommon.StepConfigureVMX{
 			CustomData: b.config.VMXData,
 		},
 		&vmwcommon.StepSuppressMessages{},
+		&vmwcommon.StepRegister{
+			KeepRegistered: b.config.KeepRegistered,
+		},
 		&vmwcommon.StepRun{
 			BootWait:           b.config.BootWait,
 			DurationBeforeStop: 5 * time.Second,
@@ -101,6 +121,19 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&vmwcommon.StepCompactDisk{
 			Skip: b.config.SkipCompaction,
 		},
+		&vmwcommon.StepCreateSnapshot{
+			Name: b.config.SnapshotName,
+		},
+		&vmwcommon.StepExport{
+			Format:         b.config.Format,
+			OutputDir:      b.config.OutputDir,
+			OVFToolPath:    b.config.OVFToolPath,
+			RemoteHost:     b.config.RemoteHost,
+			RemotePassword: b.config.RemotePassword,
+			RemoteUser:     b.config.RemoteUser,
+			SkipExport:     b.config.SkipExport,
+			VMName:         b.config.VMName,
+		},
 	}
 
 	// Run the steps.
@@ -128,7 +161,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		return nil, errors.New("Build was halted.")
 	}
 
-	return vmwcommon.NewLocalArtifact(b.config.OutputDir)
+	return vmwcommon.NewArtifact(state, &b.config.ExportConfig, b.config.VMName)
 }
 
 // Cancel.
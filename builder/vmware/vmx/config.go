@@ -12,7 +12,9 @@ import (
 // Config is the configuration structure for the builder.
 type Config struct {
 	common.PackerConfig      `mapstructure:",squash"`
+	vmwcommon.DeviceConfig   `mapstructure:",squash"`
 	vmwcommon.DriverConfig   `mapstructure:",squash"`
+	vmwcommon.ExportConfig   `mapstructure:",squash"`
 	vmwcommon.OutputConfig   `mapstructure:",squash"`
 	vmwcommon.RunConfig      `mapstructure:",squash"`
 	vmwcommon.ShutdownConfig `mapstructure:",squash"`
@@ -21,9 +23,10 @@ type Config struct {
 	vmwcommon.VMXConfig      `mapstructure:",squash"`
 
 	FloppyFiles    []string `mapstructure:"floppy_files"`
-	RemoteType     string   `mapstructure:"remote_type"`
+	Linked         bool     `mapstructure:"linked"`
 	SkipCompaction bool     `mapstructure:"skip_compaction"`
 	SourcePath     string   `mapstructure:"source_path"`
+	SourceSnapshot string   `mapstructure:"source_snapshot"`
 	VMName         string   `mapstructure:"vm_name"`
 
 	tpl *packer.ConfigTemplate
@@ -49,7 +52,9 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 
 	// Prepare the errors
 	errs := common.CheckUnusedConfig(md)
+	errs = packer.MultiErrorAppend(errs, c.DeviceConfig.Prepare(c.tpl)...)
 	errs = packer.MultiErrorAppend(errs, c.DriverConfig.Prepare(c.tpl)...)
+	errs = packer.MultiErrorAppend(errs, c.ExportConfig.Prepare(c.tpl)...)
 	errs = packer.MultiErrorAppend(errs, c.OutputConfig.Prepare(c.tpl, &c.PackerConfig)...)
 	errs = packer.MultiErrorAppend(errs, c.RunConfig.Prepare(c.tpl)...)
 	errs = packer.MultiErrorAppend(errs, c.ShutdownConfig.Prepare(c.tpl)...)
@@ -58,9 +63,9 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	errs = packer.MultiErrorAppend(errs, c.VMXConfig.Prepare(c.tpl)...)
 
 	templates := map[string]*string{
-		"remote_type": &c.RemoteType,
-		"source_path": &c.SourcePath,
-		"vm_name":     &c.VMName,
+		"source_path":     &c.SourcePath,
+		"source_snapshot": &c.SourceSnapshot,
+		"vm_name":         &c.VMName,
 	}
 
 	for n, ptr := range templates {
@@ -74,13 +79,24 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 
 	if c.SourcePath == "" {
 		errs = packer.MultiErrorAppend(errs, fmt.Errorf("source_path is required"))
-	} else {
+	} else if c.RemoteType == "" {
+		// For remote builds the source is on the remote host.
 		if _, err := os.Stat(c.SourcePath); err != nil {
 			errs = packer.MultiErrorAppend(errs,
 				fmt.Errorf("source_path is invalid: %s", err))
 		}
 	}
 
+	if c.Linked && c.SourceSnapshot == "" {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("source_snapshot is required for linked clones"))
+	}
+
+	if c.Format != "" && c.RemoteType == "" {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("format is only supported for builds with a remote_type."))
+	}
+
 	// Warnings
 	var warnings []string
 	if c.ShutdownCommand == "" {
@@ -89,6 +105,12 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 				"will forcibly halt the virtual machine, which may result in data loss.")
 	}
 
+	if c.KeepRegistered && c.RemoteType == "" {
+		warnings = append(warnings,
+			"keep_registered only applies to builds with a remote_type. Local VMs\n"+
+				"are always left in the output directory.")
+	}
+
 	// Check for any errors.
 	if errs != nil && len(errs.Errors) > 0 {
 		return nil, warnings, errs
@@ -57,3 +57,88 @@ func TestNewConfig_sourcePath(t *testing.T) {
 	_, warns, errs = NewConfig(c)
 	testConfigOk(t, warns, errs)
 }
+
+func TestNewConfig_devices(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	// Bad
+	c := testConfig(t)
+	c["source_path"] = tf.Name()
+	c["cdrom_adapter_type"] = "floppy"
+	_, warns, errs := NewConfig(c)
+	testConfigErr(t, warns, errs)
+
+	// Good
+	c = testConfig(t)
+	c["source_path"] = tf.Name()
+	c["disk_additional_size"] = []uint{1024}
+	c["cdrom_adapter_type"] = "scsi"
+	c["network_adapter_type"] = "e1000e"
+	c["serial"] = "NONE"
+	_, warns, errs = NewConfig(c)
+	testConfigOk(t, warns, errs)
+}
+
+func TestNewConfig_sourceSnapshot(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	// Bad: linked without a snapshot
+	c := testConfig(t)
+	c["source_path"] = tf.Name()
+	c["linked"] = true
+	_, warns, errs := NewConfig(c)
+	testConfigErr(t, warns, errs)
+
+	c["source_snapshot"] = `{{"base"}}`
+	config, warns, errs := NewConfig(c)
+	testConfigOk(t, warns, errs)
+
+	if !config.Linked {
+		t.Fatal("should be linked")
+	}
+	if config.SourceSnapshot != "base" {
+		t.Fatalf("bad: %s", config.SourceSnapshot)
+	}
+}
+
+func TestNewConfig_remote(t *testing.T) {
+	// Bad: format without a remote_type
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	c := testConfig(t)
+	c["source_path"] = tf.Name()
+	c["format"] = "ovf"
+	_, warns, errs := NewConfig(c)
+	testConfigErr(t, warns, errs)
+
+	// Good: the source is on the remote host
+	c = testConfig(t)
+	c["source_path"] = "/vmfs/volumes/datastore1/base/base.vmx"
+	c["remote_type"] = "esx5"
+	c["remote_host"] = "esxi.example.com"
+	c["format"] = "ovf"
+	config, warns, errs := NewConfig(c)
+	testConfigOk(t, warns, errs)
+
+	if config.RemoteType != "esx5" {
+		t.Fatalf("bad: %s", config.RemoteType)
+	}
+	if config.Format != "ovf" {
+		t.Fatalf("bad: %s", config.Format)
+	}
+}
@@ -2,7 +2,9 @@ package vmx
 
 import (
 	"fmt"
+	"io/ioutil"
 	"log"
+	"os"
 	"path/filepath"
 
 	"github.com/mitchellh/multistep"
@@ -11,10 +13,20 @@ import (
 )
 
 // StepCloneVMX takes a VMX file and clones the VM into the output directory.
+// With Linked set, the clone shares the disks of the source VM instead of
+// copying them. If a Snapshot is given, the clone is made from it.
+//
+// For remote drivers the clone is made on the remote host and its VMX file
+// is downloaded into a temporary directory, so that the following steps
+// can edit it before it is registered.
 type StepCloneVMX struct {
+	Linked    bool
 	OutputDir string
 	Path      string
+	Snapshot  string
 	VMName    string
+
+	tempDir string
 }
 
 func (s *StepCloneVMX) Run(state multistep.StateBag) multistep.StepAction {
@@ -23,14 +35,42 @@ func (s *StepCloneVMX) Run(state multistep.StateBag) multistep.StepAction {
 
 	vmxPath := filepath.Join(s.OutputDir, s.VMName+".vmx")
 
-	ui.Say("Cloning source VM...")
+	if s.Linked {
+		ui.Say("Creating linked clone of source VM...")
+	} else {
+		ui.Say("Cloning source VM...")
+	}
 	log.Printf("Cloning from: %s", s.Path)
 	log.Printf("Cloning to: %s", vmxPath)
-	if err := driver.Clone(vmxPath, s.Path); err != nil {
+	if s.Snapshot != "" {
+		log.Printf("Cloning snapshot: %s", s.Snapshot)
+	}
+	if err := driver.Clone(vmxPath, s.Path, s.Linked, s.Snapshot); err != nil {
 		state.Put("error", err)
 		return multistep.ActionHalt
 	}
 
+	if remoteDriver, ok := driver.(vmwcommon.RemoteDriver); ok {
+		tempDir, err := ioutil.TempDir("", "packer-vmx")
+		if err != nil {
+			err := fmt.Errorf("Error downloading VMX file: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		s.tempDir = tempDir
+
+		localPath := filepath.Join(tempDir, filepath.Base(vmxPath))
+		if err := remoteDriver.Download(vmxPath, localPath); err != nil {
+			err := fmt.Errorf("Error downloading VMX file: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		vmxPath = localPath
+	}
+
 	vmxData, err := vmwcommon.ReadVMX(vmxPath)
 	if err != nil {
 		state.Put("error", err)
@@ -50,4 +90,7 @@ func (s *StepCloneVMX) Run(state multistep.StateBag) multistep.StepAction {
 }
 
 func (s *StepCloneVMX) Cleanup(state multistep.StateBag) {
+	if s.tempDir != "" {
+		os.RemoveAll(s.tempDir)
+	}
 }
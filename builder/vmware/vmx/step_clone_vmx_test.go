@@ -72,3 +72,91 @@ func TestStepCloneVMX(t *testing.T) {
 const testCloneVMX = `
 scsi0:0.fileName = "foo"
 `
+
+func TestStepCloneVMX_linked(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	// Create the dest because the mock driver won't
+	destPath := filepath.Join(td, "foo.vmx")
+	if err := ioutil.WriteFile(destPath, []byte(testCloneVMX), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state := testState(t)
+	step := new(StepCloneVMX)
+	step.Linked = true
+	step.OutputDir = td
+	step.Path = filepath.Join(td, "source.vmx")
+	step.Snapshot = "base"
+	step.VMName = "foo"
+
+	driver := state.Get("driver").(*vmwcommon.DriverMock)
+
+	// Test the run
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+
+	// Test we cloned
+	if !driver.CloneCalled {
+		t.Fatal("should call clone")
+	}
+	if !driver.CloneLinked {
+		t.Fatal("should be a linked clone")
+	}
+	if driver.CloneSnapshot != "base" {
+		t.Fatalf("bad: %s", driver.CloneSnapshot)
+	}
+	if driver.CloneDst != destPath {
+		t.Fatalf("bad: %s", driver.CloneDst)
+	}
+}
+
+func TestStepCloneVMX_remote(t *testing.T) {
+	state := testState(t)
+	driver := &vmwcommon.RemoteDriverMock{DownloadData: testCloneVMX}
+	state.Put("driver", driver)
+
+	step := new(StepCloneVMX)
+	step.OutputDir = "output"
+	step.Path = "/vmfs/volumes/datastore1/source/source.vmx"
+	step.VMName = "foo"
+
+	// Test the run
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+
+	// Test we downloaded the cloned VMX
+	if !driver.DownloadCalled {
+		t.Fatal("should call download")
+	}
+	if driver.DownloadSrc != filepath.Join("output", "foo.vmx") {
+		t.Fatalf("bad: %s", driver.DownloadSrc)
+	}
+
+	// Test that the VMX path is the local copy and the disk is remote
+	vmxPath := state.Get("vmx_path").(string)
+	if vmxPath != driver.DownloadDst {
+		t.Fatalf("bad: %s", vmxPath)
+	}
+	if diskPath := state.Get("full_disk_path"); diskPath != filepath.Join("output", "foo") {
+		t.Fatalf("bad: %#v", diskPath)
+	}
+
+	// Test the cleanup removes the local copy
+	step.Cleanup(state)
+	if _, err := os.Stat(vmxPath); !os.IsNotExist(err) {
+		t.Fatalf("should remove the local copy: %s", err)
+	}
+}
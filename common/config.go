@@ -14,10 +14,14 @@ import (
 )
 
 // ScrubConfig is a helper that returns a string representation of
-// any struct with the given values stripped out.
+// any struct with the given values stripped out. Empty values are ignored.
 func ScrubConfig(target interface{}, values ...string) string {
 	conf := fmt.Sprintf("Config: %+v", target)
 	for _, value := range values {
+		if value == "" {
+			continue
+		}
+
 		conf = strings.Replace(conf, value, "<Filtered>", -1)
 	}
 	return conf
@@ -299,4 +299,11 @@ func TestScrubConfig(t *testing.T) {
 	if conf != expect {
 		t.Fatalf("got %s, expected %s", conf, expect)
 	}
+
+	// Empty values are left alone
+	expect = "Config: {Foo:foo Bar:<Filtered> Inner:{Baz:<Filtered>}}"
+	conf = ScrubConfig(c, "", c.Bar)
+	if conf != expect {
+		t.Fatalf("got %s, expected %s", conf, expect)
+	}
 }
@@ -0,0 +1,116 @@
+package common
+
+import (
+	"fmt"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// StepRecordVNC records the screen of the VM over VNC for the rest of the
+// build, so that a hanging unattended install can be diagnosed. The
+// recording is made in the output directory and only kept there if the
+// build fails.
+//
+// Uses:
+//   ui packer.Ui
+//   vnc_ip string (optional, defaults to 127.0.0.1)
+//   vnc_port uint
+//
+// Produces:
+//   vnc_record_path string - Where the recording was saved, on failure.
+type StepRecordVNC struct {
+	// Path is where the recording goes, relative to OutputDir. If it ends
+	// in ".fbs" the session is recorded, otherwise it is a directory that
+	// PNG screenshots are taken into every Interval. An empty Path
+	// disables recording.
+	Path     string
+	Interval time.Duration
+
+	// OutputDir is the directory the recording is saved to.
+	OutputDir string
+
+	// Debug prints the location of the recording while it happens.
+	Debug bool
+
+	recorder *vncRecorder
+	path     string
+}
+
+func (s *StepRecordVNC) Run(state multistep.StateBag) multistep.StepAction {
+	if s.Path == "" {
+		return multistep.ActionContinue
+	}
+
+	ui := state.Get("ui").(packer.Ui)
+	vncPort := state.Get("vnc_port").(uint)
+
+	vncIp := "127.0.0.1"
+	if raw, ok := state.GetOk("vnc_ip"); ok {
+		vncIp = raw.(string)
+	}
+
+	path := filepath.Join(s.OutputDir, s.Path)
+	if err := prepareVNCRecordPath(path); err != nil {
+		err := fmt.Errorf("Error creating VNC recording directory: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Say("Recording the screen over VNC...")
+	addr := net.JoinHostPort(vncIp, strconv.FormatUint(uint64(vncPort), 10))
+	recorder, err := newVNCRecorder(addr, path, s.Interval)
+	if err != nil {
+		err := fmt.Errorf("Error starting VNC recording: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.recorder = recorder
+	s.path = path
+
+	if s.Debug {
+		ui.Message(fmt.Sprintf("VNC recording in progress: %s", path))
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepRecordVNC) Cleanup(state multistep.StateBag) {
+	if s.path == "" {
+		return
+	}
+
+	if err := s.recorder.Stop(); err != nil {
+		log.Printf("Error stopping VNC recording: %s", err)
+	}
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		if err := os.RemoveAll(s.path); err != nil {
+			log.Printf("Error removing VNC recording: %s", err)
+		}
+		return
+	}
+
+	ui := state.Get("ui").(packer.Ui)
+	ui.Say(fmt.Sprintf("VNC recording saved to: %s", s.path))
+	state.Put("vnc_record_path", s.path)
+}
+
+// prepareVNCRecordPath makes room for a new recording at path, removing
+// any earlier one.
+func prepareVNCRecordPath(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(path)
+}
@@ -0,0 +1,111 @@
+package common
+
+import (
+	"bytes"
+	"github.com/mitchellh/multistep"
+	"github.com/mitchellh/packer/packer"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func testStepRecordVNCState(t *testing.T, addr string) multistep.StateBag {
+	host, rawPort, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	port, err := strconv.ParseUint(rawPort, 10, 0)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	})
+	state.Put("vnc_ip", host)
+	state.Put("vnc_port", uint(port))
+	return state
+}
+
+func TestStepRecordVNC_Impl(t *testing.T) {
+	var _ multistep.Step = new(StepRecordVNC)
+}
+
+func TestStepRecordVNC_disabled(t *testing.T) {
+	state := new(multistep.BasicStateBag)
+	step := new(StepRecordVNC)
+
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	step.Cleanup(state)
+	if _, ok := state.GetOk("vnc_record_path"); ok {
+		t.Fatal("should NOT save a recording")
+	}
+}
+
+func TestStepRecordVNC_success(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	state := testStepRecordVNCState(t, testVNCServer(t))
+	step := &StepRecordVNC{
+		Path:      "vnc.fbs",
+		Interval:  time.Second,
+		OutputDir: td,
+	}
+
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// A successful build throws the recording away
+	step.Cleanup(state)
+	if _, ok := state.GetOk("vnc_record_path"); ok {
+		t.Fatal("should NOT save a recording")
+	}
+	if _, err := os.Stat(filepath.Join(td, "vnc.fbs")); !os.IsNotExist(err) {
+		t.Fatal("should NOT save a recording")
+	}
+}
+
+func TestStepRecordVNC_failure(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	state := testStepRecordVNCState(t, testVNCServer(t))
+	step := &StepRecordVNC{
+		Path:      "vnc.fbs",
+		Interval:  time.Second,
+		OutputDir: filepath.Join(td, "output"),
+	}
+
+	if action := step.Run(state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// A failed build keeps the recording in the output directory
+	state.Put(multistep.StateHalted, true)
+	step.Cleanup(state)
+
+	expected := filepath.Join(td, "output", "vnc.fbs")
+	if path := state.Get("vnc_record_path").(string); path != expected {
+		t.Fatalf("bad: %s", path)
+	}
+	if _, err := os.Stat(expected); err != nil {
+		t.Fatalf("should save the recording: %s", err)
+	}
+}
@@ -0,0 +1,317 @@
+package common
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/mitchellh/go-vnc"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// vncRecorder records the screen of a VNC server, either as an RFB
+// session (.fbs) that can be replayed with tools such as rfbproxy, or as
+// PNG screenshots taken at a fixed interval.
+type vncRecorder struct {
+	conn     net.Conn
+	client   *vnc.ClientConn
+	dir      string
+	interval time.Duration
+
+	fbs *fbsConn
+
+	l     sync.Mutex
+	img   *image.RGBA
+	dirty bool
+	shots int
+
+	doneCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// newVNCRecorder starts recording the VNC server at addr. If path ends in
+// ".fbs" the session is written to that file, otherwise path is a
+// directory that screenshots are written to every interval.
+func newVNCRecorder(addr, path string, interval time.Duration) (*vncRecorder, error) {
+	nc, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &vncRecorder{
+		conn:     nc,
+		interval: interval,
+		doneCh:   make(chan struct{}),
+	}
+
+	if filepath.Ext(path) == ".fbs" {
+		f, err := os.Create(path)
+		if err != nil {
+			nc.Close()
+			return nil, err
+		}
+
+		r.fbs, err = newFBSConn(nc, f)
+		if err != nil {
+			nc.Close()
+			f.Close()
+			return nil, err
+		}
+		nc = r.fbs
+	} else {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			nc.Close()
+			return nil, err
+		}
+		r.dir = path
+	}
+
+	// The connection is shared so that the boot command can still be
+	// typed, and so that a human can connect to watch as well.
+	msgCh := make(chan vnc.ServerMessage, 10)
+	r.client, err = vnc.Client(nc, &vnc.ClientConfig{
+		Exclusive:       false,
+		ServerMessageCh: msgCh,
+	})
+	if err != nil {
+		r.conn.Close()
+		if r.fbs != nil {
+			r.fbs.closeRecording()
+		}
+		return nil, err
+	}
+
+	log.Printf("Recording VNC desktop: %s", r.client.DesktopName)
+	r.img = image.NewRGBA(image.Rect(0, 0,
+		int(r.client.FrameBufferWidth), int(r.client.FrameBufferHeight)))
+
+	r.wg.Add(1)
+	go r.update(msgCh)
+
+	if r.dir != "" {
+		r.wg.Add(1)
+		go r.screenshots()
+	}
+
+	return r, nil
+}
+
+// Stop ends the recording and flushes everything to disk.
+func (r *vncRecorder) Stop() error {
+	close(r.doneCh)
+	r.client.Close()
+	r.wg.Wait()
+
+	if r.fbs != nil {
+		return r.fbs.closeRecording()
+	}
+
+	// Make sure the very last state of the screen is captured
+	return r.screenshot()
+}
+
+// update applies the framebuffer updates sent by the server and asks for
+// the next one each time one arrives.
+func (r *vncRecorder) update(msgCh <-chan vnc.ServerMessage) {
+	defer r.wg.Done()
+
+	width := r.client.FrameBufferWidth
+	height := r.client.FrameBufferHeight
+	if err := r.client.FramebufferUpdateRequest(false, 0, 0, width, height); err != nil {
+		log.Printf("Error requesting VNC framebuffer: %s", err)
+		return
+	}
+
+	for {
+		var msg vnc.ServerMessage
+		select {
+		case msg = <-msgCh:
+		case <-r.doneCh:
+			return
+		}
+
+		update, ok := msg.(*vnc.FramebufferUpdateMessage)
+		if !ok {
+			continue
+		}
+
+		r.l.Lock()
+		for _, rect := range update.Rectangles {
+			r.draw(&rect)
+		}
+		r.dirty = true
+		r.l.Unlock()
+
+		if err := r.client.FramebufferUpdateRequest(true, 0, 0, width, height); err != nil {
+			log.Printf("Error requesting VNC framebuffer: %s", err)
+			return
+		}
+	}
+}
+
+// draw copies the pixels of a raw rectangle into the image.
+func (r *vncRecorder) draw(rect *vnc.Rectangle) {
+	enc, ok := rect.Enc.(*vnc.RawEncoding)
+	if !ok {
+		return
+	}
+
+	pf := r.client.PixelFormat
+	for i, c := range enc.Colors {
+		x := int(rect.X) + i%int(rect.Width)
+		y := int(rect.Y) + i/int(rect.Width)
+
+		var rgba color.RGBA
+		if pf.TrueColor {
+			rgba = color.RGBA{
+				R: scaleColor(c.R, pf.RedMax),
+				G: scaleColor(c.G, pf.GreenMax),
+				B: scaleColor(c.B, pf.BlueMax),
+				A: 0xff,
+			}
+		} else {
+			// Color map entries are 16 bits per channel
+			rgba = color.RGBA{
+				R: uint8(c.R >> 8),
+				G: uint8(c.G >> 8),
+				B: uint8(c.B >> 8),
+				A: 0xff,
+			}
+		}
+
+		r.img.SetRGBA(x, y, rgba)
+	}
+}
+
+// screenshots writes a screenshot every interval until the recording is
+// stopped.
+func (r *vncRecorder) screenshots() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.screenshot(); err != nil {
+				log.Printf("Error writing VNC screenshot: %s", err)
+			}
+		case <-r.doneCh:
+			return
+		}
+	}
+}
+
+// screenshot writes the current screen to the next numbered PNG, unless
+// nothing changed since the last one.
+func (r *vncRecorder) screenshot() error {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	if !r.dirty {
+		return nil
+	}
+
+	r.shots++
+	path := filepath.Join(r.dir, fmt.Sprintf("screenshot-%05d.png", r.shots))
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := png.Encode(f, r.img); err != nil {
+		return err
+	}
+
+	r.dirty = false
+	return nil
+}
+
+func scaleColor(value, max uint16) uint8 {
+	if max == 0 {
+		return 0
+	}
+
+	return uint8(uint32(value) * 0xff / uint32(max))
+}
+
+// fbsConn is a net.Conn that records everything read from the server in
+// the FBS 001.000 format: a header followed by blocks of data, each with
+// its length and the milliseconds since the start of the session.
+type fbsConn struct {
+	net.Conn
+
+	l     sync.Mutex
+	f     *os.File
+	start time.Time
+}
+
+func newFBSConn(c net.Conn, f *os.File) (*fbsConn, error) {
+	if _, err := io.WriteString(f, "FBS 001.000\n"); err != nil {
+		return nil, err
+	}
+
+	return &fbsConn{
+		Conn:  c,
+		f:     f,
+		start: time.Now(),
+	}, nil
+}
+
+func (c *fbsConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		if werr := c.writeBlock(p[:n]); werr != nil {
+			log.Printf("Error writing VNC recording: %s", werr)
+		}
+	}
+
+	return n, err
+}
+
+// closeRecording closes the recording file. Anything read from the
+// connection afterwards is no longer recorded.
+func (c *fbsConn) closeRecording() error {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if c.f == nil {
+		return nil
+	}
+
+	err := c.f.Close()
+	c.f = nil
+	return err
+}
+
+func (c *fbsConn) writeBlock(data []byte) error {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if c.f == nil {
+		return nil
+	}
+
+	if err := binary.Write(c.f, binary.BigEndian, uint32(len(data))); err != nil {
+		return err
+	}
+
+	// Data is padded to a multiple of four bytes
+	padded := make([]byte, (len(data)+3)&^3)
+	copy(padded, data)
+	if _, err := c.f.Write(padded); err != nil {
+		return err
+	}
+
+	millis := uint32(time.Since(c.start) / time.Millisecond)
+	return binary.Write(c.f, binary.BigEndian, millis)
+}
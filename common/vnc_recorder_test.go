@@ -0,0 +1,168 @@
+package common
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testVNCServer starts a VNC server that serves a 4x2 red screen to a
+// single client, and returns its address.
+func testVNCServer(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	go func() {
+		defer l.Close()
+
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+
+		// Handshake: version, no authentication, shared flag
+		io.WriteString(c, "RFB 003.008\n")
+		buf := make([]byte, 12)
+		if _, err := io.ReadFull(c, buf); err != nil {
+			return
+		}
+		c.Write([]byte{1, 1})
+		if _, err := io.ReadFull(c, buf[:1]); err != nil {
+			return
+		}
+		binary.Write(c, binary.BigEndian, uint32(0))
+		if _, err := io.ReadFull(c, buf[:1]); err != nil {
+			return
+		}
+
+		// ServerInit: 32bpp little endian true color
+		testVNCWrite(c,
+			uint16(4), uint16(2),
+			uint8(32), uint8(24), uint8(0), uint8(1),
+			uint16(255), uint16(255), uint16(255),
+			uint8(16), uint8(8), uint8(0),
+			[3]byte{},
+			uint32(4))
+		io.WriteString(c, "test")
+
+		for {
+			var msgType uint8
+			if err := binary.Read(c, binary.BigEndian, &msgType); err != nil {
+				return
+			}
+			if msgType != 3 {
+				return
+			}
+			if _, err := io.ReadFull(c, buf[:9]); err != nil {
+				return
+			}
+
+			// Don't flood the client with incremental updates
+			if buf[0] == 1 {
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			var update bytes.Buffer
+			testVNCWrite(&update,
+				uint8(0), uint8(0), uint16(1),
+				uint16(0), uint16(0), uint16(4), uint16(2), int32(0))
+			for i := 0; i < 8; i++ {
+				update.Write([]byte{0, 0, 0xff, 0})
+			}
+			if _, err := c.Write(update.Bytes()); err != nil {
+				return
+			}
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func testVNCWrite(w io.Writer, data ...interface{}) {
+	for _, v := range data {
+		binary.Write(w, binary.BigEndian, v)
+	}
+}
+
+func TestVNCRecorder_fbs(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	path := filepath.Join(td, "session.fbs")
+	r, err := newVNCRecorder(testVNCServer(t), path, time.Second)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if err := r.Stop(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.HasPrefix(data, []byte("FBS 001.000\n")) {
+		t.Fatalf("bad header: %q", data[:12])
+	}
+
+	// The first block starts with the server's protocol version
+	data = data[12:]
+	length := binary.BigEndian.Uint32(data)
+	if length == 0 || !bytes.HasPrefix(data[4:], []byte("RFB 003.008\n")) {
+		t.Fatalf("bad first block: %q", data)
+	}
+}
+
+func TestVNCRecorder_screenshots(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	path := filepath.Join(td, "screenshots")
+	r, err := newVNCRecorder(testVNCServer(t), path, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if err := r.Stop(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	f, err := os.Open(filepath.Join(path, "screenshot-00001.png"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if img.Bounds().Dx() != 4 || img.Bounds().Dy() != 2 {
+		t.Fatalf("bad size: %s", img.Bounds())
+	}
+
+	red, green, blue, _ := img.At(3, 1).RGBA()
+	if red != 0xffff || green != 0 || blue != 0 {
+		t.Fatalf("bad color: %d %d %d", red, green, blue)
+	}
+}
@@ -44,6 +44,7 @@ const defaultConfig = `
 	},
 
 	"post-processors": {
+		"disk-convert": "packer-post-processor-disk-convert",
 		"vagrant": "packer-post-processor-vagrant",
 		"vsphere": "packer-post-processor-vsphere",
 		"docker-push": "packer-post-processor-docker-push",
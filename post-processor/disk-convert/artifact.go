@@ -0,0 +1,47 @@
+package diskconvert
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Artifact is the set of disk images written by the post-processor.
+type Artifact struct {
+	format string
+	files  []string
+}
+
+func NewArtifact(format string, files []string) *Artifact {
+	return &Artifact{
+		format: format,
+		files:  files,
+	}
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (a *Artifact) Files() []string {
+	return a.files
+}
+
+func (a *Artifact) Id() string {
+	return a.format
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("Converted %s disks: %s",
+		a.format, strings.Join(a.files, ", "))
+}
+
+func (a *Artifact) Destroy() error {
+	for _, path := range a.files {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package diskconvert
+
+import (
+	"github.com/mitchellh/packer/packer"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestArtifact_impl(t *testing.T) {
+	var _ packer.Artifact = new(Artifact)
+}
+
+func TestArtifact_Destroy(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	a := NewArtifact("vhd", []string{tf.Name()})
+	if a.Id() != "vhd" {
+		t.Fatalf("bad: %s", a.Id())
+	}
+	if err := a.Destroy(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := os.Stat(tf.Name()); !os.IsNotExist(err) {
+		t.Fatal("should remove the file")
+	}
+}
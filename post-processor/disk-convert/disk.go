@@ -0,0 +1,107 @@
+package diskconvert
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// extentRe matches the extra extent files of split and flat VMDKs. These
+// are only readable through the descriptor that references them.
+var extentRe = regexp.MustCompile(`-(s|f)\d{3}\.vmdk$|-flat\.vmdk$`)
+
+// diskExtensions maps the extensions disk images are commonly given to
+// their format.
+var diskExtensions = map[string]string{
+	".img":   "raw",
+	".qcow2": "qcow2",
+	".raw":   "raw",
+	".vdi":   "vdi",
+	".vhd":   "vhd",
+	".vhdx":  "vhdx",
+	".vmdk":  "vmdk",
+}
+
+// diskFormat returns the format of the disk image at path, or an empty
+// string if the file doesn't look like a disk image. The header is
+// checked first so that images without a telling extension, such as
+// those of the QEMU builder, are still recognized.
+func diskFormat(path string) (string, error) {
+	if extentRe.MatchString(path) {
+		return "", nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	header := make([]byte, 512)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, []byte("QFI\xfb")):
+		return "qcow2", nil
+	case bytes.HasPrefix(header, []byte("vhdxfile")):
+		return "vhdx", nil
+	case bytes.HasPrefix(header, []byte("KDMV")),
+		bytes.HasPrefix(header, []byte("# Disk DescriptorFile")):
+		return "vmdk", nil
+	case bytes.HasPrefix(header, []byte("conectix")):
+		return "vhd", nil
+	case len(header) >= 0x44 &&
+		binary.LittleEndian.Uint32(header[0x40:0x44]) == 0xbeda107f:
+		return "vdi", nil
+	}
+
+	format := diskExtensions[strings.ToLower(filepath.Ext(path))]
+	if format == "vhd" || format == "raw" {
+		// A fixed VHD carries its footer at the end instead, so a raw
+		// image might actually be one.
+		if info, err := f.Stat(); err == nil && info.Size() >= vhdFooterSize {
+			cookie := make([]byte, 8)
+			if _, err := f.ReadAt(cookie, info.Size()-vhdFooterSize); err == nil &&
+				string(cookie) == "conectix" {
+				return "vhd", nil
+			}
+		}
+	}
+
+	return format, nil
+}
+
+// extensionless reports whether the file at path has no real extension.
+// Names such as "packer-1.0" only have a version after the dot.
+func extensionless(path string) bool {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	return strings.Trim(ext, "0123456789") == ""
+}
+
+// qemuImgFormat returns the name qemu-img uses for the given format.
+func qemuImgFormat(format string) string {
+	if format == "vhd" {
+		return "vpc"
+	}
+
+	return format
+}
+
+// qemuImgArgs returns the arguments to convert src in format "from" to
+// dst in format "to" with qemu-img.
+func qemuImgArgs(from, to, src, dst string) []string {
+	args := []string{"convert"}
+	if from != "" {
+		args = append(args, "-f", qemuImgFormat(from))
+	}
+	args = append(args, "-O", qemuImgFormat(to))
+	return append(args, src, dst)
+}
@@ -0,0 +1,75 @@
+package diskconvert
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiskFormat(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	vdi := make([]byte, 512)
+	copy(vdi[0x40:], []byte{0x7f, 0x10, 0xda, 0xbe})
+
+	cases := []struct {
+		Name     string
+		Data     []byte
+		Expected string
+	}{
+		{"packer-foo", []byte("QFI\xfb\x00\x00\x00\x03"), "qcow2"},
+		{"disk.vhdx", []byte("vhdxfile"), "vhdx"},
+		{"disk.vmdk", []byte("# Disk DescriptorFile\n"), "vmdk"},
+		{"disk-s001.vmdk", []byte("KDMV"), ""},
+		{"disk-flat.vmdk", make([]byte, 512), ""},
+		{"disk.vdi", vdi, "vdi"},
+		{"disk.img", make([]byte, 512), "raw"},
+		{"disk.vmx", []byte("foo = \"bar\""), ""},
+		{"packer-bar", make([]byte, 512), ""},
+	}
+
+	for _, tc := range cases {
+		path := filepath.Join(td, tc.Name)
+		if err := ioutil.WriteFile(path, tc.Data, 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		format, err := diskFormat(path)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if format != tc.Expected {
+			t.Fatalf("bad format for %s: %#v", tc.Name, format)
+		}
+	}
+}
+
+func TestQemuImgArgs(t *testing.T) {
+	args := qemuImgArgs("vhd", "raw", "disk.vhd", "disk.raw")
+	expected := []string{
+		"convert",
+		"-f", "vpc",
+		"-O", "raw",
+		"disk.vhd", "disk.raw",
+	}
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad: %#v", args)
+	}
+
+	args = qemuImgArgs("qcow2", "vhdx", "disk.qcow2", "disk.vhdx")
+	expected = []string{
+		"convert",
+		"-f", "qcow2",
+		"-O", "vhdx",
+		"disk.qcow2", "disk.vhdx",
+	}
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad: %#v", args)
+	}
+}
@@ -0,0 +1,228 @@
+package diskconvert
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/mitchellh/packer/common"
+	"github.com/mitchellh/packer/packer"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const BuilderId = "packer.post-processor.disk-convert"
+
+// qemuBuilderId is the ID of the artifacts of the QEMU builder, whose
+// raw disks are recognized by their lack of an extension.
+const qemuBuilderId = "transcend.qemu"
+
+// The formats disks can be converted to, and the extension each of them
+// is written with.
+var formatExtensions = map[string]string{
+	"qcow2": ".qcow2",
+	"raw":   ".raw",
+	"vhd":   ".vhd",
+	"vhdx":  ".vhdx",
+}
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	Format      string `mapstructure:"format"`
+	OutputDir   string `mapstructure:"output_directory"`
+	QemuImgPath string `mapstructure:"qemu_img_path"`
+
+	tpl *packer.ConfigTemplate
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	md, err := common.DecodeConfig(&p.config, raws...)
+	if err != nil {
+		return err
+	}
+
+	p.config.tpl, err = packer.NewConfigTemplate()
+	if err != nil {
+		return err
+	}
+	p.config.tpl.UserVars = p.config.PackerUserVars
+
+	// Defaults
+	if p.config.QemuImgPath == "" {
+		p.config.QemuImgPath = "qemu-img"
+	}
+
+	// Accumulate any errors
+	errs := common.CheckUnusedConfig(md)
+
+	templates := map[string]*string{
+		"format":           &p.config.Format,
+		"output_directory": &p.config.OutputDir,
+		"qemu_img_path":    &p.config.QemuImgPath,
+	}
+
+	for key, ptr := range templates {
+		*ptr, err = p.config.tpl.Process(*ptr, nil)
+		if err != nil {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("Error processing %s: %s", key, err))
+		}
+	}
+
+	p.config.Format = strings.ToLower(p.config.Format)
+	if _, ok := formatExtensions[p.config.Format]; !ok {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"format must be one of 'qcow2', 'raw', 'vhd' or 'vhdx'"))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (packer.Artifact, bool, error) {
+	qemuImgPath, err := exec.LookPath(p.config.QemuImgPath)
+	if err != nil {
+		log.Printf("qemu-img not found, only raw disks can be converted: %s", err)
+		qemuImgPath = ""
+	}
+
+	files := make([]string, 0, 1)
+	converted, unchanged := false, false
+	for _, path := range artifact.Files() {
+		format, err := diskFormat(path)
+		if err != nil {
+			return nil, false, err
+		}
+
+		// The QEMU builder writes raw disks without an extension, and
+		// raw disks have no header to recognize them by. Other files it
+		// leaves in the output directory, such as UEFI variables, do
+		// have one.
+		if format == "" && artifact.BuilderId() == qemuBuilderId && extensionless(path) {
+			format = "raw"
+		}
+
+		if format == "" {
+			log.Printf("Skipping non-disk file: %s", path)
+			continue
+		}
+
+		if format == p.config.Format {
+			ui.Message(fmt.Sprintf("Disk %s is already in the %s format", path, format))
+			files = append(files, path)
+			unchanged = true
+			continue
+		}
+
+		dst, err := p.target(path)
+		if err != nil {
+			return nil, false, err
+		}
+
+		ui.Message(fmt.Sprintf("Converting %s disk %s to %s",
+			format, path, p.config.Format))
+		if err := p.convert(qemuImgPath, format, path, dst); err != nil {
+			return nil, false, fmt.Errorf("Error converting %s: %s", path, err)
+		}
+
+		files = append(files, dst)
+		converted = true
+	}
+
+	if len(files) == 0 {
+		return nil, false, fmt.Errorf(
+			"No disk images were found in the artifact: %s", artifact)
+	}
+
+	// Nothing needed converting, so the input is already the result.
+	if !converted {
+		return artifact, true, nil
+	}
+
+	// The converted disks sit next to the originals unless an output
+	// directory was given, so destroying the input would take them too.
+	// Disks that were already in the format are the originals themselves.
+	keep := p.config.OutputDir == "" || unchanged
+	return NewArtifact(p.config.Format, files), keep, nil
+}
+
+// target returns the path the converted version of the disk at path is
+// written to.
+func (p *PostProcessor) target(path string) (string, error) {
+	dir := p.config.OutputDir
+	if dir == "" {
+		dir = filepath.Dir(path)
+	} else if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	if _, ok := diskExtensions[filepath.Ext(path)]; !ok {
+		// Extension-less names may contain dots, e.g. "packer-1.0"
+		name = filepath.Base(path)
+	}
+
+	return filepath.Join(dir, name+formatExtensions[p.config.Format]), nil
+}
+
+// convert converts the disk at src in the given format to dst. VHDs are
+// always written by writeFixedVHD, from a raw copy made with qemu-img if
+// the disk isn't raw already, so that they are padded and laid out the
+// same whatever the source. Other formats require qemu-img.
+func (p *PostProcessor) convert(qemuImgPath, format, src, dst string) error {
+	if src == dst {
+		return fmt.Errorf("disk is already in the %s format", format)
+	}
+
+	if p.config.Format == "vhd" && format == "raw" {
+		return writeFixedVHD(dst, src)
+	}
+
+	if qemuImgPath == "" {
+		return fmt.Errorf(
+			"qemu-img is required to convert %s disks to %s", format, p.config.Format)
+	}
+
+	if p.config.Format == "vhd" {
+		raw := dst + ".raw"
+		defer os.Remove(raw)
+
+		if err := qemuImgConvert(qemuImgPath, format, "raw", src, raw); err != nil {
+			return err
+		}
+
+		return writeFixedVHD(dst, raw)
+	}
+
+	return qemuImgConvert(qemuImgPath, format, p.config.Format, src, dst)
+}
+
+// qemuImgConvert converts src in format "from" to dst in format "to"
+// with the qemu-img binary at the given path.
+func qemuImgConvert(qemuImgPath, from, to, src, dst string) error {
+	var stderr bytes.Buffer
+
+	args := qemuImgArgs(from, to, src, dst)
+	log.Printf("Executing qemu-img: %#v", args)
+	cmd := exec.Command(qemuImgPath, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			err = fmt.Errorf("qemu-img error: %s",
+				strings.TrimSpace(stderr.String()))
+		}
+		return err
+	}
+
+	return nil
+}
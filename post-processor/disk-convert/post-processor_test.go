@@ -0,0 +1,278 @@
+package diskconvert
+
+import (
+	"bytes"
+	"github.com/mitchellh/packer/packer"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"format":        "vhd",
+		"qemu_img_path": "/i/dont/exist/qemu-img",
+	}
+}
+
+func testUi() *packer.BasicUi {
+	return &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	}
+}
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packer.PostProcessor = new(PostProcessor)
+}
+
+func TestPostProcessorConfigure_format(t *testing.T) {
+	var p PostProcessor
+
+	// Bad
+	c := testConfig()
+	delete(c, "format")
+	if err := p.Configure(c); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Bad
+	c["format"] = "vmdk"
+	p = PostProcessor{}
+	if err := p.Configure(c); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Good
+	c["format"] = "VHDX"
+	p = PostProcessor{}
+	if err := p.Configure(c); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.Format != "vhdx" {
+		t.Fatalf("bad: %s", p.config.Format)
+	}
+}
+
+func TestPostProcessorPostProcess_raw(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	disk := filepath.Join(td, "disk.raw")
+	if err := ioutil.WriteFile(disk, make([]byte, 4096), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	other := filepath.Join(td, "notes.txt")
+	if err := ioutil.WriteFile(other, []byte("foo"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var p PostProcessor
+	c := testConfig()
+	c["output_directory"] = filepath.Join(td, "output")
+	if err := p.Configure(c); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	artifact := &packer.MockArtifact{
+		FilesValue: []string{disk, other},
+	}
+
+	result, keep, err := p.PostProcess(testUi(), artifact)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if keep {
+		t.Fatal("should not keep the input artifact")
+	}
+
+	expected := filepath.Join(td, "output", "disk.vhd")
+	files := result.Files()
+	if len(files) != 1 || files[0] != expected {
+		t.Fatalf("bad: %#v", files)
+	}
+
+	format, err := diskFormat(expected)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if format != "vhd" {
+		t.Fatalf("bad: %s", format)
+	}
+}
+
+func TestPostProcessorPostProcess_qemu(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	disk := filepath.Join(td, "packer-1.0")
+	vars := filepath.Join(td, "packer-1.0_VARS.fd")
+	for _, path := range []string{disk, vars} {
+		if err := ioutil.WriteFile(path, make([]byte, 4096), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	var p PostProcessor
+	c := testConfig()
+	c["output_directory"] = filepath.Join(td, "output")
+	if err := p.Configure(c); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	artifact := &packer.MockArtifact{
+		BuilderIdValue: qemuBuilderId,
+		FilesValue:     []string{disk, vars},
+	}
+
+	result, _, err := p.PostProcess(testUi(), artifact)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := filepath.Join(td, "output", "packer-1.0.vhd")
+	files := result.Files()
+	if len(files) != 1 || files[0] != expected {
+		t.Fatalf("bad: %#v", files)
+	}
+}
+
+func TestPostProcessorPostProcess_sameFormat(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	disk := filepath.Join(td, "disk.qcow2")
+	if err := ioutil.WriteFile(disk, []byte("QFI\xfb"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var p PostProcessor
+	c := testConfig()
+	c["format"] = "qcow2"
+	if err := p.Configure(c); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	artifact := &packer.MockArtifact{
+		FilesValue: []string{disk},
+	}
+
+	result, keep, err := p.PostProcess(testUi(), artifact)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !keep {
+		t.Fatal("should keep the input artifact")
+	}
+	if result != artifact {
+		t.Fatalf("should pass the artifact through: %#v", result)
+	}
+}
+
+func TestPostProcessorPostProcess_vhdQemuImg(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	// A stand-in for qemu-img that only copies the disk
+	qemuImg := filepath.Join(td, "qemu-img")
+	script := "#!/bin/sh\ncp \"$6\" \"$7\"\n"
+	if err := ioutil.WriteFile(qemuImg, []byte(script), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	disk := filepath.Join(td, "disk.vmdk")
+	data := append([]byte("KDMV"), make([]byte, 4092)...)
+	if err := ioutil.WriteFile(disk, data, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var p PostProcessor
+	c := testConfig()
+	c["qemu_img_path"] = qemuImg
+	if err := p.Configure(c); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	artifact := &packer.MockArtifact{
+		FilesValue: []string{disk},
+	}
+
+	if _, _, err := p.PostProcess(testUi(), artifact); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The VHD is padded like one made from a raw disk, and the
+	// intermediate raw copy is removed
+	info, err := os.Stat(filepath.Join(td, "disk.vhd"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if info.Size() != vhdAlignment+vhdFooterSize {
+		t.Fatalf("bad size: %d", info.Size())
+	}
+	if _, err := os.Stat(filepath.Join(td, "disk.vhd.raw")); err == nil {
+		t.Fatal("raw copy should be removed")
+	}
+}
+
+func TestPostProcessorPostProcess_noQemuImg(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	disk := filepath.Join(td, "disk.qcow2")
+	if err := ioutil.WriteFile(disk, []byte("QFI\xfb"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var p PostProcessor
+	if err := p.Configure(testConfig()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	artifact := &packer.MockArtifact{
+		FilesValue: []string{disk},
+	}
+
+	if _, _, err := p.PostProcess(testUi(), artifact); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestPostProcessorTarget(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(testConfig()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := map[string]string{
+		filepath.Join("output", "disk.vmdk"):    filepath.Join("output", "disk.vhd"),
+		filepath.Join("output", "packer-1.0"):   filepath.Join("output", "packer-1.0.vhd"),
+		filepath.Join("output", "packer.qcow2"): filepath.Join("output", "packer.vhd"),
+	}
+
+	for path, expected := range cases {
+		target, err := p.target(path)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if target != expected {
+			t.Fatalf("bad target for %s: %s", path, target)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package diskconvert
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"os"
+	"time"
+)
+
+// The VHD format is described in Microsoft's "Virtual Hard Disk Image
+// Format Specification". A fixed VHD is the raw disk followed by a single
+// 512 byte footer, which is all that is needed to turn a raw image into
+// something Hyper-V and Azure accept without going through qemu-img.
+
+const (
+	vhdFooterSize = 512
+
+	// Azure only accepts fixed VHDs whose virtual size is a whole
+	// number of megabytes, so raw images are padded up to that.
+	vhdAlignment = 1024 * 1024
+)
+
+// vhdEpoch is the reference point for the footer timestamp.
+var vhdEpoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// vhdFooter is the on-disk layout of a VHD footer.
+type vhdFooter struct {
+	Cookie             [8]byte
+	Features           uint32
+	FileFormatVersion  uint32
+	DataOffset         uint64
+	TimeStamp          uint32
+	CreatorApplication [4]byte
+	CreatorVersion     uint32
+	CreatorHostOS      [4]byte
+	OriginalSize       uint64
+	CurrentSize        uint64
+	DiskGeometry       vhdGeometry
+	DiskType           uint32
+	Checksum           uint32
+	UniqueId           [16]byte
+	SavedState         uint8
+	Reserved           [427]byte
+}
+
+type vhdGeometry struct {
+	Cylinders       uint16
+	Heads           uint8
+	SectorsPerTrack uint8
+}
+
+// newVHDFooter returns the footer for a fixed VHD of the given size.
+func newVHDFooter(size uint64, now time.Time) (*vhdFooter, error) {
+	f := &vhdFooter{
+		Features:          0x2,
+		FileFormatVersion: 0x00010000,
+		DataOffset:        0xFFFFFFFFFFFFFFFF,
+		TimeStamp:         uint32(now.Sub(vhdEpoch) / time.Second),
+		CreatorVersion:    0x00010000,
+		OriginalSize:      size,
+		CurrentSize:       size,
+		DiskGeometry:      vhdChs(size),
+		DiskType:          2,
+	}
+	copy(f.Cookie[:], "conectix")
+	copy(f.CreatorApplication[:], "pckr")
+	copy(f.CreatorHostOS[:], "Wi2k")
+
+	if _, err := io.ReadFull(rand.Reader, f.UniqueId[:]); err != nil {
+		return nil, err
+	}
+
+	f.Checksum = f.checksum()
+	return f, nil
+}
+
+// checksum is the one's complement of the sum of all the bytes in the
+// footer, skipping the checksum field itself.
+func (f *vhdFooter) checksum() uint32 {
+	c := *f
+	c.Checksum = 0
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, &c)
+
+	var sum uint32
+	for _, b := range buf.Bytes() {
+		sum += uint32(b)
+	}
+
+	return ^sum
+}
+
+// vhdChs computes the CHS geometry for a disk of the given size, using
+// the algorithm from appendix A of the specification.
+func vhdChs(size uint64) vhdGeometry {
+	var sectorsPerTrack, heads, cylinderTimesHeads uint64
+
+	totalSectors := size / 512
+	if totalSectors > 65535*16*255 {
+		totalSectors = 65535 * 16 * 255
+	}
+
+	if totalSectors >= 65535*16*63 {
+		sectorsPerTrack = 255
+		heads = 16
+		cylinderTimesHeads = totalSectors / sectorsPerTrack
+	} else {
+		sectorsPerTrack = 17
+		cylinderTimesHeads = totalSectors / sectorsPerTrack
+
+		heads = (cylinderTimesHeads + 1023) / 1024
+		if heads < 4 {
+			heads = 4
+		}
+
+		if cylinderTimesHeads >= heads*1024 || heads > 16 {
+			sectorsPerTrack = 31
+			heads = 16
+			cylinderTimesHeads = totalSectors / sectorsPerTrack
+		}
+
+		if cylinderTimesHeads >= heads*1024 {
+			sectorsPerTrack = 63
+			heads = 16
+			cylinderTimesHeads = totalSectors / sectorsPerTrack
+		}
+	}
+
+	return vhdGeometry{
+		Cylinders:       uint16(cylinderTimesHeads / heads),
+		Heads:           uint8(heads),
+		SectorsPerTrack: uint8(sectorsPerTrack),
+	}
+}
+
+// writeFixedVHD writes the raw disk image at src as a fixed VHD to dst.
+func writeFixedVHD(dst, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	n, err := io.Copy(out, in)
+	if err != nil {
+		return err
+	}
+
+	size := uint64(n)
+	if rem := size % vhdAlignment; rem != 0 {
+		size += vhdAlignment - rem
+	}
+
+	// Pad the data out to the aligned size. Seeking leaves a hole that
+	// reads back as zeroes without having to write them.
+	if _, err := out.Seek(int64(size), os.SEEK_SET); err != nil {
+		return err
+	}
+
+	footer, err := newVHDFooter(size, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err := binary.Write(out, binary.BigEndian, footer); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,101 @@
+package diskconvert
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVhdChs(t *testing.T) {
+	cases := map[uint64]vhdGeometry{
+		// 10 MB
+		10 * 1024 * 1024: {Cylinders: 301, Heads: 4, SectorsPerTrack: 17},
+		// 1 GB
+		1024 * 1024 * 1024: {Cylinders: 2080, Heads: 16, SectorsPerTrack: 63},
+		// 128 GB
+		128 * 1024 * 1024 * 1024: {Cylinders: 65535, Heads: 16, SectorsPerTrack: 255},
+	}
+
+	for size, expected := range cases {
+		if chs := vhdChs(size); chs != expected {
+			t.Fatalf("bad geometry for %d: %#v", size, chs)
+		}
+	}
+}
+
+func TestNewVHDFooter(t *testing.T) {
+	now := vhdEpoch.Add(10 * time.Second)
+	f, err := newVHDFooter(vhdAlignment, now)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.BigEndian, f); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	data := buf.Bytes()
+	if len(data) != vhdFooterSize {
+		t.Fatalf("bad footer size: %d", len(data))
+	}
+	if string(data[0:8]) != "conectix" {
+		t.Fatalf("bad cookie: %q", data[0:8])
+	}
+	if f.TimeStamp != 10 {
+		t.Fatalf("bad timestamp: %d", f.TimeStamp)
+	}
+	if f.DiskType != 2 {
+		t.Fatalf("bad disk type: %d", f.DiskType)
+	}
+
+	// The checksum covers every byte but its own
+	var sum uint32
+	for i, b := range data {
+		if i >= 64 && i < 68 {
+			continue
+		}
+		sum += uint32(b)
+	}
+	if ^sum != f.Checksum {
+		t.Fatalf("bad checksum: %x", f.Checksum)
+	}
+}
+
+func TestWriteFixedVHD(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	src := filepath.Join(td, "disk.raw")
+	data := bytes.Repeat([]byte("packer"), 1000)
+	if err := ioutil.WriteFile(src, data, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	dst := filepath.Join(td, "disk.vhd")
+	if err := writeFixedVHD(dst, src); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	result, err := ioutil.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(result) != vhdAlignment+vhdFooterSize {
+		t.Fatalf("bad size: %d", len(result))
+	}
+	if !bytes.Equal(result[:len(data)], data) {
+		t.Fatal("data should be copied")
+	}
+	if string(result[vhdAlignment:vhdAlignment+8]) != "conectix" {
+		t.Fatal("should have a footer")
+	}
+}
@@ -44,7 +44,9 @@ func (p *ParallelsProvider) Process(ui packer.Ui, artifact packer.Artifact, dir
 		if matches != nil {
 			pvmPath = filepath.FromSlash(matches[2])
 		} else {
-			continue // Just copy a pvm
+			// Just copy a pvm. A packed .pvmp archive is skipped, Vagrant
+			// uses the unpacked .pvm the builder leaves next to it.
+			continue
 		}
 		dstPath := filepath.Join(dir, pvmPath)
 
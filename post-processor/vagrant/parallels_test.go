@@ -1,9 +1,54 @@
 package vagrant
 
 import (
+	"github.com/mitchellh/packer/packer"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
 func TestParallelsProvider_impl(t *testing.T) {
 	var _ Provider = new(ParallelsProvider)
 }
+
+func TestParallelsProvider_packed(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	src, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(src)
+
+	pvmp := filepath.Join(src, "foo.pvmp")
+	config := filepath.Join(src, "foo.pvm", "config.pvs")
+	if err := os.MkdirAll(filepath.Dir(config), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, path := range []string{pvmp, config} {
+		if err := ioutil.WriteFile(path, []byte("foo"), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	artifact := &packer.MockArtifact{
+		FilesValue: []string{pvmp, config},
+	}
+
+	p := new(ParallelsProvider)
+	if _, _, err := p.Process(testUi(), artifact, td); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(td, "foo.pvm", "config.pvs")); err != nil {
+		t.Fatalf("should copy the pvm: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(td, "foo.pvmp")); err == nil {
+		t.Fatal("should not copy the pvmp")
+	}
+}